@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ABTest is a debug mode that alternates two strategies on a single seat:
+// the seat's even turns are played by A and its odd turns by B. Every
+// decision is logged together with what the other strategy would have done,
+// so disagreements can be grouped by decision type.
+type ABTest struct {
+	Seat  int
+	A, B  Strategy
+	turns int
+	log   io.WriteCloser
+}
+
+// newABTest parses a spec of the form "A,B" (e.g. "greedy,random") and opens
+// the decision log.
+func newABTest(spec string, seat int, logFile string) (*ABTest, error) {
+	parts := strings.Split(spec, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("expected two comma separated strategies, got %q", spec)
+	}
+	a, err := lookupStrategy(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := lookupStrategy(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	f, err := createFile(logFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ABTest{Seat: seat, A: a, B: b, log: f}, nil
+}
+
+func (ab *ABTest) current() Strategy {
+	if ab.turns%2 == 0 {
+		return ab.A
+	}
+	return ab.B
+}
+
+func (ab *ABTest) other() Strategy {
+	if ab.turns%2 == 0 {
+		return ab.B
+	}
+	return ab.A
+}
+
+func (ab *ABTest) label(s Strategy) string {
+	if s == ab.A {
+		return "A=" + s.Name()
+	}
+	return "B=" + s.Name()
+}
+
+func (ab *ABTest) Close() error {
+	return ab.log.Close()
+}
+
+func describeMove(m Move) string {
+	switch m.Type {
+	case Place:
+		return fmt.Sprintf("place %s at %s score %.2f", tileLabel(m.Tile), m.Cell, m.Score)
+	case Swap:
+		return fmt.Sprintf("swap %s for %s at %s score %.2f", tileLabel(m.Tile), tileLabel(m.OldTile), m.Cell, m.Score)
+	case Discard:
+		return fmt.Sprintf("discard %s", tileLabel(m.Tile))
+	}
+	return fmt.Sprintf("draw %s", tileLabel(m.Tile))
+}
+
+func sameDecision(a, b Move) bool {
+	if a.Type != b.Type || a.Tile != b.Tile {
+		return false
+	}
+	if a.Cell == nil || b.Cell == nil {
+		return a.Cell == b.Cell
+	}
+	return *a.Cell == *b.Cell
+}
+
+// abLogTable records the pile-or-table decision of the A/B seat.
+func (state *GameState) abLogTable(seat int, move Move, fromTable bool) {
+	ab := state.ABTest
+	if ab == nil || ab.Seat != seat {
+		return
+	}
+	alt, altFromTable := ab.other().PickFromTable(state)
+	chosen, would := "pile", "pile"
+	if fromTable {
+		chosen = "table: " + describeMove(move)
+	}
+	if altFromTable {
+		would = "table: " + describeMove(alt)
+	}
+	agree := fromTable == altFromTable && (!fromTable || sameDecision(move, alt))
+	ab.write("draw", agree, chosen, would)
+}
+
+// abLogPlace records what the A/B seat did with the tile it is holding.
+func (state *GameState) abLogPlace(seat, tile int, move Move, ok bool) {
+	ab := state.ABTest
+	if ab == nil || ab.Seat != seat {
+		return
+	}
+	alt, altOk := ab.other().ChooseMove(state, tile)
+	chosen, would := "discard "+tileLabel(tile), "discard "+tileLabel(tile)
+	if ok {
+		chosen = describeMove(move)
+	}
+	if altOk {
+		would = describeMove(alt)
+	}
+	kind := "discard"
+	if ok {
+		kind = map[MoveType]string{Place: "place", Swap: "swap"}[move.Type]
+	}
+	ab.write(kind, ok == altOk && (!ok || sameDecision(move, alt)), chosen, would)
+}
+
+func (ab *ABTest) write(kind string, agree bool, chosen, would string) {
+	verdict := "agree"
+	if !agree {
+		verdict = "DIFFER"
+	}
+	fmt.Fprintf(ab.log, "turn %d\t%s\t%s\t%s\tchose: %s\t%s would: %s\n",
+		ab.turns, ab.label(ab.current()), kind, verdict, chosen, ab.other().Name(), would)
+}
+
+// abEndTurn advances the A/B seat's turn counter once its turn is over.
+func (state *GameState) abEndTurn(seat int) {
+	if state.ABTest != nil && state.ABTest.Seat == seat {
+		state.ABTest.turns++
+	}
+}
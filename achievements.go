@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Achievement is a noteworthy accomplishment a human seat can earn in a
+// finished game. Earned ones are kept in the player's profile across games.
+type Achievement struct {
+	ID    string
+	Name  string
+	About string
+	// earned reports whether seat earned it in the game winner decided.
+	earned func(state *GameState, seat, winner int) bool
+}
+
+// earlyFinishPile is how many tiles must still be in the pile for an early
+// finish.
+const earlyFinishPile = 5
+
+var achievements = []Achievement{
+	{"first-win", "First win", "Win a game", func(state *GameState, seat, winner int) bool {
+		return state.won(seat, winner)
+	}},
+	{"no-table", "Self-made", "Win without taking a tile from the table", func(state *GameState, seat, winner int) bool {
+		return state.won(seat, winner) && state.Boards[seat].TableTakes == 0
+	}},
+	{"straight", "Straight", "Complete a row of consecutive numbers", func(state *GameState, seat, winner int) bool {
+		return consecutiveRow(state.Boards[seat])
+	}},
+	{"early-finish", "Early finish", fmt.Sprintf("Win with %d or more tiles left in the pile", earlyFinishPile), func(state *GameState, seat, winner int) bool {
+		return state.won(seat, winner) && len(state.Draw) >= earlyFinishPile
+	}},
+	{"puzzler", "Puzzler", "Solve a solo puzzle", func(state *GameState, seat, winner int) bool {
+		return state.Puzzle != nil && state.Boards[seat].IsFull()
+	}},
+}
+
+// won reports whether seat won, alone or with its team.
+func (state *GameState) won(seat, winner int) bool {
+	return winner >= 0 && (seat == winner || state.teammates(seat, winner))
+}
+
+// consecutiveRow reports a full row whose tiles climb by exactly one.
+func consecutiveRow(board *Board) bool {
+	for r := 0; r < BoardSize; r++ {
+		ok := true
+		for c := 1; c < BoardSize && ok; c++ {
+			ok = isTile(board.Grid[r][c-1]) && board.Grid[r][c] == board.Grid[r][c-1]+1
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Earned records when an achievement was first earned and how often since.
+type Earned struct {
+	First time.Time `json:"first"`
+	Count int       `json:"count"`
+}
+
+// Profile is the local player's record across games, or a named player's
+// when Name is set.
+type Profile struct {
+	Name         string            `json:"name,omitempty"`
+	Games        int               `json:"games"`
+	Wins         int               `json:"wins"`
+	Achievements map[string]Earned `json:"achievements"`
+	Campaign     int               `json:"campaign,omitempty"` // campaign stages cleared
+
+	EmptyCells int               `json:"empty_cells,omitempty"` // left on the board, over all games
+	Variants   map[string]int    `json:"variants,omitempty"`    // games played under each rule
+	Settings   map[string]string `json:"settings,omitempty"`    // the last game's setup, by flag name
+	LastPlayed time.Time         `json:"last_played,omitempty"`
+	Rating     float64           `json:"rating,omitempty"` // Elo, after RatedGames games
+	RatedGames int               `json:"rated_games,omitempty"`
+}
+
+// defaultProfilePath keeps the profile in the user's config directory,
+// falling back to the working directory.
+func defaultProfilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "unlucky_profile.json"
+	}
+	return filepath.Join(dir, "unlucky_numbers", "profile.json")
+}
+
+// loadProfile reads the profile at path; a missing file is a new profile.
+func loadProfile(path string) (*Profile, error) {
+	p := &Profile{Achievements: map[string]Earned{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if p.Achievements == nil {
+		p.Achievements = map[string]Earned{}
+	}
+	return p, nil
+}
+
+func (p *Profile) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// record adds a finished game to the profile, crediting what the human
+// seats earned, and returns the achievements earned for the first time.
+func (p *Profile) record(state *GameState, winner int, now time.Time) []Achievement {
+	humans := false
+	for seat, b := range state.Boards {
+		if !b.IsAi {
+			humans = true
+			if state.won(seat, winner) {
+				p.Wins++
+				break
+			}
+		}
+	}
+	if !humans {
+		return nil
+	}
+	p.Games++
+	var unlocked []Achievement
+	for _, a := range achievements {
+		for seat, b := range state.Boards {
+			if b.IsAi || !a.earned(state, seat, winner) {
+				continue
+			}
+			if p.earn(a, now) {
+				unlocked = append(unlocked, a)
+			}
+			break
+		}
+	}
+	return unlocked
+}
+
+// earn credits an achievement, reporting whether it is the first time.
+func (p *Profile) earn(a Achievement, now time.Time) bool {
+	e := p.Achievements[a.ID]
+	first := e.Count == 0
+	if first {
+		e.First = now
+	}
+	e.Count++
+	p.Achievements[a.ID] = e
+	return first
+}
+
+// updateProfile records the finished game in the profile file and
+// announces newly unlocked achievements.
+func (state *GameState) updateProfile(winner int) {
+	p, err := loadProfile(state.ProfilePath)
+	if err != nil {
+		fmt.Println("Failed to read profile:", err)
+		return
+	}
+	for _, a := range p.record(state, winner, time.Now()) {
+		fmt.Printf(term.text("Achievement unlocked: %s — %s\n"), a.Name, a.About)
+	}
+	if err := p.save(state.ProfilePath); err != nil {
+		fmt.Println("Failed to save profile:", err)
+	}
+}
+
+// printProfile shows the record and every achievement, earned or not.
+func printProfile(w io.Writer, p *Profile) {
+	fmt.Fprintf(w, "Games: %d  Wins: %d  Campaign: %d of %d stages\n", p.Games, p.Wins, p.Campaign, len(campaign))
+	earned := 0
+	for _, a := range achievements {
+		mark, note := "[ ]", ""
+		if e, ok := p.Achievements[a.ID]; ok && e.Count > 0 {
+			earned++
+			mark, note = "[x]", fmt.Sprintf(" (x%d, first %s)", e.Count, e.First.Format("2006-01-02"))
+		}
+		fmt.Fprintf(w, "%s %-13s %s%s\n", mark, a.Name, a.About, note)
+	}
+	fmt.Fprintf(w, "%d of %d achievements earned.\n", earned, len(achievements))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A study of a position can be annotated as it goes: note <text> writes a
+// note on the turn, and eval keeps the engine's best moves for the tile in
+// hand with their scores. The notes are kept in JSON saves, so the study
+// reopens with them, and in the move log, where replay shows each one at
+// the turn it was written on; notes lists them.
+
+// annotateCandidates is how many of the best moves eval keeps.
+const annotateCandidates = 5
+
+// Annotation is a note or the candidate moves kept on a turn.
+type Annotation struct {
+	Turn       int         `json:"turn"` // from 1, as the display counts
+	Seat       int         `json:"seat"`
+	Note       string      `json:"note,omitempty"`
+	Tile       int         `json:"tile,omitempty"` // the tile the candidates are for
+	Candidates []Candidate `json:"candidates,omitempty"`
+}
+
+// Candidate is a move eval kept, with the engine's score for it.
+type Candidate struct {
+	Move  SavedMove `json:"move"`
+	Score float64   `json:"score"`
+}
+
+// annotate keeps a on the current turn, writes it to the move log and
+// returns it.
+func (state *GameState) annotate(a Annotation) Annotation {
+	a.Turn, a.Seat = state.Turn+1, state.Current
+	state.Annotations = append(state.Annotations, a)
+	state.logEvent(LoggedMove{Seat: a.Seat, Type: "note", Annotation: &a})
+	return a
+}
+
+// annotateNote keeps a note on the current turn.
+func (state *GameState) annotateNote(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		fmt.Println(tr("Write the note after the word note."))
+		return
+	}
+	state.annotate(Annotation{Note: text})
+	fmt.Printf(tr("Note kept on turn %d.\n"), state.Turn+1)
+}
+
+// annotateEval keeps the best moves for tile with their scores.
+func (state *GameState) annotateEval(tile int) {
+	recs := state.bestMoves(tile)
+	if len(recs) == 0 {
+		fmt.Println(tr("No legal placements found."))
+		return
+	}
+	a := Annotation{Tile: tile}
+	for _, m := range recs[:min(len(recs), annotateCandidates)] {
+		a.Candidates = append(a.Candidates, Candidate{Move: savedMove(state.Current, m), Score: m.Score})
+	}
+	fmt.Print(state.describeAnnotation(state.annotate(a)))
+}
+
+// describeAnnotation tells an annotation in a line or two, each ending in a
+// newline.
+func (state *GameState) describeAnnotation(a Annotation) string {
+	var b strings.Builder
+	who := fmt.Sprintf("%d", a.Seat)
+	if a.Seat >= 0 && a.Seat < len(state.Boards) {
+		who = state.seatLabel(a.Seat)
+	}
+	if a.Note != "" {
+		fmt.Fprintf(&b, tr("Note on turn %d (%s): %s\n"), a.Turn, who, a.Note)
+	}
+	if len(a.Candidates) > 0 {
+		parts := make([]string, len(a.Candidates))
+		for i, c := range a.Candidates {
+			where := ""
+			if c.Move.Cell != nil {
+				where = " " + c.Move.Cell.String()
+			}
+			parts[i] = fmt.Sprintf("%s%s %.2f", tr(strings.ToUpper(c.Move.Type[:1])+c.Move.Type[1:]), where, c.Score)
+		}
+		fmt.Fprintf(&b, tr("Candidates for %s on turn %d (%s): %s\n"), tileLabel(a.Tile), a.Turn, who, strings.Join(parts, ", "))
+	}
+	return b.String()
+}
+
+// printAnnotations lists every annotation of the game.
+func (state *GameState) printAnnotations(w io.Writer) {
+	if len(state.Annotations) == 0 {
+		fmt.Fprintln(w, tr("No notes yet."))
+		return
+	}
+	for _, a := range state.Annotations {
+		fmt.Fprint(w, state.describeAnnotation(a))
+	}
+}
+
+// annotationCommand runs note, notes or, with a tile in hand, eval, and
+// reports whether line was one of them. tile is 0 before the draw.
+func (state *GameState) annotationCommand(line string, tile int) bool {
+	word, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	switch strings.ToLower(word) {
+	case "note":
+		state.annotateNote(rest)
+	case "notes":
+		state.printAnnotations(os.Stdout)
+	case "eval":
+		if tile == 0 {
+			fmt.Println(tr("Draw a tile first; eval keeps the best moves for it."))
+			return true
+		}
+		state.annotateEval(tile)
+	default:
+		return false
+	}
+	return true
+}
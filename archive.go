@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// openingMoves is how many moves per seat the archive keeps as the opening.
+const openingMoves = 3
+
+// ArchivedGame is one finished game in the archive, a JSON-lines file.
+type ArchivedGame struct {
+	Time     time.Time      `json:"time"`
+	Rules    string         `json:"rules"` // rules configuration, see rulesTag
+	Seats    []string       `json:"seats"` // "human" or the computer's strategy
+	Names    []string       `json:"names,omitempty"`
+	Winner   int            `json:"winner"`
+	Openings []ArchivedMove `json:"openings"`
+}
+
+// ArchivedMove is one opening move: the seat's Nth move (from 1).
+type ArchivedMove struct {
+	Seat int    `json:"seat"`
+	N    int    `json:"n"`
+	Type string `json:"type"`
+	Tile int    `json:"tile"`
+	Cell *Cell  `json:"cell,omitempty"`
+	// Partner marks a team play move made on the teammate's board.
+	Partner bool `json:"partner,omitempty"`
+}
+
+// rulesTag names the rules configuration a game was played under. Games
+// with different tags must not share opening statistics.
+func (state *GameState) rulesTag() string {
+	var rules []string
+	if state.BrunoVariant {
+		rules = append(rules, "bruno")
+		if !state.Bruno.standard() {
+			rules = append(rules, fmt.Sprintf("bruno-%s-chain%d", strings.ReplaceAll(state.Bruno.directions().String(), "+", "-"), state.Bruno.maxChain()))
+		}
+	}
+	if state.ForcedTable {
+		rules = append(rules, "forcedtable")
+	}
+	if state.HandSize > 0 {
+		rules = append(rules, fmt.Sprintf("hand%d", state.HandSize))
+	}
+	if state.StealSwap {
+		rules = append(rules, "steal")
+	}
+	if state.OpenPile {
+		rules = append(rules, "openpile")
+	}
+	if state.DiagonalRule {
+		rules = append(rules, "increasingdiagonal")
+	}
+	if state.SharedPool {
+		rules = append(rules, "sharedpool")
+	}
+	if state.Mulligan {
+		rules = append(rules, "mulligan")
+	}
+	if state.NonDecreasing {
+		rules = append(rules, "nondecreasing")
+	}
+	if state.End == EndPileScore {
+		rules = append(rules, "pilescore")
+	}
+	if state.Puzzle != nil {
+		rules = append(rules, "puzzle")
+	}
+	for _, b := range state.Boards {
+		if b.Handicap != (Handicap{}) {
+			rules = append(rules, "handicap")
+			break
+		}
+	}
+	if state.Teams {
+		rules = append(rules, "teams")
+	}
+	if len(state.Holes) > 0 {
+		rules = append(rules, fmt.Sprintf("holes%d", len(state.Holes)))
+	}
+	if state.Wildcards > 0 {
+		rules = append(rules, "wildcards")
+	}
+	if BoardSize != standardBoardSize {
+		rules = append(rules, fmt.Sprintf("%dx%d", BoardSize, BoardSize))
+	}
+	if state.maxTile() != MaxTile {
+		rules = append(rules, fmt.Sprintf("tiles1-%d", state.maxTile()))
+	}
+	if state.Distribution != nil {
+		rules = append(rules, "dist"+state.Distribution.String())
+	}
+	if len(rules) == 0 {
+		return "classic"
+	}
+	return strings.Join(rules, "+")
+}
+
+var moveTypeNames = map[MoveType]string{Place: "place", Swap: "swap", Discard: "discard", Draw: "draw", FromHand: "hand", Steal: "steal"}
+
+func (state *GameState) archiveEntry(winner int) ArchivedGame {
+	g := ArchivedGame{Time: time.Now(), Rules: state.rulesTag(), Winner: winner, Names: state.names()}
+	for i, b := range state.Boards {
+		seat := "human"
+		if b.IsAi {
+			seat = state.strategyFor(i).Name()
+		}
+		g.Seats = append(g.Seats, seat)
+	}
+	count := map[int]int{}
+	for _, p := range state.History {
+		if count[p.Seat] >= openingMoves {
+			continue
+		}
+		count[p.Seat]++
+		g.Openings = append(g.Openings, ArchivedMove{
+			Seat: p.Seat, N: count[p.Seat], Type: moveTypeNames[p.Move.Type], Tile: p.Move.Tile, Cell: p.Move.Cell, Partner: p.Move.Partner,
+		})
+	}
+	return g
+}
+
+func appendArchive(path string, g ArchivedGame) error {
+	f, err := appendFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(g); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readArchive(path string) ([]ArchivedGame, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var games []ArchivedGame
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var g ArchivedGame
+		if err := json.Unmarshal(scanner.Bytes(), &g); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		games = append(games, g)
+	}
+	return games, scanner.Err()
+}
+
+// gameOver settles a decided game, archiving it and updating the player
+// profiles, and marks it Over for the game loop to end the game, or in a
+// match the round. winner is -1 when nobody filled their board.
+func (state *GameState) gameOver(winner int, msg string) {
+	fmt.Println(term.text(msg))
+	state.logEvent(LoggedMove{Seat: winner, Type: "end", Message: msg})
+	if state.Puzzle != nil {
+		state.printPuzzleResult(os.Stdout)
+	}
+	if state.ArchivePath != "" {
+		if err := appendArchive(state.ArchivePath, state.archiveEntry(winner)); err != nil {
+			fmt.Println("Failed to archive game:", err)
+		}
+	}
+	if state.ProfilePath != "" {
+		state.updateProfile(winner)
+	}
+	if profilesDir != "" {
+		state.updateNamedProfiles(winner)
+	}
+	state.clearAutosave()
+	if state.Match == nil {
+		state.clearRunning()
+	}
+	state.Over, state.Winner = true, winner
+}
+
+// openingStat aggregates one kind of opening move across games.
+type openingStat struct {
+	key       string
+	count     int
+	won       int
+	tileTotal int
+}
+
+// printOpeningStats reports, separately for every rules configuration, how
+// often each opening move was played and how often its player went on to win.
+func printOpeningStats(w io.Writer, games []ArchivedGame) {
+	byRules := map[string][]ArchivedGame{}
+	for _, g := range games {
+		byRules[g.Rules] = append(byRules[g.Rules], g)
+	}
+	tags := make([]string, 0, len(byRules))
+	for tag := range byRules {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		games := byRules[tag]
+		fmt.Fprintf(w, "== %s: %d games ==\n", tag, len(games))
+		for n := 1; n <= openingMoves; n++ {
+			stats := map[string]*openingStat{}
+			for _, g := range games {
+				for _, m := range g.Openings {
+					if m.N != n {
+						continue
+					}
+					key := m.Type
+					if m.Cell != nil {
+						key = fmt.Sprintf("%s at %s", m.Type, m.Cell)
+					}
+					s := stats[key]
+					if s == nil {
+						s = &openingStat{key: key}
+						stats[key] = s
+					}
+					s.count++
+					s.tileTotal += m.Tile
+					if g.Winner == m.Seat {
+						s.won++
+					}
+				}
+			}
+			list := make([]*openingStat, 0, len(stats))
+			for _, s := range stats {
+				list = append(list, s)
+			}
+			sort.Slice(list, func(i, j int) bool {
+				if list[i].count != list[j].count {
+					return list[i].count > list[j].count
+				}
+				return list[i].key < list[j].key
+			})
+			fmt.Fprintf(w, "  move %d:\n", n)
+			for i, s := range list {
+				if i == 5 {
+					break
+				}
+				fmt.Fprintf(w, "    %-16s %4d times, avg tile %5.1f, won %3.0f%%\n",
+					s.key, s.count, float64(s.tileTotal)/float64(s.count), 100*float64(s.won)/float64(s.count))
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Every finished turn is saved in JSON to the autosave directory, keeping
+// the last few turns, so a crash, a closed terminal or a q typed by mistake
+// does not lose a long game. The next start offers to resume the newest
+// autosave. A game that ends clears them.
+//
+// A tile once drawn is saved too, aside from the turns, so a crash while
+// deciding where it goes resumes with the tile still in hand. A marker in
+// the directory stands while a game is played and goes when it ends or is
+// left, so the next start can tell a game that was cut short.
+
+// autosaveKeep is how many turns back the autosaves go.
+const autosaveKeep = 3
+
+// defaultAutosaveDir keeps the autosaves next to the profile, in the user's
+// config directory, falling back to the working directory.
+func defaultAutosaveDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "unlucky_autosave"
+	}
+	return filepath.Join(dir, "unlucky_numbers", "autosave")
+}
+
+// autosavePath is the file of the autosave n turns back, 0 the newest.
+func autosavePath(dir string, n int) string {
+	if n == 0 {
+		return filepath.Join(dir, "autosave.json")
+	}
+	return filepath.Join(dir, fmt.Sprintf("autosave-%d.json", n))
+}
+
+// autosave saves the game as the newest autosave, moving the older ones
+// back a place. It says nothing unless it fails.
+func (state *GameState) autosave() {
+	if state.AutosaveDir == "" {
+		return
+	}
+	if err := state.writeAutosave(); err != nil {
+		fmt.Println("Autosave failed:", err)
+	}
+}
+
+// drawnPath is the autosave of a turn whose tile is drawn but not played.
+func drawnPath(dir string) string {
+	return filepath.Join(dir, "autosave-drawn.json")
+}
+
+// runningPath is the marker of a game being played.
+func runningPath(dir string) string {
+	return filepath.Join(dir, "running")
+}
+
+func (state *GameState) writeAutosave() error {
+	dir := state.AutosaveDir
+	tmp, err := state.writeAside()
+	if err != nil {
+		return err
+	}
+	for n := autosaveKeep - 1; n > 0; n-- {
+		if err := os.Rename(autosavePath(dir, n-1), autosavePath(dir, n)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, autosavePath(dir, 0)); err != nil {
+		return err
+	}
+	// the turn is over, and its drawn tile with it
+	if err := os.Remove(drawnPath(dir)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// writeAside saves the game to a file of its own in the autosave directory
+// and returns it, so a crash never leaves half a save in place.
+func (state *GameState) writeAside() (string, error) {
+	if err := os.MkdirAll(state.AutosaveDir, 0o755); err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(state.AutosaveDir, "autosave.tmp")
+	return tmp, state.saveToJSON(tmp)
+}
+
+// autosaveDrawn saves the game with the tile just drawn still to play.
+func (state *GameState) autosaveDrawn() {
+	if state.AutosaveDir == "" {
+		return
+	}
+	tmp, err := state.writeAside()
+	if err == nil {
+		err = os.Rename(tmp, drawnPath(state.AutosaveDir))
+	}
+	if err != nil {
+		fmt.Println("Autosave failed:", err)
+	}
+}
+
+// stopDrawn ends the game at the placement prompt. The drawn autosave
+// keeps the tile, so the next start offers the game back with it to place.
+func (state *GameState) stopDrawn(tile int) {
+	state.Stopped = true
+	if state.AutosaveDir == "" {
+		fmt.Printf(tr("The drawn %s is not kept without autosaves; save with s before quitting to come back to it.\n"), tileLabel(tile))
+		return
+	}
+	fmt.Printf(tr("The drawn %s is kept: the next start offers the game back with it to place.\n"), tileLabel(tile))
+}
+
+// markRunning leaves the marker of a game being played.
+func (state *GameState) markRunning() {
+	if state.AutosaveDir == "" {
+		return
+	}
+	if os.MkdirAll(state.AutosaveDir, 0o755) == nil {
+		os.WriteFile(runningPath(state.AutosaveDir), []byte(fmt.Sprintf("%d %s\n", os.Getpid(), time.Now().Format(time.RFC3339))), 0o644)
+	}
+}
+
+// clearRunning takes the marker away once the game is over or left.
+func (state *GameState) clearRunning() {
+	if state.AutosaveDir != "" {
+		os.Remove(runningPath(state.AutosaveDir))
+	}
+}
+
+// clearAutosave removes the autosaves of a game that has ended.
+func (state *GameState) clearAutosave() {
+	if state.AutosaveDir == "" {
+		return
+	}
+	for n := 0; n < autosaveKeep; n++ {
+		os.Remove(autosavePath(state.AutosaveDir, n))
+	}
+	os.Remove(drawnPath(state.AutosaveDir))
+}
+
+// promptResume offers the newest autosave in dir, if any, and returns its
+// path when the player takes it up. A game cut short is said to be, and
+// one cut short with a tile drawn goes on from the draw.
+func promptResume(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	_, err := os.Stat(runningPath(dir))
+	crashed := err == nil
+	os.Remove(runningPath(dir))
+	path := drawnPath(dir)
+	info, err := os.Stat(path)
+	if err != nil {
+		path = autosavePath(dir, 0)
+		if info, err = os.Stat(path); err != nil {
+			return ""
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var g SavedGame
+	if json.Unmarshal(data, &g) != nil {
+		return ""
+	}
+	if crashed {
+		fmt.Println(tr("The last game did not finish: the program stopped in the middle of it."))
+	}
+	if g.Pending != nil {
+		fmt.Printf(tr("Resume the game autosaved %s, on turn %d with %s drawn? (Y/n): "), info.ModTime().Format("2006-01-02 15:04"), g.Turn+1, tileLabel(g.Pending.Tile))
+	} else {
+		fmt.Printf(tr("Resume the game autosaved %s, on turn %d? (Y/n): "), info.ModTime().Format("2006-01-02 15:04"), g.Turn+1)
+	}
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	if line == "n" || line == "no" {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// import-bga turns a Lucky Numbers game log copied from Board Game Arena's
+// log panel into a move log, so a game played online can be replayed, drawn
+// as a GIF or gone through like one played here. The log is read a line
+// per action, in English, in the order played:
+//
+//	Alice's starting clovers: 3, 8, 12, 19
+//	Alice takes 7 from the draw pile
+//	Alice places 7 on row 2, column 1
+//	Bob takes 7 from the face-up clovers
+//	Bob replaces 12 with 7 on row 3, column 3
+//	Alice puts 14 face up
+//
+// Rows and columns count from 1, as the site shows them; B2 and the like
+// are read too. A few wordings of each action are accepted, as the site's
+// wording has changed over time, and lines that are none of these — chat,
+// timestamps, scores — are skipped. The starting clovers must come first,
+// one line per player, as the site deals them without a move of its own;
+// the tiles no one drew are shuffled into the pile.
+
+var (
+	bgaTime    = regexp.MustCompile(`^\[?\d{1,2}:\d{2}(:\d{2})?\]?\s*`)
+	bgaStart   = regexp.MustCompile(`(?i)^(.+?)(?:'s)? (?:starting|initial) (?:clovers|tiles|diagonal)\s*:\s*([\d,\s]+)$`)
+	bgaDraw    = regexp.MustCompile(`(?i)^(.+?) (?:takes|draws|picks) (?:a |clover |tile )*(\d+) from the (draw pile|deck|pile|face-?down clovers|table|face-?up clovers|common area)$`)
+	bgaDiscard = regexp.MustCompile(`(?i)^(.+?) (?:discards (?:a |clover |tile )*(\d+)(?: face up)?|(?:puts|places|lays) (?:a |clover |tile )*(\d+) (?:face up|(?:back )?on the table|in the common area))$`)
+	bgaSwap    = regexp.MustCompile(`(?i)^(.+?) (?:replaces|swaps) (\d+) (?:with|for) (\d+) (?:on|at|in) (.+)$`)
+	bgaPlace   = regexp.MustCompile(`(?i)^(.+?) (?:places|puts|plays) (?:a |clover |tile )*(\d+) (?:on|at|in) (.+)$`)
+	bgaWin     = regexp.MustCompile(`(?i)^(.+?) (?:wins|has won)(?: the game)?!?$`)
+	bgaRowCol  = regexp.MustCompile(`(?i)^row (\d+),? column (\d+)$`)
+)
+
+// bgaCell reads a cell as the log names it.
+func bgaCell(s string) (Cell, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.ToLower(s), "their garden "))
+	if m := bgaRowCol.FindStringSubmatch(s); m != nil {
+		r, _ := strconv.Atoi(m[1])
+		c, _ := strconv.Atoi(m[2])
+		if !onBoard(r-1, c-1) {
+			return Cell{}, fmt.Errorf("row %d, column %d is off the board", r, c)
+		}
+		return Cell{R: r - 1, C: c - 1}, nil
+	}
+	return parseCellName(s)
+}
+
+// importBGA reads the log at path and writes its game as a move log to
+// out, returning the number of moves.
+func importBGA(path, out string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := bgaTime.ReplaceAllString(strings.TrimSpace(scanner.Text()), "")
+		lines = append(lines, strings.TrimSuffix(line, "."))
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	// the starting clovers name the seats and set the boards up
+	state := &GameState{}
+	seats := map[string]int{}
+	used := map[int]int{}
+	first := 0
+	for ; first < len(lines); first++ {
+		m := bgaStart.FindStringSubmatch(lines[first])
+		if m == nil {
+			if len(seats) > 0 && isBGAMove(lines[first]) {
+				break
+			}
+			continue
+		}
+		name, err := checkName(m[1])
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", first+1, err)
+		}
+		var diagonal []int
+		for _, field := range strings.FieldsFunc(m[2], func(r rune) bool { return r == ',' || r == ' ' }) {
+			t, err := parseTile(field, MaxTile)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: %w", first+1, err)
+			}
+			diagonal = append(diagonal, t)
+			used[t]++
+		}
+		if len(seats) == 0 {
+			if len(diagonal) < minBoardSize || len(diagonal) > maxBoardSize {
+				return 0, fmt.Errorf("line %d: %d starting clovers, boards are %d-%d", first+1, len(diagonal), minBoardSize, maxBoardSize)
+			}
+			BoardSize = len(diagonal)
+		}
+		if len(diagonal) != BoardSize {
+			return 0, fmt.Errorf("line %d: %d starting clovers, the first player had %d", first+1, len(diagonal), BoardSize)
+		}
+		b := &Board{Name: name}
+		for i, t := range diagonal {
+			b.Grid[i][i] = t
+		}
+		seats[strings.ToLower(name)] = len(state.Boards)
+		state.Boards = append(state.Boards, b)
+	}
+	if len(state.Boards) == 0 {
+		return 0, fmt.Errorf("%s has no starting clovers; copy the log from the start of the game", path)
+	}
+	if len(state.Boards) > maxPlayers {
+		return 0, fmt.Errorf("%d players, a game has 1-%d", len(state.Boards), maxPlayers)
+	}
+	if err := state.checkPosition(state.step()); err != nil {
+		return 0, err
+	}
+	state.Draw = state.unusedTiles(used)
+
+	log, err := newMoveLog(out)
+	if err != nil {
+		return 0, err
+	}
+	defer log.Close()
+	now := time.Now()
+	write := func(e LoggedMove) error {
+		e.Time = now
+		if e.Game == nil {
+			e.Player = state.seatLabel(e.Seat)
+		}
+		return log.enc.Encode(e)
+	}
+	g := state.saved()
+	if err := write(LoggedMove{Turn: 1, Type: "start", Player: state.seatLabel(0), Game: &g}); err != nil {
+		return 0, err
+	}
+
+	seat := func(n int, name string) (int, error) {
+		s, ok := seats[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("line %d: %q has no starting clovers", n, name)
+		}
+		return s, nil
+	}
+	turn, moves, drawn := 0, 0, 0
+	for i := first; i < len(lines); i++ {
+		n, line := i+1, lines[i]
+		if m := bgaWin.FindStringSubmatch(line); m != nil {
+			if s, err := seat(n, m[1]); err == nil {
+				end := LoggedMove{Turn: max(turn, 1), Seat: s, Type: "end", Message: fmt.Sprintf("%s wins!", state.seatLabel(s))}
+				if err := write(end); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		who, e, err := parseBGAMove(line)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", n, err)
+		}
+		if who == "" {
+			continue
+		}
+		s, err := seat(n, who)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case e.Type == "draw" && drawn != 0:
+			return 0, fmt.Errorf("line %d: %s draws %s before playing %s", n, who, tileLabel(e.Tile), tileLabel(drawn))
+		case e.Type == "draw":
+			turn, drawn = turn+1, e.Tile
+		case e.Tile != drawn || s != state.Current:
+			return 0, fmt.Errorf("line %d: %s plays %s without drawing it", n, who, tileLabel(e.Tile))
+		default:
+			drawn = 0
+		}
+		e.Seat, e.Turn = s, max(turn, 1)
+		if e.Cell != nil {
+			board := s
+			e.Board = &board
+		}
+		// play it through to catch a log that does not add up
+		if _, err := state.replayEvent(e); err != nil {
+			return 0, fmt.Errorf("line %d: %w", n, err)
+		}
+		if e.Type != "draw" {
+			moves++
+		}
+		if err := write(e); err != nil {
+			return 0, err
+		}
+	}
+	return moves, nil
+}
+
+// parseBGAMove reads a draw or move from a log line, with who made it, or
+// returns no one for a line that is neither.
+func parseBGAMove(line string) (string, LoggedMove, error) {
+	var e LoggedMove
+	if m := bgaDraw.FindStringSubmatch(line); m != nil {
+		e.Type, e.Tile, e.From = "draw", atoi(m[2]), "pile"
+		where := strings.ToLower(m[3])
+		if where == "table" || where == "common area" || strings.HasSuffix(where, "up clovers") {
+			e.From = "table"
+		}
+		return m[1], e, nil
+	}
+	if m := bgaSwap.FindStringSubmatch(line); m != nil {
+		cell, err := bgaCell(m[4])
+		e.Type, e.OldTile, e.Tile, e.Cell = "swap", atoi(m[2]), atoi(m[3]), &cell
+		return m[1], e, err
+	}
+	if m := bgaDiscard.FindStringSubmatch(line); m != nil {
+		e.Type, e.Tile = "discard", atoi(m[2]+m[3])
+		return m[1], e, nil
+	}
+	if m := bgaPlace.FindStringSubmatch(line); m != nil {
+		cell, err := bgaCell(m[3])
+		e.Type, e.Tile, e.Cell = "place", atoi(m[2]), &cell
+		return m[1], e, err
+	}
+	return "", e, nil
+}
+
+// isBGAMove reports whether line is a move rather than a note.
+func isBGAMove(line string) bool {
+	for _, re := range []*regexp.Regexp{bgaDraw, bgaSwap, bgaDiscard, bgaPlace} {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultBlunderMargin is how many points below the engine's best move a
+// placement must score before the player is asked to confirm it.
+const defaultBlunderMargin = 30.0
+
+// moveScore is what the engine makes of a placement, found among its ranked
+// moves or scored afresh for swaps it would not consider.
+func (state *GameState) moveScore(move Move, ranked []Move) float64 {
+	for _, m := range ranked {
+		if *m.Cell == *move.Cell && m.Partner == move.Partner {
+			return m.Score
+		}
+	}
+	defer state.asSeat(state.moveSeat(state.Current, move))()
+	return state.placementScore(move.Tile, move.Cell.R, move.Cell.C)
+}
+
+// confirmMove asks before a human plays a placement the engine rates far
+// below its best, and reports whether to go ahead. A BlunderMargin of 0
+// never asks.
+func (state *GameState) confirmMove(move Move) bool {
+	if state.BlunderMargin <= 0 {
+		return true
+	}
+	ranked := state.bestMoves(move.Tile)
+	if len(ranked) == 0 {
+		return true
+	}
+	best := ranked[0]
+	score := state.moveScore(move, ranked)
+	if best.Score-score < state.BlunderMargin {
+		return true
+	}
+	where := ""
+	if best.Partner {
+		where = fmt.Sprintf(tr(" on partner %d's board"), state.partner(state.Current))
+	}
+	fmt.Printf(term.text(tr("The engine prefers %s at %s%s, score %.0f vs your %.0f — continue? (y/N): ")),
+		tr(map[MoveType]string{Place: "Place", Swap: "Swap"}[best.Type]), best.Cell, where, best.Score, score)
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// -paste reads the position from board art pasted on standard input, the
+// boards as the game draws them copied back from a terminal or a chat:
+//
+//	+-------------------- Table: 2 tiles --------------------+
+//	|                          7,20                          |
+//	+----------------- Draw pile: 24 tiles ------------------+
+//	         Player 0                     Player 1
+//	     A     B     C     D          A     B     C     D
+//	  +-----+-----+-----+-----+    +-----+-----+-----+-----+
+//	1 |  5  |  .  |  .  |  9  |  1 |  6  |  .  |  .  |  .  |
+//	  +-----+-----+-----+-----+    +-----+-----+-----+-----+
+//	...
+//
+// Unicode box lines, colour codes and the marks around the last move and
+// the legal cells are read past. A plainer dot grid reads too, a line a row
+// with the boards side by side between bars and the table on a line of its
+// own:
+//
+//	table 7,20
+//	5 . . 9   | 6 . . .
+//	. 7 . .   | . 10 . .
+//
+// A blank line after the boards ends the paste. The art does not say whose
+// turn it is, so the first seat moves, nor the rules beyond the board size:
+// the tiles run as high as the preset of that size has them, or as the
+// highest tile on show.
+
+var (
+	artEscape   = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	artBoxRow   = regexp.MustCompile(`^\s*\d+\s*\|`)
+	artRule     = regexp.MustCompile(`^[\s+\-|]+$`)
+	artLetters  = regexp.MustCompile(`^\s*A(\s+[A-Z])+\s*$`)
+	artTableRow = regexp.MustCompile(`(?i)^\s*table:?\s+(.*)$`)
+	artCell     = regexp.MustCompile(`^(\d+|\.|#|\*|\+|<\S+>|\(\S+\))$`)
+)
+
+// artVerticals turns the box drawing characters into the ASCII ones.
+var artVerticals = strings.NewReplacer("│", "|", "┃", "|", "─", "-", "━", "-",
+	"┌", "+", "┐", "+", "└", "+", "┘", "+", "├", "+", "┤", "+", "┬", "+", "┴", "+", "┼", "+")
+
+// readBoardArt reads pasted board art a line at a time up to the blank
+// line after it, or the end of the input.
+func readBoardArt(r lineReader) []string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if strings.TrimSpace(line) == "" {
+			if len(lines) > 0 || err != nil {
+				return lines
+			}
+			continue // blank lines before the paste
+		}
+		lines = append(lines, line)
+		if err != nil {
+			return lines
+		}
+	}
+}
+
+// boardArtPosition reads the boards and the table from board art and
+// returns them as a position string.
+func boardArtPosition(lines []string) (string, error) {
+	var boards [][]string // each board's rows, its cells joined by dots
+	var table []string
+	tableSeen := false
+	size, base := 0, 0
+	high := 0 // highest tile on show
+	cell := func(s string) (string, error) {
+		s = strings.TrimSpace(s)
+		s = strings.Trim(s, "<>()[] ") // the last move, a legal swap, the cursor
+		switch s {
+		case "", ".", legalLabel:
+			return "", nil
+		case blockedLabel, wildcardLabel:
+			return s, nil
+		}
+		t, err := strconv.Atoi(s)
+		if err != nil || t < 1 {
+			return "", fmt.Errorf("%q is not a tile", s)
+		}
+		high = max(high, t)
+		return s, nil
+	}
+	addRow := func(n, r int, cells []string) error {
+		if r == 0 {
+			base = len(boards)
+		}
+		for i := 0; i < n; i++ {
+			if base+i >= len(boards) {
+				if r != 0 {
+					return fmt.Errorf("row %d of a board whose first row is missing", r+1)
+				}
+				boards = append(boards, nil)
+			}
+			if len(boards[base+i]) != r {
+				return fmt.Errorf("board %d: row %d after %d rows", base+i, r+1, len(boards[base+i]))
+			}
+			row := make([]string, size)
+			for c := range row {
+				var err error
+				if row[c], err = cell(cells[i*size+c]); err != nil {
+					return fmt.Errorf("board %d %s: %w", base+i, Cell{R: r, C: c}, err)
+				}
+			}
+			boards[base+i] = append(boards[base+i], strings.Join(row, "."))
+		}
+		return nil
+	}
+
+	dotRow := 0 // rows read of the dot grid boards in hand
+	for n, line := range lines {
+		line = artVerticals.Replace(artEscape.ReplaceAllString(strings.TrimRight(line, "\r\n"), ""))
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case artLetters.MatchString(line):
+			f := strings.Fields(line)
+			size = len(f)
+			for i := 1; i < len(f); i++ {
+				if f[i] == "A" {
+					size = i
+					break
+				}
+			}
+		case artBoxRow.MatchString(line):
+			parts := strings.Split(line, "|")
+			r, _ := strconv.Atoi(strings.TrimSpace(parts[0]))
+			if size == 0 {
+				size = artRowSize(parts, r)
+			}
+			// a row number, size cells, and again for each board beside
+			var cells []string
+			for i := 0; i+size < len(parts); i += size + 1 {
+				if strings.TrimSpace(parts[i]) != strconv.Itoa(r) {
+					return "", fmt.Errorf("line %d: expected row %d of the next board, got %q", n+1, r, parts[i])
+				}
+				cells = append(cells, parts[i+1:i+1+size]...)
+			}
+			if len(cells) == 0 {
+				return "", fmt.Errorf("line %d: a board row without its %d cells", n+1, size)
+			}
+			if err := addRow(len(cells)/size, r-1, cells); err != nil {
+				return "", fmt.Errorf("line %d: %w", n+1, err)
+			}
+		case artRule.MatchString(line):
+			// the lines between the rows
+		case strings.HasPrefix(trimmed, "|") && strings.HasSuffix(trimmed, "|") && !tableSeen && len(boards) == 0:
+			// the table box; the open pile's line under it is not a list
+			list := strings.TrimSpace(strings.Trim(trimmed, "|"))
+			if list == "(empty)" {
+				tableSeen = true
+			} else if strings.Trim(list, "0123456789,*") == "" {
+				table, tableSeen = strings.Split(list, ","), true
+			}
+		case artTableRow.MatchString(line):
+			list := strings.TrimSpace(artTableRow.FindStringSubmatch(line)[1])
+			if list != "-" && list != "" {
+				table = strings.Split(strings.ReplaceAll(list, " ", ""), ",")
+			}
+			tableSeen = true
+		default:
+			// a dot grid row: each board's cells between bars
+			var cells []string
+			groups := strings.Split(line, "|")
+			for _, g := range groups {
+				f := strings.Fields(g)
+				for _, c := range f {
+					if !artCell.MatchString(c) {
+						f = nil
+						break
+					}
+				}
+				if len(f) == 0 || size != 0 && len(f) != size {
+					cells = nil
+					break
+				}
+				size = len(f)
+				cells = append(cells, f...)
+			}
+			if cells == nil {
+				continue // the counters, the names and the like
+			}
+			if err := addRow(len(groups), dotRow, cells); err != nil {
+				return "", fmt.Errorf("line %d: %w", n+1, err)
+			}
+			if dotRow = (dotRow + 1) % size; dotRow == 0 {
+				base = len(boards)
+			}
+		}
+	}
+	if len(boards) == 0 {
+		return "", fmt.Errorf("no boards in the pasted art")
+	}
+	if size < minBoardSize || size > maxBoardSize {
+		return "", fmt.Errorf("boards of %d columns, boards are %d-%d", size, minBoardSize, maxBoardSize)
+	}
+	for i, rows := range boards {
+		if len(rows) != size {
+			return "", fmt.Errorf("board %d has %d rows, expected %d", i, len(rows), size)
+		}
+	}
+	for _, t := range table {
+		if n, err := strconv.Atoi(t); err == nil {
+			high = max(high, n)
+		}
+	}
+
+	// the rules as far as the art tells them
+	rules := []string{}
+	tiles := MaxTile
+	for _, p := range presets {
+		if p.BoardSize == size {
+			tiles = p.TileRange
+			if p.End == EndPileScore {
+				rules = append(rules, "pilescore")
+			}
+		}
+	}
+	if tiles = max(tiles, high); tiles != MaxTile {
+		rules = append(rules, fmt.Sprintf("tiles1-%d", tiles))
+	}
+	if len(rules) == 0 {
+		rules = append(rules, "classic")
+	}
+
+	spec := make([]string, len(boards))
+	for i, rows := range boards {
+		spec[i] = strings.Join(rows, "/")
+	}
+	list := "-"
+	if len(table) > 0 {
+		list = strings.Join(table, ",")
+	}
+	return fmt.Sprintf("%s %s 0 %s", strings.Join(spec, "|"), list, strings.Join(rules, "+")), nil
+}
+
+// artRowSize works out the board size from a boxed row without the column
+// letters above it: the smallest size at which every board's row number
+// falls in place.
+func artRowSize(parts []string, r int) int {
+	for size := minBoardSize; size <= maxBoardSize; size++ {
+		ok := (len(parts)-1)%(size+1) == 0
+		for i := 0; ok && i+size < len(parts); i += size + 1 {
+			ok = strings.TrimSpace(parts[i]) == strconv.Itoa(r)
+		}
+		if ok {
+			return size
+		}
+	}
+	return 0
+}
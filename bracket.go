@@ -0,0 +1,294 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A bracket is a competition among named participants, players at the
+// keyboard and computer strategies alike, over one or more games per
+// pairing. In a knockout the participants left are reseeded every round so
+// the best seed meets the worst, and with an odd number left the best seed
+// gets a bye. In a round robin everyone meets everyone once, a round at a
+// time, and the most points win.
+
+// maxTiebreakGames caps the single games played to settle a drawn knockout
+// pairing; if it is still level the better seed goes through.
+const maxTiebreakGames = 5
+
+// errAbandoned stops a bracket whose players quit a game.
+var errAbandoned = errors.New("a game was abandoned")
+
+// Entrant is one bracket participant and their record so far.
+type Entrant struct {
+	Name     string
+	Strategy string // empty for a human
+	Seed     int    // 1 for the first listed
+	Wins     int
+	Draws    int
+	Losses   int
+}
+
+func (e *Entrant) human() bool { return e.Strategy == "" }
+
+func (e *Entrant) points() float64 { return float64(e.Wins) + 0.5*float64(e.Draws) }
+
+// parseEntrants reads the participants in seed order: a strategy name, or
+// "human" or "human:Name" for a player at the keyboard.
+func parseEntrants(specs []string) ([]*Entrant, error) {
+	if len(specs) < 2 {
+		return nil, fmt.Errorf("a bracket needs at least two participants")
+	}
+	entrants := make([]*Entrant, 0, len(specs))
+	seen := map[string]int{}
+	humans := 0
+	for i, spec := range specs {
+		e := &Entrant{Seed: i + 1}
+		if kind, name, _ := strings.Cut(spec, ":"); strings.EqualFold(kind, "human") {
+			humans++
+			e.Name = name
+			if e.Name == "" {
+				e.Name = fmt.Sprintf("Human %d", humans)
+			}
+		} else {
+			s, err := lookupStrategy(spec)
+			if err != nil {
+				return nil, err
+			}
+			e.Name, e.Strategy = s.Name(), s.Name()
+		}
+		if seen[e.Name]++; seen[e.Name] > 1 {
+			e.Name = fmt.Sprintf("%s#%d", e.Name, seen[e.Name])
+		}
+		entrants = append(entrants, e)
+	}
+	return entrants, nil
+}
+
+// Pairing is one meeting in a bracket round; B is nil for a bye.
+type Pairing struct {
+	A, B         *Entrant
+	WinsA, WinsB int
+	Draws        int
+}
+
+// winner is the entrant going through: the one with more wins, else A, the
+// better seed.
+func (p *Pairing) winner() *Entrant {
+	if p.B != nil && p.WinsB > p.WinsA {
+		return p.B
+	}
+	return p.A
+}
+
+// play adds games between A and B to the pairing. Computers alone play
+// headless, alternating who starts; with a human they sit at the table.
+func (p *Pairing) play(games int) error {
+	if p.A.human() || p.B.human() {
+		return p.playAtTable(games)
+	}
+	for g := 0; g < games; g++ {
+		seats := []*Entrant{p.A, p.B}
+		if (p.WinsA+p.WinsB+p.Draws)%2 == 1 {
+			seats[0], seats[1] = p.B, p.A
+		}
+		strategies := make([]Strategy, len(seats))
+		for i, e := range seats {
+			s, err := lookupStrategy(e.Strategy)
+			if err != nil {
+				return err
+			}
+			strategies[i] = s
+		}
+		state, err := newHeadlessGame(len(seats))
+		if err != nil {
+			return err
+		}
+		switch winner := state.playHeadless(strategies, nil); {
+		case winner < 0:
+			p.Draws++
+		case seats[winner] == p.A:
+			p.WinsA++
+		default:
+			p.WinsB++
+		}
+	}
+	return nil
+}
+
+// playAtTable plays games as a match at the keyboard.
+func (p *Pairing) playAtTable(games int) error {
+	state := &GameState{Heuristics: defaultHeuristics, Match: newMatch(games, 2)}
+	for _, e := range []*Entrant{p.A, p.B} {
+		state.Boards = append(state.Boards, &Board{IsAi: !e.human(), Strategy: e.Strategy})
+	}
+	fmt.Printf("%s plays as %s, %s as %s.\n", p.A.Name, state.seatLabel(0), p.B.Name, state.seatLabel(1))
+	if err := state.dealRound(); err != nil {
+		return err
+	}
+	state.playMatch(false)
+	m := state.Match
+	if m.Played < m.Rounds {
+		return errAbandoned
+	}
+	p.WinsA += m.Wins[0]
+	p.WinsB += m.Wins[1]
+	p.Draws += m.Played - m.Wins[0] - m.Wins[1]
+	return nil
+}
+
+// settle adds the pairing's results to both entrants' records.
+func (p *Pairing) settle() {
+	if p.B == nil {
+		return
+	}
+	p.A.Wins, p.A.Losses, p.A.Draws = p.A.Wins+p.WinsA, p.A.Losses+p.WinsB, p.A.Draws+p.Draws
+	p.B.Wins, p.B.Losses, p.B.Draws = p.B.Wins+p.WinsB, p.B.Losses+p.WinsA, p.B.Draws+p.Draws
+}
+
+// runBracket plays the bracket in the named format, printing its state
+// after every round, and returns the champion.
+func runBracket(format string, entrants []*Entrant, games int, w io.Writer) (*Entrant, error) {
+	if games < 1 {
+		return nil, fmt.Errorf("a pairing needs at least one game")
+	}
+	switch strings.ToLower(format) {
+	case "knockout", "single", "single-elimination":
+		return runKnockout(entrants, games, w)
+	case "roundrobin", "round-robin":
+		return runRoundRobin(entrants, games, w)
+	}
+	return nil, fmt.Errorf("unknown bracket format %q (want knockout or roundrobin)", format)
+}
+
+func runKnockout(entrants []*Entrant, games int, w io.Writer) (*Entrant, error) {
+	alive := append([]*Entrant{}, entrants...)
+	bySeed := func() { sort.SliceStable(alive, func(i, j int) bool { return alive[i].Seed < alive[j].Seed }) }
+	bySeed()
+	for round := 1; len(alive) > 1; round++ {
+		var pairings []*Pairing
+		rest := alive
+		if len(rest)%2 == 1 {
+			pairings = append(pairings, &Pairing{A: rest[0]})
+			rest = rest[1:]
+		}
+		for i := 0; i < len(rest)/2; i++ {
+			pairings = append(pairings, &Pairing{A: rest[i], B: rest[len(rest)-1-i]})
+		}
+		alive = alive[:0:0]
+		for _, p := range pairings {
+			if p.B != nil {
+				if err := p.play(games); err != nil {
+					return nil, err
+				}
+				for n := 0; p.WinsA == p.WinsB && n < maxTiebreakGames; n++ {
+					if err := p.play(1); err != nil {
+						return nil, err
+					}
+				}
+				p.settle()
+			}
+			alive = append(alive, p.winner())
+		}
+		printRound(w, fmt.Sprintf("Knockout round %d", round), pairings)
+		bySeed()
+		if len(alive) > 1 {
+			names := make([]string, len(alive))
+			for i, e := range alive {
+				names[i] = e.Name
+			}
+			fmt.Fprintf(w, "Still in: %s\n", strings.Join(names, ", "))
+		}
+	}
+	return alive[0], nil
+}
+
+func runRoundRobin(entrants []*Entrant, games int, w io.Writer) (*Entrant, error) {
+	schedule := roundRobinSchedule(len(entrants))
+	for r, round := range schedule {
+		var pairings []*Pairing
+		for _, m := range round {
+			p := &Pairing{A: entrants[m[0]]}
+			if m[1] >= 0 {
+				p.B = entrants[m[1]]
+				if err := p.play(games); err != nil {
+					return nil, err
+				}
+				p.settle()
+			}
+			pairings = append(pairings, p)
+		}
+		printRound(w, fmt.Sprintf("Round %d of %d", r+1, len(schedule)), pairings)
+		printStandings(w, entrants)
+	}
+	return standings(entrants)[0], nil
+}
+
+// roundRobinSchedule pairs n entrants by the circle method: the first
+// stays put while the rest rotate, so everyone meets everyone once. With
+// an odd n one entrant a round is paired with -1, a bye.
+func roundRobinSchedule(n int) [][][2]int {
+	ring := make([]int, n)
+	for i := range ring {
+		ring[i] = i
+	}
+	if n%2 == 1 {
+		ring = append(ring, -1)
+	}
+	m := len(ring)
+	rounds := make([][][2]int, 0, m-1)
+	for r := 0; r < m-1; r++ {
+		var round [][2]int
+		for i := 0; i < m/2; i++ {
+			a, b := ring[i], ring[m-1-i]
+			if a < 0 || (b >= 0 && b < a) {
+				a, b = b, a
+			}
+			round = append(round, [2]int{a, b})
+		}
+		rounds = append(rounds, round)
+		ring = append(ring[:1], append([]int{ring[m-1]}, ring[1:m-1]...)...)
+	}
+	return rounds
+}
+
+// standings orders entrants by points, then wins, then seed.
+func standings(entrants []*Entrant) []*Entrant {
+	order := append([]*Entrant{}, entrants...)
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if a.points() != b.points() {
+			return a.points() > b.points()
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Seed < b.Seed
+	})
+	return order
+}
+
+func printRound(w io.Writer, title string, pairings []*Pairing) {
+	fmt.Fprintf(w, "=== %s ===\n", title)
+	for _, p := range pairings {
+		if p.B == nil {
+			fmt.Fprintf(w, "  %-16s bye\n", p.A.Name)
+			continue
+		}
+		result := fmt.Sprintf("%d-%d", p.WinsA, p.WinsB)
+		if p.Draws > 0 {
+			result += fmt.Sprintf(" (%d drawn)", p.Draws)
+		}
+		fmt.Fprintf(w, "  %-16s %-14s %s\n", p.A.Name, result, p.B.Name)
+	}
+}
+
+func printStandings(w io.Writer, entrants []*Entrant) {
+	fmt.Fprintf(w, "%-16s %4s %5s %5s %6s %6s\n", "Participant", "Seed", "Wins", "Draws", "Losses", "Points")
+	for _, e := range standings(entrants) {
+		fmt.Fprintf(w, "%-16s %4d %5d %5d %6d %6.1f\n", e.Name, e.Seed, e.Wins, e.Draws, e.Losses, e.points())
+	}
+}
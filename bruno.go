@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Weights the evaluator applies under the Bruno variant.
+const (
+	brunoExtraTurnBonus = 1.5 // placement earns an extra turn
+	brunoGiftPenalty    = 0.8 // swap hands an opponent an extra-turn tile
+)
+
+// BrunoDirections is a set of the directions along which a neighbouring
+// twin earns a Bruno extra turn.
+type BrunoDirections int
+
+const (
+	BrunoDiagonal BrunoDirections = 1 << iota
+	BrunoRow
+	BrunoColumn
+
+	BrunoOrthogonal = BrunoRow | BrunoColumn
+	BrunoAny        = BrunoDiagonal | BrunoOrthogonal
+)
+
+var brunoDirectionDeltas = map[BrunoDirections][][2]int{
+	BrunoDiagonal: {{-1, -1}, {-1, 1}, {1, -1}, {1, 1}},
+	BrunoRow:      {{0, -1}, {0, 1}},
+	BrunoColumn:   {{-1, 0}, {1, 0}},
+}
+
+var brunoDirectionNames = []struct {
+	Name string
+	Dirs BrunoDirections
+}{
+	{"diagonal", BrunoDiagonal},
+	{"row", BrunoRow},
+	{"column", BrunoColumn},
+	{"orthogonal", BrunoOrthogonal},
+	{"any", BrunoAny},
+}
+
+func (d BrunoDirections) String() string {
+	for _, n := range brunoDirectionNames {
+		if n.Dirs == d {
+			return n.Name
+		}
+	}
+	var parts []string
+	for _, n := range brunoDirectionNames[:3] {
+		if d&n.Dirs != 0 {
+			parts = append(parts, n.Name)
+		}
+	}
+	return strings.Join(parts, "+")
+}
+
+// parseBrunoDirections reads a set such as "diagonal", "orthogonal" or
+// "diagonal+row".
+func parseBrunoDirections(s string) (BrunoDirections, error) {
+	var dirs BrunoDirections
+	for _, part := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r == '+' || r == ',' || unicode.IsSpace(r) }) {
+		found := false
+		for _, n := range brunoDirectionNames {
+			if part == n.Name {
+				dirs |= n.Dirs
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown direction %q (want diagonal, row, column, orthogonal or any)", part)
+		}
+	}
+	return dirs, nil
+}
+
+// BrunoRules configures the Bruno variant. The zero value is the original
+// rule: diagonal twins only, chained up to maxExtraTurns times.
+type BrunoRules struct {
+	Directions BrunoDirections
+	MaxChain   int // extra turns one turn may chain into
+}
+
+func (b BrunoRules) directions() BrunoDirections {
+	if b.Directions == 0 {
+		return BrunoDiagonal
+	}
+	return b.Directions
+}
+
+func (b BrunoRules) maxChain() int {
+	if b.MaxChain <= 0 {
+		return maxExtraTurns
+	}
+	return b.MaxChain
+}
+
+// standard reports whether b plays the same as the original rule.
+func (b BrunoRules) standard() bool {
+	return b.directions() == BrunoDiagonal && b.maxChain() == maxExtraTurns
+}
+
+func (b BrunoRules) String() string {
+	return fmt.Sprintf("%s, chain %d", b.directions(), b.maxChain())
+}
+
+// brunoMatch reports a neighbour of (r,c) on board holding tile along one
+// of the configured directions, which is what earns an extra turn under the
+// Bruno variant.
+func (state *GameState) brunoMatch(board *Board, tile, r, c int) (Cell, bool) {
+	if !isTile(tile) {
+		return Cell{}, false
+	}
+	dirs := state.Bruno.directions()
+	for _, n := range brunoDirectionNames[:3] {
+		if dirs&n.Dirs == 0 {
+			continue
+		}
+		for _, d := range brunoDirectionDeltas[n.Dirs] {
+			nr, nc := r+d[0], c+d[1]
+			if onBoard(nr, nc) && board.Grid[nr][nc] == tile {
+				return Cell{R: nr, C: nc}, true
+			}
+		}
+	}
+	return Cell{}, false
+}
+
+// checkBrunoExtra announces whether the tile just placed at (r,c) on board
+// earns an extra turn, counting it against the chain limit.
+func (state *GameState) checkBrunoExtra(board *Board, r, c int) bool {
+	match, ok := state.brunoMatch(board, board.Grid[r][c], r, c)
+	if !ok {
+		return false
+	}
+	if state.ExtraTurns >= state.Bruno.maxChain() {
+		fmt.Printf(term.text("Bruno’s Variant: matching tile at %s, but %d extra turns in a row is the limit.\n"), match, state.Bruno.maxChain())
+		return false
+	}
+	state.ExtraTurns++
+	fmt.Printf(term.text("Bruno’s Variant: matching tile at %s! Extra turn granted.\n"), match)
+	return true
+}
+
+// promptBrunoRules asks how the Bruno variant is played; blank answers keep
+// the original rule.
+func promptBrunoRules() BrunoRules {
+	var rules BrunoRules
+	for {
+		fmt.Print("Bruno twins count along (diagonal, row, column, orthogonal, any; default diagonal): ")
+		line, _ := reader.ReadString('\n')
+		dirs, err := parseBrunoDirections(line)
+		if err == nil {
+			rules.Directions = dirs
+			break
+		}
+		fmt.Println(err)
+	}
+	fmt.Printf("Most extra turns in a row (1-%d, default %d): ", maxExtraTurns, maxExtraTurns)
+	line, _ := reader.ReadString('\n')
+	if n, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && n >= 1 && n <= maxExtraTurns {
+		rules.MaxChain = n
+	}
+	return rules
+}
+
+// brunoFactor weights placing tile at (r,c) on the current board by the
+// extra turn it would earn.
+func (state *GameState) brunoFactor(tile, r, c int) float64 {
+	board := state.Boards[state.Current]
+	if board.Grid[r][c] == tile {
+		return 1 // already there, the extra turn was spent
+	}
+	if _, ok := state.brunoMatch(board, tile, r, c); ok {
+		return brunoExtraTurnBonus
+	}
+	return 1
+}
+
+// giftsBrunoMatch reports whether putting tile on the table would let some
+// opponent legally place it next to a twin.
+func (state *GameState) giftsBrunoMatch(tile int) bool {
+	me := state.Current
+	defer func() { state.Current = me }()
+	for seat, board := range state.Boards {
+		if seat == me || state.teammates(seat, me) {
+			continue
+		}
+		state.Current = seat
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if _, ok := state.brunoMatch(board, tile, r, c); ok && state.isPlacementFeasible(tile, r, c) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The campaign is a ladder of solo games against ever stronger or stranger
+// computer opponents. Winning a stage unlocks the next; progress is kept in
+// the player's profile, so a campaign can be picked up in a later session.
+
+// Stage is one campaign game: who the player faces and under which rules.
+type Stage struct {
+	Name      string
+	About     string
+	Opponents []string // strategy of each computer seat
+	Player    Handicap // the human's handicap, to ease the early stages
+	Rules     func(state *GameState)
+}
+
+var campaign = []Stage{
+	{Name: "The Rookie", About: "a beginner who places tiles anywhere; you may peek at the pile",
+		Opponents: []string{"random"}, Player: Handicap{Peek: true}},
+	{Name: "The Apprentice", About: "the standard computer, on even terms",
+		Opponents: []string{"greedy"}},
+	{Name: "Double Trouble", About: "two opponents at once",
+		Opponents: []string{"random", "greedy"}},
+	{Name: "Careful Carla", About: "a cautious player who keeps her gaps easy to fill",
+		Opponents: []string{"cautious"}},
+	{Name: "The Pickpocket", About: "once a game anyone may steal a tile from a board",
+		Opponents: []string{"greedy"}, Rules: func(state *GameState) { state.StealSwap = true }},
+	{Name: "Bruno's Club", About: "matching a diagonal neighbour earns another turn",
+		Opponents: []string{"cautious"}, Rules: func(state *GameState) { state.BrunoVariant = true }},
+	{Name: "Short Handed", About: "you start one diagonal tile down",
+		Opponents: []string{"cautious"}, Player: Handicap{Withheld: 1}},
+	{Name: "The Gauntlet", About: "three strong opponents, every table tile must be placed",
+		Opponents: []string{"cautious", "greedy", "cautious"}, Rules: func(state *GameState) { state.ForcedTable = true }},
+}
+
+// newCampaignGame deals stage n with the human in seat 0.
+func newCampaignGame(n int) (*GameState, error) {
+	stage := campaign[n]
+	state := &GameState{Heuristics: defaultHeuristics}
+	if stage.Rules != nil {
+		stage.Rules(state)
+	}
+	state.Boards = append(state.Boards, &Board{Handicap: stage.Player})
+	for _, name := range stage.Opponents {
+		s, err := lookupStrategy(name)
+		if err != nil {
+			return nil, err
+		}
+		state.Boards = append(state.Boards, &Board{IsAi: true, Strategy: s.Name()})
+	}
+	state.initDrawStack(len(state.Boards))
+	for _, b := range state.Boards {
+		if err := state.arrangeDiagonal(b); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// playCampaign plays stage n, or the next uncleared stage when n is -1, and
+// records a win in the profile at path.
+func playCampaign(path string, n int) error {
+	p, err := loadProfile(path)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		n = p.Campaign
+	}
+	if n >= len(campaign) {
+		fmt.Println("You have cleared the whole campaign! Replay a stage with: campaign N")
+		return nil
+	}
+	if n > p.Campaign {
+		return fmt.Errorf("stage %d is locked; clear stage %d first", n+1, p.Campaign+1)
+	}
+	stage := campaign[n]
+	fmt.Printf("=== Stage %d of %d: %s ===\nYou face %s.\n", n+1, len(campaign), stage.Name, stage.About)
+	state, err := newCampaignGame(n)
+	if err != nil {
+		return err
+	}
+	state.ProfilePath = path
+	state.Match = newMatch(1, len(state.Boards))
+	state.PrettyPrintBoardsGridCentered()
+	winner, finished := state.playRound()
+	if !finished {
+		fmt.Println("Stage abandoned.")
+		return nil
+	}
+	if winner != 0 {
+		fmt.Printf("%s is not beaten yet. Try again with: campaign %d\n", stage.Name, n+1)
+		return nil
+	}
+	// The finished game has updated the profile, so read it afresh.
+	if p, err = loadProfile(path); err != nil {
+		return err
+	}
+	if p.clearStage(n) {
+		if n+1 < len(campaign) {
+			fmt.Printf("Stage cleared! Next up: %s.\n", campaign[n+1].Name)
+		} else {
+			fmt.Println("Stage cleared! That was the final stage: the campaign is yours.")
+		}
+	}
+	return p.save(path)
+}
+
+// clearStage records a win at stage n, unlocking the next one. It reports
+// whether n had not been cleared before.
+func (p *Profile) clearStage(n int) bool {
+	if n < p.Campaign {
+		return false
+	}
+	p.Campaign = n + 1
+	return true
+}
+
+// printCampaign lists the stages, marking the cleared ones and the next.
+func printCampaign(w io.Writer, p *Profile) {
+	for i, stage := range campaign {
+		mark := "   "
+		switch {
+		case i < p.Campaign:
+			mark = "[x]"
+		case i == p.Campaign:
+			mark = "-->"
+		}
+		fmt.Fprintf(w, "%s %d. %-15s vs %s\n", mark, i+1, stage.Name, strings.Join(stage.Opponents, ", "))
+	}
+	fmt.Fprintf(w, "%d of %d stages cleared.\n", p.Campaign, len(campaign))
+}
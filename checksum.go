@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Saves end in a checksum of what comes before it, so a save that was edited
+// by hand or cut short — a full disk, a copy stopped halfway — is told apart
+// from a good one on load with a plain message, instead of failing somewhere
+// in the middle of reading it. -force loads such a save anyway, with a
+// warning, for those who edited it on purpose. Saves from before the
+// checksum, CSV format 4 and JSON format 1 and older, load as they did.
+
+// forceLoad loads saves whose checksum does not match.
+var forceLoad bool
+
+// csvChecksumVersion is the first CSV format with a CHECKSUM record.
+const csvChecksumVersion = 5
+
+// jsonChecksumVersion is the first JSON format with a checksum.
+const jsonChecksumVersion = 2
+
+// checksum is the hex SHA-256 of data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%x", sum)
+}
+
+// changedSave is the error for a save that no longer matches its checksum,
+// or, under -force, the warning printed before loading it anyway.
+func changedSave(filename, why string) error {
+	if forceLoad {
+		fmt.Printf("Warning: %s %s; loading it anyway as -force asks.\n", filename, why)
+		return nil
+	}
+	return fmt.Errorf("%s %s since it was saved; load it anyway with -force", filename, why)
+}
+
+// csvChecksumRecord is the record ending a CSV save whose contents are data.
+func csvChecksumRecord(data []byte) string {
+	return "CHECKSUM," + checksum(data) + "\n"
+}
+
+// checkCSVChecksum takes the CHECKSUM record off a CSV save's data and checks
+// it against the rest. Saves in a newer format are left for checkCSVVersion to
+// refuse.
+func checkCSVChecksum(filename string, data []byte) ([]byte, error) {
+	version := 1
+	first, _, _ := strings.Cut(string(data), "\n")
+	if field, v, ok := strings.Cut(strings.TrimSpace(first), ","); ok && field == "VERSION" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			version = n
+		}
+	}
+	if version > csvVersion {
+		return data, nil
+	}
+	body := bytes.TrimRight(data, "\r\n")
+	last := bytes.LastIndexByte(body, '\n')
+	if field, sum, ok := strings.Cut(string(body[last+1:]), ","); ok && field == "CHECKSUM" {
+		body = data[:last+1]
+		if strings.TrimSpace(sum) != checksum(body) {
+			return body, changedSave(filename, "was changed or cut short")
+		}
+		return body, nil
+	}
+	if version >= csvChecksumVersion {
+		return data, changedSave(filename, "has lost its checksum: it was cut short or changed")
+	}
+	return data, nil
+}
+
+// jsonChecksum is the checksum of a JSON save, taken over the game without
+// it.
+func jsonChecksum(g SavedGame) (string, error) {
+	g.Checksum = ""
+	data, err := json.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	return checksum(data), nil
+}
+
+// checkJSONChecksum checks a JSON save's game against its checksum.
+func checkJSONChecksum(filename string, g SavedGame) error {
+	if g.Checksum == "" {
+		if g.Version >= jsonChecksumVersion && g.Version <= jsonVersion {
+			return changedSave(filename, "has lost its checksum: it was cut short or changed")
+		}
+		return nil
+	}
+	sum, err := jsonChecksum(g)
+	if err != nil {
+		return err
+	}
+	if sum != g.Checksum {
+		return changedSave(filename, "was changed")
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock is a chess clock for the human seats: each starts with the same
+// time bank, which runs down only while that seat is answering a prompt
+// on its own turn.
+type Clock struct {
+	Bank    time.Duration
+	Left    []time.Duration // per seat
+	Forfeit bool            // running out loses the game instead of handing the moves to the computer
+
+	running bool // a human seat's turn is being timed
+	seat    int
+}
+
+func newClock(bank time.Duration, seats int, forfeit bool) *Clock {
+	c := &Clock{Bank: bank, Forfeit: forfeit}
+	c.reset(seats)
+	return c
+}
+
+// reset gives every seat a full bank again, for a new round.
+func (c *Clock) reset(seats int) {
+	c.Left = make([]time.Duration, seats)
+	for i := range c.Left {
+		c.Left[i] = c.Bank
+	}
+}
+
+// errTimeout is what a read that gave up waiting returns; on a clock, the
+// seat is out of time and its prompts return.
+var errTimeout = errors.New("timed out")
+
+type lineResult struct {
+	line string
+	err  error
+	at   time.Time // when it was read
+}
+
+// timedReader reads lines on a goroutine so a read can give up waiting.
+// Lines typed after a read timed out and before the next began answer the
+// prompt that gave up, so the next read drops them: in hot-seat play one
+// player's late move must not become the next player's.
+type timedReader struct {
+	src    lineReader
+	lines  chan lineResult
+	gaveUp bool // the last read timed out
+}
+
+func newTimedReader(src lineReader) *timedReader {
+	return &timedReader{src: src}
+}
+
+// readTimeout returns the next line, or errTimeout if none arrives within d.
+func (r *timedReader) readTimeout(delim byte, d time.Duration) (string, error) {
+	if r.lines == nil {
+		r.lines = make(chan lineResult)
+		go func() {
+			for {
+				line, err := r.src.ReadString(delim)
+				r.lines <- lineResult{line, err, time.Now()}
+				if err != nil {
+					close(r.lines)
+					return
+				}
+			}
+		}()
+	}
+	start := time.Now()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case res, ok := <-r.lines:
+			if !ok {
+				return "", errors.New("input closed")
+			}
+			if r.gaveUp && res.err == nil && res.at.Before(start) {
+				continue // typed too late for the read that gave up
+			}
+			r.gaveUp = false
+			return res.line, res.err
+		case <-timer.C:
+			r.gaveUp = true
+			return "", errTimeout
+		}
+	}
+}
+
+// clockReader is the prompts' reader while a clock is in play. During a
+// timed turn it shows the time left and charges the wait to the seat, and
+// once the seat is out of time every read returns errTimeout.
+type clockReader struct {
+	*timedReader
+	state *GameState
+}
+
+func (r clockReader) ReadString(delim byte) (string, error) {
+	c := r.state.Clock
+	if c == nil || !c.running {
+		return r.readTimeout(delim, 1<<62)
+	}
+	if c.Left[c.seat] <= 0 {
+		return "", errTimeout
+	}
+	fmt.Printf("(%s left) ", formatClock(c.Left[c.seat]))
+	start := time.Now()
+	line, err := r.readTimeout(delim, c.Left[c.seat])
+	c.Left[c.seat] -= time.Since(start)
+	if errors.Is(err, errTimeout) || c.Left[c.seat] <= 0 {
+		c.Left[c.seat] = 0
+		fmt.Println()
+		return "", errTimeout
+	}
+	return line, err
+}
+
+func formatClock(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// timed runs prompt on the current human seat's clock and reports whether
+// the seat ran out of time during it, the prompt having returned on
+// errTimeout without a move. A seat already out of time is not asked at
+// all.
+func (state *GameState) timed(prompt func()) bool {
+	c := state.Clock
+	if c == nil {
+		prompt()
+		return false
+	}
+	if c.Left[state.Current] <= 0 {
+		return true
+	}
+	c.running, c.seat = true, state.Current
+	defer func() { c.running = false }()
+	prompt()
+	return c.Left[c.seat] <= 0
+}
+
+// timeUp applies the clock's rule to a seat that ran out: it forfeits, or
+// the computer makes the rest of its turn. drawn is the tile the seat had
+// already taken, if any.
+func (state *GameState) timeUp(drawn *Move) {
+	seat := state.Current
+	if state.Clock.Forfeit {
+		msg := fmt.Sprintf("%s ran out of time and forfeits.", state.seatLabel(seat))
+		winner := -1
+		for _, s := range state.ranking() {
+			if s != seat && !state.teammates(s, seat) {
+				winner = s
+				msg += fmt.Sprintf(" %s wins.", state.winnerLabel(s))
+				break
+			}
+		}
+		state.PrettyPrintBoardsGridCentered()
+		state.gameOver(winner, msg)
+		return
+	}
+	fmt.Printf("%s is out of time: the computer moves for them.\n", state.seatLabel(seat))
+	board := state.Boards[seat]
+	board.IsAi = true
+	defer func() { board.IsAi = false }()
+	if drawn == nil {
+		move := state.computerDraw()
+		if state.Over {
+			return
+		}
+		state.logDraw(move)
+		drawn = &move
+	}
+	state.promptPlacement(*drawn)
+}
+
+// promptClock asks for the human seats' time bank and what running out
+// means; it returns nil for untimed play.
+func promptClock(seats int) *Clock {
+	fmt.Print("Minutes on each human's clock (0 or blank for no clock): ")
+	line, _ := reader.ReadString('\n')
+	minutes, err := strconv.ParseFloat(strings.TrimSpace(line), 64)
+	if err != nil || minutes <= 0 {
+		return nil
+	}
+	fmt.Print("When a clock runs out, [f]orfeit the game or let the computer [a]uto-move? (default auto): ")
+	line, _ = reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	forfeit := line == "f" || line == "forfeit"
+	return newClock(time.Duration(minutes*float64(time.Minute)), seats, forfeit)
+}
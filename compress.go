@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// Files whose names end in .gz are written and read gzipped, without
+// anything else to ask for: game.json.gz is a JSON save, game.csv.gz a CSV
+// one, and the transcript, the move log, the archive and the -ab log all
+// compress the same way. Writers flush every write through to the file, so
+// a game that stops in the middle leaves a transcript or move log that
+// reads up to its last line, and appending to a .gz file adds a member that
+// readers take as part of the same stream.
+
+// isGzip reports whether name is a gzipped file.
+func isGzip(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".gz")
+}
+
+// plainName is name without a .gz ending, to tell the format inside.
+func plainName(name string) string {
+	if isGzip(name) {
+		return name[:len(name)-len(".gz")]
+	}
+	return name
+}
+
+// gzipFile writes gzipped to a file, closing both together.
+type gzipFile struct {
+	gz *gzip.Writer
+	f  *os.File
+}
+
+func (g *gzipFile) Write(p []byte) (int, error) {
+	n, err := g.gz.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, g.gz.Flush()
+}
+
+func (g *gzipFile) WriteString(s string) (int, error) {
+	return g.Write([]byte(s))
+}
+
+func (g *gzipFile) Close() error {
+	err := g.gz.Close()
+	if cerr := g.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// fileWriter is a file being written, gzipped or not.
+type fileWriter interface {
+	io.WriteCloser
+	io.StringWriter
+}
+
+// wrapWriter gzips writes to f when name asks for it.
+func wrapWriter(name string, f *os.File) fileWriter {
+	if isGzip(name) {
+		return &gzipFile{gz: gzip.NewWriter(f), f: f}
+	}
+	return f
+}
+
+// createFile creates name, gzipped when it ends in .gz.
+func createFile(name string) (fileWriter, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return wrapWriter(name, f), nil
+}
+
+// appendFile opens name to add to its end, creating it if needed.
+func appendFile(name string) (fileWriter, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return wrapWriter(name, f), nil
+}
+
+// gzipReader reads a gzipped file, closing both together.
+type gzipReader struct {
+	*gzip.Reader
+	f *os.File
+}
+
+// Read ends a stream that stops without its gzip trailer, as a file still
+// being written or one left by a game that stopped does, where it stops.
+func (g *gzipReader) Read(p []byte) (int, error) {
+	n, err := g.Reader.Read(p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (g *gzipReader) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// openFile opens name to read, ungzipping it when it ends in .gz.
+func openFile(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !isGzip(name) {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		if err == io.EOF {
+			return io.NopCloser(bytes.NewReader(nil)), nil // nothing written yet
+		}
+		return nil, err
+	}
+	return &gzipReader{gz, f}, nil
+}
+
+// readFile reads all of name, ungzipped.
+func readFile(name string) ([]byte, error) {
+	if !isGzip(name) {
+		return os.ReadFile(name)
+	}
+	f, err := openFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// writeFile writes data to name, gzipped when it ends in .gz.
+func writeFile(name string, data []byte) error {
+	if !isGzip(name) {
+		return os.WriteFile(name, data, 0o644)
+	}
+	f, err := createFile(name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// stateHash fingerprints the position including the hidden draw order, so two
+// bug reports can be matched. Late in a game the few orders left can be tried
+// against it, so it is only dumped once the game is over.
+func (state *GameState) stateHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "current=%d\n", state.Current)
+	for _, b := range state.Boards {
+		fmt.Fprintf(h, "board ai=%v %v\n", b.IsAi, b.Grid)
+	}
+	fmt.Fprintf(h, "table=%v\ndraw=%v\n", state.Table, state.Draw)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func drawHash(draw []int) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprint(draw))))
+}
+
+// writeDebugDump writes everything needed to reproduce a bug report. The
+// draw pile is listed sorted; its order, the hashes taken over it and the
+// seed that dealt it are left out until the game is over, so the dump is
+// safe to share mid-game.
+func (state *GameState) writeDebugDump(w io.Writer) {
+	fmt.Fprintf(w, "time: %s\n", time.Now().Format(time.RFC3339))
+	if state.Over {
+		fmt.Fprintf(w, "seed: %d\n", state.Seed)
+		fmt.Fprintf(w, "state hash: %s\n", state.stateHash())
+	} else {
+		fmt.Fprintln(w, "seed and hashes: withheld until the game is over")
+	}
+	fmt.Fprintf(w, "current: %d\n", state.Current)
+	fmt.Fprintf(w, "rules: analyze=%v bruno=%v (%s) nondecreasing=%v size=%d tiles=1-%d wildcards=%d end=%s teams=%v forcedtable=%v openpile=%v hand=%d dist=%s increasingdiagonal=%v steal=%v holes=%v mulligan=%v sharedpool=%v\n", state.Analyze, state.BrunoVariant, state.Bruno, state.NonDecreasing, BoardSize, state.maxTile(), state.Wildcards, endModeNames[state.End], state.Teams, state.ForcedTable, state.OpenPile, state.HandSize, state.tileCopies(len(state.Boards)), state.DiagonalRule, state.StealSwap, state.Holes, state.Mulligan, state.SharedPool)
+	fmt.Fprintf(w, "heuristics: %+v\n", state.Heuristics)
+	fmt.Fprintf(w, "game stage: %.2f table threshold: %.2f\n", state.gameStage(), state.tableThreshold())
+	if ab := state.ABTest; ab != nil {
+		fmt.Fprintf(w, "ab test: seat=%d a=%s b=%s turns=%d\n", ab.Seat, ab.A.Name(), ab.B.Name(), ab.turns)
+	}
+
+	for _, b := range state.Backfills {
+		fmt.Fprintf(w, "backfill: seat %d taken over by %s at %s\n", b.Seat, b.Strategy, b.At.Format(time.RFC3339))
+	}
+
+	sorted := append([]int{}, state.Draw...)
+	sort.Ints(sorted)
+	fmt.Fprintf(w, "draw (%d, sorted): %v\n", len(sorted), sorted)
+	if state.Over {
+		fmt.Fprintf(w, "draw order hash: %s\n", drawHash(state.Draw))
+	}
+	fmt.Fprintf(w, "table (%d): %v\n", len(state.Table), state.Table)
+
+	for i, b := range state.Boards {
+		kind := "human"
+		if b.Controlled {
+			kind = "human, controlled by tester"
+		}
+		if b.IsAi {
+			kind = "computer, strategy " + state.strategyFor(i).Name()
+		}
+		if b.Handicap != (Handicap{}) {
+			kind += ", handicap " + b.Handicap.String()
+		}
+		fmt.Fprintf(w, "board %d (%s):\n", i, kind)
+		for r := 0; r < BoardSize; r++ {
+			fmt.Fprintf(w, "  %v\n", b.Grid[r])
+		}
+	}
+}
+
+// dumpDebug writes a debug dump to a fresh timestamped file and returns its
+// name.
+func (state *GameState) dumpDebug() (string, error) {
+	name := fmt.Sprintf("unlucky-debug-%s.txt", time.Now().Format("20060102-150405"))
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	state.writeDebugDump(f)
+	return name, f.Close()
+}
+
+// handleDebugCommand serves the hidden "debug" command at any prompt.
+func (state *GameState) handleDebugCommand() {
+	name, err := state.dumpDebug()
+	if err != nil {
+		fmt.Println("Debug dump failed:", err)
+		return
+	}
+	fmt.Println("State written to", name)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// demoSeed fixes the shuffle so every demo plays the same game.
+const demoSeed = 2024
+
+var demoIntro = []string{
+	"Welcome to Unlucky Numbers!",
+	"Each player fills a 4x4 board with tiles numbered 1 to 20.",
+	"Every row must increase left to right and every column top to bottom.",
+	"On your turn draw from the pile or take a face-up tile from the table,",
+	"then place it, swap it for a tile already on your board, or discard it.",
+	"The first player to fill their board wins. Two computers will show you how.",
+}
+
+// demo plays a fixed computer-vs-computer game, pausing between turns and
+// explaining each kind of decision the first time it comes up.
+type demo struct {
+	state *GameState
+	delay time.Duration
+	seen  map[string]bool
+}
+
+func runDemo(delay time.Duration) {
+	seedRNG(demoSeed)
+	state := &GameState{BrunoVariant: true, Seed: demoSeed}
+	state.initDrawStack(2)
+	for p := 0; p < 2; p++ {
+		b := &Board{IsAi: true}
+		if err := state.fillRandomDiagonal(b); err != nil {
+			fmt.Println("Demo setup failed:", err)
+			return
+		}
+		state.Boards = append(state.Boards, b)
+	}
+
+	d := &demo{state: state, delay: delay, seen: map[string]bool{}}
+	d.say(demoIntro...)
+	state.PrettyPrintBoardsGridCentered()
+	d.say("Both boards start with four tiles on the diagonal.",
+		"The Bruno variant is on: matching a diagonal neighbour earns an extra turn.")
+
+	for turn := 1; ; turn++ {
+		if turn%10 == 0 {
+			d.say(fmt.Sprintf("Turn %d: %d tiles left in the pile, %d on the table.",
+				turn, len(state.Draw), len(state.Table)))
+		}
+		move := state.computerDraw()
+		if state.Over {
+			return
+		}
+		d.explain(move)
+		state.promptPlacement(move)
+		state.PrettyPrintBoardsGridCentered()
+		if state.Over {
+			return
+		}
+		time.Sleep(d.delay)
+		state.Current = (state.Current + 1) % len(state.Boards)
+	}
+}
+
+// explain comments on the move the current computer is about to make with
+// the tile it just picked up.
+func (d *demo) explain(picked Move) {
+	state := d.state
+	board := state.Boards[state.Current]
+	move, ok := picked, true
+	if picked.Type == Draw || picked.Type == FromHand {
+		move, ok = state.strategyFor(state.Current).ChooseMove(state, picked.Tile)
+	} else {
+		d.once("table", fmt.Sprintf("Computer %d takes the %d from the table instead of the pile.", state.Current, picked.Tile),
+			"Table tiles are visible, so you know exactly what you are getting.")
+	}
+	if !ok {
+		d.once("discard", fmt.Sprintf("The %d fits nowhere, so it is discarded to the table.", picked.Tile),
+			"Any player may pick it up on a later turn.")
+		return
+	}
+	switch move.Type {
+	case Place:
+		d.once("place", fmt.Sprintf("The %d goes in an empty cell at %s.", move.Tile, move.Cell),
+			"Smaller numbers belong near the top left, bigger ones near the bottom right.")
+	case Swap:
+		d.once("swap", fmt.Sprintf("The %d replaces the %d at %s.", move.Tile, move.OldTile, move.Cell),
+			"Swapping keeps the board flexible; the old tile goes to the table.")
+	}
+	if _, twin := state.brunoMatch(board, move.Tile, move.Cell.R, move.Cell.C); twin {
+		d.say(fmt.Sprintf("The %d lands diagonally next to its twin: that is a Bruno extra turn!", move.Tile))
+	}
+	if move.Type == Place && emptyCells(board) == 1 {
+		d.say(fmt.Sprintf("This fills the last cell of Computer %d's board. Game over!", state.Current))
+	}
+}
+
+func emptyCells(board *Board) int {
+	n := 0
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if board.Grid[r][c] == 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// once says lines the first time key comes up.
+func (d *demo) once(key string, lines ...string) {
+	if d.seen[key] {
+		return
+	}
+	d.seen[key] = true
+	d.say(lines...)
+}
+
+// say prints a boxed commentary overlay and gives the audience time to read.
+func (d *demo) say(lines ...string) {
+	width := 0
+	for _, l := range lines {
+		if n := len([]rune(l)); n > width {
+			width = n
+		}
+	}
+	fmt.Println("  .-" + strings.Repeat("-", width) + "-.")
+	for _, l := range lines {
+		fmt.Println("  | " + l + strings.Repeat(" ", width-len([]rune(l))) + " |")
+	}
+	fmt.Println("  '-" + strings.Repeat("-", width) + "-'")
+	time.Sleep(d.delay)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Under the increasing-diagonal rule the main diagonal must strictly
+// increase from top left to bottom right as well, whatever the row and
+// column rule, which leaves fewer tiles able to fill a diagonal gap.
+
+// diagonalFeasible checks tile at diagonal cell (i,i) against the nearest
+// tiles up-left and down-right of it, and that any gap between them can
+// still be filled from remaining.
+func (state *GameState) diagonalFeasible(tile, i int, remaining []int) bool {
+	board := state.Boards[state.Current]
+	gap := 0
+	for d := i - 1; d >= 0; d-- {
+		v := board.Grid[d][d]
+		if !isTile(v) {
+			if v == 0 {
+				gap++
+			}
+			continue
+		}
+		if v >= tile || (gap > 0 && !canFillGap(remaining, v, tile, gap, 1)) {
+			return false
+		}
+		break
+	}
+	gap = 0
+	for d := i + 1; d < BoardSize; d++ {
+		v := board.Grid[d][d]
+		if !isTile(v) {
+			if v == 0 {
+				gap++
+			}
+			continue
+		}
+		if v <= tile || (gap > 0 && !canFillGap(remaining, tile, v, gap, 1)) {
+			return false
+		}
+		break
+	}
+	return true
+}
+
+// diagonalFactor weights placing tile on diagonal cell (i,i) by how well
+// the empty diagonal runs either side of it can still be filled.
+func (state *GameState) diagonalFactor(tile, i int) float64 {
+	if tile == Wildcard {
+		return 1
+	}
+	remaining := append(append([]int{}, state.Draw...), state.Table...)
+	return state.gapFactor(tile, i, i, -1, -1, 1, remaining) * state.gapFactor(tile, i, i, 1, 1, 1, remaining)
+}
+
+// dealIncreasing draws n tiles of different values for a diagonal, in
+// increasing order. Repeated values go back into the pile.
+func (state *GameState) dealIncreasing(n int) ([]int, error) {
+	var tiles, spare []int
+	seen := map[int]bool{}
+	for len(tiles) < n {
+		tile, err := state.popDraw()
+		if err != nil {
+			return nil, err
+		}
+		if isTile(tile) && seen[tile] {
+			spare = append(spare, tile)
+			continue
+		}
+		seen[tile] = true
+		tiles = append(tiles, tile)
+	}
+	state.returnToPile(spare...)
+	sort.Ints(tiles)
+	return tiles, nil
+}
+
+// diagonalIncreases reports whether board's diagonal tiles strictly
+// increase, skipping empty cells and wildcards.
+func diagonalIncreases(board *Board) bool {
+	last := 0
+	for i := 0; i < BoardSize; i++ {
+		v := board.Grid[i][i]
+		if !isTile(v) {
+			continue
+		}
+		if v <= last {
+			return false
+		}
+		last = v
+	}
+	return true
+}
+
+func promptDiagonalRule() bool {
+	fmt.Print("Must the main diagonal strictly increase too? (y/N): ")
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
+
+// The official setup deals each player one tile per diagonal cell, all of
+// different values, and lets them choose where each goes as long as the
+// diagonal increases. With a full diagonal of numbered tiles that leaves
+// one way to do it; the choice is real once a handicap withholds cells or a
+// wildcard is dealt.
+
+// arrangeDiagonal runs the setup phase for board: computers use
+// bestArrangement, humans are offered it and may pick their own.
+func (state *GameState) arrangeDiagonal(board *Board) error {
+	tiles, err := state.dealIncreasing(BoardSize - board.Handicap.Withheld)
+	if err != nil {
+		return fmt.Errorf("dealing starting tiles: %w", err)
+	}
+	if state.Mulligan && state.wantsMulligan(board, tiles) {
+		state.returnToPile(tiles...)
+		if tiles, err = state.dealIncreasing(len(tiles)); err != nil {
+			return fmt.Errorf("redealing starting tiles: %w", err)
+		}
+		fmt.Printf("Mulligan: %s redrew %s.\n", map[bool]string{true: "the computer", false: "the player"}[board.IsAi], handLabel(tiles))
+	}
+	cells := state.bestArrangement(tiles)
+	if !board.IsAi && (len(tiles) < BoardSize || tiles[0] == Wildcard) {
+		cells = state.promptArrangement(tiles, cells)
+	}
+	for i, t := range tiles {
+		board.Grid[cells[i]][cells[i]] = t
+	}
+	return nil
+}
+
+// bestArrangement picks increasing diagonal cells for the sorted tiles,
+// putting each as close as it can to the cell its value suits best.
+func (state *GameState) bestArrangement(tiles []int) []int {
+	var best []int
+	bestCost := math.Inf(1)
+	cells := make([]int, 0, len(tiles))
+	var walk func(i, from int, cost float64)
+	walk = func(i, from int, cost float64) {
+		if cost >= bestCost {
+			return
+		}
+		if i == len(tiles) {
+			best, bestCost = append([]int{}, cells...), cost
+			return
+		}
+		for d := from; d <= BoardSize-(len(tiles)-i); d++ {
+			cells = append(cells, d)
+			walk(i+1, d+1, cost+state.diagonalCost(tiles[i], d))
+			cells = cells[:len(cells)-1]
+		}
+	}
+	walk(0, 0, 0)
+	return best
+}
+
+// diagonalCost is how far tile sits from the value that suits diagonal
+// cell d; a wildcard suits every cell.
+func (state *GameState) diagonalCost(tile, d int) float64 {
+	if !isTile(tile) {
+		return 0
+	}
+	return math.Abs(xOfT(tile, state.maxTile()) - float64(2+2*d))
+}
+
+// validArrangement checks that cells puts tiles on distinct diagonal cells
+// with the numbered ones increasing.
+func validArrangement(tiles, cells []int) error {
+	if len(cells) != len(tiles) {
+		return fmt.Errorf("expected %d cells, one per tile", len(tiles))
+	}
+	at := map[int]int{}
+	for i, d := range cells {
+		if d < 0 || d >= BoardSize {
+			return fmt.Errorf("cell %d is off the diagonal (0-%d)", d, BoardSize-1)
+		}
+		if _, taken := at[d]; taken {
+			return fmt.Errorf("cell %d is used twice", d)
+		}
+		at[d] = tiles[i]
+	}
+	last := 0
+	for d := 0; d < BoardSize; d++ {
+		if t, ok := at[d]; ok && isTile(t) {
+			if t <= last {
+				return fmt.Errorf("the diagonal must increase, but %d comes after %d", t, last)
+			}
+			last = t
+		}
+	}
+	return nil
+}
+
+// promptArrangement asks a human where each starting tile goes, offering
+// suggested as the default.
+func (state *GameState) promptArrangement(tiles, suggested []int) []int {
+	labels := make([]string, len(suggested))
+	for i, d := range suggested {
+		labels[i] = strconv.Itoa(d)
+	}
+	for {
+		fmt.Printf("Your starting tiles: %s. Diagonal cell (0-%d) for each, in order (Enter for %s): ",
+			handLabel(tiles), BoardSize-1, strings.Join(labels, " "))
+		line, err := reader.ReadString('\n')
+		fields := strings.Fields(line)
+		if len(fields) == 0 || err != nil && len(fields) < len(tiles) {
+			return suggested
+		}
+		cells := make([]int, len(fields))
+		for i, f := range fields {
+			if cells[i], err = strconv.Atoi(f); err != nil {
+				cells[i] = -1
+			}
+		}
+		if err := validArrangement(tiles, cells); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return cells
+	}
+}
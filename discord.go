@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The discord command runs the game as a Discord bot, a game to a channel
+// played by commands typed in it:
+//
+//	!un new [computers] [@friends]   start a game, against one computer unless told
+//	!un draw                         draw from the pile
+//	!un take 7                       take a tile from the table
+//	!un place B3, or just !un B3     place the tile drawn, swapping a tile there
+//	!un discard                      put it on the table
+//	!un hint                         the engine's advice
+//	!un board                        show the game
+//	!un stop                         end the channel's game
+//
+// Whoever starts a game takes the first seat and the friends mentioned the
+// next, then the computers; each seat is played by its own Discord user
+// only. Boards come back as monospace code blocks after every move. The
+// games are the serve command's, with its rules and its computer players.
+// The bot's token is read from DISCORD_BOT_TOKEN, and the bot needs the
+// message content intent turned on in the developer portal.
+
+const (
+	discordAPI      = "https://discord.com/api/v10"
+	discordGateway  = "wss://gateway.discord.gg/?v=10&encoding=json"
+	discordPrefix   = "!un"
+	discordMaxReply = 2000 // characters a message may hold
+	// guild messages, direct messages and message content
+	discordIntents = 1<<9 | 1<<12 | 1<<15
+)
+
+// discordUser is a message's author or someone it mentions.
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+// name is what the user is called at the table.
+func (u discordUser) name() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+// discordMessage is a message posted in a channel the bot reads.
+type discordMessage struct {
+	ChannelID string        `json:"channel_id"`
+	Content   string        `json:"content"`
+	Author    discordUser   `json:"author"`
+	Mentions  []discordUser `json:"mentions"`
+}
+
+// discordGame is a channel's game.
+type discordGame struct {
+	game  *servedGame
+	users []string // the Discord user playing each seat, "" for a computer
+	shown int      // moves in the history the channel has been told
+}
+
+// discordBot plays a game in each channel that starts one.
+type discordBot struct {
+	server *gameServer
+	games  map[string]*discordGame // by channel
+}
+
+func newDiscordBot() *discordBot {
+	return &discordBot{server: newGameServer(), games: map[string]*discordGame{}}
+}
+
+// handle answers a message, "" when it is not for the bot.
+func (bot *discordBot) handle(m discordMessage) string {
+	fields := strings.Fields(m.Content)
+	if m.Author.Bot || len(fields) == 0 || fields[0] != discordPrefix {
+		return ""
+	}
+	args := fields[1:]
+	if len(args) == 0 || args[0] == "help" {
+		return discordHelp()
+	}
+	bot.server.mu.Lock()
+	defer bot.server.mu.Unlock()
+	if args[0] == "new" {
+		return bot.newGame(m, args[1:])
+	}
+	dg := bot.games[m.ChannelID]
+	if dg == nil {
+		return "No game in this channel; start one with `!un new`."
+	}
+	g := dg.game
+	defer g.enter()()
+	switch args[0] {
+	case "board":
+		return dg.report("")
+	case "stop":
+		if len(dg.seatsOf(m.Author.ID)) == 0 {
+			return "Only the game's players can stop it."
+		}
+		delete(bot.games, m.ChannelID)
+		bot.forget(dg)
+		return fmt.Sprintf("%s stopped the game.", m.Author.name())
+	case "hint":
+		advice, err := g.recommend()
+		if err != nil {
+			return err.Error() + "."
+		}
+		return dg.describeAdvice(advice)
+	}
+
+	req := moveRequest{Type: args[0]}
+	switch {
+	case args[0] == "take" && len(args) == 2:
+		tile, err := parseTile(args[1], g.state.maxTile())
+		if err != nil {
+			return err.Error() + "."
+		}
+		req.Tile = tile
+	case args[0] == "place" && len(args) == 2:
+		req.Cell = args[1]
+	case len(args) == 1 && (args[0] == "draw" || args[0] == "discard"):
+	case len(args) == 1:
+		if _, err := parseCell(args[0]); err != nil {
+			return fmt.Sprintf("`%s` is not a command; `!un help` lists them.", args[0])
+		}
+		req = moveRequest{Type: "place", Cell: args[0]}
+	default:
+		return "That is not a command; `!un help` lists them."
+	}
+	if g.over {
+		return "The game is over; start another with `!un new`."
+	}
+	if dg.users[g.state.Current] != m.Author.ID {
+		return fmt.Sprintf("It is %s's turn.", g.state.seatLabel(g.state.Current))
+	}
+	req.Token = g.tokens[g.state.Current]
+	if err := g.remoteMove(req); err != nil {
+		return err.Error() + "."
+	}
+	if g.over {
+		bot.forget(dg)
+	}
+	return dg.report("")
+}
+
+// forget takes a channel's game off the server once the channel is done
+// with it. The channel keeps a finished game to show until the next starts.
+func (bot *discordBot) forget(dg *discordGame) {
+	delete(bot.server.games, dg.game.id)
+}
+
+// newGame starts the channel's game for the author, the friends mentioned
+// and the computers asked for.
+func (bot *discordBot) newGame(m discordMessage, args []string) string {
+	if dg := bot.games[m.ChannelID]; dg != nil && !dg.game.over {
+		return "A game is under way in this channel; `!un stop` ends it."
+	}
+	players := []discordUser{m.Author}
+	seen := map[string]bool{m.Author.ID: true}
+	for _, u := range m.Mentions {
+		if !u.Bot && !seen[u.ID] {
+			players, seen[u.ID] = append(players, u), true
+		}
+	}
+	computers := 0
+	if len(players) == 1 {
+		computers = 1
+	}
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			computers = n
+		}
+	}
+	var names []string
+	for _, u := range players {
+		names = append(names, u.name())
+	}
+	humans := len(players)
+	g, err := bot.server.newGame(newGameRequest{Players: humans + computers, Humans: &humans, Names: names})
+	if err != nil {
+		return err.Error() + "."
+	}
+	dg := &discordGame{game: g, users: make([]string, len(g.state.Boards))}
+	for i, u := range players {
+		dg.users[i] = u.ID
+	}
+	bot.games[m.ChannelID] = dg
+	defer g.enter()()
+	return dg.report("New game: " + strings.Join(g.view().seatNames(), " vs ") + ".")
+}
+
+// seatsOf lists the seats user plays.
+func (dg *discordGame) seatsOf(user string) []int {
+	var seats []int
+	for i, u := range dg.users {
+		if u == user {
+			seats = append(seats, i)
+		}
+	}
+	return seats
+}
+
+// seatNames lists who plays.
+func (v servedState) seatNames() []string {
+	var names []string
+	for _, s := range v.Seats {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// report tells the channel the moves made since it was last told, the
+// game, and whose move it is.
+func (dg *discordGame) report(head string) string {
+	g, state := dg.game, dg.game.state
+	var b strings.Builder
+	if head != "" {
+		b.WriteString(head + "\n")
+	}
+	for _, p := range state.History[min(dg.shown, len(state.History)):] {
+		b.WriteString(state.describePlayed(p) + "\n")
+	}
+	dg.shown = len(state.History)
+	b.WriteString("```\n" + discordBoards(state) + "```\n")
+	switch {
+	case g.over && g.winner >= 0:
+		fmt.Fprintf(&b, "Game over: %s wins!", state.winnerLabel(g.winner))
+	case g.over:
+		b.WriteString("Game over: the pile ran out with no winner.")
+	case state.Pending != nil:
+		fmt.Fprintf(&b, "<@%s>, place your %s: `!un B3` or `!un discard`.", dg.users[state.Current], tileLabel(state.Pending.Tile))
+	default:
+		fmt.Fprintf(&b, "<@%s>, your turn: `!un draw`", dg.users[state.Current])
+		if len(state.Table) > 0 {
+			b.WriteString(" or `!un take <tile>`")
+		}
+		b.WriteString(".")
+	}
+	return b.String()
+}
+
+// describeAdvice puts the engine's advice in words.
+func (dg *discordGame) describeAdvice(advice servedAdvice) string {
+	state := dg.game.state
+	var b strings.Builder
+	switch {
+	case state.Pending == nil && advice.Take != 0:
+		fmt.Fprintf(&b, "Take %s from the table", tileLabel(advice.Take))
+		if len(advice.Moves) > 0 {
+			fmt.Fprintf(&b, " for %s", advice.Moves[0].Cell)
+		}
+		b.WriteString(".")
+	case state.Pending == nil:
+		b.WriteString("Draw from the pile.")
+	case len(advice.Moves) == 0:
+		fmt.Fprintf(&b, "%s fits nowhere; discard it.", tileLabel(state.Pending.Tile))
+	default:
+		b.WriteString("Best places for " + tileLabel(state.Pending.Tile) + ":")
+		for i, m := range advice.Moves[:min(len(advice.Moves), 3)] {
+			fmt.Fprintf(&b, "\n%d. %s %s (score %.1f)", i+1, m.Type, m.Cell, *m.Score)
+		}
+	}
+	return b.String()
+}
+
+// discordBoards draws the game for a code block: the counters, the table
+// and pile, then the boards side by side, the seat to play marked.
+func discordBoards(state *GameState) string {
+	var b strings.Builder
+	table := append([]int{}, state.Table...)
+	sort.Ints(table)
+	labels := []string{}
+	for _, t := range table {
+		labels = append(labels, tileLabel(t))
+	}
+	if len(labels) == 0 {
+		labels = append(labels, "empty")
+	}
+	fmt.Fprintf(&b, "%s\nTable: %s   Pile: %d\n\n", state.counters(), strings.Join(labels, ", "), len(state.Draw))
+	width := 3 + 3*BoardSize // a board's columns, its row numbers first
+	line := func(cell func(seat int) string) {
+		parts := make([]string, len(state.Boards))
+		for seat := range state.Boards {
+			parts[seat] = fmt.Sprintf("%-*s", width, cell(seat))
+		}
+		b.WriteString(strings.TrimRight(strings.Join(parts, "   "), " ") + "\n")
+	}
+	line(func(seat int) string {
+		name := state.seatLabel(seat)
+		if seat == state.Current {
+			name = "> " + name
+		}
+		return name
+	})
+	line(func(int) string {
+		s := "  "
+		for c := 0; c < BoardSize; c++ {
+			s += fmt.Sprintf("%3c", 'A'+c)
+		}
+		return s
+	})
+	for r := 0; r < BoardSize; r++ {
+		line(func(seat int) string {
+			s := fmt.Sprintf("%2d", r+1)
+			for c := 0; c < BoardSize; c++ {
+				label := "."
+				if v := state.Boards[seat].Grid[r][c]; v != 0 {
+					label = tileLabel(v)
+				}
+				s += fmt.Sprintf("%3s", label)
+			}
+			return s
+		})
+	}
+	return b.String()
+}
+
+func discordHelp() string {
+	return "Unlucky Numbers, played here:\n" +
+		"`!un new [computers] [@friends]` start a game\n" +
+		"`!un draw` or `!un take 7` take a tile\n" +
+		"`!un B3` or `!un discard` play it\n" +
+		"`!un hint` the engine's advice, `!un board` the game, `!un stop` end it"
+}
+
+// runDiscordBot connects to Discord with token and answers the channels'
+// messages, connecting again whenever the connection drops.
+func runDiscordBot(token string) error {
+	bot := newDiscordBot()
+	for {
+		err := bot.session(token)
+		var fatal *discordFatal
+		if errors.As(err, &fatal) {
+			return err
+		}
+		fmt.Println("Disconnected from Discord:", err, "- connecting again.")
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// discordFatal is a gateway error that connecting again does not cure, a
+// bad token or a missing intent.
+type discordFatal struct{ msg string }
+
+func (e *discordFatal) Error() string { return e.msg }
+
+// gatewayPayload is a message on Discord's gateway.
+type gatewayPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// session runs one connection to the gateway: it identifies, keeps the
+// heartbeat and answers messages until the connection ends.
+func (bot *discordBot) session(token string) error {
+	conn, err := dialWebSocket(discordGateway, 1<<24)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	send := func(op int, d any) error {
+		data, err := json.Marshal(map[string]any{"op": op, "d": d})
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(data)
+	}
+
+	var seq *int64
+	done := make(chan struct{})
+	defer close(done)
+	beats := make(chan *int64, 1)
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			if err == errWSClosed {
+				return discordCloseError(conn)
+			}
+			return err
+		}
+		var p gatewayPayload
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		if p.S != nil {
+			seq = p.S
+			select {
+			case <-beats:
+			default:
+			}
+			beats <- seq
+		}
+		switch p.Op {
+		case 10: // hello: start the heartbeat and identify
+			var hello struct {
+				Interval int `json:"heartbeat_interval"`
+			}
+			json.Unmarshal(p.D, &hello)
+			go discordHeartbeat(time.Duration(hello.Interval)*time.Millisecond, beats, done, send)
+			err = send(2, map[string]any{
+				"token":      token,
+				"intents":    discordIntents,
+				"properties": map[string]string{"os": "linux", "browser": "unlucky_numbers", "device": "unlucky_numbers"},
+			})
+		case 1: // the gateway asks for a heartbeat now
+			err = send(1, seq)
+		case 7, 9: // reconnect, invalid session
+			return fmt.Errorf("the gateway asked to connect again (op %d)", p.Op)
+		case 0:
+			switch p.T {
+			case "READY":
+				var ready struct {
+					User discordUser `json:"user"`
+				}
+				json.Unmarshal(p.D, &ready)
+				fmt.Printf("Connected to Discord as %s.\n", ready.User.Username)
+			case "MESSAGE_CREATE":
+				var m discordMessage
+				if err := json.Unmarshal(p.D, &m); err != nil {
+					continue
+				}
+				if reply := bot.handle(m); reply != "" {
+					go func() {
+						if err := postDiscordMessage(token, m.ChannelID, reply); err != nil {
+							fmt.Println("Failed to answer on Discord:", err)
+						}
+					}()
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// discordHeartbeat sends the heartbeat every interval with the last
+// sequence number seen, until done.
+func discordHeartbeat(interval time.Duration, beats <-chan *int64, done <-chan struct{}, send func(int, any) error) {
+	var seq *int64
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case s := <-beats:
+			seq = s
+		case <-ticker.C:
+			if send(1, seq) != nil {
+				return
+			}
+		}
+	}
+}
+
+// discordCloseError is the error for a gateway that closed the connection;
+// a bad token, a bad shard or an intent not granted is not cured by
+// connecting again.
+func discordCloseError(conn *wsConn) error {
+	switch code := conn.closeCode; {
+	case code == 4004:
+		return &discordFatal{"Discord refused the bot's token"}
+	case code == 4014:
+		return &discordFatal{"Discord refused the bot's intents; turn on the message content intent in the developer portal"}
+	case code >= 4010 && code <= 4013:
+		return &discordFatal{fmt.Sprintf("Discord closed the connection for good (code %d)", code)}
+	default:
+		return fmt.Errorf("the gateway closed the connection (code %d)", code)
+	}
+}
+
+// postDiscordMessage posts text to a channel, cut to the length Discord
+// takes, waiting once if it is asked to slow down.
+func postDiscordMessage(token, channel, text string) error {
+	if len([]rune(text)) > discordMaxReply {
+		text = string([]rune(text)[:discordMaxReply-1]) + "…"
+	}
+	body, err := json.Marshal(map[string]string{"content": text})
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("POST", discordAPI+"/channels/"+channel+"/messages", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			var limited struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			json.Unmarshal(data, &limited)
+			time.Sleep(time.Duration(limited.RetryAfter * float64(time.Second)))
+			continue
+		}
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("%s: %s", resp.Status, data)
+		}
+		return nil
+	}
+}
+
+// discordToken is the bot's token from the environment.
+func discordToken() (string, error) {
+	token := strings.TrimSpace(os.Getenv("DISCORD_BOT_TOKEN"))
+	if token == "" {
+		return "", errors.New("set DISCORD_BOT_TOKEN to the bot's token")
+	}
+	return token, nil
+}
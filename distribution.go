@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Distribution is the exact multiset of numbered tiles in a game: how many
+// copies of each value go into the pile before the diagonals are dealt.
+// Without one, every player brings one set of tiles 1-maxTile.
+type Distribution map[int]int
+
+// maxCopies bounds the copies of one value a distribution may hold, far
+// above any real game's but low enough that building the pile stays cheap.
+const maxCopies = 99
+
+// parseDistribution reads a comma separated list of values or ranges, each
+// optionally with a copy count: "1-20x2" is two full sets of 1-20, and
+// "1-20x2,8-13" adds a third copy of the middle values.
+func parseDistribution(spec string, maxTile int) (Distribution, error) {
+	d := Distribution{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(strings.ToLower(part))
+		if part == "" {
+			continue
+		}
+		lo, hi, n, err := parseDistributionTerm(part, maxTile)
+		if err != nil {
+			return nil, err
+		}
+		for t := lo; t <= hi; t++ {
+			d[t] += n
+		}
+	}
+	if len(d) == 0 {
+		return nil, fmt.Errorf("empty tile distribution")
+	}
+	if err := d.checkCopies(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// checkCopies makes sure no value has more than maxCopies copies.
+func (d Distribution) checkCopies() error {
+	for t, n := range d {
+		if n < 0 || n > maxCopies {
+			return fmt.Errorf("tile distribution has %d copies of %d, a value has 0-%d", n, t, maxCopies)
+		}
+	}
+	return nil
+}
+
+// parseDistributionTerm reads one "lo-hi" or "t" term with an optional "xN".
+func parseDistributionTerm(part string, maxTile int) (lo, hi, n int, err error) {
+	n = 1
+	if body, count, ok := strings.Cut(part, "x"); ok {
+		if n, err = strconv.Atoi(count); err != nil || n < 0 || n > maxCopies {
+			return 0, 0, 0, fmt.Errorf("%q: copies must be a count of 0-%d, e.g. x2", part, maxCopies)
+		}
+		part = body
+	}
+	from, to, isRange := strings.Cut(part, "-")
+	if lo, err = parseTile(from, maxTile); err != nil {
+		return 0, 0, 0, err
+	}
+	hi = lo
+	if isRange {
+		if hi, err = parseTile(to, maxTile); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	if lo == Wildcard || hi == Wildcard || lo > hi {
+		return 0, 0, 0, fmt.Errorf("%q is not a range of numbered tiles", part)
+	}
+	return lo, hi, n, nil
+}
+
+// String writes d back in the form parseDistribution reads, merging runs of
+// values with the same count.
+func (d Distribution) String() string {
+	values := make([]int, 0, len(d))
+	for t, n := range d {
+		if n > 0 {
+			values = append(values, t)
+		}
+	}
+	sort.Ints(values)
+	var parts []string
+	for i := 0; i < len(values); {
+		j := i
+		for j+1 < len(values) && values[j+1] == values[j]+1 && d[values[j+1]] == d[values[i]] {
+			j++
+		}
+		part := strconv.Itoa(values[i])
+		if j > i {
+			part += "-" + strconv.Itoa(values[j])
+		}
+		if n := d[values[i]]; n != 1 {
+			part += "x" + strconv.Itoa(n)
+		}
+		parts = append(parts, part)
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// total counts the tiles in d.
+func (d Distribution) total() int {
+	total := 0
+	for _, n := range d {
+		total += n
+	}
+	return total
+}
+
+// tileCopies is the distribution the game's pile is built from.
+func (state *GameState) tileCopies(players int) Distribution {
+	if state.Distribution != nil {
+		return state.Distribution
+	}
+	if state.SharedPool {
+		players = 1
+	}
+	d := Distribution{}
+	for t := 1; t <= state.maxTile(); t++ {
+		d[t] = players
+	}
+	return d
+}
+
+// unusedTiles shuffles into a pile the tiles left over when used holds the
+// copies of each tile already on the table or a board. Every player brings
+// one set of tiles 1-maxTile, unless the game has its own distribution.
+func (state *GameState) unusedTiles(used map[int]int) []int {
+	copies := state.tileCopies(len(state.Boards))
+	remaining := []int{}
+	for i := 1; i <= state.maxTile(); i++ {
+		for n := used[i]; n < copies[i]; n++ {
+			remaining = append(remaining, i)
+		}
+	}
+	rng.Shuffle(len(remaining), func(i, j int) { remaining[i], remaining[j] = remaining[j], remaining[i] })
+	return remaining
+}
+
+// tileTotal is how many numbered tiles the game started with.
+func (state *GameState) tileTotal() int {
+	return state.tileCopies(len(state.Boards)).total()
+}
+
+// checkDistribution makes sure a custom distribution fits the tile range and
+// has enough tiles to deal every diagonal.
+func (state *GameState) checkDistribution(players int) error {
+	d := state.Distribution
+	if d == nil && state.SharedPool {
+		d = state.tileCopies(players)
+	}
+	if d == nil {
+		return nil
+	}
+	for t := range d {
+		if t < 1 || t > state.maxTile() {
+			return fmt.Errorf("tile distribution has %d, outside tiles 1-%d", t, state.maxTile())
+		}
+	}
+	if need := players * BoardSize; d.total()+state.Wildcards < need {
+		return fmt.Errorf("tile distribution has %d tiles, the diagonals alone need %d", d.total(), need)
+	}
+	return nil
+}
+
+// print lists how many copies of each value the pile holds.
+func (d Distribution) print(maxTile int) {
+	for t := 1; t <= maxTile; t++ {
+		fmt.Printf("%3d:%-2d", t, d[t])
+		if t%10 == 0 || t == maxTile {
+			fmt.Println()
+		}
+	}
+	fmt.Printf("%d tiles in all.\n", d.total())
+}
+
+// promptDistribution asks for the tile distribution, opening the editor on
+// request. Blank keeps one set per player.
+func (state *GameState) promptDistribution(players int) {
+	for {
+		fmt.Print("Tile distribution (blank for one set per player, a spec like 1-20x2,8-13, or edit): ")
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			return
+		case strings.EqualFold(line, "edit"):
+			state.Distribution = state.editDistribution(state.tileCopies(players))
+			return
+		}
+		d, err := parseDistribution(line, state.maxTile())
+		if err == nil {
+			state.Distribution = d
+			return
+		}
+		fmt.Println(err)
+	}
+}
+
+// editDistribution lets the player change the copies of single values or
+// ranges until they are done.
+func (state *GameState) editDistribution(d Distribution) Distribution {
+	edited := Distribution{}
+	for t, n := range d {
+		edited[t] = n
+	}
+	for {
+		edited.print(state.maxTile())
+		fmt.Print(`Set copies with "t=n" or "lo-hi=n", add with "lo-hi+n", or press Enter when done: `)
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" || err != nil {
+			return edited
+		}
+		sep := strings.IndexAny(line, "=+")
+		if sep < 0 {
+			fmt.Println(`Expected "t=n", "lo-hi=n" or "lo-hi+n".`)
+			continue
+		}
+		lo, hi, _, err := parseDistributionTerm(line[:sep], state.maxTile())
+		n, nerr := strconv.Atoi(line[sep+1:])
+		if err != nil || nerr != nil || n < 0 {
+			fmt.Println("Invalid edit:", line)
+			continue
+		}
+		for t := lo; t <= hi; t++ {
+			if line[sep] == '+' {
+				edited[t] += n
+			} else {
+				edited[t] = n
+			}
+			edited[t] = min(edited[t], maxCopies)
+		}
+	}
+}
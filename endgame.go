@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// EndMode is what happens when the draw pile runs out before anyone has
+// filled their board. Filling a board always wins outright.
+type EndMode int
+
+const (
+	EndClassic   EndMode = iota // the game stops without a winner
+	EndPileScore                // boards are ranked by fewest empty cells
+)
+
+var endModeNames = map[EndMode]string{EndClassic: "classic", EndPileScore: "pile scoring"}
+
+// ranking orders the seats by empty cells, fewest first, keeping seat order
+// between equal boards. Resigned seats come last.
+func (state *GameState) ranking() []int {
+	order := make([]int, len(state.Boards))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if ra, rb := state.Boards[order[a]].Resigned, state.Boards[order[b]].Resigned; ra != rb {
+			return rb
+		}
+		return emptyCells(state.Boards[order[a]]) < emptyCells(state.Boards[order[b]])
+	})
+	return order
+}
+
+// pileWinner is the seat that wins when the pile runs out: the one with the
+// fewest empty cells under pile scoring, or -1 for a tie between opponents
+// or classic rules.
+func (state *GameState) pileWinner() int {
+	if state.End != EndPileScore {
+		return -1
+	}
+	order := state.ranking()
+	best := emptyCells(state.Boards[order[0]])
+	for _, seat := range order[1:] {
+		if emptyCells(state.Boards[seat]) == best && !state.Boards[seat].Resigned && !state.teammates(seat, order[0]) {
+			return -1
+		}
+	}
+	return order[0]
+}
+
+// pileExhausted ends the game once the draw pile is empty, ranking the
+// boards first under pile scoring.
+func (state *GameState) pileExhausted() {
+	if state.End != EndPileScore {
+		state.gameOver(-1, tr("Draw pile is empty — game over."))
+		return
+	}
+	fmt.Println(term.text(tr("Draw pile is empty — ranking boards by empty cells:")))
+	rank, last := 0, -1
+	for i, seat := range state.ranking() {
+		empty := emptyCells(state.Boards[seat])
+		if empty != last {
+			rank, last = i+1, empty
+		}
+		fmt.Printf(tr("  %d. %-18s %d empty\n"), rank, state.seatLabel(seat), empty)
+	}
+	winner := state.pileWinner()
+	if winner < 0 {
+		state.gameOver(-1, tr("GAME OVER! It's a tie."))
+		return
+	}
+	state.gameOver(winner, fmt.Sprintf(tr("GAME OVER! %s wins."), state.winnerLabel(winner)))
+}
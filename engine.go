@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The engine command speaks a line protocol on standard input and output,
+// after the Universal Chess Interface, so a GUI written elsewhere can ask
+// the computer for its moves and other engines can be played against it:
+//
+//	uln                          answered with the engine's id and ulnok
+//	isready                      answered with readyok
+//	setoption name strategy value cautious
+//	newgame                      forgets the position
+//	position <position string> [tile <t>]
+//	go                           answered with info lines and a bestmove
+//	quit
+//
+// The position is a position string, as -position takes, the seat to move
+// being the engine's. With a tile given, it is the tile the seat holds and
+// go answers where it goes, "bestmove place B3", "bestmove swap B3" or
+// "bestmove discard", with " partner" after the cell for the teammate's
+// board. Without one go answers where to take a tile from, "bestmove take
+// 7" from the table or "bestmove draw" from the pile. Before the bestmove,
+// an info line gives each move considered for the tile, best first, with
+// its score. Anything else is answered with an "info string" line saying
+// so and otherwise ignored.
+
+// runEngine speaks the engine protocol on in and out until quit or the
+// end of the input.
+func runEngine(in lineReader, out io.Writer) {
+	strategy := defaultStrategy
+	var state *GameState
+	holding := 0
+	defer func(size int) { BoardSize = size }(BoardSize)
+	for {
+		line, err := in.ReadString('\n')
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			switch fields[0] {
+			case "uln":
+				fmt.Fprintln(out, "id name Unlucky Numbers")
+				fmt.Fprintf(out, "option name strategy type combo default %s var %s\n", defaultStrategy.Name(), strings.Join(strategyNames(), " var "))
+				fmt.Fprintln(out, "ulnok")
+			case "isready":
+				fmt.Fprintln(out, "readyok")
+			case "setoption":
+				if len(fields) != 5 || fields[1] != "name" || fields[2] != "strategy" || fields[3] != "value" {
+					fmt.Fprintln(out, "info string the only option is: setoption name strategy value <name>")
+					break
+				}
+				s, err := lookupStrategy(fields[4])
+				if err != nil {
+					fmt.Fprintln(out, "info string", err)
+					break
+				}
+				strategy = s
+			case "newgame":
+				state, holding = nil, 0
+			case "position":
+				var err error
+				state, holding, err = enginePosition(fields[1:])
+				if err != nil {
+					fmt.Fprintln(out, "info string invalid position:", err)
+				}
+			case "go":
+				if state == nil {
+					fmt.Fprintln(out, "info string no position to move in")
+					break
+				}
+				state.engineGo(out, strategy, holding)
+			case "quit":
+				return
+			default:
+				fmt.Fprintf(out, "info string unknown command %q\n", fields[0])
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// enginePosition reads a position command's arguments: a position string
+// and the tile held, if any, which is taken out of the pile.
+func enginePosition(args []string) (*GameState, int, error) {
+	held := ""
+	if n := len(args); n >= 2 && args[n-2] == "tile" {
+		args, held = args[:n-2], args[n-1]
+	}
+	if len(args) != 4 {
+		return nil, 0, fmt.Errorf("a position has 4 fields (boards, table, seat to move, rules), not %d", len(args))
+	}
+	state := &GameState{Heuristics: defaultHeuristics}
+	if err := state.decodePosition(strings.Join(args, " ")); err != nil {
+		return nil, 0, err
+	}
+	if held == "" {
+		return state, 0, nil
+	}
+	tile, err := parseTile(held, state.maxTile())
+	if err != nil {
+		return nil, 0, fmt.Errorf("tile: %w", err)
+	}
+	for i, t := range state.Draw {
+		if t == tile {
+			state.Draw = append(state.Draw[:i], state.Draw[i+1:]...)
+			return state, tile, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("every %s is in sight already", tileLabel(tile))
+}
+
+// engineGo answers go: where to take a tile from, or with a tile held,
+// where it goes.
+func (state *GameState) engineGo(out io.Writer, strategy Strategy, holding int) {
+	if holding == 0 {
+		move, fromTable := strategy.PickFromTable(state)
+		if fromTable && state.tablePickAllowed(move) && !state.prefersPile(move) {
+			state.engineInfo(out, move.Tile)
+			fmt.Fprintln(out, "bestmove take", tileLabel(move.Tile))
+		} else {
+			fmt.Fprintln(out, "bestmove draw")
+		}
+		return
+	}
+	state.engineInfo(out, holding)
+	move, ok := strategy.ChooseMove(state, holding)
+	if !ok {
+		fmt.Fprintln(out, "bestmove discard")
+		return
+	}
+	fmt.Fprintln(out, "bestmove", engineMove(move))
+}
+
+// engineInfo lists the moves for tile, best first, with their scores.
+func (state *GameState) engineInfo(out io.Writer, tile int) {
+	for _, m := range state.bestMoves(tile) {
+		fmt.Fprintf(out, "info move %s score %.2f\n", engineMove(m), m.Score)
+	}
+}
+
+// engineMove writes a move to a cell as bestmove does.
+func engineMove(m Move) string {
+	s := fmt.Sprintf("%s %s", moveTypeNames[m.Type], m.Cell)
+	if m.Partner {
+		s += " partner"
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TileEquity is the value of taking one table tile for the current player.
+type TileEquity struct {
+	Tile   int
+	Best   Move    // best move with the tile; Cell is nil when there is none
+	Score  float64 // score of Best, 0 when the tile has no legal move
+	Equity float64 // Score minus the expected score of a pile draw instead
+}
+
+// pileExpectation is the expected best-move score of drawing from the pile,
+// averaged over the unseen tiles, or the known top tile's score when the
+// pile is visible. A draw with no legal move scores 0.
+func (state *GameState) pileExpectation() float64 {
+	if len(state.Draw) == 0 {
+		return 0
+	}
+	if state.pileVisible() {
+		if moves := state.bestMoves(state.Draw[0]); len(moves) > 0 {
+			return moves[0].Score
+		}
+		return 0
+	}
+	best := map[int]float64{}
+	total := 0.0
+	for _, t := range state.Draw {
+		score, seen := best[t]
+		if !seen {
+			if moves := state.bestMoves(t); len(moves) > 0 {
+				score = moves[0].Score
+			}
+			best[t] = score
+		}
+		total += score
+	}
+	return total / float64(len(state.Draw))
+}
+
+// drawAlternative is what the current seat scores by not taking a table
+// tile: its best hand tile in the hand variant, otherwise a pile draw.
+func (state *GameState) drawAlternative() float64 {
+	if state.holdsHand() {
+		_, score := state.bestHandTile()
+		return score
+	}
+	return state.pileExpectation()
+}
+
+// tableEquity ranks every distinct table tile by its equity, best first.
+func (state *GameState) tableEquity() []TileEquity {
+	cost := state.drawAlternative()
+	seen := map[int]bool{}
+	var equities []TileEquity
+	for _, t := range state.Table {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		e := TileEquity{Tile: t, Best: Move{Tile: t, Type: Discard}}
+		if moves := state.bestMoves(t); len(moves) > 0 {
+			e.Best = moves[0]
+			e.Score = moves[0].Score
+		} else if state.ForcedTable {
+			continue // it could not be placed, so it cannot be taken
+		}
+		e.Equity = e.Score - cost
+		equities = append(equities, e)
+	}
+	sort.SliceStable(equities, func(i, j int) bool {
+		return equities[i].Equity > equities[j].Equity
+	})
+	return equities
+}
+
+func (state *GameState) printTableEquity() {
+	equities := state.tableEquity()
+	if len(equities) == 0 {
+		fmt.Println("The table is empty.")
+		return
+	}
+	if state.holdsHand() {
+		fmt.Printf("Best score from your hand: %5.2f\n", state.drawAlternative())
+	} else {
+		fmt.Printf("Expected score of a pile draw: %5.2f\n", state.pileExpectation())
+	}
+	for i, e := range equities {
+		if e.Best.Cell == nil {
+			fmt.Printf(term.text("%d) tile %2s — no legal move, equity %6.2f\n"), i+1, tileLabel(e.Tile), e.Equity)
+			continue
+		}
+		fmt.Printf(term.text("%d) tile %2s — %s at %s score %5.2f, equity %+6.2f\n"),
+			i+1, tileLabel(e.Tile), map[MoveType]string{Place: "Place", Swap: "Swap"}[e.Best.Type],
+			e.Best.Cell, e.Score, e.Equity)
+	}
+}
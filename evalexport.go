@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"math/rand"
+	"strconv"
+)
+
+// With -eval-csv every move of a game, or of a move log played back by
+// replay, is written to a CSV file a row at a time, for charting a game in a
+// spreadsheet: the engine's score for the move made, its best move for the
+// same tile and what the move gave up against it, then each seat's chance
+// of winning from the position the move left. The chances come from games
+// played out to the end by the seats' strategies with the pile shuffled
+// afresh, on random numbers of their own so the game being exported plays
+// the same with the file as without it.
+
+// evalRollouts is how many games are played out for the win chances.
+const evalRollouts = 100
+
+// EvalExport is the file the evaluations are written to.
+type EvalExport struct {
+	f      fileWriter
+	w      *csv.Writer
+	header bool // the header row is written
+}
+
+// newEvalExport starts an evaluation export at path, replacing any earlier
+// one.
+func newEvalExport(path string) (*EvalExport, error) {
+	f, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &EvalExport{f: f, w: csv.NewWriter(f)}, nil
+}
+
+func (x *EvalExport) Close() error {
+	x.w.Flush()
+	return x.f.Close()
+}
+
+// write writes a row, with the header before the first, and flushes it so
+// a game that ends the program leaves every row in the file.
+func (x *EvalExport) write(state *GameState, row []string) {
+	if !x.header {
+		header := []string{"turn", "seat", "player", "move", "tile", "cell", "score", "best_move", "best_cell", "best_score", "loss"}
+		for seat := range state.Boards {
+			header = append(header, "win_"+state.seatLabel(seat))
+		}
+		x.w.Write(header)
+		x.header = true
+	}
+	x.w.Write(row)
+	x.w.Flush()
+}
+
+// evalMove scores move for the current seat before it is made and returns
+// the function that writes its row once it is, nothing without an export.
+func (state *GameState) evalMove(move Move) func() {
+	x := state.EvalExport
+	if x == nil || move.Type == Steal {
+		return func() {}
+	}
+	seat := state.Current
+	score, bestMove, bestCell, bestScore, loss := "", "", "", "", ""
+	if best, s, ok := state.judgeMove(move); ok {
+		bestMove, bestCell, bestScore = moveTypeNames[best.Type], best.Cell.String(), formatScore(best.Score)
+		if move.Cell != nil {
+			score, loss = formatScore(s), formatScore(max(best.Score-s, 0))
+		}
+	}
+	cell := ""
+	if move.Cell != nil {
+		cell = move.Cell.String()
+	}
+	return func() {
+		row := []string{strconv.Itoa(state.Turn + 1), strconv.Itoa(seat), state.seatLabel(seat), moveTypeNames[move.Type],
+			tileLabel(move.Tile), cell, score, bestMove, bestCell, bestScore, loss}
+		for _, p := range state.winChances(evalRollouts) {
+			row = append(row, strconv.FormatFloat(p, 'f', 3, 64))
+		}
+		x.write(state, row)
+	}
+}
+
+// judgeMove is what the engine makes of move for the current seat: its best
+// move for the same tile and, for a move to a cell, the move's own score. ok
+// is false when the tile has nowhere to go.
+func (state *GameState) judgeMove(move Move) (best Move, score float64, ok bool) {
+	ranked := state.bestMoves(move.Tile)
+	if len(ranked) == 0 {
+		return Move{}, 0, false
+	}
+	if move.Cell != nil {
+		score = state.moveScore(move, ranked)
+	}
+	return ranked[0], score, true
+}
+
+// formatScore writes an engine score for the export.
+func formatScore(s float64) string {
+	return strconv.FormatFloat(s, 'f', 2, 64)
+}
+
+// winChances plays the game out n times from the seat after the current
+// one and returns the share of games each seat won.
+func (state *GameState) winChances(n int) []float64 {
+	chances := make([]float64, len(state.Boards))
+	for seat, b := range state.Boards {
+		if b.IsFull() {
+			for s := range chances {
+				if state.won(s, seat) {
+					chances[s] = 1
+				}
+			}
+			return chances
+		}
+	}
+	seeded := rng
+	defer func() { rng = seeded }()
+	rng = rand.New(rand.NewSource(state.Seed + int64(len(state.History))))
+
+	seats := make([]Strategy, len(state.Boards))
+	for seat := range seats {
+		seats[seat] = state.strategyFor(seat)
+	}
+	for i := 0; i < n; i++ {
+		game := state.clone()
+		game.Current = (state.Current + 1) % len(state.Boards)
+		if !state.OpenPile {
+			rng.Shuffle(len(game.Draw), func(a, b int) { game.Draw[a], game.Draw[b] = game.Draw[b], game.Draw[a] })
+		}
+		winner := game.playHeadless(seats, nil)
+		for seat := range chances {
+			if game.won(seat, winner) {
+				chances[seat]++
+			}
+		}
+	}
+	for seat := range chances {
+		chances[seat] /= float64(n)
+	}
+	return chances
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// evalModel is an externally trained evaluator loaded from a JSON weights
+// file. It scores the current seat's position as a small feed-forward
+// network over named board features:
+//
+//	{"name": "mlp-v1",
+//	 "features": ["filled", "dead_cells", "cell_0_0", ...],
+//	 "layers": [{"weights": [[...], ...], "bias": [...], "activation": "tanh"},
+//	            {"weights": [[...]], "bias": [0], "activation": "linear"}]}
+//
+// Each layer's weights has one row per output; the last layer must have a
+// single output, the position's value.
+type evalModel struct {
+	Name     string       `json:"name"`
+	Features []string     `json:"features"`
+	Layers   []modelLayer `json:"layers"`
+}
+
+type modelLayer struct {
+	Weights    [][]float64 `json:"weights"`
+	Bias       []float64   `json:"bias"`
+	Activation string      `json:"activation"` // linear (default), tanh, relu or sigmoid
+}
+
+// boardFeatures are the named position features a model may use, besides
+// cell_R_C (the tile at row R, column C divided by the highest tile, 0 when empty).
+var boardFeatures = map[string]func(state *GameState) float64{
+	"bias": func(*GameState) float64 { return 1 },
+	"filled": func(state *GameState) float64 {
+		return 1 - float64(emptyCells(state.Boards[state.Current]))/float64(BoardSize*BoardSize)
+	},
+	"cell_fit": func(state *GameState) float64 {
+		board, sum := state.Boards[state.Current], 0.0
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if t := board.Grid[r][c]; isTile(t) {
+					sum += baseScore(t, r, c, state.maxTile()) / 100
+				}
+			}
+		}
+		return sum / float64(BoardSize*BoardSize)
+	},
+	"completion": func(state *GameState) float64 {
+		return state.emptyCellSupport(func(p float64, _ int) float64 { return p })
+	},
+	"dead_cells": func(state *GameState) float64 {
+		return state.emptyCellSupport(func(_ float64, n int) float64 { return b2f(n == 0) })
+	},
+	"tight_cells": func(state *GameState) float64 {
+		return state.emptyCellSupport(func(_ float64, n int) float64 { return b2f(n > 0 && n <= 2) })
+	},
+	"table_size": func(state *GameState) float64 { return float64(len(state.Table)) / float64(state.maxTile()) },
+	"pile_fraction": func(state *GameState) float64 {
+		return float64(len(state.Draw)) / float64(state.tileTotal())
+	},
+}
+
+func b2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// emptyCellSupport sums f over the current board's empty cells, given the
+// share p and count n of unseen tiles that fit each one, scaled by the
+// board's cell count.
+func (state *GameState) emptyCellSupport(f func(p float64, n int) float64) float64 {
+	board := state.Boards[state.Current]
+	remaining := append(append([]int{}, state.Draw...), state.Table...)
+	sum := 0.0
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if board.Grid[r][c] != 0 {
+				continue
+			}
+			lo1, hi1 := state.rowConstraints(r, c)
+			lo2, hi2 := state.colConstraints(r, c)
+			lo, hi := max(lo1, lo2), min(hi1, hi2)
+			n := 0
+			for _, t := range remaining {
+				if t == Wildcard || (t >= lo && t <= hi) {
+					n++
+				}
+			}
+			p := 0.0
+			if len(remaining) > 0 {
+				p = float64(n) / float64(len(remaining))
+			}
+			sum += f(p, n)
+		}
+	}
+	return sum / float64(BoardSize*BoardSize)
+}
+
+func boardFeature(name string) (func(state *GameState) float64, error) {
+	if f, ok := boardFeatures[name]; ok {
+		return f, nil
+	}
+	if rest, ok := strings.CutPrefix(name, "cell_"); ok {
+		parts := strings.Split(rest, "_")
+		if len(parts) == 2 {
+			r, err1 := strconv.Atoi(parts[0])
+			c, err2 := strconv.Atoi(parts[1])
+			if err1 == nil && err2 == nil && onBoard(r, c) {
+				return func(state *GameState) float64 {
+					return float64(state.Boards[state.Current].Grid[r][c]) / float64(state.maxTile())
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown feature %q", name)
+}
+
+func loadEvalModel(path string) (*evalModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &evalModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+func (m *evalModel) validate() error {
+	for _, name := range m.Features {
+		if _, err := boardFeature(name); err != nil {
+			return err
+		}
+	}
+	if len(m.Layers) == 0 {
+		return fmt.Errorf("model has no layers")
+	}
+	width := len(m.Features)
+	for i, l := range m.Layers {
+		if len(l.Bias) != len(l.Weights) {
+			return fmt.Errorf("layer %d has %d outputs but %d biases", i, len(l.Weights), len(l.Bias))
+		}
+		for _, row := range l.Weights {
+			if len(row) != width {
+				return fmt.Errorf("layer %d expects %d inputs, got a row of %d", i, width, len(row))
+			}
+		}
+		switch l.Activation {
+		case "", "linear", "tanh", "relu", "sigmoid":
+		default:
+			return fmt.Errorf("layer %d: unknown activation %q", i, l.Activation)
+		}
+		width = len(l.Weights)
+	}
+	if width != 1 {
+		return fmt.Errorf("last layer has %d outputs, expected 1", width)
+	}
+	return nil
+}
+
+// evaluate scores the current seat's position.
+func (m *evalModel) evaluate(state *GameState) float64 {
+	x := make([]float64, len(m.Features))
+	for i, name := range m.Features {
+		f, _ := boardFeature(name)
+		x[i] = f(state)
+	}
+	for _, l := range m.Layers {
+		y := make([]float64, len(l.Weights))
+		for j, row := range l.Weights {
+			v := l.Bias[j]
+			for i, w := range row {
+				v += w * x[i]
+			}
+			switch l.Activation {
+			case "tanh":
+				v = math.Tanh(v)
+			case "relu":
+				v = math.Max(0, v)
+			case "sigmoid":
+				v = 1 / (1 + math.Exp(-v))
+			}
+			y[j] = v
+		}
+		x = y
+	}
+	return x[0]
+}
+
+// modelStrategy plays the move leading to the position the loaded model
+// values highest, in place of placementScore.
+type modelStrategy struct {
+	model *evalModel
+}
+
+func (modelStrategy) Name() string { return "model" }
+
+// afterValue scores the position left by playing move.
+func (s modelStrategy) afterValue(state *GameState, move Move) float64 {
+	next := state.clone()
+	next.commitMove(move)
+	next.Current = next.moveSeat(state.Current, move)
+	return s.model.evaluate(next)
+}
+
+func (s modelStrategy) bestFor(state *GameState, tile int, allowDiscard bool) (Move, float64) {
+	candidates := state.bestMoves(tile)
+	if allowDiscard || len(candidates) == 0 {
+		candidates = append(candidates, Move{Type: Discard, Tile: tile})
+	}
+	best, bestValue := Move{}, math.Inf(-1)
+	for _, m := range candidates {
+		if v := s.afterValue(state, m); v > bestValue {
+			best, bestValue = m, v
+		}
+	}
+	return best, bestValue
+}
+
+func (s modelStrategy) PickFromTable(state *GameState) (Move, bool) {
+	if len(state.Table) == 0 {
+		return Move{}, false
+	}
+	pile, seen := 0.0, map[int]float64{}
+	for _, t := range state.Draw {
+		v, ok := seen[t]
+		if !ok {
+			_, v = s.bestFor(state, t, true)
+			seen[t] = v
+		}
+		pile += v
+	}
+	if len(state.Draw) > 0 {
+		pile /= float64(len(state.Draw))
+	}
+	best, found := Move{}, false
+	for _, t := range state.Table {
+		if len(state.bestMoves(t)) == 0 {
+			continue
+		}
+		if m, v := s.bestFor(state, t, false); v > pile {
+			best, pile, found = m, v, true
+		}
+	}
+	return best, found
+}
+
+func (s modelStrategy) ChooseMove(state *GameState, tile int) (Move, bool) {
+	m, _ := s.bestFor(state, tile, true)
+	return m, m.Type != Discard
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The export command draws the boards and the table to an SVG or PNG
+// picture, to post a position on a forum or put it in a write-up. Both come
+// from the same picture of boxes and text; the PNG spells the text in a
+// small built-in pixel font, so no font files are needed.
+
+// picture is a drawing of the position in pixels.
+type picture struct {
+	W, H  int
+	boxes []picBox
+	texts []picText
+}
+
+// picBox is a filled rectangle outlined in Line.
+type picBox struct {
+	X, Y, W, H int
+	Fill, Line color.RGBA
+}
+
+// picText is a line of text centred on X, or starting at it when Left, and
+// centred on Y. Size is the height of a capital letter.
+type picText struct {
+	X, Y, Size int
+	Left       bool
+	Text       string
+	Color      color.RGBA
+}
+
+var (
+	picBackground = color.RGBA{0xfa, 0xf8, 0xf2, 0xff}
+	picEmpty      = color.RGBA{0xec, 0xe7, 0xda, 0xff}
+	picTile       = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	picWildcard   = color.RGBA{0xff, 0xef, 0xb0, 0xff}
+	picHole       = color.RGBA{0x55, 0x55, 0x55, 0xff}
+	picInk        = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	picFaint      = color.RGBA{0x88, 0x88, 0x88, 0xff}
+	picCurrent    = color.RGBA{0x1f, 0x5f, 0xbf, 0xff}
+)
+
+// picPalette holds every colour a picture uses, for GIF frames.
+var picPalette = color.Palette{picBackground, picEmpty, picTile, picWildcard, picHole, picInk, picFaint, picCurrent}
+
+// Sizes in the picture, in pixels.
+const (
+	picPad    = 24 // around the edge
+	picCell   = 44 // a board cell
+	picGutter = 20 // row numbers left of a board
+	picGap    = 36 // between boards
+	picTable  = 32 // a tile on the table
+)
+
+// picture lays out the counters, the table and every board side by side.
+func (state *GameState) picture() *picture {
+	p := &picture{}
+	boardW := picGutter + BoardSize*picCell
+	p.W = 2*picPad + len(state.Boards)*boardW + (len(state.Boards)-1)*picGap
+	p.W = max(p.W, 2*picPad+len(state.Table)*(picTable+4))
+
+	y := picPad
+	p.texts = append(p.texts, picText{X: picPad, Y: y + 8, Size: 15, Left: true, Text: state.counters(), Color: picInk})
+	table, pile := state.tileCounts()
+	y += 28
+	p.texts = append(p.texts, picText{X: picPad, Y: y + 7, Size: 12, Left: true, Text: table + ", " + pile, Color: picFaint})
+	y += 20
+	for i, t := range state.Table {
+		x := picPad + i*(picTable+4)
+		p.boxes = append(p.boxes, picBox{X: x, Y: y, W: picTable, H: picTable, Fill: picTile, Line: picInk})
+		p.texts = append(p.texts, picText{X: x + picTable/2, Y: y + picTable/2, Size: 12, Text: tileLabel(t), Color: picInk})
+	}
+	y += picTable + 24
+
+	for i, b := range state.Boards {
+		x := picPad + i*(boardW+picGap)
+		name := picText{X: x + picGutter + BoardSize*picCell/2, Y: y + 7, Size: 14, Text: state.seatLabel(i), Color: picInk}
+		if i == state.Current {
+			name.Color = picCurrent
+		}
+		p.texts = append(p.texts, name)
+		top := y + 40
+		for c := 0; c < BoardSize; c++ {
+			p.texts = append(p.texts, picText{X: x + picGutter + c*picCell + picCell/2, Y: top - 12, Size: 10, Text: columnLetter(c), Color: picFaint})
+		}
+		for r := 0; r < BoardSize; r++ {
+			cy := top + r*picCell
+			p.texts = append(p.texts, picText{X: x + picGutter/2, Y: cy + picCell/2, Size: 10, Text: fmt.Sprint(r + 1), Color: picFaint})
+			for c := 0; c < BoardSize; c++ {
+				cell := picBox{X: x + picGutter + c*picCell, Y: cy, W: picCell, H: picCell, Fill: picEmpty, Line: picFaint}
+				v := b.Grid[r][c]
+				switch {
+				case v == Blocked:
+					cell.Fill = picHole
+				case v == Wildcard:
+					cell.Fill = picWildcard
+				case isTile(v):
+					cell.Fill = picTile
+				}
+				p.boxes = append(p.boxes, cell)
+				if v != 0 && v != Blocked {
+					p.texts = append(p.texts, picText{X: cell.X + picCell/2, Y: cy + picCell/2, Size: 16, Text: tileLabel(v), Color: picInk})
+				}
+			}
+		}
+	}
+	p.H = y + 40 + BoardSize*picCell + picPad
+	return p
+}
+
+// caption writes lines of text under the boards.
+func (p *picture) caption(lines ...string) {
+	for _, line := range lines {
+		p.texts = append(p.texts, picText{X: picPad, Y: p.H - picPad + 16, Size: 12, Left: true, Text: line, Color: picInk})
+		p.H += 22
+	}
+}
+
+// hex writes a colour the way SVG reads it.
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// writeSVG draws the picture as SVG.
+func (p *picture) writeSVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", p.W, p.H, p.W, p.H)
+	fmt.Fprintf(bw, "<rect width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", p.W, p.H, hex(picBackground))
+	for _, b := range p.boxes {
+		fmt.Fprintf(bw, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\" stroke=\"%s\"/>\n", b.X, b.Y, b.W, b.H, hex(b.Fill), hex(b.Line))
+	}
+	for _, t := range p.texts {
+		anchor := "middle"
+		if t.Left {
+			anchor = "start"
+		}
+		// a capital is about 0.7 of the font size
+		fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\" font-family=\"sans-serif\" font-size=\"%d\" text-anchor=\"%s\" dominant-baseline=\"central\" fill=\"%s\">", t.X, t.Y, t.Size*10/7, anchor, hex(t.Color))
+		xml.EscapeText(bw, []byte(t.Text))
+		fmt.Fprintln(bw, "</text>")
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// writePNG draws the picture as PNG.
+func (p *picture) writePNG(w io.Writer) error {
+	return png.Encode(w, p.raster(p.W, p.H))
+}
+
+// raster draws the picture in pixels on a canvas of w×h, at least its own
+// size.
+func (p *picture) raster(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill := func(r image.Rectangle, c color.RGBA) {
+		draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	fill(img.Bounds(), picBackground)
+	for _, b := range p.boxes {
+		r := image.Rect(b.X, b.Y, b.X+b.W, b.Y+b.H)
+		fill(r, b.Line)
+		fill(r.Inset(1), b.Fill)
+	}
+	for _, t := range p.texts {
+		scale := max(t.Size/5, 1)
+		text := unaccent.Replace(strings.ToUpper(t.Text))
+		width := len([]rune(text))*4*scale - scale
+		x := t.X - width/2
+		if t.Left {
+			x = t.X
+		}
+		y := t.Y - 5*scale/2
+		for _, ch := range text {
+			glyph := pixelFont[ch]
+			for gy, row := range glyph {
+				for gx, on := range row {
+					if on == '1' {
+						fill(image.Rect(x+gx*scale, y+gy*scale, x+(gx+1)*scale, y+(gy+1)*scale), t.Color)
+					}
+				}
+			}
+			x += 4 * scale
+		}
+	}
+	return img
+}
+
+// pixelFont is a 3×5 font for the characters the picture writes. Anything
+// else is left blank.
+var pixelFont = map[rune][5]string{
+	'0': {"111", "101", "101", "101", "111"}, '1': {"010", "110", "010", "010", "111"},
+	'2': {"111", "001", "111", "100", "111"}, '3': {"111", "001", "111", "001", "111"},
+	'4': {"101", "101", "111", "001", "001"}, '5': {"111", "100", "111", "001", "111"},
+	'6': {"111", "100", "111", "101", "111"}, '7': {"111", "001", "010", "010", "010"},
+	'8': {"111", "101", "111", "101", "111"}, '9': {"111", "101", "111", "001", "111"},
+	'A': {"010", "101", "111", "101", "101"}, 'B': {"110", "101", "110", "101", "110"},
+	'C': {"011", "100", "100", "100", "011"}, 'D': {"110", "101", "101", "101", "110"},
+	'E': {"111", "100", "110", "100", "111"}, 'F': {"111", "100", "110", "100", "100"},
+	'G': {"011", "100", "101", "101", "011"}, 'H': {"101", "101", "111", "101", "101"},
+	'I': {"111", "010", "010", "010", "111"}, 'J': {"001", "001", "001", "101", "010"},
+	'K': {"101", "101", "110", "101", "101"}, 'L': {"100", "100", "100", "100", "111"},
+	'M': {"101", "111", "111", "101", "101"}, 'N': {"110", "101", "101", "101", "101"},
+	'O': {"010", "101", "101", "101", "010"}, 'P': {"110", "101", "110", "100", "100"},
+	'Q': {"010", "101", "101", "110", "011"}, 'R': {"110", "101", "110", "101", "101"},
+	'S': {"011", "100", "010", "001", "110"}, 'T': {"111", "010", "010", "010", "010"},
+	'U': {"101", "101", "101", "101", "111"}, 'V': {"101", "101", "101", "101", "010"},
+	'W': {"101", "101", "111", "111", "101"}, 'X': {"101", "101", "010", "101", "101"},
+	'Y': {"101", "101", "010", "010", "010"}, 'Z': {"111", "001", "010", "100", "111"},
+	'*': {"000", "101", "010", "101", "000"}, '#': {"101", "111", "101", "111", "101"},
+	':': {"000", "010", "000", "010", "000"}, ';': {"000", "010", "000", "010", "100"},
+	'-': {"000", "000", "111", "000", "000"}, '\'': {"010", "010", "000", "000", "000"},
+	'.': {"000", "000", "000", "000", "010"}, ',': {"000", "000", "000", "010", "100"},
+	'(': {"001", "010", "010", "010", "001"}, ')': {"100", "010", "010", "010", "100"},
+	'[': {"011", "010", "010", "010", "011"}, ']': {"110", "010", "010", "010", "110"},
+}
+
+// unaccent spells accented capitals without the accent for pixelFont.
+var unaccent = strings.NewReplacer("Á", "A", "É", "E", "Í", "I", "Ó", "O", "Ú", "U", "Ü", "U", "Ñ", "N")
+
+// exportPicture draws the position to filename, as PNG when it ends in
+// .png and as SVG otherwise.
+func (state *GameState) exportPicture(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	p := state.picture()
+	write := p.writeSVG
+	if strings.EqualFold(filepath.Ext(filename), ".png") {
+		write = p.writePNG
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// promptExport asks for a file and draws the position to it.
+func (state *GameState) promptExport() {
+	fmt.Print(tr("File for the picture (.svg or .png): "))
+	line, _ := reader.ReadString('\n')
+	filename := strings.TrimSpace(line)
+	if filename == "" {
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".svg" && ext != ".png" {
+		filename += ".svg"
+	}
+	if err := state.exportPicture(filename); err != nil {
+		fmt.Println(tr("Failed to export:"), err)
+		return
+	}
+	fmt.Printf(tr("Picture written to %s.\n"), filename)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Players far apart share a served game over WebSockets: each connects to
+// /games/{id}/socket?seat=N&token=... for a human seat of a game started
+// with more than one human, with the seat's token, or without a seat to
+// watch. A seat is played by one
+// socket at a time, and once taken, moves for it only come over that
+// socket. The client sends the moves POST /games/{id}/moves takes, on its
+// turn only, and every client is sent
+//
+//	{"type": "state", "seat": 1, "moves": [...], "state": {...}}
+//
+// when it connects and after every change, moves being those made since
+// the last update, the computer's included. A move refused comes back to
+// its sender alone as {"type": "error", "seat": 1, "error": "..."}.
+
+// socketBacklog is how many updates a client may fall behind by before it
+// is dropped, so a stalled connection never holds up the game.
+const socketBacklog = 32
+
+// gameSocket is a client connected to a game.
+type gameSocket struct {
+	conn *wsConn
+	seat int // the seat it plays, -1 to watch
+	out  chan []byte
+}
+
+// socketMessage is what a client is sent.
+type socketMessage struct {
+	Type  string       `json:"type"`
+	Seat  int          `json:"seat"`
+	Moves []servedMove `json:"moves,omitempty"`
+	State *servedState `json:"state,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// connect takes a WebSocket for a seat of a game, or to watch it, and
+// plays the moves it sends until it closes. The handshake is answered
+// without holding the games, so the seat is checked before it, to refuse
+// it over HTTP, and again after, in case another socket took it meanwhile.
+func (s *gameServer) connect(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	g, seat, err := s.socketSeat(r)
+	s.mu.Unlock()
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	if _, _, err := s.socketSeat(r); err != nil {
+		s.mu.Unlock()
+		if data, err := json.Marshal(socketMessage{Type: "error", Seat: seat, Error: err.Error()}); err == nil {
+			conn.WriteMessage(data)
+		}
+		conn.Close()
+		return
+	}
+	sock := &gameSocket{conn: conn, seat: seat, out: make(chan []byte, socketBacklog)}
+	g.sockets[sock] = true
+	go sock.writeOut()
+	restore := g.enter()
+	view := g.view()
+	restore()
+	g.send(sock, socketMessage{Type: "state", Seat: seat, State: &view})
+	s.mu.Unlock()
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		s.mu.Lock()
+		g.socketMove(sock, msg)
+		s.mu.Unlock()
+	}
+	s.mu.Lock()
+	g.drop(sock)
+	s.mu.Unlock()
+}
+
+// socketSeat finds the game a socket request names and the seat it claims,
+// -1 to watch.
+func (s *gameServer) socketSeat(r *http.Request) (*servedGame, int, error) {
+	g, err := s.game(r.PathValue("id"))
+	if err != nil {
+		return nil, -1, err
+	}
+	q := r.URL.Query().Get("seat")
+	if q == "" {
+		return g, -1, nil
+	}
+	seat, err := g.claim(q, r.URL.Query().Get("token"))
+	return g, seat, err
+}
+
+// claim checks that the seat named by q is a human's, that token is its,
+// and that it is free.
+func (g *servedGame) claim(q, token string) (int, error) {
+	seat, err := strconv.Atoi(q)
+	if err != nil || seat < 0 || seat >= len(g.state.Boards) {
+		return 0, badRequest("seat %q is not a seat 0-%d", q, len(g.state.Boards)-1)
+	}
+	if g.state.Boards[seat].IsAi {
+		return 0, conflict("%s is the computer's", g.state.seatLabel(seat))
+	}
+	if !g.holdsSeat(seat, token) {
+		return 0, forbidden("%s needs its token", g.state.seatLabel(seat))
+	}
+	if g.socketFor(seat) != nil {
+		return 0, conflict("%s is taken", g.state.seatLabel(seat))
+	}
+	return seat, nil
+}
+
+// socketFor is the socket playing seat, or nil.
+func (g *servedGame) socketFor(seat int) *gameSocket {
+	for sock := range g.sockets {
+		if sock.seat == seat {
+			return sock
+		}
+	}
+	return nil
+}
+
+// socketMove plays a move a client sent, telling it if the move is
+// refused and everyone if it is made. A client already dropped, its seat
+// perhaps another's by now, moves no more.
+func (g *servedGame) socketMove(sock *gameSocket, msg []byte) {
+	if !g.sockets[sock] {
+		return
+	}
+	g.used = time.Now()
+	refuse := func(err error) {
+		g.send(sock, socketMessage{Type: "error", Seat: sock.seat, Error: err.Error()})
+	}
+	var req moveRequest
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		refuse(badRequest("unreadable move: %v", err))
+		return
+	}
+	switch {
+	case sock.seat < 0:
+		refuse(conflict("watchers do not move"))
+		return
+	case g.over:
+		refuse(conflict("the game is over"))
+		return
+	case g.state.Current != sock.seat:
+		refuse(conflict("it is %s's turn", g.state.seatLabel(g.state.Current)))
+		return
+	}
+	defer g.enter()()
+	if err := g.move(req); err != nil {
+		refuse(err)
+		return
+	}
+	g.broadcast()
+}
+
+// broadcast sends every client the game as it is now, with the moves made
+// since the last update.
+func (g *servedGame) broadcast() {
+	var moves []servedMove
+	for _, p := range g.state.History[g.told:] {
+		moves = append(moves, servedMoveOf(p.Seat, p.Move, false))
+	}
+	g.told = len(g.state.History)
+	view := g.view()
+	for sock := range g.sockets {
+		g.send(sock, socketMessage{Type: "state", Seat: sock.seat, Moves: moves, State: &view})
+	}
+}
+
+// send queues a message for a client, dropping the client when it has
+// fallen too far behind.
+func (g *servedGame) send(sock *gameSocket, m socketMessage) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	select {
+	case sock.out <- data:
+	default:
+		g.drop(sock)
+	}
+}
+
+// drop forgets a client, freeing its seat; its connection closes once the
+// updates queued for it are written.
+func (g *servedGame) drop(sock *gameSocket) {
+	if g.sockets[sock] {
+		delete(g.sockets, sock)
+		close(sock.out)
+	}
+}
+
+// writeOut writes a client's updates until it is dropped.
+func (sock *gameSocket) writeOut() {
+	defer sock.conn.Close()
+	for data := range sock.out {
+		if err := sock.conn.WriteMessage(data); err != nil {
+			break
+		}
+	}
+}
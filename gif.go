@@ -0,0 +1,71 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"os"
+	"strings"
+)
+
+// The gif command turns a -move-log into an animated GIF of the game, a
+// frame for every move with what happened written under the boards, to
+// share a comeback or to watch what the computer did move by move.
+
+// Frame times, in hundredths of a second.
+const (
+	gifFrameDelay = 100
+	gifLastDelay  = 400 // the final position stays up longer before looping
+)
+
+// exportGIF draws every position of the move log at path as a frame of
+// an animated GIF written to out.
+func exportGIF(path, out string) error {
+	var frames []*picture
+	drawn := ""
+	_, err := walkMoveLog(path, func(state *GameState, e LoggedMove, said string) bool {
+		if e.Type == "draw" {
+			drawn = said // told with the move the tile is played in
+			return false
+		}
+		if e.Type == "note" {
+			return false
+		}
+		p := state.picture()
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(drawn+said), "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+		p.caption(lines...)
+		frames = append(frames, p)
+		drawn = ""
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	// the table grows and shrinks, so every frame gets the largest canvas
+	w, h := 0, 0
+	for _, p := range frames {
+		w, h = max(w, p.W), max(h, p.H)
+	}
+	anim := &gif.GIF{Config: image.Config{ColorModel: picPalette, Width: w, Height: h}}
+	for i, p := range frames {
+		frame := image.NewPaletted(image.Rect(0, 0, w, h), picPalette)
+		draw.Draw(frame, frame.Bounds(), p.raster(w, h), image.Point{}, draw.Src)
+		delay := gifFrameDelay
+		if i == len(frames)-1 {
+			delay = gifLastDelay
+		}
+		anim.Image, anim.Delay = append(anim.Image, frame), append(anim.Delay, delay)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
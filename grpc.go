@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// serve answers gRPC too, the service unlucky.proto publishes, on the same
+// address and with the same games as its HTTP API, for backends in other
+// languages to generate a client from the proto instead of writing one for
+// the JSON. gRPC is HTTP/2 with the messages framed in the body and the
+// status in the trailers, so the server's HTTP/2 without TLS and the few
+// protocol buffer encodings the service's messages use are enough, without
+// the gRPC libraries.
+
+// gRPC status codes.
+const (
+	grpcOK                 = 0
+	grpcInvalidArgument    = 3
+	grpcNotFound           = 5
+	grpcPermissionDenied   = 7
+	grpcFailedPrecondition = 9
+	grpcUnimplemented      = 12
+	grpcInternal           = 13
+	grpcUnavailable        = 14
+)
+
+// grpcError is a call that fails with a gRPC status.
+type grpcError struct {
+	code int
+	msg  string
+}
+
+func (e *grpcError) Error() string { return e.msg }
+
+// grpcStatus is the gRPC status for an error of the game server.
+func grpcStatus(err error) (int, string) {
+	var ge *grpcError
+	if errors.As(err, &ge) {
+		return ge.code, ge.msg
+	}
+	var se *serveError
+	if errors.As(err, &se) {
+		switch se.status {
+		case http.StatusBadRequest:
+			return grpcInvalidArgument, se.msg
+		case http.StatusForbidden:
+			return grpcPermissionDenied, se.msg
+		case http.StatusNotFound:
+			return grpcNotFound, se.msg
+		case http.StatusConflict:
+			return grpcFailedPrecondition, se.msg
+		case http.StatusServiceUnavailable:
+			return grpcUnavailable, se.msg
+		}
+	}
+	return grpcInternal, err.Error()
+}
+
+// grpcHandler answers the calls of the Engine service.
+func (s *gameServer) grpcHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	reply, err := s.grpcCall(r)
+	w.WriteHeader(http.StatusOK)
+	code, msg := grpcOK, ""
+	if err == nil {
+		frame := make([]byte, 5, 5+len(reply))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(reply)))
+		w.Write(append(frame, reply...))
+	} else {
+		code, msg = grpcStatus(err)
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set("Grpc-Message", grpcPercentEncode(msg))
+	}
+}
+
+// grpcCall reads a call's message and runs the method it names.
+func (s *gameServer) grpcCall(r *http.Request) ([]byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r.Body, head[:]); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "no request message"}
+	}
+	if head[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "compressed messages are not spoken"}
+	}
+	n := binary.BigEndian.Uint32(head[1:])
+	if n > wsMaxMessage {
+		return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("a message of %d bytes", n)}
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r.Body, msg); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "the request message is cut short"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	method := r.PathValue("method")
+	if method == "NewGame" {
+		req, err := pbNewGameRequest(msg)
+		if err != nil {
+			return nil, err
+		}
+		g, err := s.newGame(req)
+		if err != nil {
+			return nil, err
+		}
+		defer g.enter()()
+		b := pbGame(g.view(), g.size)
+		for _, token := range g.tokens {
+			b.bytes(13, []byte(token))
+		}
+		return b, nil
+	}
+	id, move, err := pbGameCall(msg)
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.game(id)
+	if err != nil {
+		return nil, err
+	}
+	defer g.enter()()
+	switch method {
+	case "GetGame":
+		return pbGame(g.view(), g.size), nil
+	case "Play":
+		if err := g.remoteMove(move); err != nil {
+			return nil, err
+		}
+		return pbGame(g.view(), g.size), nil
+	case "LegalMoves":
+		moves, err := g.legalMoves()
+		if err != nil {
+			return nil, err
+		}
+		var b pbBuffer
+		for _, m := range moves {
+			b.message(1, pbMove(m))
+		}
+		return b, nil
+	case "Recommend":
+		advice, err := g.recommend()
+		if err != nil {
+			return nil, err
+		}
+		var b pbBuffer
+		b.int(1, advice.Take)
+		for _, m := range advice.Moves {
+			b.message(2, pbMove(m))
+		}
+		return b, nil
+	}
+	return nil, &grpcError{grpcUnimplemented, fmt.Sprintf("no method %q", method)}
+}
+
+// grpcPercentEncode encodes a status message as the grpc-message trailer
+// wants it.
+func grpcPercentEncode(s string) string {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= ' ' && c <= '~' && c != '%' {
+			out = append(out, c)
+		} else {
+			out = append(out, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+	return string(out)
+}
+
+// pbBuffer is a protocol buffer message being written. Fields at their zero
+// value are left out, as proto3 does.
+type pbBuffer []byte
+
+func (b *pbBuffer) tag(field, wire int) {
+	*b = binary.AppendUvarint(*b, uint64(field<<3|wire))
+}
+
+// int writes an int32 or int64 field; negative numbers take ten bytes.
+func (b *pbBuffer) int(field, v int) {
+	if v != 0 {
+		b.tag(field, 0)
+		*b = binary.AppendUvarint(*b, uint64(int64(v)))
+	}
+}
+
+func (b *pbBuffer) bool(field int, v bool) {
+	if v {
+		b.int(field, 1)
+	}
+}
+
+func (b *pbBuffer) double(field int, v float64) {
+	if v != 0 {
+		b.tag(field, 1)
+		*b = binary.LittleEndian.AppendUint64(*b, math.Float64bits(v))
+	}
+}
+
+func (b *pbBuffer) string(field int, s string) {
+	if s != "" {
+		b.bytes(field, []byte(s))
+	}
+}
+
+func (b *pbBuffer) bytes(field int, data []byte) {
+	b.tag(field, 2)
+	*b = binary.AppendUvarint(*b, uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+// message writes an embedded message, kept even when empty as an element
+// of a repeated field.
+func (b *pbBuffer) message(field int, m pbBuffer) {
+	b.bytes(field, m)
+}
+
+// packed writes a repeated int32 field packed, as proto3 does.
+func (b *pbBuffer) packed(field int, vs []int) {
+	if len(vs) == 0 {
+		return
+	}
+	var p []byte
+	for _, v := range vs {
+		p = binary.AppendUvarint(p, uint64(int64(v)))
+	}
+	b.bytes(field, p)
+}
+
+// pbField is a field read from a message: its number, and its value as a
+// number or as bytes by its wire type.
+type pbField struct {
+	num  int
+	wire int
+	n    uint64
+	data []byte
+}
+
+// pbFields reads the fields of a message in order.
+func pbFields(msg []byte) ([]pbField, error) {
+	var fields []pbField
+	for len(msg) > 0 {
+		key, k := binary.Uvarint(msg)
+		if k <= 0 {
+			return nil, &grpcError{grpcInvalidArgument, "a field key cannot be read"}
+		}
+		msg = msg[k:]
+		f := pbField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case 0:
+			v, k := binary.Uvarint(msg)
+			if k <= 0 {
+				return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("field %d cannot be read", f.num)}
+			}
+			f.n, msg = v, msg[k:]
+		case 1:
+			if len(msg) < 8 {
+				return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("field %d is cut short", f.num)}
+			}
+			f.n, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case 2:
+			l, k := binary.Uvarint(msg)
+			if k <= 0 || uint64(len(msg)-k) < l {
+				return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("field %d is cut short", f.num)}
+			}
+			f.data, msg = msg[k:k+int(l)], msg[k+int(l):]
+		case 5:
+			if len(msg) < 4 {
+				return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("field %d is cut short", f.num)}
+			}
+			f.n, msg = uint64(binary.LittleEndian.Uint32(msg)), msg[4:]
+		default:
+			return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("field %d has wire type %d", f.num, f.wire)}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// pbNewGameRequest reads a NewGameRequest.
+func pbNewGameRequest(msg []byte) (newGameRequest, error) {
+	var req newGameRequest
+	fields, err := pbFields(msg)
+	if err != nil {
+		return req, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			req.Players = int(int32(f.n))
+		case 2:
+			humans := int(int32(f.n))
+			req.Humans = &humans
+		case 3:
+			req.Names = append(req.Names, string(f.data))
+		case 4:
+			req.Strategy = string(f.data)
+		case 5:
+			req.Position = string(f.data)
+		case 6:
+			seed := int64(f.n)
+			req.Seed = &seed
+		}
+	}
+	return req, nil
+}
+
+// pbGameCall reads a GameRequest or a PlayRequest, whose first field is
+// the game's id.
+func pbGameCall(msg []byte) (string, moveRequest, error) {
+	var id string
+	var move moveRequest
+	fields, err := pbFields(msg)
+	if err != nil {
+		return "", move, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			id = string(f.data)
+		case 2:
+			move.Type = string(f.data)
+		case 3:
+			move.Tile = int(int32(f.n))
+		case 4:
+			move.Cell = string(f.data)
+		case 5:
+			move.Partner = f.n != 0
+		case 6:
+			move.Token = string(f.data)
+		}
+	}
+	return id, move, nil
+}
+
+// pbGame writes a Game.
+func pbGame(v servedState, size int) pbBuffer {
+	var b pbBuffer
+	b.string(1, v.ID)
+	for _, seat := range v.Seats {
+		var m pbBuffer
+		m.string(1, seat.Name)
+		m.bool(2, seat.Computer)
+		b.message(2, m)
+	}
+	b.int(3, size)
+	for _, rows := range v.Boards {
+		var cells []int
+		for _, row := range rows {
+			cells = append(cells, row...)
+		}
+		var m pbBuffer
+		m.packed(1, cells)
+		b.message(4, m)
+	}
+	b.packed(5, v.Table)
+	b.int(6, v.Pile)
+	b.int(7, v.Current)
+	b.int(8, v.Drawn)
+	b.bool(9, v.Over)
+	b.int(10, v.Winner)
+	for _, m := range v.History {
+		b.message(11, pbMove(m))
+	}
+	b.string(12, v.Position)
+	return b
+}
+
+// pbMove writes a Move.
+func pbMove(sm servedMove) pbBuffer {
+	var b pbBuffer
+	b.int(1, sm.Seat)
+	b.string(2, sm.Type)
+	b.int(3, sm.Tile)
+	b.string(4, sm.Cell)
+	b.bool(5, sm.Partner)
+	if sm.Score != nil {
+		b.double(6, *sm.Score)
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// In the hand-of-tiles variant every player holds HandSize tiles. A turn
+// plays one of them, or takes a table tile instead, and the hand is drawn
+// back up from the pile at the end of the turn.
+
+// maxHandSize is the largest hand the setup prompt offers.
+const maxHandSize = 3
+
+// dealHands fills every seat's hand from the pile.
+func (state *GameState) dealHands() {
+	for seat := range state.Boards {
+		state.refillHand(seat)
+	}
+}
+
+// refillHand draws seat's hand back up to HandSize while the pile lasts.
+func (state *GameState) refillHand(seat int) {
+	b := state.Boards[seat]
+	for len(b.Hand) < state.HandSize {
+		tile, err := state.popDraw()
+		if err != nil {
+			return
+		}
+		b.Hand = append(b.Hand, tile)
+	}
+}
+
+// holdsHand reports whether the current seat has a hand tile to play.
+func (state *GameState) holdsHand() bool {
+	return state.HandSize > 0 && len(state.Boards[state.Current].Hand) > 0
+}
+
+// playFromHand takes tile out of the current seat's hand.
+func (state *GameState) playFromHand(tile int) Move {
+	b := state.Boards[state.Current]
+	for i, t := range b.Hand {
+		if t == tile {
+			b.Hand = append(b.Hand[:i:i], b.Hand[i+1:]...)
+			break
+		}
+	}
+	return Move{Tile: tile, Type: FromHand}
+}
+
+// bestHandTile picks the current seat's hand tile with the best move, and
+// that move's score; 0 when none of them fits.
+func (state *GameState) bestHandTile() (int, float64) {
+	hand := state.Boards[state.Current].Hand
+	best, bestScore := hand[0], 0.0
+	for _, t := range hand {
+		if moves := state.bestMoves(t); len(moves) > 0 && moves[0].Score > bestScore {
+			best, bestScore = t, moves[0].Score
+		}
+	}
+	return best, bestScore
+}
+
+func handLabel(hand []int) string {
+	labels := make([]string, len(hand))
+	for i, t := range hand {
+		labels[i] = tileLabel(t)
+	}
+	return strings.Join(labels, " ")
+}
+
+// handFooter is what the renderer shows under seat's board: the tiles of a
+// human on turn, otherwise only how many there are.
+func (state *GameState) handFooter(seat int) string {
+	b := state.Boards[seat]
+	if seat == state.Current && !b.IsAi {
+		return "hand: " + handLabel(b.Hand)
+	}
+	return fmt.Sprintf("hand: %d tiles", len(b.Hand))
+}
+
+// promptHandTile asks the current human which hand tile to play.
+func (state *GameState) promptHandTile() Move {
+	hand := state.Boards[state.Current].Hand
+	for {
+		fmt.Printf("Your hand: %s. Play which tile? ", handLabel(hand))
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, errTimeout) {
+			return Move{}
+		}
+		if err != nil {
+			return state.playFromHand(hand[0]) // input ended
+		}
+		tile, err := parseTile(strings.TrimSpace(line), state.maxTile())
+		if err == nil && contains(hand, tile) {
+			return state.playFromHand(tile)
+		}
+		fmt.Println("That tile is not in your hand.")
+	}
+}
+
+func promptHandSize() int {
+	fmt.Printf("Hold a hand of tiles? (0-%d tiles, default 0): ", maxHandSize)
+	line, _ := reader.ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 0 || n > maxHandSize {
+		return 0
+	}
+	return n
+}
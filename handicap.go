@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Handicap evens out a game between players of different strength. A
+// stronger player starts with some diagonal cells left empty; a weaker one
+// may peek at the next pile tile before choosing between pile and table.
+type Handicap struct {
+	Withheld int  // diagonal tiles not dealt at setup
+	Peek     bool // sees the top of the pile before drawing
+}
+
+func (h Handicap) String() string {
+	var parts []string
+	if h.Withheld > 0 {
+		parts = append(parts, fmt.Sprintf("d%d", h.Withheld))
+	}
+	if h.Peek {
+		parts = append(parts, "peek")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "+")
+}
+
+// parseHandicap reads a handicap such as "d2", "peek" or "d1+peek".
+func parseHandicap(s string) (Handicap, error) {
+	var h Handicap
+	for _, part := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r == '+' || r == ',' || unicode.IsSpace(r) }) {
+		switch {
+		case part == "none":
+		case part == "peek":
+			h.Peek = true
+		case strings.HasPrefix(part, "d"):
+			n, err := strconv.Atoi(part[1:])
+			if err != nil || n < 1 || n >= BoardSize {
+				return Handicap{}, fmt.Errorf("%q: withhold 1 to %d diagonal tiles, e.g. d2", part, BoardSize-1)
+			}
+			h.Withheld = n
+		default:
+			return Handicap{}, fmt.Errorf("unknown handicap %q (want dN, peek or none)", part)
+		}
+	}
+	return h, nil
+}
+
+// promptHandicap asks for a seat's handicap, re-asking until it parses.
+func promptHandicap(seat int) Handicap {
+	for {
+		fmt.Printf("Handicap for seat %d (dN: N fewer diagonal tiles, peek: see the next draw; blank for none): ", seat)
+		line, _ := reader.ReadString('\n')
+		h, err := parseHandicap(line)
+		if err == nil {
+			return h
+		}
+		fmt.Println(err)
+	}
+}
+
+func promptUseHandicaps() bool {
+	fmt.Print("Set handicaps for mixed-skill players? (y/N): ")
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
+
+// withheldCells picks which diagonal cells a board starts without.
+func withheldCells(n int) map[int]bool {
+	skip := map[int]bool{}
+	for _, i := range rng.Perm(BoardSize)[:n] {
+		skip[i] = true
+	}
+	return skip
+}
+
+// peekPile tells a player who can see the pile what it holds next.
+func (state *GameState) peekPile() {
+	if !state.pileVisible() {
+		return
+	}
+	fmt.Printf("Peek: the next pile tile is %s.\n", tileLabel(state.Draw[0]))
+}
+
+// peekBeatsTable reports whether a computer that can see the pile should
+// take the known pile tile rather than the table move it was going to make.
+func (state *GameState) peekBeatsTable(tableMove Move) bool {
+	if len(state.Draw) == 0 {
+		return false
+	}
+	moves := state.bestMoves(state.Draw[0])
+	return len(moves) > 0 && moves[0].Score > tableMove.Score
+}
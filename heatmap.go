@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The heatmap grades every cell by its placementScore for a tile, relative
+// to the best cell, so the recommendation data reads at a glance: a colour
+// terminal shades the cell backgrounds from cold blue to hot red, a plain
+// one prefixes each score with a shade character.
+
+// heatColors are 256-colour backgrounds from coldest to hottest.
+var heatColors = []int{17, 18, 25, 31, 37, 71, 142, 178, 208, 202, 196}
+
+var (
+	asciiShades   = []string{" ", ".", ":", "*", "#"}
+	unicodeShades = []string{" ", "░", "▒", "▓", "█"}
+)
+
+// heatLevel places score on one of levels steps between 0 and max.
+func heatLevel(score, max float64, levels int) int {
+	if max <= 0 || score <= 0 {
+		return 0
+	}
+	level := int(score / max * float64(levels-1))
+	if level >= levels {
+		level = levels - 1
+	}
+	return level
+}
+
+// printHeatmap draws the current board's heatmap for tile. Cells the tile
+// cannot legally take are left blank.
+func (state *GameState) printHeatmap(w io.Writer, tile int) {
+	scores := make([][]float64, BoardSize)
+	best := 0.0
+	for r := range scores {
+		scores[r] = make([]float64, BoardSize)
+		for c := range scores[r] {
+			if !state.isPlacementFeasible(tile, r, c) {
+				scores[r][c] = -1
+				continue
+			}
+			scores[r][c] = state.placementScore(tile, r, c)
+			if scores[r][c] > best {
+				best = scores[r][c]
+			}
+		}
+	}
+	shades := asciiShades
+	if term.Unicode {
+		shades = unicodeShades
+	}
+	g := term.box()
+	fmt.Fprintf(w, "Heatmap for %s (hotter is better)\n", tileLabel(tile))
+	var letters strings.Builder
+	letters.WriteString("  ")
+	for c := 0; c < BoardSize; c++ {
+		fmt.Fprintf(&letters, "     %s   ", columnLetter(c))
+	}
+	fmt.Fprintln(w, strings.TrimRight(letters.String(), " "))
+	for r := 0; r < BoardSize; r++ {
+		var line strings.Builder
+		fmt.Fprintf(&line, "%d %s", r+1, g.V)
+		for c := 0; c < BoardSize; c++ {
+			score := scores[r][c]
+			switch {
+			case score < 0:
+				line.WriteString("   --   ")
+			case term.Color:
+				style := fmt.Sprintf("48;5;%d;97", heatColors[heatLevel(score, best, len(heatColors))])
+				line.WriteString(term.paint(style, fmt.Sprintf(" %6.2f ", score)))
+			default:
+				line.WriteString(fmt.Sprintf("%s%6.2f ", shades[heatLevel(score, best, len(shades))], score))
+			}
+			line.WriteString(g.V)
+		}
+		fmt.Fprintln(w, line.String())
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Heuristics holds the knobs of the placement evaluator. It can be loaded
+// from a JSON file; fields left out keep their defaults.
+type Heuristics struct {
+	// RiskAware discounts placements whose neighbouring gaps can only be
+	// completed by a handful of specific tiles, preferring ones that many
+	// remaining tiles could finish even when the expected value is similar.
+	RiskAware bool
+
+	// A table tile is only taken when its best move scores above a
+	// threshold that slides from OpeningThreshold on a fresh board and full
+	// pile to EndgameThreshold as cells fill and the pile runs down.
+	OpeningThreshold float64
+	EndgameThreshold float64
+	// PileWeight is how much the pile running down counts towards the game
+	// stage, against the current board filling up (0 to 1).
+	PileWeight float64
+}
+
+var defaultHeuristics = Heuristics{
+	OpeningThreshold: 20,
+	EndgameThreshold: 0.25,
+	PileWeight:       0.5,
+}
+
+func loadHeuristics(path string) (Heuristics, error) {
+	h := defaultHeuristics
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h, err
+	}
+	err = json.Unmarshal(data, &h)
+	return h, err
+}
+
+// gameStage runs from 0 at the start of the game to 1 when the current board
+// is full or the pile is empty, whichever the PileWeight leans on.
+func (state *GameState) gameStage() float64 {
+	h := state.heuristics()
+	free := BoardSize*BoardSize - BoardSize - len(state.Holes) // the diagonal is dealt at setup
+	fill := 1 - float64(emptyCells(state.Boards[state.Current]))/float64(free)
+	if fill < 0 {
+		fill = 0
+	}
+	pile := 1.0
+	if total := state.tileTotal(); total > 0 {
+		pile = 1 - float64(len(state.Draw))/float64(total)
+	}
+	return h.PileWeight*pile + (1-h.PileWeight)*fill
+}
+
+// tableThreshold is the score a table tile must beat to be worth taking now.
+func (state *GameState) tableThreshold() float64 {
+	h := state.heuristics()
+	stage := state.gameStage()
+	return h.OpeningThreshold + stage*(h.EndgameThreshold-h.OpeningThreshold)
+}
+
+// heuristics returns the state's heuristics, falling back to the default
+// thresholds when none were configured.
+func (state *GameState) heuristics() Heuristics {
+	h := state.Heuristics
+	if h.OpeningThreshold == 0 && h.EndgameThreshold == 0 {
+		h.OpeningThreshold = defaultHeuristics.OpeningThreshold
+		h.EndgameThreshold = defaultHeuristics.EndgameThreshold
+		h.PileWeight = defaultHeuristics.PileWeight
+	}
+	return h
+}
+
+// riskFactor scores how robust placing tile at (r,c) leaves the empty runs
+// next to it. Each run between the tile and the next filled cell (or the
+// edge) needs one tile per empty cell from a fixed value range; every spare
+// tile in that range beyond the minimum makes the run less dependent on any
+// single draw. A run with no spare tiles halves the score.
+func (state *GameState) riskFactor(tile, r, c int) float64 {
+	if tile == Wildcard {
+		return 1
+	}
+	remaining := append(append([]int{}, state.Draw...), state.Table...)
+	factor := 1.0
+	for _, d := range [][2]int{{0, -1}, {0, 1}, {-1, 0}, {1, 0}} {
+		factor *= state.gapFactor(tile, r, c, d[0], d[1], state.step(), remaining)
+	}
+	return factor
+}
+
+// gapFactor scores the empty run from (r,c) in direction (dr,dc), whose
+// values must climb by at least step, by the spare tiles that could fill it.
+func (state *GameState) gapFactor(tile, r, c, dr, dc, step int, remaining []int) float64 {
+	board := state.Boards[state.Current]
+	cells := 0
+	lo, hi := 1, state.maxTile()
+	rr, cc := r+dr, c+dc
+	for ; onBoard(rr, cc) && !isTile(board.Grid[rr][cc]); rr, cc = rr+dr, cc+dc {
+		if board.Grid[rr][cc] == 0 {
+			cells++
+		}
+	}
+	if cells == 0 {
+		return 1
+	}
+	towardStart := dr < 0 || dc < 0
+	if towardStart {
+		hi = tile - step
+		if onBoard(rr, cc) {
+			lo = board.Grid[rr][cc] + step
+		}
+	} else {
+		lo = tile + step
+		if onBoard(rr, cc) {
+			hi = board.Grid[rr][cc] - step
+		}
+	}
+	support := 0
+	for _, t := range remaining {
+		if t == Wildcard || (t >= lo && t <= hi) {
+			support++
+		}
+	}
+	slack := support - cells
+	if slack < 0 {
+		slack = 0
+	}
+	return float64(slack+1) / float64(slack+2)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+// With -hints the best few moves for a drawn tile are shown as soon as a
+// human has it, to be picked by number; the score tables and heatmap stay
+// behind r.
+var autoHints bool
+
+// autoHintCount is how many moves the hints list.
+const autoHintCount = 3
+
+// printHints lists the best moves for tile and returns them, in the order
+// they are numbered.
+func (state *GameState) printHints(tile int) []Move {
+	recs := state.bestMoves(tile)
+	if len(recs) == 0 {
+		fmt.Println(tr("No legal placements found."))
+		return nil
+	}
+	recs = recs[:min(len(recs), autoHintCount)]
+	fmt.Printf(tr("Top moves for %s (pick one by number, r for the full map):\n"), tileLabel(tile))
+	for i, m := range recs {
+		where := ""
+		if m.Partner {
+			where = fmt.Sprintf(tr(" on partner %d's board"), state.partner(state.Current))
+		}
+		fmt.Printf(term.text(tr("%d) %s at %s%s — score %5.2f\n")),
+			i+1, tr(map[MoveType]string{Place: "Place", Swap: "Swap"}[m.Type]), m.Cell, where, m.Score)
+	}
+	return recs
+}
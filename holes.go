@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Blocked marks a hole: a cell that can never be filled. Holes sit in the
+// same places on every board; the neighbours ignore them and a board is
+// full once every other cell is.
+const Blocked = -2
+
+// blockedLabel is how a hole is written on screen and in saves.
+const blockedLabel = "#"
+
+// maxHoles caps the holes per board, leaving the board worth playing.
+func maxHoles() int { return BoardSize }
+
+// blockHoles punches the game's holes into board.
+func (state *GameState) blockHoles(board *Board) {
+	for _, h := range state.Holes {
+		board.Grid[h.R][h.C] = Blocked
+	}
+}
+
+// randomHoles picks n cells off the diagonal, which is dealt at setup.
+func randomHoles(n int) []Cell {
+	var cells []Cell
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if r != c {
+				cells = append(cells, Cell{R: r, C: c})
+			}
+		}
+	}
+	rng.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+	return cells[:n]
+}
+
+// parseHoles reads a hole layout: a count of random holes, or the cells
+// themselves as "D1 B3", or "r,c r,c".
+func parseHoles(s string) ([]Cell, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > maxHoles() {
+			return nil, fmt.Errorf("%d holes: want 0 to %d", n, maxHoles())
+		}
+		return randomHoles(n), nil
+	}
+	var holes []Cell
+	seen := map[Cell]bool{}
+	for _, f := range strings.Fields(s) {
+		cell, err := parseCell(f)
+		if err != nil {
+			return nil, err
+		}
+		if cell.R == cell.C {
+			return nil, fmt.Errorf("%s is on the diagonal, which is dealt at setup", cell)
+		}
+		if !seen[cell] {
+			seen[cell] = true
+			holes = append(holes, cell)
+		}
+	}
+	if len(holes) > maxHoles() {
+		return nil, fmt.Errorf("%d holes: want at most %d", len(holes), maxHoles())
+	}
+	return holes, nil
+}
+
+// blockedCells counts board's holes.
+func blockedCells(board *Board) int {
+	n := 0
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if board.Grid[r][c] == Blocked {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (state *GameState) promptHoles() {
+	for {
+		fmt.Printf("Blocked cells on every board (blank for none, 1-%d for random ones, or cells like D1 B3): ", maxHoles())
+		line, _ := reader.ReadString('\n')
+		holes, err := parseHoles(line)
+		if err == nil {
+			state.Holes = holes
+			return
+		}
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"image/color"
+	"os"
+	"strings"
+)
+
+// The html command turns a -move-log into a single web page that steps
+// through the game, for friends who would rather click than read a
+// transcript: the boards and the table after every move, the list of moves
+// to jump around in, what the engine made of each move, and the notes kept
+// while studying it. Everything the page needs is inside it, so it can be
+// mailed or put anywhere and opened without the game or a network.
+
+// htmlStep is the game after a move, as the page shows it.
+type htmlStep struct {
+	Counters string       `json:"counters"`
+	Said     string       `json:"said"` // what happened, the draw first
+	Label    string       `json:"label"`
+	Eval     string       `json:"eval,omitempty"`
+	Notes    []string     `json:"notes,omitempty"`
+	Boards   [][][]string `json:"boards"`         // each board's rows of cell labels, "" empty
+	Last     []int        `json:"last,omitempty"` // board, row and column of the move
+	Current  int          `json:"current"`
+	Table    []string     `json:"table"`
+	Pile     string       `json:"pile"`
+}
+
+// htmlReplay is everything the page shows.
+type htmlReplay struct {
+	Title   string     `json:"title"`
+	Players []string   `json:"players"`
+	Steps   []htmlStep `json:"steps"`
+}
+
+// htmlStepOf captures the position for the page.
+func (state *GameState) htmlStepOf() htmlStep {
+	s := htmlStep{Counters: state.counters(), Current: state.Current, Table: []string{}, Pile: fmt.Sprint(len(state.Draw))}
+	for _, b := range state.Boards {
+		rows := make([][]string, BoardSize)
+		for r := range rows {
+			rows[r] = make([]string, BoardSize)
+			for c := range rows[r] {
+				if v := b.Grid[r][c]; v != 0 {
+					rows[r][c] = tileLabel(v)
+				}
+			}
+		}
+		s.Boards = append(s.Boards, rows)
+	}
+	for _, t := range state.Table {
+		s.Table = append(s.Table, tileLabel(t))
+	}
+	return s
+}
+
+// describeJudgement says in a line what the engine makes of move, from the
+// position it was made in.
+func (state *GameState) describeJudgement(move Move) string {
+	if move.Type == Steal {
+		return ""
+	}
+	best, score, ok := state.judgeMove(move)
+	if !ok {
+		return tileLabel(move.Tile) + " fitted nowhere; discarding was the only move."
+	}
+	bestMove := fmt.Sprintf("%s %s at %s", moveTypeNames[best.Type], tileLabel(best.Tile), best.Cell)
+	if move.Cell == nil {
+		return fmt.Sprintf("Discarded; the engine would %s (score %.1f).", bestMove, best.Score)
+	}
+	if *move.Cell == *best.Cell && move.Partner == best.Partner {
+		return fmt.Sprintf("Score %.1f, the engine's best move.", score)
+	}
+	return fmt.Sprintf("Score %.1f; the engine would %s (score %.1f, %.1f better).", score, bestMove, best.Score, max(best.Score-score, 0))
+}
+
+// exportHTML writes the move log at path as a replay page to out.
+func exportHTML(path, out string) error {
+	var page htmlReplay
+	var before *GameState // the position the next move is made in
+	drawn, notes := "", []string(nil)
+	last, err := walkMoveLog(path, func(state *GameState, e LoggedMove, said string) bool {
+		defer func() { before = state.clone() }()
+		switch e.Type {
+		case "draw":
+			drawn = said // told with the move the tile is played in
+			return false
+		case "note":
+			notes = append(notes, strings.TrimSpace(said))
+			return false
+		case "start":
+			if page.Title == "" {
+				page.Title = "Replay of the game started " + e.Time.Local().Format("2006-01-02 15:04")
+			}
+		}
+		step := state.htmlStepOf()
+		step.Said = strings.TrimSpace(drawn + said)
+		lines := strings.Split(step.Said, "\n")
+		step.Label = strings.TrimSpace(lines[len(lines)-1])
+		step.Notes, drawn, notes = notes, "", nil
+		switch e.Type {
+		case "place", "swap", "discard", "steal":
+			played := state.History[len(state.History)-1]
+			if before != nil {
+				step.Eval = before.describeJudgement(played.Move)
+			}
+			if m := played.Move; m.Cell != nil {
+				board := state.moveSeat(played.Seat, m)
+				if m.Type == Steal {
+					board = m.Target
+				}
+				step.Last = []int{board, m.Cell.R, m.Cell.C}
+			}
+		}
+		page.Steps = append(page.Steps, step)
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	for i := range last.Boards {
+		page.Players = append(page.Players, last.seatLabel(i))
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := htmlReplayPage.Execute(f, page); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// htmlColours are the picture colours, for the page to match the GIFs.
+var htmlColours = map[string]color.RGBA{
+	"picBackground": picBackground, "picEmpty": picEmpty, "picTile": picTile, "picWildcard": picWildcard,
+	"picHole": picHole, "picInk": picInk, "picFaint": picFaint, "picCurrent": picCurrent,
+}
+
+// htmlReplayPage is the page, its viewer written in plain JavaScript.
+var htmlReplayPage = template.Must(template.New("replay").Funcs(template.FuncMap{
+	"colour": func(name string) template.CSS { return template.CSS(hex(htmlColours[name])) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { background: {{colour "picBackground"}}; color: {{colour "picInk"}}; font-family: system-ui, sans-serif; margin: 24px; }
+h1 { font-size: 1.2em; margin: 0 0 4px; }
+#players, #counters, .faint { color: {{colour "picFaint"}}; }
+main { display: flex; gap: 32px; align-items: flex-start; flex-wrap: wrap; }
+#boards { display: flex; gap: 28px; flex-wrap: wrap; margin: 12px 0; }
+.board h2 { font-size: 1em; text-align: center; margin: 0 0 6px; }
+.board.current h2 { color: {{colour "picCurrent"}}; }
+.board table { border-collapse: collapse; }
+.board td { width: 40px; height: 40px; text-align: center; font-size: 1.1em; border: 1px solid {{colour "picFaint"}}; background: {{colour "picEmpty"}}; }
+.board td.tile { background: {{colour "picTile"}}; }
+.board td.wild { background: {{colour "picWildcard"}}; }
+.board td.hole { background: {{colour "picHole"}}; }
+.board td.last { outline: 3px solid {{colour "picCurrent"}}; outline-offset: -3px; font-weight: bold; }
+.board th { color: {{colour "picFaint"}}; font-weight: normal; font-size: 0.8em; }
+#table span { display: inline-block; min-width: 28px; padding: 4px; margin: 2px; text-align: center; background: {{colour "picTile"}}; border: 1px solid {{colour "picInk"}}; }
+#said { white-space: pre-line; margin: 12px 0 4px; }
+#eval { font-style: italic; }
+#notes li { margin: 2px 0; }
+#controls button { font-size: 1em; min-width: 40px; }
+#moves { max-height: 70vh; overflow-y: auto; min-width: 260px; padding-left: 28px; margin: 0; }
+#moves li { cursor: pointer; padding: 2px 4px; }
+#moves li.shown { background: {{colour "picEmpty"}}; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div id="players">{{range $i, $p := .Players}}{{if $i}} · {{end}}{{$p}}{{end}}</div>
+<main>
+<section>
+<div id="controls">
+<button id="first" title="First (Home)">⏮</button>
+<button id="prev" title="Back (←)">◀</button>
+<button id="play" title="Play or pause (space)">▶</button>
+<button id="next" title="Forward (→)">▶|</button>
+<button id="end" title="Last (End)">⏭</button>
+<span id="step" class="faint"></span>
+</div>
+<div id="counters"></div>
+<div id="table"></div>
+<div id="boards"></div>
+<div id="said"></div>
+<div id="eval"></div>
+<ul id="notes"></ul>
+</section>
+<ol id="moves" start="0"></ol>
+</main>
+<script>
+const game = {{.}};
+const players = game.players;
+const el = id => document.getElementById(id);
+let shown = 0, timer = null;
+
+function cell(td, label) {
+	td.textContent = label === "#" ? "" : label;
+	td.className = label === "" ? "" : label === "#" ? "hole" : label === "*" ? "wild" : "tile";
+}
+
+function show(i) {
+	shown = Math.max(0, Math.min(game.steps.length - 1, i));
+	const s = game.steps[shown];
+	el("step").textContent = "move " + shown + " of " + (game.steps.length - 1);
+	el("counters").textContent = s.counters + " · draw pile: " + s.pile;
+	el("table").innerHTML = "";
+	const label = document.createElement("span");
+	label.className = "faint";
+	label.textContent = "Table: ";
+	el("table").append(label);
+	if (s.table.length === 0) {
+		el("table").append("empty");
+	}
+	for (const t of s.table) {
+		const span = document.createElement("span");
+		span.textContent = t;
+		el("table").append(span);
+	}
+	el("boards").innerHTML = "";
+	s.boards.forEach((rows, b) => {
+		const div = document.createElement("div");
+		div.className = "board" + (b === s.current ? " current" : "");
+		const h = document.createElement("h2");
+		h.textContent = players[b] || "Player " + b;
+		div.append(h);
+		const table = document.createElement("table");
+		const head = table.insertRow();
+		head.append(document.createElement("th"));
+		rows.forEach((_, c) => {
+			const th = document.createElement("th");
+			th.textContent = String.fromCharCode(65 + c);
+			head.append(th);
+		});
+		rows.forEach((row, r) => {
+			const tr = table.insertRow();
+			const th = document.createElement("th");
+			th.textContent = r + 1;
+			tr.append(th);
+			row.forEach((label, c) => {
+				const td = tr.insertCell();
+				cell(td, label);
+				if (s.last && s.last[0] === b && s.last[1] === r && s.last[2] === c) {
+					td.classList.add("last");
+				}
+			});
+		});
+		div.append(table);
+		el("boards").append(div);
+	});
+	el("said").textContent = s.said;
+	el("eval").textContent = s.eval || "";
+	el("notes").innerHTML = "";
+	for (const n of s.notes || []) {
+		const li = document.createElement("li");
+		li.textContent = n;
+		el("notes").append(li);
+	}
+	const items = el("moves").children;
+	for (let j = 0; j < items.length; j++) {
+		items[j].classList.toggle("shown", j === shown);
+	}
+	if (items[shown]) {
+		items[shown].scrollIntoView({block: "nearest"});
+	}
+}
+
+function play() {
+	if (timer) {
+		clearInterval(timer);
+		timer = null;
+		el("play").textContent = "▶";
+		return;
+	}
+	if (shown === game.steps.length - 1) {
+		show(0);
+	}
+	el("play").textContent = "⏸";
+	timer = setInterval(() => {
+		if (shown === game.steps.length - 1) {
+			play();
+			return;
+		}
+		show(shown + 1);
+	}, 1000);
+}
+
+game.steps.forEach((s, i) => {
+	const li = document.createElement("li");
+	li.textContent = s.label;
+	li.title = s.eval || "";
+	li.onclick = () => show(i);
+	el("moves").append(li);
+});
+el("first").onclick = () => show(0);
+el("prev").onclick = () => show(shown - 1);
+el("next").onclick = () => show(shown + 1);
+el("end").onclick = () => show(game.steps.length - 1);
+el("play").onclick = play;
+document.addEventListener("keydown", e => {
+	const keys = {ArrowLeft: () => show(shown - 1), ArrowRight: () => show(shown + 1), Home: () => show(0), End: () => show(game.steps.length - 1), " ": play};
+	if (keys[e.key]) {
+		e.preventDefault();
+		keys[e.key]();
+	}
+});
+show(0);
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Messages are written in English at their call sites and looked up in the
+// catalog of the chosen language by that English text, gettext style. A
+// message a catalog lacks stays English, so catalogs can grow one message at
+// a time.
+
+// defaultLocale is the language of the messages as written.
+const defaultLocale = "en"
+
+// locale is the language messages are shown in.
+var locale = defaultLocale
+
+// catalogs maps a language to its translations, keyed by the English text.
+var catalogs = map[string]map[string]string{
+	"es": spanish,
+}
+
+// tr translates a message, or a format for fmt, into the chosen language.
+func tr(s string) string {
+	if t, ok := catalogs[locale][s]; ok {
+		return t
+	}
+	return s
+}
+
+// locales lists the languages messages can be shown in.
+func locales() []string {
+	names := []string{defaultLocale}
+	for name := range catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// detectLocale reads the language from the environment the way the C
+// library does, LC_ALL before LC_MESSAGES before LANG, and falls back to
+// English for languages without a catalog.
+func detectLocale(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		fields := strings.FieldsFunc(value, func(r rune) bool { return r == '_' || r == '.' || r == '@' })
+		if len(fields) == 0 {
+			continue
+		}
+		lang := strings.ToLower(fields[0])
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+		return defaultLocale
+	}
+	return defaultLocale
+}
+
+// setLocale picks the language by name, "auto" reading the environment.
+func setLocale(name string, getenv func(string) string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "" || name == "auto":
+		locale = detectLocale(getenv)
+	case name == defaultLocale:
+		locale = name
+	case catalogs[name] != nil:
+		locale = name
+	default:
+		return fmt.Errorf("no messages in %q (have %s)", name, strings.Join(locales(), ", "))
+	}
+	return nil
+}
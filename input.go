@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxTile is the highest tile value in the standard set; a game can choose
+// another range with GameState.TileRange.
+const MaxTile = 20
+
+// maxTileRange is the highest tile a configurable range may go up to.
+const maxTileRange = 99
+
+// minTileRange is the smallest range that can fill a board: the length of a
+// strictly increasing path from one corner to the other.
+func minTileRange() int {
+	return 2*BoardSize - 1
+}
+
+var errPileEmpty = errors.New("draw pile is empty")
+
+// maxTile is the highest tile value in play.
+func (state *GameState) maxTile() int {
+	if state.TileRange > 0 {
+		return state.TileRange
+	}
+	return MaxTile
+}
+
+// parseTile reads a tile value typed by the user or stored in a save, which
+// must lie in 1-maxTile.
+func parseTile(s string, maxTile int) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case wildcardLabel, "w":
+		return Wildcard, nil
+	}
+	t, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf(tr("%q is not a number"), s)
+	}
+	if t < 1 || t > maxTile {
+		return 0, fmt.Errorf(tr("tile %d out of range 1-%d"), t, maxTile)
+	}
+	return t, nil
+}
+
+// parseCell reads a cell either in the notation the game prints, a column
+// letter and a row number like "B3", or as a 0-indexed "row,col" pair, and
+// checks it lies on the board.
+func parseCell(s string) (Cell, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ",") {
+		return parseCellName(s)
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return Cell{}, fmt.Errorf(tr("expected a cell like B3 or row,col but got %q"), s)
+	}
+	r, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	c, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil {
+		return Cell{}, fmt.Errorf(tr("expected a cell like B3 or row,col but got %q"), s)
+	}
+	if !onBoard(r, c) {
+		return Cell{}, fmt.Errorf(tr("(%d,%d) is off the board"), r, c)
+	}
+	return Cell{R: r, C: c}, nil
+}
+
+// parseCellName reads the "B3" notation: column letter, then row from 1.
+func parseCellName(s string) (Cell, error) {
+	upper := strings.ToUpper(s)
+	if len(upper) < 2 || upper[0] < 'A' || upper[0] > 'Z' {
+		return Cell{}, fmt.Errorf(tr("expected a cell like B3 or row,col but got %q"), s)
+	}
+	row, err := strconv.Atoi(upper[1:])
+	if err != nil {
+		return Cell{}, fmt.Errorf(tr("expected a cell like B3 or row,col but got %q"), s)
+	}
+	cell := Cell{R: row - 1, C: int(upper[0] - 'A')}
+	if !onBoard(cell.R, cell.C) {
+		return Cell{}, fmt.Errorf(tr("%s is off the board"), upper)
+	}
+	return cell, nil
+}
+
+// String names the cell the way players read the board: the column letter
+// and the row counted from 1, so (2,1) is B3.
+func (c Cell) String() string {
+	return columnLetter(c.C) + strconv.Itoa(c.R+1)
+}
+
+// columnLetter labels column c of a board.
+func columnLetter(c int) string {
+	return string(rune('A' + c))
+}
+
+func onBoard(r, c int) bool {
+	return r >= 0 && r < BoardSize && c >= 0 && c < BoardSize
+}
+
+// popDraw takes the top tile off the draw pile.
+func (state *GameState) popDraw() (int, error) {
+	if len(state.Draw) == 0 {
+		return 0, errPileEmpty
+	}
+	tile := state.Draw[0]
+	state.Draw = state.Draw[1:]
+	return tile, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Evaluator sanity properties checked over many random positions, so changes
+// to the scoring functions can't quietly start producing nonsense.
+
+const invariantPositions = 200
+
+// randomPosition deals a two-player game and fills a random number of legal
+// cells on the current board, using only rng so failures reproduce.
+func randomPosition(rng *rand.Rand) *GameState {
+	state := &GameState{Heuristics: defaultHeuristics}
+	for p := 0; p < 2; p++ {
+		for t := 1; t <= MaxTile; t++ {
+			state.Draw = append(state.Draw, t)
+		}
+	}
+	rng.Shuffle(len(state.Draw), func(i, j int) { state.Draw[i], state.Draw[j] = state.Draw[j], state.Draw[i] })
+	for p := 0; p < 2; p++ {
+		b := &Board{}
+		diag := append([]int{}, state.Draw[:BoardSize]...)
+		state.Draw = state.Draw[BoardSize:]
+		sort.Ints(diag)
+		for i, t := range diag {
+			b.Grid[i][i] = t
+		}
+		state.Boards = append(state.Boards, b)
+	}
+	for n := rng.Intn(10); n > 0 && len(state.Draw) > 0; n-- {
+		tile := state.Draw[0]
+		state.Draw = state.Draw[1:]
+		r, c := rng.Intn(BoardSize), rng.Intn(BoardSize)
+		if state.Boards[0].Grid[r][c] == 0 && state.isPlacementFeasible(tile, r, c) {
+			state.Boards[0].Grid[r][c] = tile
+		} else {
+			state.Table = append(state.Table, tile)
+		}
+	}
+	return state
+}
+
+// transposed mirrors every board across the main diagonal.
+func transposed(state *GameState) *GameState {
+	t := state.clone()
+	for _, b := range t.Boards {
+		for r := 0; r < BoardSize; r++ {
+			for c := r + 1; c < BoardSize; c++ {
+				b.Grid[r][c], b.Grid[c][r] = b.Grid[c][r], b.Grid[r][c]
+			}
+		}
+	}
+	return t
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func inUnit(p float64) bool { return p >= 0 && p <= 1 }
+
+func TestInvariantProbabilitiesInRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < invariantPositions; i++ {
+		state := randomPosition(rng)
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if p := state.futureRowProbability(r, c); !inUnit(p) {
+					t.Fatalf("position %d: row probability %v at (%d,%d)", i, p, r, c)
+				}
+				if p := state.futureColProbability(r, c); !inUnit(p) {
+					t.Fatalf("position %d: column probability %v at (%d,%d)", i, p, r, c)
+				}
+				for tile := 1; tile <= MaxTile; tile++ {
+					if f := state.riskFactor(tile, r, c); f <= 0 || f > 1 {
+						t.Fatalf("position %d: risk factor %v for %d at (%d,%d)", i, f, tile, r, c)
+					}
+					if s := state.placementScore(tile, r, c); s < 0 || s > 100*brunoExtraTurnBonus {
+						t.Fatalf("position %d: score %v for %d at (%d,%d)", i, s, tile, r, c)
+					}
+				}
+			}
+		}
+		for lo := 0; lo <= MaxTile+1; lo += 3 {
+			for hi := lo; hi <= MaxTile+1; hi += 4 {
+				if p := state.remainingProbability(lo, hi); !inUnit(p) {
+					t.Fatalf("position %d: remaining probability %v in [%d,%d]", i, p, lo, hi)
+				}
+			}
+		}
+		if th := state.tableThreshold(); th < defaultHeuristics.EndgameThreshold || th > defaultHeuristics.OpeningThreshold {
+			t.Fatalf("position %d: table threshold %v outside its bounds", i, th)
+		}
+	}
+}
+
+func TestInvariantTransposeSymmetry(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < invariantPositions; i++ {
+		state := randomPosition(rng)
+		state.BrunoVariant = i%2 == 0
+		state.Heuristics.RiskAware = i%3 == 0
+		mirror := transposed(state)
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				for tile := 1; tile <= MaxTile; tile++ {
+					if a, b := state.isPlacementFeasible(tile, r, c), mirror.isPlacementFeasible(tile, c, r); a != b {
+						t.Fatalf("position %d: %d at (%d,%d) feasible=%v but %v transposed", i, tile, r, c, a, b)
+					}
+					if a, b := state.placementScore(tile, r, c), mirror.placementScore(tile, c, r); !almostEqual(a, b) {
+						t.Fatalf("position %d: %d at (%d,%d) scores %v but %v transposed", i, tile, r, c, a, b)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestInvariantMonotonicity(t *testing.T) {
+	// On an empty board small tiles belong top left, large ones bottom right.
+	state := &GameState{Boards: []*Board{{}}}
+	for tile := 1; tile <= MaxTile; tile++ {
+		state.Draw = append(state.Draw, tile)
+	}
+	for tile := 1; tile <= 5; tile++ {
+		if state.placementScore(tile, 0, 0) < state.placementScore(tile, BoardSize-1, BoardSize-1) {
+			t.Errorf("Expected %d to prefer the top left corner", tile)
+		}
+	}
+	for tile := 16; tile <= MaxTile; tile++ {
+		if state.placementScore(tile, BoardSize-1, BoardSize-1) < state.placementScore(tile, 0, 0) {
+			t.Errorf("Expected %d to prefer the bottom right corner", tile)
+		}
+	}
+
+	// More tiles that could fill a gap never make a placement riskier.
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < invariantPositions; i++ {
+		state := randomPosition(rng)
+		extra := state.clone()
+		extra.Draw = append(extra.Draw, rng.Intn(MaxTile)+1)
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				for tile := 1; tile <= MaxTile; tile++ {
+					if extra.riskFactor(tile, r, c) < state.riskFactor(tile, r, c) {
+						t.Fatalf("position %d: an extra unseen tile made %d at (%d,%d) riskier", i, tile, r, c)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestInvariantBestMovesRankedAndLegal(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < invariantPositions; i++ {
+		state := randomPosition(rng)
+		tile := rng.Intn(MaxTile) + 1
+		moves := state.bestMoves(tile)
+		for j, m := range moves {
+			if j > 0 && m.Score > moves[j-1].Score {
+				t.Fatalf("position %d: moves not sorted by score", i)
+			}
+			if !state.isPlacementFeasible(tile, m.Cell.R, m.Cell.C) {
+				t.Fatalf("position %d: suggested illegal %d at (%d,%d)", i, tile, m.Cell.R, m.Cell.C)
+			}
+			if (m.Type == Place) != (state.Boards[0].Grid[m.Cell.R][m.Cell.C] == 0) {
+				t.Fatalf("position %d: move type %v does not match cell contents", i, m.Type)
+			}
+		}
+	}
+}
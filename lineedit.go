@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// On a terminal the prompts read through a small line editor: the arrow
+// keys move along the line and through the answers given earlier, the usual
+// control keys edit it, and tab completes cells, files and the words the
+// prompts know. Anywhere stty is missing it falls back to plain lines.
+
+// editor is the running line editor, or nil when the prompts read plain
+// lines.
+var editor *lineEditor
+
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer // the terminal, even while a transcript takes over os.Stdout
+	history []string
+	plain   bool // read plain lines, as under the chess clock
+
+	// complete lists what a word can be completed to.
+	complete func(word string) []string
+}
+
+func newLineEditor() *lineEditor {
+	return &lineEditor{in: bufio.NewReader(os.Stdin), out: os.Stdout, complete: completeWord}
+}
+
+// ReadString reads one line with editing. Only '\n' lines are edited.
+func (e *lineEditor) ReadString(delim byte) (string, error) {
+	if e.plain || delim != '\n' {
+		return e.in.ReadString(delim)
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return e.in.ReadString(delim)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return e.in.ReadString(delim)
+	}
+	defer stty(strings.TrimSpace(saved))
+	return e.edit()
+}
+
+// edit runs the keys of one line, echoing it to out as it changes.
+func (e *lineEditor) edit() (string, error) {
+	var line []rune
+	pos, shown := 0, 0 // cursor position, and where the terminal's cursor is
+	recalled, draft := len(e.history), ""
+	redraw := func() {
+		if shown > 0 {
+			fmt.Fprintf(e.out, "\033[%dD", shown)
+		}
+		fmt.Fprint(e.out, string(line), "\033[K")
+		if back := len(line) - pos; back > 0 {
+			fmt.Fprintf(e.out, "\033[%dD", back)
+		}
+		shown = pos
+	}
+	recall := func(i int) {
+		if recalled == len(e.history) {
+			draft = string(line)
+		}
+		recalled = i
+		if i == len(e.history) {
+			line = []rune(draft)
+		} else {
+			line = []rune(e.history[i])
+		}
+		pos = len(line)
+	}
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if len(line) == 0 {
+				return "", err
+			}
+			fmt.Fprintln(e.out)
+			return string(line), err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprintln(e.out)
+			e.remember(string(line))
+			return string(line) + "\n", nil
+		case 4: // ctrl-d ends the input on an empty line
+			if len(line) == 0 {
+				fmt.Fprintln(e.out)
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case 127, 8:
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case 1: // ctrl-a
+			pos = 0
+		case 5: // ctrl-e
+			pos = len(line)
+		case 11: // ctrl-k
+			line = line[:pos]
+		case 21: // ctrl-u
+			line, pos = line[pos:], 0
+		case '\t':
+			line, pos = e.completeAt(line, pos, &shown)
+		case 27:
+			if next, _, _ := e.in.ReadRune(); next != '[' && next != 'O' {
+				continue
+			}
+			switch code, _, _ := e.in.ReadRune(); code {
+			case 'A':
+				if recalled > 0 {
+					recall(recalled - 1)
+				}
+			case 'B':
+				if recalled < len(e.history) {
+					recall(recalled + 1)
+				}
+			case 'C':
+				if pos < len(line) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(line)
+			case '3': // delete, sent as ESC [ 3 ~
+				e.in.ReadRune()
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		default:
+			if r < ' ' {
+				continue
+			}
+			line = append(line[:pos], append([]rune{r}, line[pos:]...)...)
+			pos++
+		}
+		redraw()
+	}
+}
+
+// remember adds an answer to the history, skipping blanks and repeats.
+func (e *lineEditor) remember(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+// completeAt completes the word before pos: a single match is filled in,
+// several are filled in as far as they agree and, if that adds nothing,
+// listed on the line above.
+func (e *lineEditor) completeAt(line []rune, pos int, shown *int) ([]rune, int) {
+	start := pos
+	for start > 0 && line[start-1] != ' ' {
+		start--
+	}
+	word := string(line[start:pos])
+	matches := e.complete(word)
+	if len(matches) == 0 {
+		return line, pos
+	}
+	fill := matches[0]
+	for _, m := range matches[1:] {
+		fill = commonPrefix(fill, m)
+	}
+	if len(matches) == 1 && !strings.HasSuffix(fill, string(filepath.Separator)) {
+		fill += " "
+	}
+	if len(matches) > 1 && strings.EqualFold(fill, word) {
+		// show the choices, then the line again below them
+		fmt.Fprintf(e.out, "\n%s\n", strings.Join(matches, "  "))
+		*shown = 0
+		return line, pos
+	}
+	rest := append([]rune(fill), line[pos:]...)
+	return append(line[:start:start], rest...), start + len([]rune(fill))
+}
+
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && strings.EqualFold(a[n:n+1], b[n:n+1]) {
+		n++
+	}
+	return a[:n]
+}
+
+// completeWord lists what the prompts accept starting with word: cells like
+// B3, words such as strategy and preset names, and files for saves and
+// loads.
+func completeWord(word string) []string {
+	if word == "" {
+		return nil
+	}
+	var matches []string
+	upper := strings.ToUpper(word)
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if name := (Cell{R: r, C: c}).String(); strings.HasPrefix(name, upper) {
+				matches = append(matches, name)
+			}
+		}
+	}
+	words := append([]string{"debug", "odds", "tracker", "position", "export", "resign", "undo", "note", "notes", "eval", "custom", "yes", "no", "human"}, strategyNames()...)
+	for _, p := range presets {
+		words = append(words, p.Name)
+	}
+	for _, w := range words {
+		if strings.HasPrefix(w, strings.ToLower(word)) && w != word {
+			matches = append(matches, w)
+		}
+	}
+	files, _ := filepath.Glob(word + "*")
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil && info.IsDir() {
+			f += string(filepath.Separator)
+		}
+		matches = append(matches, f)
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,212 @@
+package main
+
+// spanish is the Spanish message catalog (-lang es). The keys the prompts
+// answer to stay the English letters, so they are shown in brackets.
+var spanish = map[string]string{
+	// setup
+	"Number of human players (0-4, default 1): ":                                "Número de jugadores humanos (0-4, por defecto 1): ",
+	"Number of computer players (0-4, default 1): ":                             "Número de jugadores de la computadora (0-4, por defecto 1): ",
+	"Please enter a number from %d to %d.\n":                                    "Escribe un número del %d al %d.\n",
+	"A game has 1 to 4 players, not %d; try again.\n":                           "Una partida tiene de 1 a 4 jugadores, no %d; prueba otra vez.\n",
+	"%d human and %d computer players. Go ahead? (Y/n): ":                       "%d jugadores humanos y %d de la computadora. ¿Seguimos? (Y/n): ",
+	"Computer %d board initialized.\n":                                          "Tablero de la computadora %d preparado.\n",
+	"Player %d board initialized.\n":                                            "Tablero del jugador %d preparado.\n",
+	"Enter %d numbers for %s diagonal positions (or leave blank for random): ":  "Escribe %d números para la diagonal de %s (o deja en blanco para sortearlos): ",
+	"Skipping diagonal cell %d: %v\n":                                           "Se omite la casilla %d de la diagonal: %v\n",
+	"Warning: this diagonal does not strictly increase, as the rule requires.":  "Aviso: esta diagonal no es estrictamente creciente, como exige la regla.",
+	"Strategy for computer seat %d (%s; default %s): ":                          "Estrategia de la computadora en el asiento %d (%s; por defecto %s): ",
+	"Must tiles taken from the table be placed, never discarded again? (y/N): ": "¿Las fichas tomadas de la mesa deben colocarse, sin volver a descartarlas? (y/N): ",
+	"Allow equal values next to each other in a row or column? (y/N): ":         "¿Permitir valores iguales juntos en una fila o columna? (y/N): ",
+	"Enable Bruno variant? (extra turn for placing next to a twin) (y/N): ":     "¿Activar la variante de Bruno? (turno extra al colocar junto a un gemelo) (y/N): ",
+
+	// seats
+	"Computer %d":            "Computadora %d",
+	"Player %d":              "Jugador %d",
+	"Player %d [test]":       "Jugador %d [prueba]",
+	" on partner %d's board": " en el tablero del compañero %d",
+	" [test]":                " [prueba]",
+	"Name for player %d (blank for Player %d): ": "Nombre del jugador %d (en blanco para Jugador %d): ",
+
+	// drawing
+	"[d]raw, [r]ecommend, [e]quity, [o]dds, [t]racker, [u]ndo, [s]ave, position, export, resign, or [q]uit? ":            "¿[d] robar, [r] recomendar, [e] equidad, [o] probabilidades, [t] recuento, [u] deshacer, [s] guardar, position para la posición, export para un dibujo, resign para abandonar o [q] salir? ",
+	"[d]raw, [r]ecommend, [e]quity, [o]dds, [t]racker, steal [x], [u]ndo, [s]ave, position, export, resign, or [q]uit? ": "¿[d] robar, [r] recomendar, [e] equidad, [o] probabilidades, [t] recuento, [x] quitar, [u] deshacer, [s] guardar, position para la posición, export para un dibujo, resign para abandonar o [q] salir? ",
+	"Play from your [h]and or take from the [t]able? (default hand): ":                                                   "¿Jugar de la [h] mano o tomar de la [t] mesa? (por defecto la mano): ",
+	"Draw %s from the [p]ile or from the [t]able? (default pile): ":                                                      "¿Robar %s del [p] montón o de la [t] mesa? (por defecto el montón): ",
+	"Draw from [p]ile or [t]able? (default pile): ":                                                                      "¿Robar del [p] montón o de la [t] mesa? (por defecto el montón): ",
+	"Tiles on table:":      "Fichas en la mesa:",
+	"Enter tile to pick: ": "Ficha que tomas: ",
+	"Enter drawn tile: ":   "Ficha robada: ",
+	" drew a %s\n":         " robó un %s\n",
+	"%s fits nowhere, and tiles taken from the table must be placed.\n": "%s no cabe en ningún sitio, y las fichas tomadas de la mesa deben colocarse.\n",
+	"No steal available.": "No hay nada que quitar.",
+	"enter file name for save (ending in .json to keep the draw pile and rules)": "nombre del archivo donde guardar (acabado en .json para conservar el montón y las reglas)",
+	"Failed to save:":                         "No se pudo guardar:",
+	"Game saved.":                             "Partida guardada.",
+	"Exiting game.":                           "Saliendo de la partida.",
+	"-- Testing: you are playing %s --\n":     "-- Pruebas: juegas como %s --\n",
+	"Invalid option.":                         "Opción no válida.",
+	"Invalid choice.":                         "Elección no válida.",
+	"Invalid tile number:":                    "Número de ficha no válido:",
+	"Player should draw from the draw stack":  "Conviene robar del montón",
+	"Player should play %s from their hand\n": "Conviene jugar %s de la mano\n",
+	"Swapping tile %s from the table into %s is the best choice\n": "Lo mejor es cambiar la ficha %s de la mesa por la de %s\n",
+	"Placing tile %s from the table into %s is the best choice\n":  "Lo mejor es colocar la ficha %s de la mesa en %s\n",
+
+	// placing
+	"The green cells can take %s.\n":                                                                                   "Las casillas verdes admiten %s.\n",
+	"The cells marked %s, or a tile in parentheses to swap, can take %s.\n":                                            "Las casillas marcadas con %s, o las fichas entre paréntesis para cambiar, admiten %s.\n",
+	"Action for %s? ([r]ecommend, [d]iscard, [u]ndo, [s]ave, [q]uit, a cell like B3, or p B3 on partner %d's board): ": "¿Qué haces con %s? ([r] recomendar, [d] descartar, [u] deshacer, [s] guardar, [q] salir, una casilla como B3, o p B3 en el tablero del compañero %d): ",
+	"Action for %s? ([r]ecommend, [d]iscard, [u]ndo, [s]ave, [q]uit, or a cell like B3): ":                             "¿Qué haces con %s? ([r] recomendar, [d] descartar, [u] deshacer, [s] guardar, [q] salir o una casilla como B3): ",
+	"A tile taken from the table must be placed.":                                                                      "Una ficha tomada de la mesa debe colocarse.",
+	"Placed on table.":               "Dejada en la mesa.",
+	"No legal placements found.":     "No hay ninguna casilla válida.",
+	"Place":                          "Colocar",
+	"Swap":                           "Cambiar",
+	"%d) %s at %s%s — score %5.2f\n": "%d) %s en %s%s — puntuación %5.2f\n",
+	"Top moves for %s (pick one by number, r for the full map):\n":               "Mejores jugadas para %s (elige una por su número, r para el mapa completo):\n",
+	"Choose move number or press Enter to skip: ":                                "Elige el número de jugada o pulsa Intro para seguir: ",
+	"Invalid input (%v), try again.\n":                                           "Entrada no válida (%v), prueba otra vez.\n",
+	"The engine prefers %s at %s%s, score %.0f vs your %.0f — continue? (y/N): ": "El motor prefiere %s en %s%s, puntuación %.0f frente a tu %.0f — ¿seguir? (y/N): ",
+	"%s cannot go at %s, try again.\n":                                           "%s no puede ir en %s, prueba otra vez.\n",
+	"Swapped %s into table, placed %s at %s.\n":                                  "%s pasa a la mesa; %s colocado en %s.\n",
+	"Placed %s at %s.\n":                                                         "%s colocado en %s.\n",
+	"%s to the table\n":                                                          "%s a la mesa\n",
+
+	// computer narration
+	"%s contemplates %s.\n":                "%s estudia el %s.\n",
+	"%s is %v tile %s\n":                   "%s está %v la ficha %s\n",
+	"%s is %v tile %s, %s\n":               "%s está %v la ficha %s, %s\n",
+	"%s is %v tile %s, %s on %s's board\n": "%s está %v la ficha %s, %s en el tablero de %s\n",
+	"placing":                              "colocando",
+	"swapping":                             "cambiando",
+	"discarding":                           "descartando",
+	"%s discards %s to table.\n":           "%s descarta el %s a la mesa.\n",
+	"%s gets extra turn!\n":                "¡%s tiene un turno extra!\n",
+	"%s sees the pile's top tile and prefers it.\n": "%s ve la ficha de arriba del montón y la prefiere.\n",
+	"%s is drawing %s from the table\n":             "%s toma el %s de la mesa\n",
+	"%s plays %s from its hand\n":                   "%s juega %s de su mano\n",
+	"%s draws from pile ":                           "%s roba del montón ",
+	"Stopped watching.":                             "Fin de la observación.",
+
+	// odds
+	"Odds for %s's board, %d unseen tiles:\n": "Probabilidades del tablero de %s, %d fichas sin ver:\n",
+	"none": "ninguno",
+	"  %-3s %-7s %3d unseen (%4.1f%%), %d on the table\n": "  %-3s %-7s %3d sin ver (%4.1f%%), %d en la mesa\n",
+
+	// tile tracker
+	"Tile tracker, seen and unseen copies of each value:": "Recuento de fichas, copias vistas y sin ver de cada valor:",
+	"tile":                             "ficha",
+	"seen":                             "vistas",
+	"unseen":                           "sin ver",
+	"Wildcards: %d seen, %d unseen.\n": "Comodines: %d vistos, %d sin ver.\n",
+
+	// undo
+	"Nothing to undo.":                                "No hay nada que deshacer.",
+	"Script finished after %d lines.\n":               "Guion terminado tras %d líneas.\n",
+	"Move taken back.":                                "Jugada deshecha.",
+	"%s has moved since, so your last move stands.\n": "%s ya ha jugado después, así que tu última jugada se queda.\n",
+
+	// resigning
+	"Resign and leave the game? (y/N): ": "¿Abandonar la partida? (y/N): ",
+	"%s resigns and leaves the game.\n":  "%s abandona la partida.\n",
+	"%s resigns. GAME OVER! %s wins.":    "%s abandona. ¡FIN DE LA PARTIDA! Gana %s.",
+
+	// moves told afterwards
+	"%s discarded %s.":               "%s descartó %s.",
+	"%s stole %s from %s.":           "%s quitó %s a %s.",
+	"%s swapped %s in for %s at %s.": "%s cambió %s por %s en %s.",
+	"%s placed %s at %s.":            "%s colocó %s en %s.",
+
+	// counters
+	"Table: 1 tile":               "Mesa: 1 ficha",
+	"Table: %d tiles":             "Mesa: %d fichas",
+	"Draw pile: 1 tile":           "Montón: 1 ficha",
+	"Draw pile: %d tiles":         "Montón: %d fichas",
+	"Turn %d, moves %d, draws %d": "Turno %d, jugadas %d, robos %d",
+
+	// the end
+	"GAME OVER!":                      "¡FIN DE LA PARTIDA!",
+	"GAME OVER PG!":                   "¡FIN DE LA PARTIDA!",
+	"GAME OVER! %s wins.":             "¡FIN DE LA PARTIDA! Gana %s.",
+	"GAME OVER! It's a tie.":          "¡FIN DE LA PARTIDA! Es un empate.",
+	"Draw pile is empty — game over.": "El montón está vacío — fin de la partida.",
+	"Draw pile is empty — ranking boards by empty cells:": "El montón está vacío — clasificación por casillas vacías:",
+	"  %d. %-18s %d empty\n":                              "  %d. %-18s %d vacías\n",
+
+	// errors
+	"expected a cell like B3 or row,col but got %q": "se esperaba una casilla como B3 o fila,columna, pero llegó %q",
+	"(%d,%d) is off the board":                      "(%d,%d) está fuera del tablero",
+	"%s is off the board":                           "%s está fuera del tablero",
+	"%q is not a number":                            "%q no es un número",
+	"tile %d out of range 1-%d":                     "la ficha %d está fuera del rango 1-%d",
+
+	// screen reader
+	"empty":                            "vacía",
+	"wildcard":                         "comodín",
+	"blocked":                          "bloqueada",
+	"Table: empty.":                    "Mesa: vacía.",
+	"Table: %s.\n":                     "Mesa: %s.\n",
+	"Pile: %d tiles.\n":                "Montón: %d fichas.\n",
+	", to play":                        ", le toca",
+	"%s's board%s, %d empty cells:\n":  "Tablero de %s%s, %d casillas vacías:\n",
+	"%s, row %d: %s.\n":                "%s, fila %d: %s.\n",
+	"Last move: %s\n":                  "Última jugada: %s\n",
+	"%s fits nowhere on %s's board.\n": "%s no cabe en el tablero de %s.\n",
+	"%s can go on %s's board at %s.\n": "%s puede ir en el tablero de %s en %s.\n",
+
+	// replay
+	"Replaying the game started %s.\n":               "Repetición de la partida empezada el %s.\n",
+	"%s takes a move back.\n":                        "%s deshace una jugada.\n",
+	"%s draws %s from the pile.\n":                   "%s roba %s del montón.\n",
+	"%s takes %s from the table.\n":                  "%s toma %s de la mesa.\n",
+	"%s plays %s from the hand.\n":                   "%s juega %s de la mano.\n",
+	"%s resigns.\n":                                  "%s abandona.\n",
+	"%s places %s at %s%s.\n":                        "%s coloca %s en %s%s.\n",
+	"%s swaps %s into %s%s; %s goes to the table.\n": "%s cambia %s en %s%s; %s va a la mesa.\n",
+	"%s discards %s.\n":                              "%s descarta %s.\n",
+	"%s steals %s from %s on %s's board.\n":          "%s quita %s de %s en el tablero de %s.\n",
+
+	// autosave
+	"Resume the game autosaved %s, on turn %d? (Y/n): ": "¿Seguir la partida guardada automáticamente el %s, en el turno %d? (Y/n): ",
+
+	// save slots
+	"No saved games.":     "No hay partidas guardadas.",
+	"%s to move":          "le toca a %s",
+	"turn %d, %s to move": "turno %d, le toca a %s",
+	"there is no save %s; the saves are numbered 1-%d": "no hay ninguna partida %s; las partidas van del 1 al %d",
+	"Give the numbers of the saves to delete.":         "Indica los números de las partidas que borrar.",
+	"Delete %s? (y/N): ":                               "¿Borrar %s? (y/N): ",
+	"Failed to delete:":                                "No se pudo borrar:",
+	"Deleted %s.\n":                                    "%s borrada.\n",
+	"Saved games:":                                     "Partidas guardadas:",
+	"Load a saved game? (number, file name, l to list, delete N, blank for new game): ": "¿Cargar una partida guardada? (número, nombre de archivo, l para listar, delete N, vacío para una nueva): ",
+
+	// export
+	"File for the picture (.svg or .png): ": "Archivo para el dibujo (.svg o .png): ",
+	"Failed to export:":                     "No se pudo exportar:",
+	"Picture written to %s.\n":              "Dibujo guardado en %s.\n",
+
+	// match saves
+	"A CSV save keeps only this round; save as .json to keep the match.": "Un guardado CSV solo conserva esta ronda; guarda como .json para conservar el partido.",
+
+	// player profiles
+	"Welcome back, %s: %d played, %d won, %.1f empty cells a game.\n": "Hola de nuevo, %s: %d jugadas, %d ganadas, %.1f casillas vacías por partida.\n",
+	"Ratings: %s\n": "Puntuaciones Elo: %s\n",
+
+	// crash resume
+	"The last game did not finish: the program stopped in the middle of it.": "La última partida no terminó: el programa se detuvo a mitad.",
+	"Resume the game autosaved %s, on turn %d with %s drawn? (Y/n): ":        "¿Seguir la partida guardada automáticamente el %s, en el turno %d con el %s robado? (Y/n): ",
+
+	// annotations
+	"Write the note after the word note.":                             "Escribe la nota después de la palabra note.",
+	"Note kept on turn %d.\n":                                         "Nota guardada en el turno %d.\n",
+	"Note on turn %d (%s): %s\n":                                      "Nota del turno %d (%s): %s\n",
+	"Candidates for %s on turn %d (%s): %s\n":                         "Candidatos para el %s en el turno %d (%s): %s\n",
+	"No notes yet.":                                                   "Aún no hay notas.",
+	"Draw a tile first; eval keeps the best moves for it.":            "Roba una ficha antes; eval guarda las mejores jugadas para ella.",
+	"A CSV save does not keep the notes; save as .json to keep them.": "Un guardado CSV no conserva las notas; guarda como .json para conservarlas.",
+
+	// quitting with a tile drawn
+	"The drawn %s is not kept without autosaves; save with s before quitting to come back to it.\n": "La ficha robada %s no se guarda sin autoguardado; guarda con s antes de salir para volver a ella.\n",
+	"The drawn %s is kept: the next start offers the game back with it to place.\n":                 "La ficha robada %s se conserva: al volver a empezar se ofrece la partida para colocarla.\n",
+}
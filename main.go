@@ -2,7 +2,10 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
 	"math"
 	"math/rand"
@@ -11,9 +14,18 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-const BoardSize = 4
+// BoardSize is the side of every board in the game. It is standardBoardSize
+// unless a preset chose another, up to maxBoardSize.
+var BoardSize = standardBoardSize
+
+const (
+	standardBoardSize = 4
+	minBoardSize      = 3
+	maxBoardSize      = 5
+)
 
 type Cell struct{ R, C int }
 
@@ -24,98 +36,187 @@ const (
 	Swap
 	Discard
 	Draw
+	FromHand // the tile was played from the hand, like Draw for the pile
+	Steal    // a tile taken off an opponent's board to the table
 )
 
 type Move struct {
-	Type    MoveType
-	Cell    *Cell
-	Tile    int
-	OldTile int     // only set if Type==Swap
-	Score   float64 // for ranking which moves are "best"
+	Type      MoveType
+	Cell      *Cell
+	Tile      int
+	OldTile   int     // only set if Type==Swap
+	Score     float64 // for ranking which moves are "best"
+	Partner   bool    // team play: made on the teammate's board
+	FromTable bool    // the tile was taken from the table, not the pile
+	Target    int     // only set if Type==Steal: the seat robbed
 }
 
 type Board struct {
-	Grid [BoardSize][BoardSize]int
-	IsAi bool // the enemy!
+	Grid       [maxBoardSize][maxBoardSize]int // rows and columns from BoardSize on stay empty
+	Name       string                          // what the seat is called; empty for "Player 0" and the like
+	IsAi       bool                            // the enemy!
+	Controlled bool                            // a human seat driven by the tester via -control
+	Strategy   string                          // computer strategy for this seat; empty means the default
+	Handicap   Handicap
+	Hand       []int // tiles held in the hand-of-tiles variant
+	TableTakes int   // tiles this seat has taken from the table
+	StealUsed  bool  // the steal-swap variant's one steal is spent
+	Resigned   bool  // the player left the game; the seat's turns are skipped
 }
 
 type GameState struct {
-	Boards       []*Board
-	Table        []int
-	Draw         []int
-	Analyze      bool // analysis mode aka we tell it what numbers we draw.
-	BrunoVariant bool
-	Current      int
+	Boards        []*Board
+	Table         []int
+	Draw          []int
+	Analyze       bool // analysis mode aka we tell it what numbers we draw.
+	BrunoVariant  bool
+	Bruno         BrunoRules // how the Bruno variant is played
+	ExtraTurns    int        // Bruno extra turns chained so far this turn
+	Current       int
+	ABTest        *ABTest     // debug: alternate two strategies on one seat
+	MoveLog       *MoveLog    // every draw and move is logged here when set
+	EvalExport    *EvalExport // every move's evaluation is written here when set
+	Heuristics    Heuristics
+	Seed          int64
+	Backfills     []Backfill // seats handed to a computer mid-game
+	History       []Played
+	ArchivePath   string       // finished games are appended here when set
+	Wildcards     int          // wildcard tiles shuffled into the draw pile
+	TileRange     int          // highest tile value, MaxTile when 0
+	NonDecreasing bool         // equal neighbours allowed in a row or column
+	End           EndMode      // how the game ends if the pile runs out
+	Teams         bool         // 2v2: seats 0 and 2 against 1 and 3
+	Puzzle        *Puzzle      // solo puzzle with a draw limit
+	Match         *Match       // multi-round match this game is a round of
+	ForcedTable   bool         // a tile taken from the table must be placed
+	OpenPile      bool         // the top tile of the draw pile is face up
+	HandSize      int          // tiles each player holds; 0 plays without a hand
+	Distribution  Distribution // exact tiles in the pile; nil for one set per player
+	DiagonalRule  bool         // the main diagonal must strictly increase too
+	ProfilePath   string       // achievements are recorded here when set
+	AutosaveDir   string       // every turn is saved here when set
+	Clock         *Clock       // chess clock for the human seats
+	StealSwap     bool         // once per game a player may steal a tile
+	Holes         []Cell       // blocked cells, the same on every board
+	Holding       int          // tile a human is placing, whose legal cells are marked; 0 for none
+	Mulligan      bool         // each player may redraw their diagonal once
+	SharedPool    bool         // one set of tiles for everyone, not one each
+	Undo          *Undo        // positions the humans can take back to
+	Pending       *Move        // tile drawn this turn and not yet played, kept in saves
+	Stopped       bool         // the player quit with a tile drawn, kept to resume with
+	Over          bool         // the game is decided and gameOver has settled it
+	Winner        int          // who won it once Over, -1 for nobody
+	Annotations   []Annotation // notes and candidate moves kept while studying the game
+	BlunderMargin float64      // humans confirm placements this far below the best; 0 never asks
+	Turn          int          // turns finished; the display counts from the one under way
+	Draws         int          // tiles taken from the pile, the table or a hand so far
+}
+
+// Played is a move in the game's history.
+type Played struct {
+	Seat int
+	Move Move
 }
 
-var reader = bufio.NewReader(os.Stdin)
-var threshold = .50
+var reader lineReader = bufio.NewReader(os.Stdin)
+
+// rng drives every shuffle and random choice, so seeding it replays a game.
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func seedRNG(seed int64) {
+	rng = rand.New(rand.NewSource(seed))
+}
 
 func (state *GameState) isPlacementFeasible(tile, r, c int) bool {
-	remaining := append(state.Draw, state.Table...)
-	remainingHigher := []int{}
-	remainingLower := []int{}
-	for r := 0; r < len(remaining); r++ {
-		if tile < remaining[r] {
-			remainingHigher = append(remainingHigher, remaining[r])
-		}
-		if tile > remaining[r] {
-			remainingLower = append(remainingLower, remaining[r])
-		}
+	if !onBoard(r, c) || state.Boards[state.Current].Grid[r][c] == Blocked {
+		return false
 	}
+	if tile == Wildcard {
+		return true
+	}
+	remaining := append(state.Draw, state.Table...)
+	// neighbours must differ by at least step; a gap of empty cells needs
+	// a climbing run of remaining tiles strictly between lo and hi
+	step := state.step()
+	between := func(lo, hi, cells int) bool { return canFillGap(remaining, lo, hi, cells, step) }
 	board := state.Boards[state.Current]
-	// Check above
+	// Check above; gap counts the empty cells separating the tiles, as
+	// wildcards and holes need no value between them
+	gap := 0
 	for rr := r - 1; rr >= 0; rr-- {
 		v := board.Grid[rr][c]
-		if v == 0 {
+		if !isTile(v) {
+			if v == 0 {
+				gap++
+			}
 			continue
 		}
-		if v >= tile || (rr < r-1 && !inRange(remainingLower, v, tile)) {
+		if v > tile-step || (gap > 0 && !between(v, tile, gap)) {
 			return false
 		}
 		break
 	}
 
 	// Check left
+	gap = 0
 	for cc := c - 1; cc >= 0; cc-- {
 		v := board.Grid[r][cc]
-		if v == 0 {
+		if !isTile(v) {
+			if v == 0 {
+				gap++
+			}
 			continue
 		}
-		if v >= tile || (cc < c-1 && !inRange(remainingLower, v, tile)) {
+		if v > tile-step || (gap > 0 && !between(v, tile, gap)) {
 			return false
 		}
 		break
 	}
 
 	// Check downward feasibility
-
+	gap = 0
 	for rr := r + 1; rr < BoardSize; rr++ {
 		v := board.Grid[rr][c]
-		if v != 0 {
-			if v <= tile || (rr > r+1 && !inRange(remainingHigher, tile, v)) {
+		if isTile(v) {
+			if v < tile+step || (gap > 0 && !between(tile, v, gap)) {
 				return false
 			}
 			break
 		}
-
+		if v == 0 {
+			gap++
+		}
 	}
 
 	// Check rightward feasibility
+	gap = 0
 	for cc := c + 1; cc < BoardSize; cc++ {
 		v := board.Grid[r][cc]
-		if v != 0 {
-			if v <= tile || (cc > c+1 && !inRange(remainingHigher, tile, v)) {
+		if isTile(v) {
+			if v < tile+step || (gap > 0 && !between(tile, v, gap)) {
 				return false
 			}
 			break
 		}
+		if v == 0 {
+			gap++
+		}
 	}
 
+	if state.DiagonalRule && r == c && !state.diagonalFeasible(tile, r, remaining) {
+		return false
+	}
 	return true
 }
 
+// bestMoves ranks every move for tile, best first, including those on the
+// partner's board in team play.
 func (state *GameState) bestMoves(tile int) []Move {
+	return state.teamMoves(state.boardMoves(tile), tile)
+}
+
+// boardMoves ranks the moves for tile on the current seat's own board.
+func (state *GameState) boardMoves(tile int) []Move {
 	moves := []Move{}
 	board := state.Boards[state.Current]
 	for r := 0; r < BoardSize; r++ {
@@ -137,6 +238,9 @@ func (state *GameState) bestMoves(tile int) []Move {
 			if current != 0 && feasible {
 				newScore := state.placementScore(tile, r, c)
 				oldScore := state.placementScore(current, r, c)
+				if state.BrunoVariant && state.giftsBrunoMatch(current) {
+					newScore *= brunoGiftPenalty
+				}
 
 				// Only swap if significant improvement and feasible future
 				if newScore > oldScore*1.10 { // at least 10% improvement
@@ -161,32 +265,49 @@ func (state *GameState) bestMoves(tile int) []Move {
 	return moves
 }
 
-// score for how well tile t fits cell (r,c)
-func baseScore(tile, r, c int) float64 {
+// score for how well tile t fits cell (r,c) when tiles run 1-maxTile
+func baseScore(tile, r, c, maxTile int) float64 {
 	alpha := 1.00 // this is a score tolerance
 	dCell := float64(2 + r + c)
-	diff := xOfT(tile) - dCell
+	diff := xOfT(tile, maxTile) - dCell
 	return 100 * math.Exp(-alpha*diff*diff)
 }
-func xOfT(t int) float64 {
-	return 2.0 + 0.32*float64(t-1)
+
+// xOfT maps tile t onto the cell index 2+r+c it fits best. The slope was
+// tuned for tiles 1-20 on a 4x4 board and stretches with the range and size.
+func xOfT(t, maxTile int) float64 {
+	stretch := float64(MaxTile-1) / float64(maxTile-1) * float64(BoardSize-1) / float64(standardBoardSize-1)
+	return 2.0 + 0.32*float64(t-1)*stretch
 }
 
 func (state *GameState) placementScore(tile, r, c int) float64 {
-	base := baseScore(tile, r, c)
+	if tile == Wildcard {
+		return state.wildcardScore(r, c)
+	}
+	base := baseScore(tile, r, c, state.maxTile())
 	rowProb := state.futureRowProbability(r, c)
 	colProb := state.futureColProbability(r, c)
-	return base * rowProb * colProb
+	score := base * rowProb * colProb
+	if state.Heuristics.RiskAware {
+		score *= state.riskFactor(tile, r, c)
+	}
+	if state.BrunoVariant {
+		score *= state.brunoFactor(tile, r, c)
+	}
+	if state.DiagonalRule && r == c {
+		score *= state.diagonalFactor(tile, r)
+	}
+	return score
 }
 
 func (state *GameState) printMap(tile int) {
-	fmt.Printf("tile %d ", tile)
+	fmt.Printf("tile %s ", tileLabel(tile))
 	fmt.Println("Base score")
 	for r := 0; r < BoardSize; r++ {
 
 		for c := 0; c < BoardSize; c++ {
 			fmt.Print("| ")
-			score := baseScore(tile, r, c)
+			score := baseScore(tile, r, c, state.maxTile())
 			fmt.Printf("%5.2f", score)
 			fmt.Print("| ")
 		}
@@ -205,6 +326,7 @@ func (state *GameState) printMap(tile int) {
 		fmt.Println()
 		fmt.Println("____________________________________")
 	}
+	state.printHeatmap(os.Stdout, tile)
 }
 
 // Compute probability row can be filled with unplayed tiles
@@ -254,7 +376,7 @@ func (state *GameState) remainingProbability(min, max int) float64 {
 	total := 0
 	count := 0
 	for _, t := range append(state.Draw, state.Table...) {
-		if t >= min && t <= max {
+		if t == Wildcard || (t >= min && t <= max) {
 			count++
 		}
 		total++
@@ -267,19 +389,19 @@ func (state *GameState) remainingProbability(min, max int) float64 {
 
 // rowConstraints returns the min/max value that a cell in the row can hold
 func (state *GameState) rowConstraints(r, c int) (int, int) {
-	min, max := 1, BoardSize*5
+	min, max := 1, state.maxTile()
 	board := state.Boards[state.Current]
 	// look left
 	for cc := c - 1; cc >= 0; cc-- {
-		if board.Grid[r][cc] != 0 {
-			min = board.Grid[r][cc] + 1
+		if isTile(board.Grid[r][cc]) {
+			min = board.Grid[r][cc] + state.step()
 			break
 		}
 	}
 	// look right
 	for cc := c + 1; cc < BoardSize; cc++ {
-		if board.Grid[r][cc] != 0 {
-			max = board.Grid[r][cc] - 1
+		if isTile(board.Grid[r][cc]) {
+			max = board.Grid[r][cc] - state.step()
 			break
 		}
 	}
@@ -289,67 +411,106 @@ func (state *GameState) rowConstraints(r, c int) (int, int) {
 // colConstraints returns the min/max value that a cell in the column can hold
 func (state *GameState) colConstraints(r, c int) (int, int) {
 	board := state.Boards[state.Current]
-	min, max := 1, BoardSize*5
+	min, max := 1, state.maxTile()
 	// look above
 	for rr := r - 1; rr >= 0; rr-- {
-		if board.Grid[rr][c] != 0 {
-			min = board.Grid[rr][c] + 1
+		if isTile(board.Grid[rr][c]) {
+			min = board.Grid[rr][c] + state.step()
 			break
 		}
 	}
 	// look below
 	for rr := r + 1; rr < BoardSize; rr++ {
-		if board.Grid[rr][c] != 0 {
-			max = board.Grid[rr][c] - 1
+		if isTile(board.Grid[rr][c]) {
+			max = board.Grid[rr][c] - state.step()
 			break
 		}
 	}
 	return min, max
 }
 
+// commitMove changes the board and table for a move by the current seat,
+// without any output.
+func (state *GameState) commitMove(move Move) {
+	board := state.Boards[state.moveSeat(state.Current, move)]
+	switch move.Type {
+	case Place:
+		board.Grid[move.Cell.R][move.Cell.C] = move.Tile
+	case Swap:
+		old := board.Grid[move.Cell.R][move.Cell.C]
+		board.Grid[move.Cell.R][move.Cell.C] = move.Tile
+		state.Table = append(state.Table, old)
+	case Discard:
+		state.Table = append(state.Table, move.Tile)
+	case Steal:
+		board.Grid[move.Cell.R][move.Cell.C] = 0
+		state.Table = append(state.Table, move.Tile)
+		state.Boards[state.Current].StealUsed = true
+	}
+}
+
 func (state *GameState) applyMove(move Move) bool {
 	current := state.Current
 	board := state.Boards[current]
-	tile := move.Tile
+	if (move.Type == Place || move.Type == Swap) && (move.Cell == nil || !onBoard(move.Cell.R, move.Cell.C)) {
+		fmt.Println("Ignoring move without a cell on the board.")
+		return false
+	}
+	if move.Type == Steal {
+		state.applySteal(move)
+		return false
+	}
 	if board.IsAi {
 		prettyType := "nothing?"
 		switch move.Type {
 		case Place:
-			prettyType = "placing"
+			prettyType = tr("placing")
 		case Swap:
-			prettyType = "swapping"
+			prettyType = tr("swapping")
 
 		case Discard:
-			prettyType = "discarding"
+			prettyType = tr("discarding")
 		}
 		if move.Type == Discard {
-			fmt.Printf("Computer %d is %v tile %d\n", current, prettyType, move.Tile)
+			narrate("%s is %v tile %s\n", state.seatLabel(current), prettyType, spokenTile(move.Tile))
+		} else if move.Partner {
+			narrate("%s is %v tile %s, %s on %s's board\n", state.seatLabel(current), prettyType, spokenTile(move.Tile), move.Cell, state.seatLabel(state.partner(current)))
 		} else {
-			fmt.Printf("Computer %d is %v tile %d, (%d,%d)\n", current, prettyType, move.Tile, move.Cell.R, move.Cell.C)
+			narrate("%s is %v tile %s, %s\n", state.seatLabel(current), prettyType, spokenTile(move.Tile), move.Cell)
 		}
 	}
-	switch move.Type {
-	case Place:
-		board.Grid[move.Cell.R][move.Cell.C] = tile
-	case Swap:
-		old := board.Grid[move.Cell.R][move.Cell.C]
-		board.Grid[move.Cell.R][move.Cell.C] = tile
-		fmt.Printf("%v to the table\n", old)
-		state.Table = append(state.Table, old)
-	case Discard:
-		state.Table = append(state.Table, tile)
+	target := state.moveSeat(current, move)
+	board = state.Boards[target]
+	old := 0
+	if move.Type == Swap {
+		old = board.Grid[move.Cell.R][move.Cell.C]
+		fmt.Printf(tr("%s to the table\n"), tileLabel(old))
+	}
+	exported := state.evalMove(move)
+	state.commitMove(move)
+	state.History = append(state.History, Played{Seat: current, Move: move})
+	state.logMove(move, old)
+	exported()
+	if move.Type == Discard {
 		return false
 	}
 	if board.IsFull() {
 		state.PrettyPrintBoardsGridCentered()
-		fmt.Println("GAME OVER!")
-		os.Exit(0)
+		if state.Teams {
+			state.gameOver(target, fmt.Sprintf(tr("GAME OVER! %s wins."), state.winnerLabel(target)))
+		} else {
+			state.gameOver(current, tr("GAME OVER!"))
+		}
+		return false
 	}
-	return state.BrunoVariant && board.checkBrunoExtra(move.Cell.R, move.Cell.C)
+	return state.BrunoVariant && state.checkBrunoExtra(board, move.Cell.R, move.Cell.C)
 }
 
 func (state *GameState) PrettyPrintBoardsGridCentered() {
-	cellWidth := 5
+	if screenReader {
+		state.describeBoards(os.Stdout)
+		return
+	}
 	repeat := func(s string, n int) string {
 		res := ""
 		for i := 0; i < n; i++ {
@@ -358,13 +519,25 @@ func (state *GameState) PrettyPrintBoardsGridCentered() {
 		return res
 	}
 
-	// --- Print Table header ---
-	boardWidth := BoardSize*(cellWidth+1) + 1
-	totalWidth := boardWidth*len(state.Boards) + (len(state.Boards)-1)*2 // spaces between boards
+	g := term.box()
 
-	tableHeader := " TABLE "
-	dashesEachSide := (totalWidth - len(tableHeader)) / 2
-	fmt.Println("+" + repeat("-", dashesEachSide) + tableHeader + repeat("-", totalWidth-len(tableHeader)-dashesEachSide) + "+")
+	// --- Print Table header ---
+	_, boardWidth := boardGeometry()
+	perRow := boardsPerRow(boardWidth, len(state.Boards))
+	totalWidth := boardWidth*perRow + (perRow-1)*2 // spaces between boards
+
+	counters := state.counters()
+	fmt.Println(repeat(" ", max((totalWidth+2-len(counters))/2, 0)) + counters)
+
+	// the borders tell how many tiles are on the table and in the pile
+	tableCount, pileCount := state.tileCounts()
+	border := func(left, label, right string) string {
+		label = " " + label + " "
+		n := utf8.RuneCountInString(label)
+		dashesEachSide := (totalWidth - n) / 2
+		return left + repeat(g.H, dashesEachSide) + label + repeat(g.H, totalWidth-n-dashesEachSide) + right
+	}
+	fmt.Println(border(g.TopLeft, tableCount, g.TopRight))
 
 	// --- Print Table contents ---
 	tableTiles := append([]int{}, state.Table...)
@@ -374,86 +547,251 @@ func (state *GameState) PrettyPrintBoardsGridCentered() {
 		if i > 0 {
 			content += ","
 		}
-		content += fmt.Sprintf("%d", t)
+		content += tileLabel(t)
 	}
 	if content == "" {
 		content = "(empty)"
 	}
 	padding := (totalWidth - len(content)) / 2
-	fmt.Println("|" + repeat(" ", padding) + content + repeat(" ", totalWidth-len(content)-padding) + "|")
-	fmt.Println("+" + repeat("-", totalWidth) + "+")
+	fmt.Println(g.V + repeat(" ", padding) + content + repeat(" ", totalWidth-len(content)-padding) + g.V)
+	if state.OpenPile {
+		pile := state.pileTopLabel()
+		padding = (totalWidth - len(pile)) / 2
+		fmt.Println(g.V + repeat(" ", padding) + pile + repeat(" ", totalWidth-len(pile)-padding) + g.V)
+	}
+	fmt.Println(border(g.BotLeft, pileCount, g.BotRight))
 
 	// --- Print Boards ---
-	for i := range state.Boards {
-		header := fmt.Sprintf("Player %d", i)
-		if state.Boards[i].IsAi {
-			header = fmt.Sprintf("Computer %d", i)
+	// as many side by side as the terminal fits, the rest below
+	last := state.lastPlaced()
+	legal := state.legalCells()
+	for first := 0; first < len(state.Boards); first += perRow {
+		state.printBoardRow(seatRange(first, min(first+perRow, len(state.Boards))), last, legal)
+	}
+}
+
+// counters heads the display with the turn being played, the moves made
+// and the tiles drawn, for turn limits and for pointing at a position.
+func (state *GameState) counters() string {
+	return fmt.Sprintf(tr("Turn %d, moves %d, draws %d"), state.Turn+1, len(state.History), state.Draws)
+}
+
+// tileCounts labels the table box: the tiles on the table and those left
+// in the draw pile.
+func (state *GameState) tileCounts() (table, pile string) {
+	count := func(one, many string, n int) string {
+		if n == 1 {
+			return tr(one)
 		}
+		return fmt.Sprintf(tr(many), n)
+	}
+	return count("Table: 1 tile", "Table: %d tiles", len(state.Table)),
+		count("Draw pile: 1 tile", "Draw pile: %d tiles", len(state.Draw))
+}
+
+// cellWidth is the inside width of a board cell.
+const cellWidth = 5
+
+// boardGeometry is the width of the row-number gutter left of a board and
+// of the whole board with it.
+func boardGeometry() (gutter, width int) {
+	gutter = len(strconv.Itoa(BoardSize)) + 1
+	return gutter, gutter + BoardSize*(cellWidth+1) + 1
+}
+
+// printBoardRow draws the boards of seats side by side.
+func (state *GameState) printBoardRow(seats []int, last map[int]Cell, legal map[int]map[Cell]bool) {
+	repeat := strings.Repeat
+	g := term.box()
+	gutter, boardWidth := boardGeometry()
+	lastSeat := seats[len(seats)-1]
+	for _, i := range seats {
+		header := state.seatLabel(i)
 		padding := (boardWidth - len(header)) / 2
-		fmt.Printf("%s%s%s", repeat(" ", padding), header, repeat(" ", boardWidth-len(header)-padding))
-		if i < len(state.Boards)-1 {
+		if i == state.Current {
+			header = term.paint(styleBold, header)
+		}
+		fmt.Printf("%s%s%s", repeat(" ", padding), header, repeat(" ", boardWidth-len(state.seatLabel(i))-padding))
+		if i != lastSeat {
 			fmt.Print("  ")
 		}
 	}
 	fmt.Println()
 
-	hLine := func(width int) string {
-		line := "+"
-		for i := 0; i < width; i++ {
-			line += repeat("-", cellWidth) + "+"
+	// column letters over the middle of each column
+	letters := repeat(" ", gutter+1)
+	for c := 0; c < BoardSize; c++ {
+		letters += repeat(" ", cellWidth/2) + columnLetter(c) + repeat(" ", cellWidth-cellWidth/2)
+	}
+	for _, i := range seats {
+		fmt.Print(letters)
+		if i != lastSeat {
+			fmt.Print("  ")
 		}
-		return line
 	}
+	fmt.Println()
 
-	for r := 0; r < BoardSize; r++ {
-		for i := range state.Boards {
-			fmt.Print(hLine(BoardSize))
-			if i < len(state.Boards)-1 {
+	hLine := func(left, mid, right string) string {
+		line := left
+		for i := 0; i < BoardSize; i++ {
+			if i > 0 {
+				line += mid
+			}
+			line += repeat(g.H, cellWidth)
+		}
+		return line + right
+	}
+	// the current seat's board is drawn in colour, the rest plain
+	frame := func(i int, s string) string {
+		if i == state.Current {
+			return term.paint(styleCyan, s)
+		}
+		return s
+	}
+	printLines := func(line string) {
+		for _, i := range seats {
+			fmt.Print(repeat(" ", gutter) + frame(i, line))
+			if i != lastSeat {
 				fmt.Print("  ")
 			}
 		}
 		fmt.Println()
+	}
 
-		for i, b := range state.Boards {
-			fmt.Print("|")
+	for r := 0; r < BoardSize; r++ {
+		if r == 0 {
+			printLines(hLine(g.TopLeft, g.TeeDown, g.TopRight))
+		} else {
+			printLines(hLine(g.TeeRight, g.Cross, g.TeeLeft))
+		}
+
+		for _, i := range seats {
+			b := state.Boards[i]
+			fmt.Printf("%-*d%s", gutter, r+1, frame(i, g.V))
 			for c := 0; c < BoardSize; c++ {
 				v := b.Grid[r][c]
-				content := "."
-				if v != 0 {
-					content = fmt.Sprintf("%d", v)
+				content := tileLabel(v)
+				var styles []string
+				switch {
+				case v == Wildcard:
+					styles = append(styles, styleGreen)
+				case isTile(v):
+					styles = append(styles, tileStyle(v, state.maxTile()))
+				}
+				if legal[i][Cell{R: r, C: c}] {
+					// where the held tile may go: empty cells show a +,
+					// and without colour swaps are put in parentheses
+					if v == 0 {
+						content = legalLabel
+					} else if !term.Color {
+						content = "(" + content + ")"
+					}
+					styles = append(styles, styleGreenBackground)
 				}
 				spaces := cellWidth - len(content)
 				left := spaces / 2
 				right := spaces - left
-				fmt.Print(repeat(" ", left) + content + repeat(" ", right) + "|")
+				if screen != nil {
+					if s := screen.cellStyle(i, r, c); s != "" {
+						styles = append(styles, s)
+					}
+					if !term.Color && screen.cursorAt(i, r, c) {
+						// plain terminals bracket the cursor instead
+						content, left, right = "["+content+strings.Repeat(" ", 3-len(content))+"]", 0, 0
+					}
+				}
+				if cell, ok := last[i]; ok && cell == (Cell{R: r, C: c}) {
+					if term.Color {
+						// the whole cell, padding too, stands out
+						styles = append(styles, styleReverse)
+						content = repeat(" ", left) + content + repeat(" ", right)
+						left, right = 0, 0
+					} else if len(content)+2 <= cellWidth {
+						content = "<" + content + ">"
+						left = (cellWidth - len(content)) / 2
+						right = cellWidth - len(content) - left
+					}
+				}
+				if len(styles) > 0 {
+					content = term.paint(strings.Join(styles, ";"), content)
+				}
+				fmt.Print(repeat(" ", left) + content + repeat(" ", right) + frame(i, g.V))
+			}
+			if i != lastSeat {
+				fmt.Print("  ")
 			}
-			if i < len(state.Boards)-1 {
+		}
+		fmt.Println()
+	}
+	printLines(hLine(g.BotLeft, g.TeeUp, g.BotRight))
+	if state.HandSize > 0 {
+		for _, i := range seats {
+			footer := state.handFooter(i)
+			padding := (boardWidth - len(footer)) / 2
+			fmt.Print(repeat(" ", padding) + footer + repeat(" ", boardWidth-len(footer)-padding))
+			if i != lastSeat {
 				fmt.Print("  ")
 			}
 		}
 		fmt.Println()
 	}
+}
 
-	for i := range state.Boards {
-		fmt.Print(hLine(BoardSize))
-		if i < len(state.Boards)-1 {
-			fmt.Print("  ")
+// legalLabel marks an empty cell the held tile can go in.
+const legalLabel = "+"
+
+// legalCells maps the boards the held tile may be placed on, the current
+// one and in team play the partner's, to the cells it can legally take.
+func (state *GameState) legalCells() map[int]map[Cell]bool {
+	if state.Holding == 0 || state.Boards[state.Current].IsAi {
+		return nil
+	}
+	seats := []int{state.Current}
+	if state.Teams {
+		seats = append(seats, state.partner(state.Current))
+	}
+	legal := map[int]map[Cell]bool{}
+	for _, seat := range seats {
+		restore := state.asSeat(seat)
+		legal[seat] = map[Cell]bool{}
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if state.isPlacementFeasible(state.Holding, r, c) {
+					legal[seat][Cell{R: r, C: c}] = true
+				}
+			}
 		}
+		restore()
 	}
-	fmt.Println()
+	return legal
 }
 
-func contains(slice []int, val int) bool {
-	for _, v := range slice {
-		if v == val {
-			return true
+// lastPlaced maps every board to the cell its most recent tile went into,
+// so a player back at the keyboard sees what changed on each. Boards whose
+// last tile was stolen, or that have had none yet, are left out.
+func (state *GameState) lastPlaced() map[int]Cell {
+	last := map[int]Cell{}
+	seen := map[int]bool{}
+	for i := len(state.History) - 1; i >= 0; i-- {
+		p := state.History[i]
+		if p.Move.Cell == nil {
+			continue
+		}
+		seat := state.moveSeat(p.Seat, p.Move)
+		if seen[seat] {
+			continue
+		}
+		seen[seat] = true
+		if p.Move.Type != Steal {
+			last[seat] = *p.Move.Cell
 		}
 	}
-	return false
+	return last
 }
-func inRange(slice []int, lower, higher int) bool {
+
+func contains(slice []int, val int) bool {
 	for _, v := range slice {
-		if v > lower && v < higher {
+		if v == val {
 			return true
 		}
 	}
@@ -461,64 +799,105 @@ func inRange(slice []int, lower, higher int) bool {
 }
 
 func (state *GameState) initDrawStack(totalPlayers int) {
-	for b := 1; b <= totalPlayers; b++ {
-		for i := 1; i <= 20; i++ {
+	copies := state.tileCopies(totalPlayers)
+	for i := 1; i <= state.maxTile(); i++ {
+		for n := 0; n < copies[i]; n++ {
 			state.Draw = append(state.Draw, i)
 		}
 	}
-	rand.Shuffle(len(state.Draw), func(i, j int) {
+	for i := 0; i < state.Wildcards; i++ {
+		state.Draw = append(state.Draw, Wildcard)
+	}
+	rng.Shuffle(len(state.Draw), func(i, j int) {
 		state.Draw[i], state.Draw[j] = state.Draw[j], state.Draw[i]
 	})
 }
 
-func (state *GameState) fillRandomDiagonal(board *Board) {
+func (state *GameState) fillRandomDiagonal(board *Board) error {
+	var withheld map[int]bool
+	if n := board.Handicap.Withheld; n > 0 {
+		withheld = withheldCells(n)
+	}
+	if state.DiagonalRule {
+		tiles, err := state.dealIncreasing(BoardSize - len(withheld))
+		if err != nil {
+			return fmt.Errorf("filling diagonal: %w", err)
+		}
+		for i := 0; i < BoardSize; i++ {
+			if !withheld[i] {
+				board.Grid[i][i], tiles = tiles[0], tiles[1:]
+			}
+		}
+		return nil
+	}
 	for i := 0; i < BoardSize; i++ {
-		tile := state.Draw[0]
-		state.Draw = state.Draw[1:]
+		if withheld[i] {
+			continue
+		}
+		tile, err := state.popDraw()
+		if err != nil {
+			return fmt.Errorf("filling diagonal: %w", err)
+		}
 		board.Grid[i][i] = tile
 	}
+	return nil
 }
 
-func (state *GameState) setUpBoards() {
+func (state *GameState) setUpBoards() error {
 	// --- Ask number of human and Computer players ---
-	numHumans := 1
-	fmt.Print("Number of human players (1-4, default 1): ")
-	line, _ := reader.ReadString('\n')
-	line = strings.TrimSpace(line)
-	if line != "" {
-		n, err := strconv.Atoi(line)
-		if err == nil && n >= 1 && n <= 4 {
-			numHumans = n
-		} else {
-			numHumans = 1
-		}
-	} else {
-		numHumans = 0
+	numHumans, numAI, err := promptPlayers()
+	if err != nil {
+		return err
 	}
-
-	numAI := 1
-	fmt.Print("Number of computer players (0-4, default 1): ")
-	line, _ = reader.ReadString('\n')
-	line = strings.TrimSpace(line)
-	if line != "" {
-		n, err := strconv.Atoi(line)
-		if err == nil && n >= 0 && n <= 4 {
-			numAI = n
-		} else {
-			numAI = 1
+	totalPlayers := numHumans + numAI
+	if totalPlayers == teamSeats {
+		state.Teams = setup.yesNo("teams", setup.teams, promptTeams)
+	}
+	if setup.has("preset") && !strings.EqualFold(setup.preset, "custom") {
+		p, ok := lookupPreset(setup.preset)
+		if !ok {
+			return fmt.Errorf("unknown preset %q", setup.preset)
 		}
+		state.applyPreset(p)
+	} else if setup.has("preset") {
+		state.promptCustomSetup(totalPlayers)
 	} else {
-		numAI = 2
+		state.promptPreset(totalPlayers)
 	}
-
-	totalPlayers := numHumans + numAI
-	if totalPlayers > 4 {
-		fmt.Println("Max players is 4 — adjusting to 4")
-		totalPlayers = 4
+	if state.Distribution == nil {
+		state.SharedPool = setup.yesNo("shared-pool", setup.sharedPool, func() bool { return promptSharedPool(state.maxTile()) })
+	}
+	if err := state.checkDistribution(totalPlayers); err != nil {
+		return err
 	}
 	if !state.Analyze {
 		state.initDrawStack(totalPlayers)
 	}
+	if !state.Analyze {
+		state.Mulligan = setup.yesNo("mulligan", setup.mulligan, promptMulligan)
+	}
+	var handicaps []Handicap
+	if setup.has("handicaps") {
+		var err error
+		if handicaps, err = setup.seatHandicaps(totalPlayers); err != nil {
+			return err
+		}
+	}
+	useHandicaps := handicaps == nil && promptUseHandicaps()
+	var strategies []string
+	if setup.has("strategies") || setup.given["ai"] {
+		var err error
+		if strategies, err = setup.seatStrategies(numAI); err != nil {
+			return err
+		}
+	}
+	var names []string
+	if setup.has("names") {
+		var err error
+		if names, err = setup.seatNames(min(numHumans, totalPlayers)); err != nil {
+			return err
+		}
+	}
 	// --- Set up boards ---
 	for p := 0; p < totalPlayers; p++ {
 		b := &Board{}
@@ -526,99 +905,247 @@ func (state *GameState) setUpBoards() {
 		// Assign Computer flag
 		if p >= numHumans {
 			b.IsAi = true
-			fmt.Printf("Computer %d board initialized.\n", p-numHumans+1)
+			if strategies != nil {
+				b.Strategy = strategies[p-numHumans]
+			} else {
+				b.Strategy = promptStrategy(p)
+			}
+			b.Name = state.computerName()
+			fmt.Printf(tr("Computer %d board initialized.\n"), p-numHumans+1)
 		} else {
 			b.IsAi = false
-			fmt.Printf("Player %d board initialized.\n", p+1)
+			switch {
+			case names != nil && names[p] != "":
+				b.Name = names[p]
+			case p == 0 && setup.player != "":
+				b.Name = setup.player
+			case names == nil:
+				b.Name = promptName(p)
+				greetPlayer(b.Name)
+			}
+			fmt.Printf(tr("Player %d board initialized.\n"), p+1)
+		}
+		if handicaps != nil {
+			b.Handicap = handicaps[p]
+		} else if useHandicaps {
+			b.Handicap = promptHandicap(p)
 		}
 
 		// Diagonal setup
 		if state.Analyze {
-			fmt.Printf("Enter 4 numbers for %s diagonal positions (or leave blank for random): ",
-				map[bool]string{true: "Computer", false: "Player"}[b.IsAi])
+			fmt.Printf(tr("Enter %d numbers for %s diagonal positions (or leave blank for random): "),
+				BoardSize, map[bool]string{true: "Computer", false: "Player"}[b.IsAi])
 			input, _ := reader.ReadString('\n')
 			input = strings.TrimSpace(input)
 			if input == "" {
-				state.fillRandomDiagonal(b)
+				// Analyze mode has no pile of its own, so deal from a scratch one.
+				scratch := &GameState{TileRange: state.TileRange, DiagonalRule: state.DiagonalRule}
+				scratch.initDrawStack(1)
+				if err := scratch.fillRandomDiagonal(b); err != nil {
+					return err
+				}
 			} else {
 				nums := strings.Fields(input)
 				for i := 0; i < BoardSize && i < len(nums); i++ {
-					t, err := strconv.Atoi(nums[i])
-					if err == nil {
-						b.Grid[i][i] = t
+					t, err := parseTile(nums[i], state.maxTile())
+					if err != nil {
+						fmt.Printf(tr("Skipping diagonal cell %d: %v\n"), i, err)
+						continue
 					}
+					b.Grid[i][i] = t
+				}
+				if state.DiagonalRule && !diagonalIncreases(b) {
+					fmt.Println(tr("Warning: this diagonal does not strictly increase, as the rule requires."))
 				}
 			}
-		} else {
-			state.fillRandomDiagonal(b)
+		} else if err := state.arrangeDiagonal(b); err != nil {
+			return err
 		}
+		state.blockHoles(b)
 
 		state.Boards = append(state.Boards, b)
 	}
+	return nil
+}
+
+// promptStrategy asks which strategy a computer seat plays, re-asking until
+// the answer names a registered one.
+func promptStrategy(seat int) string {
+	for {
+		fmt.Printf(tr("Strategy for computer seat %d (%s; default %s): "),
+			seat, strings.Join(strategyNames(), ", "), defaultStrategy.Name())
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return defaultStrategy.Name()
+		}
+		s, err := lookupStrategy(line)
+		if err == nil {
+			return s.Name()
+		}
+		fmt.Println(err)
+	}
+}
+
+// step is how much a tile must exceed its neighbour above or to the left:
+// 1 when rows and columns strictly increase, 0 when equal values may touch.
+func (state *GameState) step() int {
+	if state.NonDecreasing {
+		return 0
+	}
+	return 1
+}
+
+// tablePickAllowed reports whether a move may be made with a tile taken
+// from the table: under the forced-table rule it must go on a board.
+func (state *GameState) tablePickAllowed(move Move) bool {
+	return !state.ForcedTable || (move.Type == Place || move.Type == Swap) && move.Cell != nil
+}
+
+func promptForcedTable() bool {
+	fmt.Print(tr("Must tiles taken from the table be placed, never discarded again? (y/N): "))
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
+
+func promptNonDecreasing() bool {
+	fmt.Print(tr("Allow equal values next to each other in a row or column? (y/N): "))
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
 }
 
 func promptBrunoVariant() bool {
-	fmt.Print("Enable Bruno variant? (extra turn for adjacent diagonal match) (y/N): ")
+	fmt.Print(tr("Enable Bruno variant? (extra turn for placing next to a twin) (y/N): "))
 	line, _ := reader.ReadString('\n')
 	line = strings.TrimSpace(strings.ToLower(line))
 	return line == "y" || line == "yes"
 }
 
 func (state *GameState) playGame() {
+	state.logPosition("start")
+	state.markRunning()
 	for {
 		board := state.Boards[state.Current]
+		if board.Resigned {
+			state.Current = (state.Current + 1) % len(state.Boards)
+			continue
+		}
+		state.ExtraTurns = 0
 
 		var move Move
-		bestFromTable := false
-		if board.IsAi {
-			move, bestFromTable = state.drawTileRecommendation()
-			if bestFromTable {
-				fmt.Printf("Computer is drawing %d from the table\n", move.Tile)
-				state.removeTileFromTable(move.Tile)
+		played, resumed := false, false
+		if state.Pending != nil {
+			// a game saved mid-turn goes on with the tile drawn, which
+			// was counted before the save
+			move, state.Pending = *state.Pending, nil
+			resumed = true
+		} else if board.IsAi {
+			if steal, ok := state.stealRecommendation(); ok {
+				state.applyMove(steal)
+				move, played = steal, true
 			} else {
-
-				fmt.Print("Computer draws from pile ")
-				move = state.drawTile()
+				move = state.computerDraw()
+			}
+			if state.Over {
+				return
 			}
 		} else {
+			state.markTurn()
+			if board.Controlled {
+				fmt.Printf(tr("-- Testing: you are playing %s --\n"), state.seatLabel(state.Current))
+			}
 			var quit bool
-			move, quit = state.promptDrawOrSave()
+			if state.timed(func() { move, quit = state.promptDrawOrSave() }) {
+				state.timeUp(nil)
+				played = true
+			}
+			if state.Over {
+				return
+			}
 			if quit {
-				fmt.Println("Exiting game.")
+				fmt.Println(tr("Exiting game."))
+				return
+			}
+			if state.takeUndone() {
+				state.PrettyPrintBoardsGridCentered()
+				continue
+			}
+			if board.Resigned {
+				state.PrettyPrintBoardsGridCentered()
+				continue
+			}
+			played = played || move.Type == Steal
+		}
+		if !played {
+			if !resumed {
+				state.Draws++
+				state.countPuzzleDraw()
+				state.logDraw(move)
+			}
+			moves := len(state.History)
+			if state.timed(func() { state.promptPlacement(move) }) && len(state.History) == moves {
+				state.timeUp(&move)
+			}
+			if state.Over {
+				return
+			}
+			if state.Stopped {
+				fmt.Println(tr("Exiting game."))
 				return
 			}
+			if state.takeUndone() {
+				state.PrettyPrintBoardsGridCentered()
+				continue
+			}
 		}
-		state.promptPlacement(move)
+		state.refillHand(state.Current)
+		state.Turn++
 
 		state.PrettyPrintBoardsGridCentered()
 		if board.IsFull() {
-			fmt.Println("GAME OVER PG!")
-			os.Exit(0)
+			state.gameOver(state.Current, tr("GAME OVER PG!"))
+			return
+		}
+		if state.checkPuzzleLimit(); state.Over {
+			return
+		}
+		state.abEndTurn(state.Current)
+		if spectator != nil && spectator.wait() {
+			fmt.Println(tr("Stopped watching."))
+			return
 		}
 		state.Current = (state.Current + 1) % len(state.Boards)
+		state.autosave()
 	}
 }
 
-func (board *Board) checkBrunoExtra(r, c int) bool {
-	tile := board.Grid[r][c]
-	if tile == 0 {
-		return false
+// computerDraw lets the current computer seat take a tile from the table or
+// the pile, per its strategy.
+func (state *GameState) computerDraw() Move {
+	move, fromTable := state.strategyFor(state.Current).PickFromTable(state)
+	state.abLogTable(state.Current, move, fromTable)
+	if fromTable && state.prefersPile(move) {
+		narrate("%s sees the pile's top tile and prefers it.\n", state.seatLabel(state.Current))
+		fromTable = false
 	}
-
-	deltas := [][2]int{
-		{-1, -1}, {-1, 1}, {1, -1}, {1, 1},
+	if fromTable && !state.tablePickAllowed(move) {
+		fromTable = false
 	}
-
-	for _, d := range deltas {
-		nr, nc := r+d[0], c+d[1]
-		if nr >= 0 && nr < BoardSize && nc >= 0 && nc < BoardSize {
-			if board.Grid[nr][nc] == tile {
-				fmt.Printf("Bruno’s Variant: matching diagonal at (%d,%d)! Extra turn granted.\n", nr, nc)
-				return true
-			}
-		}
+	if fromTable {
+		move.FromTable = true
+		narrate("%s is drawing %s from the table\n", state.seatLabel(state.Current), spokenTile(move.Tile))
+		state.removeTileFromTable(move.Tile)
+		return move
 	}
-	return false
+	if state.holdsHand() {
+		tile, _ := state.bestHandTile()
+		narrate("%s plays %s from its hand\n", state.seatLabel(state.Current), spokenTile(tile))
+		return state.playFromHand(tile)
+	}
+	narrate("%s draws from pile ", state.seatLabel(state.Current))
+	return state.drawTile()
 }
 
 func (b *Board) IsFull() bool {
@@ -636,59 +1163,116 @@ func (state *GameState) promptPlacement(move Move) {
 	current := state.Current
 	board := state.Boards[current]
 	tile := move.Tile
-	fmt.Printf("Computer %d contemplates %d.\n", current, tile)
 	// --- Computer-controlled board auto-play ---
 	if board.IsAi {
-		if move.Type != Draw {
-			state.applyMove(move)
-			return
+		narrate("%s contemplates %s.\n", state.seatLabel(current), spokenTile(tile))
+		state.explainScores(os.Stdout, tile)
+		best, ok := move, true
+		if move.Type == Draw || move.Type == FromHand {
+			best, ok = state.strategyFor(current).ChooseMove(state, tile)
+			state.abLogPlace(current, tile, best, ok)
 		}
-		recs := state.bestMoves(tile)
-		if len(recs) == 0 {
+		if !ok {
 			// No legal moves, discard to table
 			move.Type = Discard
 			state.applyMove(move)
-			fmt.Printf("Computer %d discards %d to table.\n", current, tile)
+			narrate("%s discards %s to table.\n", state.seatLabel(current), spokenTile(tile))
 			return
 		}
-		// Pick best move
-		move := recs[0]
+		move := best
 		extra := state.applyMove(move)
 		if extra {
-			fmt.Println("Computer gets extra turn!")
-			move, _ = state.drawTileRecommendation()
-			state.promptPlacement(move)
+			narrate("%s gets extra turn!\n", state.seatLabel(current))
+			if next := state.computerDraw(); !state.Over {
+				state.promptPlacement(next)
+			}
 		}
 
 		return
 	}
 
+	state.Pending = &move
+	defer func() { state.Pending = nil }()
+	state.autosaveDrawn()
+	if screen != nil {
+		screen.place(state, move)
+		return
+	}
+
 	// --- Human player flow continues unchanged ---
+	state.Holding = tile
+	defer func() { state.Holding = 0 }()
+	state.PrettyPrintBoardsGridCentered()
+	switch {
+	case screenReader:
+		// the board description lists the cells
+	case term.Color:
+		fmt.Printf(tr("The green cells can take %s.\n"), tileLabel(tile))
+	default:
+		fmt.Printf(tr("The cells marked %s, or a tile in parentheses to swap, can take %s.\n"), legalLabel, tileLabel(tile))
+	}
+	var hints []Move
+	if autoHints {
+		hints = state.printHints(tile)
+	}
 	for {
-		fmt.Printf("Action for %d? ([r]ecommend, [d]iscard, or row,col): ", tile)
-		action, _ := reader.ReadString('\n')
+		if state.Teams {
+			fmt.Printf(tr("Action for %s? ([r]ecommend, [d]iscard, [u]ndo, [s]ave, [q]uit, a cell like B3, or p B3 on partner %d's board): "), tileLabel(tile), state.partner(current))
+		} else {
+			fmt.Printf(tr("Action for %s? ([r]ecommend, [d]iscard, [u]ndo, [s]ave, [q]uit, or a cell like B3): "), tileLabel(tile))
+		}
+		action, err := reader.ReadString('\n')
+		if errors.Is(err, errTimeout) {
+			return
+		}
 		action = strings.TrimSpace(action)
+		if state.annotationCommand(action, tile) {
+			continue
+		}
+		if action == "" && err != nil {
+			action = "q" // the input ran out
+		}
 
 		switch action {
+		case "q":
+			state.stopDrawn(tile)
+			return
+		case "debug":
+			state.handleDebugCommand()
+		case "u", "undo":
+			if state.undo(true) {
+				return
+			}
+		case "s":
+			state.promptSave()
 		case "d":
-			move := Move{Type: Discard}
+			if move.FromTable && state.ForcedTable {
+				fmt.Println(tr("A tile taken from the table must be placed."))
+				continue
+			}
+			move := Move{Type: Discard, Tile: tile}
 			state.applyMove(move)
-			fmt.Println("Placed on table.")
+			fmt.Println(tr("Placed on table."))
 			return
 		case "r":
 			recs := state.bestMoves(tile)
 			if len(recs) == 0 {
-				fmt.Println("No legal placements found.")
+				fmt.Println(tr("No legal placements found."))
 				continue
 			}
 			state.printMap(tile)
+			state.explainScores(os.Stdout, tile)
 			for i, m := range recs {
-				fmt.Printf("%d) %s at (%d,%d) — score %5.2f\n",
+				where := ""
+				if m.Partner {
+					where = fmt.Sprintf(tr(" on partner %d's board"), state.partner(current))
+				}
+				fmt.Printf(term.text(tr("%d) %s at %s%s — score %5.2f\n")),
 					i+1,
-					map[MoveType]string{Place: "Place", Swap: "Swap"}[m.Type],
-					m.Cell.R, m.Cell.C, m.Score)
+					tr(map[MoveType]string{Place: "Place", Swap: "Swap"}[m.Type]),
+					m.Cell, where, m.Score)
 			}
-			fmt.Print("Choose move number or press Enter to skip: ")
+			fmt.Print(tr("Choose move number or press Enter to skip: "))
 			choice, _ := reader.ReadString('\n')
 			choice = strings.TrimSpace(choice)
 			if choice == "" {
@@ -702,69 +1286,134 @@ func (state *GameState) promptPlacement(move Move) {
 				}
 				return
 			}
-			fmt.Println("Invalid choice.")
+			fmt.Println(tr("Invalid choice."))
 		default:
-			parts := strings.Split(action, ",")
-			if len(parts) == 2 {
-				r, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
-				c, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
-				if err1 == nil && err2 == nil && r >= 0 && r < BoardSize && c >= 0 && c < BoardSize {
-					if state.isPlacementFeasible(tile, r, c) {
-						move := Move{Type: Place, Cell: &Cell{R: r, C: c}}
-						old := board.Grid[r][c]
-						fmt.Println("HOW DID I GET HERE!?")
-						extra := state.applyMove(move)
-						if old != 0 {
-							state.Table = append(state.Table, old)
-							fmt.Printf("Swapped %d into table, placed %d at (%d,%d).\n", old, tile, r, c)
-						} else {
-							fmt.Printf("Placed %d at (%d,%d).\n", tile, r, c)
-						}
-						if extra {
-							continue
-						}
-						return
-					}
+			if idx, err := strconv.Atoi(action); err == nil && idx >= 1 && idx <= len(hints) {
+				// one of the hints, picked by number
+				hint := hints[idx-1]
+				extra := state.applyMove(hint)
+				if hint.Type == Swap {
+					fmt.Printf(tr("Swapped %s into table, placed %s at %s.\n"), tileLabel(hint.OldTile), tileLabel(tile), hint.Cell)
+				} else {
+					fmt.Printf(tr("Placed %s at %s.\n"), tileLabel(tile), hint.Cell)
 				}
+				if extra {
+					continue
+				}
+				return
+			}
+			onPartner := false
+			if rest, ok := strings.CutPrefix(action, "p "); ok && state.Teams {
+				action, onPartner = strings.TrimSpace(rest), true
+			}
+			cell, err := parseCell(action)
+			if err != nil {
+				fmt.Printf(tr("Invalid input (%v), try again.\n"), err)
+				continue
 			}
-			fmt.Println("Invalid input, try again.")
+			r, c := cell.R, cell.C
+			target := current
+			if onPartner {
+				target = state.partner(current)
+			}
+			restore := state.asSeat(target)
+			feasible := state.isPlacementFeasible(tile, r, c)
+			restore()
+			if !feasible {
+				fmt.Printf(tr("%s cannot go at %s, try again.\n"), tileLabel(tile), cell)
+				continue
+			}
+			move := Move{Type: Place, Tile: tile, Cell: &cell, Partner: onPartner}
+			old := state.Boards[target].Grid[r][c]
+			if old != 0 {
+				move.Type, move.OldTile = Swap, old
+			}
+			if !state.confirmMove(move) {
+				continue
+			}
+			extra := state.applyMove(move)
+			if old != 0 {
+				fmt.Printf(tr("Swapped %s into table, placed %s at %s.\n"), tileLabel(old), tileLabel(tile), cell)
+			} else {
+				fmt.Printf(tr("Placed %s at %s.\n"), tileLabel(tile), cell)
+			}
+			if extra {
+				continue
+			}
+			return
 		}
 	}
 }
 
 func (state *GameState) promptDrawOrSave() (Move, bool) {
+	if screen != nil {
+		return screen.draw(state)
+	}
+	state.peekPile()
 	for {
-		fmt.Print("[d]raw, [r]ecommend, [s]ave, or [q]uit? ")
-		line, _ := reader.ReadString('\n')
+		if state.canSteal() {
+			fmt.Print(tr("[d]raw, [r]ecommend, [e]quity, [o]dds, [t]racker, steal [x], [u]ndo, [s]ave, position, export, resign, or [q]uit? "))
+		} else {
+			fmt.Print(tr("[d]raw, [r]ecommend, [e]quity, [o]dds, [t]racker, [u]ndo, [s]ave, position, export, resign, or [q]uit? "))
+		}
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, errTimeout) {
+			return Move{}, false
+		}
+		if state.annotationCommand(line, 0) {
+			continue
+		}
 		line = strings.TrimSpace(strings.ToLower(line))
 
 		switch line {
+		case "debug":
+			state.handleDebugCommand()
+		case "e":
+			state.printTableEquity()
+		case "o", "odds":
+			state.printOdds(os.Stdout)
+		case "t", "tracker":
+			state.printTracker(os.Stdout)
+		case "u", "undo":
+			if state.undo(false) {
+				return Move{}, false
+			}
+		case "x":
+			if !state.canSteal() {
+				fmt.Println(tr("No steal available."))
+				continue
+			}
+			if move, ok := state.promptSteal(); ok {
+				state.applyMove(move)
+				return move, false
+			}
 		case "q":
 			return Move{}, true
-		case "s":
-			fmt.Println("enter file name for save")
-			line, _ := reader.ReadString('\n')
-			filename := strings.TrimSpace(strings.ToLower(line))
-			if !strings.HasSuffix(filename, ".csv") {
-				filename += ".csv"
-			}
-			if err := state.saveToCSV(filename); err != nil {
-				fmt.Println("Failed to save:", err)
-			} else {
-				fmt.Println("Game saved.")
+		case "position":
+			fmt.Println(state.encodePosition())
+		case "export":
+			state.promptExport()
+		case "resign":
+			if promptResign() {
+				state.resign(state.Current)
+				return Move{}, false
 			}
-			return Move{}, true
+		case "s":
+			state.promptSave()
 		case "d", "":
 			if state.Analyze {
-				fmt.Print("Enter drawn tile: ")
-				text, _ := reader.ReadString('\n')
+				fmt.Print(tr("Enter drawn tile: "))
+				text, err := reader.ReadString('\n')
+				if errors.Is(err, errTimeout) {
+					return Move{}, false
+				}
 				text = strings.TrimSpace(text)
 				if text == "" {
 					return Move{}, true
 				}
-				tile, err := strconv.Atoi(text)
+				tile, err := parseTile(text, state.maxTile())
 				if err != nil {
-					fmt.Println("Invalid tile number.")
+					fmt.Println(tr("Invalid tile number:"), err)
 					continue
 				}
 				return Move{Tile: tile, Type: Draw}, false
@@ -772,26 +1421,67 @@ func (state *GameState) promptDrawOrSave() (Move, bool) {
 			return state.drawTile(), false
 		case "r":
 			move, shouldDrawFromTable := state.drawTileRecommendation()
+			if !shouldDrawFromTable && state.holdsHand() {
+				tile, _ := state.bestHandTile()
+				fmt.Printf(tr("Player should play %s from their hand\n"), tileLabel(tile))
+				continue
+			}
 			if !shouldDrawFromTable {
-				fmt.Println("Player should draw from the draw stack")
+				fmt.Println(tr("Player should draw from the draw stack"))
+				continue
 			}
 
 			if move.Type == Swap {
-				fmt.Printf("Swapping tile %d from the table into (%d,%d) is the best choice\n", move.Tile, move.Cell.R, move.Cell.C)
+				fmt.Printf(tr("Swapping tile %s from the table into %s is the best choice\n"), tileLabel(move.Tile), move.Cell)
 			}
 			if move.Type == Place {
-				fmt.Printf("Placing tile %d from the table into (%d,%d) is the best choice\n", move.Tile, move.Cell.R, move.Cell.C)
+				fmt.Printf(tr("Placing tile %s from the table into %s is the best choice\n"), tileLabel(move.Tile), move.Cell)
 			}
 
 		default:
-			fmt.Println("Invalid option.")
+			fmt.Println(tr("Invalid option."))
+		}
+	}
+}
+
+// promptSave asks for a file and saves the game to it; play goes on.
+func (state *GameState) promptSave() {
+	fmt.Println(tr("enter file name for save (ending in .json to keep the draw pile and rules)"))
+	line, err := reader.ReadString('\n')
+	if errors.Is(err, errTimeout) {
+		return
+	}
+	filename := strings.TrimSpace(strings.ToLower(line))
+	save := state.saveToJSON
+	if !isJSONSave(filename) {
+		if state.Match != nil {
+			fmt.Println(tr("A CSV save keeps only this round; save as .json to keep the match."))
+		}
+		if len(state.Annotations) > 0 {
+			fmt.Println(tr("A CSV save does not keep the notes; save as .json to keep them."))
+		}
+		save = state.saveToCSV
+		if !isCSVSave(filename) {
+			filename = strings.TrimSuffix(plainName(filename), ".csv") + ".csv" + strings.TrimPrefix(filename, plainName(filename))
 		}
 	}
+	if err := os.MkdirAll(savesDir, 0o755); err != nil {
+		fmt.Println(tr("Failed to save:"), err)
+		return
+	}
+	if err := save(savePath(filename)); err != nil {
+		fmt.Println(tr("Failed to save:"), err)
+	} else {
+		fmt.Println(tr("Game saved."))
+	}
 }
 
 func (state *GameState) drawTileRecommendation() (Move, bool) {
 
-	bestScore := threshold
+	bestScore := state.tableThreshold()
+	if state.pileVisible() || state.holdsHand() {
+		bestScore = state.drawAlternative() // no gamble: the alternative is known
+	}
 	bestMove := Move{}
 	bestFromTable := false
 
@@ -819,91 +1509,166 @@ func (state *GameState) drawTileRecommendation() (Move, bool) {
 func (state *GameState) drawTile() Move {
 	if !state.Boards[state.Current].IsAi {
 		if len(state.Table) > 0 {
-			fmt.Print("Draw from [p]ile or [t]able? (default pile): ")
-			choice, _ := reader.ReadString('\n')
+			if state.holdsHand() {
+				fmt.Print(tr("Play from your [h]and or take from the [t]able? (default hand): "))
+			} else if state.pileVisible() {
+				fmt.Printf(tr("Draw %s from the [p]ile or from the [t]able? (default pile): "), tileLabel(state.Draw[0]))
+			} else {
+				fmt.Print(tr("Draw from [p]ile or [t]able? (default pile): "))
+			}
+			choice, err := reader.ReadString('\n')
+			if errors.Is(err, errTimeout) {
+				return Move{} // the seat's clock ran out before it drew
+			}
 			choice = strings.TrimSpace(strings.ToLower(choice))
 			if choice == "t" {
-				fmt.Println("Tiles on table:", state.Table)
-				fmt.Print("Enter tile to pick: ")
-				input, _ := reader.ReadString('\n')
+				labels := make([]string, len(state.Table))
+				for i, t := range state.Table {
+					labels[i] = tileLabel(t)
+				}
+				fmt.Println(tr("Tiles on table:"), strings.Join(labels, " "))
+				fmt.Print(tr("Enter tile to pick: "))
+				input, err := reader.ReadString('\n')
+				if errors.Is(err, errTimeout) {
+					return Move{}
+				}
 				input = strings.TrimSpace(input)
-				tile, err := strconv.Atoi(input)
+				tile, err := parseTile(input, state.maxTile())
 				if err != nil || !contains(state.Table, tile) {
-					fmt.Println("Invalid choice.")
+					fmt.Println(tr("Invalid choice."))
+					return state.drawTile()
+				}
+				if state.ForcedTable && len(state.bestMoves(tile)) == 0 {
+					fmt.Printf(tr("%s fits nowhere, and tiles taken from the table must be placed.\n"), tileLabel(tile))
 					return state.drawTile()
 				}
 				state.removeTileFromTable(tile)
-				return Move{Tile: tile, Type: Draw}
+				return Move{Tile: tile, Type: Draw, FromTable: true}
 			}
 		}
-		if len(state.Draw) == 0 {
-			fmt.Println("Draw pile is empty — game over.")
-			os.Exit(0)
+		if state.holdsHand() {
+			return state.promptHandTile()
 		}
 	}
-	tile := state.Draw[0]
-	state.Draw = state.Draw[1:]
-	fmt.Printf(" drew a %d\n", tile)
+	tile, err := state.popDraw()
+	if err != nil {
+		state.pileExhausted()
+		return Move{}
+	}
+	if state.Boards[state.Current].IsAi {
+		narrate(" drew a %s\n", spokenTile(tile))
+	} else {
+		fmt.Printf(tr(" drew a %s\n"), tileLabel(tile))
+	}
 	return Move{Tile: tile, Type: Draw}
 }
+
+// removeTileFromTable takes tile off the table for the current seat.
 func (state *GameState) removeTileFromTable(tile int) {
 	for i, v := range state.Table {
 		if v == tile {
 			state.Table = append(state.Table[:i], state.Table[i+1:]...)
+			state.Boards[state.Current].TableTakes++
 			break
 		}
 	}
 }
 
-func (state *GameState) saveToCSV(filename string) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
+// drawnFrom names where a drawn tile came from, for the DRAWN record.
+func drawnFrom(move Move) string {
+	switch {
+	case move.FromTable:
+		return "table"
+	case move.Type == FromHand:
+		return "hand"
+	}
+	return "pile"
+}
+
+// parseDrawnFrom reads drawnFrom back into the move the tile was drawn by.
+func parseDrawnFrom(s string) (Move, error) {
+	switch strings.TrimSpace(s) {
+	case "pile":
+		return Move{Type: Draw}, nil
+	case "table":
+		return Move{Type: Draw, FromTable: true}, nil
+	case "hand":
+		return Move{Type: FromHand}, nil
 	}
-	defer f.Close()
+	return Move{}, fmt.Errorf("%q is not pile, table or hand", s)
+}
 
-	writer := csv.NewWriter(f)
-	defer writer.Flush()
+func (state *GameState) saveToCSV(filename string) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	writer.Write([]string{"VERSION", strconv.Itoa(csvVersion)})
 
 	// Write turn info
 	writer.Write([]string{"TURN", strconv.Itoa(state.Current)})
 
+	// Write the board size and tile range, when not the standard ones
+	if BoardSize != standardBoardSize {
+		writer.Write([]string{"SIZE", strconv.Itoa(BoardSize)})
+	}
+	if state.maxTile() != MaxTile {
+		writer.Write([]string{"RANGE", strconv.Itoa(state.maxTile())})
+	}
+	if state.Distribution != nil || state.SharedPool {
+		writer.Write([]string{"DIST", state.tileCopies(len(state.Boards)).String()})
+	}
+	writer.Write(state.csvSettings())
+	if resigned := state.resignedSeats(); len(resigned) > 0 {
+		writer.Write(append([]string{"RESIGNED"}, resigned...))
+	}
+	if p := state.Pending; p != nil {
+		writer.Write([]string{"DRAWN", tileLabel(p.Tile), drawnFrom(*p)})
+	}
+
 	// Write table
 	tableRow := []string{"TABLE"}
 	for _, t := range state.Table {
-		tableRow = append(tableRow, strconv.Itoa(t))
+		tableRow = append(tableRow, tileLabel(t))
 	}
 	writer.Write(tableRow)
 
-	// Write boards
+	// Write boards, each under a header saying whose it is
 	for _, board := range state.Boards {
+		writer.Write(boardHeader(board))
 		for r := 0; r < BoardSize; r++ {
 			row := make([]string, BoardSize)
 			for c := 0; c < BoardSize; c++ {
-				if board.Grid[r][c] == 0 {
-					row[c] = "."
-				} else {
-					row[c] = strconv.Itoa(board.Grid[r][c])
-				}
+				row[c] = tileLabel(board.Grid[r][c])
 			}
 			writer.Write(row)
 		}
 	}
 
-	return nil
+	// the checksum record ends the save, so changes and cuts show on load
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return writeFile(filename, append(buf.Bytes(), csvChecksumRecord(buf.Bytes())...))
 }
 
 func (state *GameState) loadFromCSV(filename string) error {
-	f, err := os.Open(filename)
+	data, err := readFile(filename)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	if data, err = checkCSVChecksum(filename, data); err != nil {
+		return err
+	}
 
-	reader := csv.NewReader(f)
+	reader := csv.NewReader(bytes.NewReader(data))
 	reader.FieldsPerRecord = -1
+	reader.Comment = '#' // notes in hand-edited saves
 	records, err := reader.ReadAll()
 	if err != nil {
+		return fmt.Errorf("%s is not a readable save: %w", filename, err)
+	}
+	if records, err = checkCSVVersion(records); err != nil {
 		return err
 	}
 
@@ -915,7 +1680,7 @@ func (state *GameState) loadFromCSV(filename string) error {
 	state.Table = []int{}
 	state.Current = 0
 
-	usedTiles := map[int]bool{}
+	usedTiles := map[int]int{} // copies of each tile already on the table or a board
 
 	// --- Parse turn ---
 	if records[0][0] != "TURN" {
@@ -924,47 +1689,175 @@ func (state *GameState) loadFromCSV(filename string) error {
 	if len(records[0]) < 2 {
 		return fmt.Errorf("TURN record missing player index")
 	}
-	cur, err := strconv.Atoi(records[0][1])
+	cur, err := strconv.Atoi(strings.TrimSpace(records[0][1]))
 	if err != nil {
-		return err
+		return fmt.Errorf("TURN record: %q is not a player index", records[0][1])
 	}
 	state.Current = cur
+	records = records[1:]
+	// --- Parse board size ---
+	BoardSize = standardBoardSize
+	if records[0][0] == "SIZE" {
+		if len(records[0]) < 2 {
+			return fmt.Errorf("SIZE record missing board size")
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(records[0][1]))
+		if err != nil || n < minBoardSize || n > maxBoardSize {
+			return fmt.Errorf("SIZE record: %q is not a board size in %d-%d", records[0][1], minBoardSize, maxBoardSize)
+		}
+		BoardSize = n
+		records = records[1:]
+		if len(records) == 0 {
+			return fmt.Errorf("CSV too short")
+		}
+	}
+	// --- Parse tile range ---
+	state.TileRange = 0
+	if records[0][0] == "RANGE" {
+		if len(records[0]) < 2 {
+			return fmt.Errorf("RANGE record missing highest tile")
+		}
+		hi, err := strconv.Atoi(strings.TrimSpace(records[0][1]))
+		if err != nil || hi < minTileRange() || hi > maxTileRange {
+			return fmt.Errorf("RANGE record: %q is not a highest tile in %d-%d", records[0][1], minTileRange(), maxTileRange)
+		}
+		state.TileRange = hi
+		records = records[1:]
+		if len(records) == 0 {
+			return fmt.Errorf("CSV too short")
+		}
+	}
+	// --- Parse tile distribution ---
+	state.Distribution = nil
+	if records[0][0] == "DIST" {
+		if len(records[0]) < 2 {
+			return fmt.Errorf("DIST record missing tile distribution")
+		}
+		d, err := parseDistribution(strings.Join(records[0][1:], ","), state.maxTile())
+		if err != nil {
+			return fmt.Errorf("DIST record: %w", err)
+		}
+		state.Distribution = d
+		records = records[1:]
+		if len(records) == 0 {
+			return fmt.Errorf("CSV too short")
+		}
+	}
+	// --- Parse the settings ---
+	var seats []string
+	settings := records[0][0] == "SETTINGS"
+	if settings {
+		if seats, err = state.parseCSVSettings(records[0][1:]); err != nil {
+			return fmt.Errorf("SETTINGS record: %w", err)
+		}
+		records = records[1:]
+		if len(records) == 0 {
+			return fmt.Errorf("CSV too short")
+		}
+	}
+	// --- Parse player names ---
+	var names []string
+	if records[0][0] == "NAMES" {
+		for _, name := range records[0][1:] {
+			name, err := checkName(name)
+			if err != nil {
+				return fmt.Errorf("NAMES record: %w", err)
+			}
+			names = append(names, name)
+		}
+		records = records[1:]
+		if len(records) == 0 {
+			return fmt.Errorf("CSV too short")
+		}
+	}
+	// --- Parse resigned seats ---
+	var resigned []int
+	if records[0][0] == "RESIGNED" {
+		for _, f := range records[0][1:] {
+			seat, err := strconv.Atoi(f)
+			if err != nil {
+				return fmt.Errorf("RESIGNED record: seat %q is not a number", f)
+			}
+			resigned = append(resigned, seat)
+		}
+		records = records[1:]
+		if len(records) == 0 {
+			return fmt.Errorf("CSV too short")
+		}
+	}
+	// --- Parse the tile drawn before saving ---
+	state.Pending = nil
+	if records[0][0] == "DRAWN" {
+		if len(records[0]) < 3 {
+			return fmt.Errorf("DRAWN record needs a tile and where it came from")
+		}
+		tile, err := parseTile(records[0][1], state.maxTile())
+		if err != nil {
+			return fmt.Errorf("DRAWN record: %w", err)
+		}
+		move, err := parseDrawnFrom(records[0][2])
+		if err != nil {
+			return fmt.Errorf("DRAWN record: %w", err)
+		}
+		move.Tile = tile
+		state.Pending = &move
+		usedTiles[tile]++
+		records = records[1:]
+		if len(records) == 0 {
+			return fmt.Errorf("CSV too short")
+		}
+	}
 	// --- Parse table ---
-	if records[1][0] != "TABLE" {
+	if records[0][0] != "TABLE" {
 		return fmt.Errorf("expected TABLE record")
 	}
-	for _, t := range records[1][1:] {
+	for _, t := range records[0][1:] {
 		if t == "." {
 			continue
 		}
-		n, err := strconv.Atoi(t)
+		n, err := parseTile(t, state.maxTile())
 		if err != nil {
-			return err
+			return fmt.Errorf("TABLE record: %w", err)
 		}
 		state.Table = append(state.Table, n)
-		usedTiles[n] = true
+		usedTiles[n]++
 	}
 
-	// --- Parse boards ---
+	// --- Parse boards, each under an optional BOARD header ---
 	var currentBoard *Board
-	rowCounter := 0
-	for _, rec := range records[2:] {
+	headed := false // currentBoard was started by its header
+	rowCounter, i := 0, 0
+	for _, rec := range records[1:] {
+		if rec[0] == "BOARD" {
+			if rowCounter != 0 || headed {
+				return fmt.Errorf("BOARD record after %d rows of a board, expected %d", rowCounter, BoardSize)
+			}
+			currentBoard, headed = &Board{}, true
+			if err := parseBoardHeader(rec, currentBoard); err != nil {
+				return fmt.Errorf("board %d: %w", len(state.Boards), err)
+			}
+			continue
+		}
+		i++
 		if len(rec) != BoardSize {
-			return fmt.Errorf("board row with wrong number of fields")
+			return fmt.Errorf("board row %d has %d fields, expected %d", i, len(rec), BoardSize)
 		}
-		if rowCounter == 0 {
+		if rowCounter == 0 && !headed {
 			currentBoard = &Board{}
 		}
+		headed = false
 		for c, val := range rec {
 			if val == "." {
 				currentBoard.Grid[rowCounter][c] = 0
+			} else if val == blockedLabel {
+				currentBoard.Grid[rowCounter][c] = Blocked
 			} else {
-				n, err := strconv.Atoi(val)
+				n, err := parseTile(val, state.maxTile())
 				if err != nil {
-					return err
+					return fmt.Errorf("board row %d: %w", i, err)
 				}
 				currentBoard.Grid[rowCounter][c] = n
-				usedTiles[n] = true
+				usedTiles[n]++
 			}
 		}
 		rowCounter++
@@ -973,51 +1866,701 @@ func (state *GameState) loadFromCSV(filename string) error {
 			rowCounter = 0
 		}
 	}
+	if rowCounter != 0 || headed {
+		return fmt.Errorf("last board has %d rows, expected %d", rowCounter, BoardSize)
+	}
+	if len(state.Boards) == 0 {
+		return fmt.Errorf("no boards in save")
+	}
+	if len(names) > len(state.Boards) {
+		return fmt.Errorf("NAMES record has %d names for %d boards", len(names), len(state.Boards))
+	}
+	for i, name := range names {
+		state.Boards[i].Name = name
+	}
+	if err := state.applySeats(seats); err != nil {
+		return err
+	}
+	for _, seat := range resigned {
+		if seat < 0 || seat >= len(state.Boards) {
+			return fmt.Errorf("RESIGNED record has seat %d but only %d boards", seat, len(state.Boards))
+		}
+		state.Boards[seat].Resigned = true
+	}
+	state.Holes = nil
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if state.Boards[0].Grid[r][c] == Blocked {
+				state.Holes = append(state.Holes, Cell{R: r, C: c})
+			}
+		}
+	}
+	if state.Current < 0 || state.Current >= len(state.Boards) {
+		return fmt.Errorf("TURN index %d but only %d boards", state.Current, len(state.Boards))
+	}
+	// a CSV save without its settings does not say whether equal
+	// neighbours are allowed, so they pass here and are checked again once
+	// the rules are asked
+	step := 0
+	if settings {
+		step = state.step()
+	}
+	if err := state.checkPosition(step); err != nil {
+		return err
+	}
 
 	// --- Generate draw pile ---
-	tileCounts := 20 * len(state.Boards) // adjust if using duplicates or more players
-	remaining := []int{}
-	for i := 1; i <= tileCounts; i++ {
-		if !usedTiles[i] {
-			remaining = append(remaining, i)
-		}
+	state.Draw = state.unusedTiles(usedTiles)
+	// the hands are not kept, so they are dealt afresh
+	if settings && state.HandSize > 0 && !state.Analyze {
+		state.dealHands()
 	}
-	rand.Shuffle(len(remaining), func(i, j int) { remaining[i], remaining[j] = remaining[j], remaining[i] })
-	state.Draw = remaining
 
 	return nil
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
+	abSpec := flag.String("ab", "", "debug: alternate two strategies on one computer seat, e.g. greedy,random")
+	abSeat := flag.Int("ab-seat", -1, "seat played by the -ab strategies (default: first computer)")
+	abLog := flag.String("ab-log", "abtest.log", "file receiving the -ab per-decision log")
+	risk := flag.Bool("risk", false, "risk-aware scoring for hints and computer players")
+	heuristicsFile := flag.String("heuristics", "", "JSON file overriding the evaluator heuristics")
+	control := flag.String("control", "", "testing: comma separated seats the human plays, including computer ones")
+	demoDelay := flag.Duration("demo-delay", 1500*time.Millisecond, "pause between turns of the demo game")
+	rlFile := flag.String("rl-weights", "", "weights file of the rl strategy (read, and written by train)")
+	trainGames := flag.Int("train-games", 10000, "self-play games for the train command")
+	modelFile := flag.String("eval-model", "", "JSON evaluation model played by the model strategy")
+	aiName := flag.String("ai", "greedy", "strategy the computer players use")
+	seedFlag := flag.Int64("seed", 0, "seed for shuffles and random choices (0: from the clock)")
+	games := flag.Int("games", 100, "games per pairing for the tournament command")
+	bracketFormat := flag.String("bracket-format", "knockout", "format of the bracket command: knockout (single elimination) or roundrobin")
+	bracketGames := flag.Int("bracket-games", 1, "games per pairing in the bracket command")
+	rounds := flag.Int("rounds", 1, "rounds in a match, scored by finishing order")
+	puzzleDraws := flag.Int("puzzle-draws", defaultPuzzleDraws, "draws allowed to complete the board in the puzzle command")
+	profile := flag.String("profile", defaultProfilePath(), "file keeping your games, wins and achievements; empty to not track them")
+	flag.StringVar(&profilesDir, "profiles", defaultProfilesDir(), "directory keeping a profile for every named player; empty to not keep them")
+	autosaveDir := flag.String("autosave", defaultAutosaveDir(), "directory the last turns are saved to, offered back at the next start; empty to not autosave")
+	archive := flag.String("archive", "", "JSON-lines file finished games are appended to, read by the openings command")
+	evalCSVFile := flag.String("eval-csv", "", "CSV file receiving every move's score, the engine's best move and each player's win chance, in a game or replay")
+	moveLogFile := flag.String("move-log", "", "file receiving every draw and move as JSON lines, for replays and reviews; gzipped when it ends in .gz")
+	flag.BoolVar(&forceLoad, "force", false, "load a save that was changed or cut short since it was saved, after a warning")
+	transcriptFile := flag.String("transcript", "", "file recording the whole session, prompts, answers and output, for bug reports; gzipped when it ends in .gz")
+	tilesSpec := flag.String("tiles", "", "exact tiles in the pile, e.g. 1-20x2 for two full sets or 1-20x2,8-13 for extra middle values (default: one set per player)")
+	termSpec := flag.String("term", "auto", "terminal capabilities: auto, or a comma separated mix of unicode/ascii, color/mono and width=N")
+	useTUI := flag.Bool("tui", false, "full-screen terminal UI with a cursor for the human turns")
+	flag.BoolVar(&screenReader, "screen-reader", false, "tell the boards in sentences instead of drawing them, without colour")
+	flag.BoolVar(&numberWords, "number-words", false, "tell the tiles as words in the language of the messages, for speech engines, in the screen-reader boards and the computer's moves")
+	lang := flag.String("lang", "auto", "language of the messages: "+strings.Join(locales(), ", ")+", or auto to follow LANG")
+	box := flag.String("box", "auto", "board lines: unicode box drawing, ascii +-| for terminals without Unicode, or auto (default from "+boxEnv+", else the locale)")
+	noColor := flag.Bool("no-color", false, "plain output without colour, for dumb terminals (same as -term mono)")
+	quiet := flag.Bool("quiet", false, "keep the computer players' turns silent apart from the boards")
+	verbose := flag.Bool("verbose", false, "show the scores and odds behind placements")
+	watchDelay := flag.Duration("watch-delay", defaultWatchDelay, "pause between moves when only computers play (0: no pause)")
+	blunderMargin := flag.Float64("blunder-margin", defaultBlunderMargin, "ask before a placement scoring this many points below the engine's best (0: never ask)")
+	flag.StringVar(&savesDir, "saves", ".", "directory saves given without a directory go to, listed at the load prompt and by the saves command")
+	flag.BoolVar(&autoHints, "hints", false, "show the best moves for every tile a human draws, to pick by number")
+	scriptFile := flag.String("script", "", "read the answers to the prompts from this file, one per line, and stop with the final position when it runs out")
+	noEdit := flag.Bool("no-edit", false, "read answers as plain lines, without arrow-key editing, history and tab completion")
+	setup.register(flag.CommandLine)
+	flag.Parse()
+	setup.noteGiven(flag.CommandLine)
+
+	if err := setLocale(*lang, os.Getenv); err != nil {
+		fmt.Println(err)
+		return
+	}
+	caps, err := detectTerm(os.Getenv, isTerminal(os.Stdout)).withBox(os.Getenv(boxEnv))
+	if err != nil {
+		fmt.Printf("%s: %v\n", boxEnv, err)
+		return
+	}
+	if caps, err = caps.override(*termSpec); err == nil {
+		caps, err = caps.withBox(*box)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *noColor || screenReader {
+		caps.Color = false
+	}
+	term = caps
+	switch {
+	case *quiet && *verbose:
+		fmt.Println("-quiet and -verbose cannot be used together")
+		return
+	case *quiet:
+		verbosity = Quiet
+	case *verbose:
+		verbosity = Verbose
+	}
+	switch {
+	case *scriptFile != "" && *useTUI:
+		fmt.Println("-script and -tui cannot be used together")
+		return
+	case *scriptFile != "":
+		if script, err = openScript(*scriptFile); err != nil {
+			fmt.Println("Failed to open script:", err)
+			return
+		}
+		reader = script
+	case !*noEdit && isTerminal(os.Stdin):
+		editor = newLineEditor()
+		reader = editor
+	}
 
-	fmt.Print("Load from CSV file? (filename or blank for new game): ")
-	csvFile, _ := reader.ReadString('\n')
-	csvFile = strings.TrimSpace(csvFile)
+	if *transcriptFile != "" {
+		if err := startTranscript(*transcriptFile); err != nil {
+			fmt.Println("Failed to start transcript:", err)
+			return
+		}
+		defer endTranscript()
+	}
 
-	state := &GameState{}
+	if flag.Arg(0) == "replay" {
+		if flag.NArg() != 2 {
+			fmt.Println("Usage: replay <move log>, a file written with -move-log")
+			return
+		}
+		// as when watching, a piped replay only waits when asked to
+		delay := *watchDelay
+		if !isTerminal(os.Stdin) && !setup.given["watch-delay"] {
+			delay = 0
+		}
+		var export *EvalExport
+		if *evalCSVFile != "" {
+			var err error
+			if export, err = newEvalExport(*evalCSVFile); err != nil {
+				fmt.Println("Failed to start the evaluation export:", err)
+				return
+			}
+			defer export.Close()
+		}
+		if _, err := runReplay(flag.Arg(1), delay, export); err != nil {
+			fmt.Println("Replay:", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "saves" {
+		runSaves(flag.Args()[1:])
+		return
+	}
+	if flag.Arg(0) == "gif" {
+		if flag.NArg() != 3 {
+			fmt.Println("Usage: gif <move log> <animation>, a move log written with -move-log")
+			return
+		}
+		if err := exportGIF(flag.Arg(1), flag.Arg(2)); err != nil {
+			fmt.Println("Failed to export:", err)
+			return
+		}
+		fmt.Println("Animation written to", flag.Arg(2))
+		return
+	}
+	if flag.Arg(0) == "html" {
+		if flag.NArg() != 3 {
+			fmt.Println("Usage: html <move log> <page>, a move log written with -move-log")
+			return
+		}
+		if err := exportHTML(flag.Arg(1), flag.Arg(2)); err != nil {
+			fmt.Println("Failed to export:", err)
+			return
+		}
+		fmt.Println("Replay page written to", flag.Arg(2))
+		return
+	}
+	if flag.Arg(0) == "serve" {
+		if flag.NArg() > 2 {
+			fmt.Println("Usage: serve [address], to play games over HTTP; address defaults to " + defaultServeAddress)
+			return
+		}
+		addr := defaultServeAddress
+		if flag.NArg() == 2 {
+			addr = flag.Arg(1)
+		}
+		if err := serveGames(addr); err != nil {
+			fmt.Println("Failed to serve:", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "engine" {
+		if flag.NArg() != 1 {
+			fmt.Println("Usage: engine, then the engine protocol on standard input")
+			return
+		}
+		runEngine(reader, os.Stdout)
+		return
+	}
+	if flag.Arg(0) == "discord" {
+		if flag.NArg() != 1 {
+			fmt.Println("Usage: discord, with the bot's token in DISCORD_BOT_TOKEN")
+			return
+		}
+		token, err := discordToken()
+		if err != nil {
+			fmt.Println("Failed to start the bot:", err)
+			return
+		}
+		if err := runDiscordBot(token); err != nil {
+			fmt.Println("Failed to run the bot:", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "import-bga" {
+		if flag.NArg() != 3 {
+			fmt.Println("Usage: import-bga <game log> <move log>, the log copied from a Board Game Arena game")
+			return
+		}
+		moves, err := importBGA(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			fmt.Println("Failed to import:", err)
+			return
+		}
+		fmt.Printf("Imported %d moves into %s; watch them with replay.\n", moves, flag.Arg(2))
+		return
+	}
+	if flag.Arg(0) == "export" {
+		if flag.NArg() != 3 {
+			fmt.Println("Usage: export <save> <picture>, the picture ending in .svg or .png")
+			return
+		}
+		state := &GameState{}
+		if err := state.loadSave(flag.Arg(1)); err != nil {
+			fmt.Println("Failed to load:", err)
+			return
+		}
+		if err := state.exportPicture(flag.Arg(2)); err != nil {
+			fmt.Println("Failed to export:", err)
+			return
+		}
+		fmt.Println("Picture written to", flag.Arg(2))
+		return
+	}
+	if flag.Arg(0) == "demo" {
+		runDemo(*demoDelay)
+		return
+	}
+	if flag.Arg(0) == "train" {
+		if *rlFile == "" {
+			fmt.Println("train needs -rl-weights to know where to save")
+			return
+		}
+		seedRNG(time.Now().UnixNano())
+		w, err := loadRLWeights(*rlFile)
+		if errors.Is(err, os.ErrNotExist) {
+			w, err = initialRLWeights(), nil
+		}
+		if err != nil {
+			fmt.Println("Failed to load weights:", err)
+			return
+		}
+		trainRL(w, *trainGames, 0.01, 0.1, func(msg string) { fmt.Println(msg) })
+		if err := w.save(*rlFile); err != nil {
+			fmt.Println("Failed to save weights:", err)
+			return
+		}
+		fmt.Printf("Saved weights after %d games to %s\n", w.Games, *rlFile)
+		return
+	}
+	if *rlFile != "" {
+		w, err := loadRLWeights(*rlFile)
+		if err != nil {
+			fmt.Println("Failed to load weights:", err)
+			return
+		}
+		registerStrategy(&rlStrategy{w: w})
+	}
+	if *modelFile != "" {
+		m, err := loadEvalModel(*modelFile)
+		if err != nil {
+			fmt.Println("Failed to load evaluation model:", err)
+			return
+		}
+		registerStrategy(modelStrategy{model: m})
+	}
+	ai, err := lookupStrategy(*aiName)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defaultStrategy = ai
 
-	fmt.Print("Play or Analyze? (p/a): ")
-	mode, _ := reader.ReadString('\n')
-	mode = strings.TrimSpace(strings.ToLower(mode))
-	if mode == "a" || mode == "analyze" {
-		state.Analyze = true
-		fmt.Println("Analyze mode selected — manual board setup enabled.")
-	} else {
-		state.Analyze = false
-		fmt.Println("Play mode selected — automatic setup and draw pile enabled.")
+	seed := *seedFlag
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if flag.Arg(0) == "openings" {
+		if *archive == "" {
+			fmt.Println("openings needs -archive")
+			return
+		}
+		games, err := readArchive(*archive)
+		if err != nil {
+			fmt.Println("Failed to read archive:", err)
+			return
+		}
+		printOpeningStats(os.Stdout, games)
+		return
 	}
+	if flag.Arg(0) == "achievements" {
+		p, err := loadProfile(*profile)
+		if err != nil {
+			fmt.Println("Failed to read profile:", err)
+			return
+		}
+		printProfile(os.Stdout, p)
+		return
+	}
+	if flag.Arg(0) == "ratings" {
+		runRatings()
+		return
+	}
+	if flag.Arg(0) == "profiles" {
+		runProfiles(flag.Args()[1:])
+		return
+	}
+	if flag.Arg(0) == "tournament" {
+		names := flag.Args()[1:]
+		if len(names) == 0 {
+			names = strategyNames()
+		}
+		table, wins, err := runTournament(names, *games, seed)
+		if err != nil {
+			fmt.Println("Tournament failed:", err)
+			return
+		}
+		printTournament(os.Stdout, table, wins)
+		return
+	}
+	if flag.Arg(0) == "campaign" {
+		if *profile == "" {
+			fmt.Println("campaign needs -profile to keep your progress")
+			return
+		}
+		n := -1
+		if arg := flag.Arg(1); arg == "list" {
+			p, err := loadProfile(*profile)
+			if err != nil {
+				fmt.Println("Failed to read profile:", err)
+				return
+			}
+			printCampaign(os.Stdout, p)
+			return
+		} else if arg != "" {
+			stage, err := strconv.Atoi(arg)
+			if err != nil || stage < 1 || stage > len(campaign) {
+				fmt.Printf("Campaign stages are 1-%d, or list.\n", len(campaign))
+				return
+			}
+			n = stage - 1
+		}
+		seedRNG(seed)
+		if err := playCampaign(*profile, n); err != nil {
+			fmt.Println("Campaign:", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "bracket" {
+		entrants, err := parseEntrants(flag.Args()[1:])
+		if err != nil {
+			fmt.Println("Bracket:", err)
+			return
+		}
+		seedRNG(seed)
+		champion, err := runBracket(*bracketFormat, entrants, *bracketGames, os.Stdout)
+		if err != nil {
+			fmt.Println("Bracket stopped:", err)
+			return
+		}
+		fmt.Printf("%s wins the bracket!\n", champion.Name)
+		return
+	}
+	if flag.Arg(0) == "puzzle" || flag.Arg(0) == "daily" {
+		date := ""
+		if flag.Arg(0) == "daily" {
+			seed, date = dailySeed(time.Now())
+		}
+		state, err := newPuzzle(seed, *puzzleDraws)
+		if err != nil {
+			fmt.Println("Failed to deal puzzle:", err)
+			return
+		}
+		state.ArchivePath, state.ProfilePath = *archive, *profile
+		state.BlunderMargin = *blunderMargin
+		state.Puzzle.Daily = date
+		if date != "" {
+			fmt.Printf("Daily challenge for %s: complete the board within %d draws.\n", date, *puzzleDraws)
+		} else {
+			fmt.Printf("Solo puzzle #%d: complete the board within %d draws.\n", seed, *puzzleDraws)
+		}
+		if script != nil {
+			script.state = state
+		}
+		if *moveLogFile != "" {
+			if state.MoveLog, err = newMoveLog(*moveLogFile); err != nil {
+				fmt.Println("Failed to start the move log:", err)
+				return
+			}
+			defer state.MoveLog.Close()
+		}
+		if *evalCSVFile != "" {
+			if state.EvalExport, err = newEvalExport(*evalCSVFile); err != nil {
+				fmt.Println("Failed to start the evaluation export:", err)
+				return
+			}
+			defer state.EvalExport.Close()
+		}
+		state.PrettyPrintBoardsGridCentered()
+		state.playGame()
+		return
+	}
+	seedRNG(seed)
+
+	if setup.player != "" {
+		if profilesDir == "" {
+			fmt.Println("-player needs -profiles, the directory the profiles are kept in")
+			return
+		}
+		if err := usePlayerProfile(setup.player); err != nil {
+			fmt.Println("Failed to read profile:", err)
+			return
+		}
+	}
+	if setup.paste {
+		if setup.position != "" {
+			fmt.Println("Give -position or -paste, not both.")
+			return
+		}
+		fmt.Println("Paste the boards as the game draws them, or rows of tiles and dots, then a blank line:")
+		position, err := boardArtPosition(readBoardArt(reader))
+		if err != nil {
+			fmt.Println("Invalid board art:", err)
+			return
+		}
+		fmt.Println("Read the position", position)
+		setup.position = position
+	}
+	csvFile := setup.load
 	if csvFile != "" {
+		csvFile = findSave(csvFile)
+	}
+	if !setup.has("load") && setup.position == "" {
+		if csvFile = promptResume(*autosaveDir); csvFile == "" {
+			csvFile = promptLoad()
+		}
+	}
+
+	state := &GameState{Heuristics: defaultHeuristics, ArchivePath: *archive, ProfilePath: *profile, BlunderMargin: *blunderMargin, AutosaveDir: *autosaveDir}
+	if script != nil {
+		script.state = state
+	}
+	if *heuristicsFile != "" {
+		h, err := loadHeuristics(*heuristicsFile)
+		if err != nil {
+			fmt.Println("Failed to load heuristics:", err)
+			return
+		}
+		state.Heuristics = h
+	}
+	if *risk {
+		state.Heuristics.RiskAware = true
+	}
+	if *tilesSpec != "" {
+		d, err := parseDistribution(*tilesSpec, maxTileRange)
+		if err != nil {
+			fmt.Println("Invalid -tiles:", err)
+			return
+		}
+		state.Distribution = d
+	}
+	state.Seed = seed
+
+	// a JSON save, a CSV save with settings or a position string brings
+	// its rules along
+	rulesGiven := isJSONSave(csvFile) || setup.position != "" || hasCSVSettings(csvFile)
+	if setup.position != "" {
+		if err := state.decodePosition(setup.position); err != nil {
+			fmt.Println("Invalid position:", err)
+			return
+		}
+		// the first -humans seats are people's, the rest the computer's
+		for i, b := range state.Boards {
+			if b.IsAi = i >= setup.humans; b.IsAi {
+				b.Name = state.computerName()
+			}
+		}
+		fmt.Println("Starting from the position given.")
+	} else if isJSONSave(csvFile) {
+		if err := state.loadFromJSON(csvFile); err != nil {
+			fmt.Println("Failed to load:", err)
+			return
+		}
+		fmt.Println("Loaded game from", csvFile)
+	} else if rulesGiven {
 		if err := state.loadFromCSV(csvFile); err != nil {
 			fmt.Println("Failed to load:", err)
 			return
 		}
 		fmt.Println("Loaded game from", csvFile)
 	} else {
-		state.setUpBoards()
+		mode := "p"
+		if setup.has("analyze") {
+			if setup.analyze {
+				mode = "a"
+			}
+		} else {
+			fmt.Print("Play or Analyze? (p/a): ")
+			mode, _ = reader.ReadString('\n')
+			mode = strings.TrimSpace(strings.ToLower(mode))
+		}
+		if mode == "a" || mode == "analyze" {
+			state.Analyze = true
+			fmt.Println(term.text("Analyze mode selected — manual board setup enabled."))
+			fmt.Println("Type note and a few words to annotate a turn, eval to keep the best moves for the tile in hand, notes to list them.")
+		} else {
+			state.Analyze = false
+			fmt.Println(term.text("Play mode selected — automatic setup and draw pile enabled."))
+		}
+		state.DiagonalRule = setup.yesNo("increasing-diagonal", setup.increasingDiagonal, promptDiagonalRule)
+		if csvFile != "" {
+			if err := state.loadFromCSV(csvFile); err != nil {
+				fmt.Println("Failed to load:", err)
+				return
+			}
+			fmt.Println("Loaded game from", csvFile)
+		} else if err := state.setUpBoards(); err != nil {
+			fmt.Println("Failed to set up:", err)
+			return
+		}
+	}
+	if *control != "" {
+		if err := state.takeControl(*control); err != nil {
+			fmt.Println("Cannot take control:", err)
+			return
+		}
+		fmt.Println("Testing mode: you control seats", *control)
+	}
+	if !rulesGiven {
+		state.BrunoVariant = setup.yesNo("bruno", setup.bruno, promptBrunoVariant)
+		if state.BrunoVariant && (setup.has("bruno-along") || setup.has("bruno-chain")) {
+			if state.Bruno, err = setup.brunoRules(); err != nil {
+				fmt.Println("Invalid Bruno rules:", err)
+				return
+			}
+		} else if state.BrunoVariant {
+			state.Bruno = promptBrunoRules()
+		}
+		state.NonDecreasing = setup.yesNo("nondecreasing", setup.nonDecreasing, promptNonDecreasing)
+		state.ForcedTable = setup.yesNo("forced-table", setup.forcedTable, promptForcedTable)
+		state.OpenPile = setup.yesNo("open-pile", setup.openPile, promptOpenPile)
+		state.StealSwap = setup.yesNo("steal", setup.steal, promptStealSwap)
+		if setup.has("hand") {
+			if state.HandSize = setup.hand; state.HandSize < 0 || state.HandSize > maxHandSize {
+				fmt.Printf("-hand must be 0-%d\n", maxHandSize)
+				return
+			}
+		} else {
+			state.HandSize = promptHandSize()
+		}
+		if state.HandSize > 0 && !state.Analyze {
+			state.dealHands()
+		}
+		if csvFile != "" {
+			if err := state.checkPosition(state.step()); err != nil {
+				fmt.Println("Failed to load:", err)
+				return
+			}
+		}
+	}
+	if !state.Analyze {
+		if setup.has("clock") {
+			if setup.clock > 0 {
+				state.Clock = newClock(setup.clock, len(state.Boards), setup.clockForfeit)
+			}
+		} else {
+			state.Clock = promptClock(len(state.Boards))
+		}
+		if state.Clock != nil {
+			// the clock reads ahead in the background, so no editing
+			if editor != nil {
+				editor.plain = true
+			}
+			reader = clockReader{newTimedReader(reader), state}
+		}
+	}
+	if *useTUI {
+		switch {
+		case state.Analyze:
+			fmt.Println("The terminal UI is for play mode; using the prompts.")
+		case state.Clock != nil:
+			fmt.Println("The terminal UI does not run the chess clock; using the prompts.")
+		case screenReader:
+			fmt.Println("The terminal UI draws the boards; using the prompts for the screen reader.")
+		default:
+			if screen, err = startTUI(); err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer stopTUI()
+		}
+	}
+	// a piped game only waits when asked to, so scripts stay fast
+	if !state.Analyze && state.onlyComputers() && *watchDelay > 0 && (isTerminal(os.Stdin) || setup.given["watch-delay"]) {
+		spectator = startSpectator(*watchDelay)
+		defer stopSpectator()
+	}
+	if *abSpec != "" {
+		seat := *abSeat
+		if seat < 0 {
+			for i, b := range state.Boards {
+				if b.IsAi {
+					seat = i
+					break
+				}
+			}
+		}
+		if seat < 0 || seat >= len(state.Boards) || !state.Boards[seat].IsAi {
+			fmt.Println("A/B mode needs a computer seat")
+			return
+		}
+		ab, err := newABTest(*abSpec, seat, *abLog)
+		if err != nil {
+			fmt.Println("A/B mode:", err)
+			return
+		}
+		defer ab.Close()
+		state.ABTest = ab
+		fmt.Printf("A/B mode: seat %d alternates %s (even turns) and %s (odd turns), logging to %s\n",
+			seat, ab.A.Name(), ab.B.Name(), *abLog)
+	}
+	if *moveLogFile != "" {
+		if state.MoveLog, err = newMoveLog(*moveLogFile); err != nil {
+			fmt.Println("Failed to start the move log:", err)
+			return
+		}
+		defer state.MoveLog.Close()
+	}
+	if *evalCSVFile != "" {
+		if state.EvalExport, err = newEvalExport(*evalCSVFile); err != nil {
+			fmt.Println("Failed to start the evaluation export:", err)
+			return
+		}
+		defer state.EvalExport.Close()
+	}
+	if state.Match != nil {
+		fmt.Printf("Resuming the match, %d of %d rounds played.\n", state.Match.Played, state.Match.Rounds)
+		state.playMatch(true)
+		state.clearRunning()
+		return
+	}
+	if *rounds > 1 && !state.Analyze {
+		state.Match = newMatch(*rounds, len(state.Boards))
+		state.playMatch(false)
+		state.clearRunning()
+		return
 	}
-	state.BrunoVariant = promptBrunoVariant()
 	state.PrettyPrintBoardsGridCentered()
 	state.playGame()
+	state.clearRunning()
 
 }
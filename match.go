@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// placePoints awards match points by finishing place in a round.
+var placePoints = []int{5, 3, 2, 1}
+
+// Match is a series of rounds between the same seats. Each round is dealt
+// afresh; the points for every round's finishing order add up to decide the
+// match.
+type Match struct {
+	Rounds int
+	Played int
+	Points []int   // per seat, running total
+	Wins   []int   // rounds won per seat
+	Orders [][]int // finishing order of every round played
+}
+
+func newMatch(rounds, seats int) *Match {
+	return &Match{Rounds: rounds, Points: make([]int, seats), Wins: make([]int, seats)}
+}
+
+// check makes sure a saved match fits a game of seats boards.
+func (m *Match) check(seats int) error {
+	if len(m.Points) != seats || len(m.Wins) != seats {
+		return fmt.Errorf("standings for %d seats in a game of %d", len(m.Points), seats)
+	}
+	if m.Played < 0 || m.Played >= m.Rounds || len(m.Orders) != m.Played {
+		return fmt.Errorf("%d of %d rounds played with %d results", m.Played, m.Rounds, len(m.Orders))
+	}
+	for _, order := range m.Orders {
+		for _, seat := range order {
+			if seat < 0 || seat >= seats {
+				return fmt.Errorf("round result with seat %d", seat)
+			}
+		}
+	}
+	return nil
+}
+
+// finishingOrder puts the round's winner first, then the other seats by
+// fewest empty cells.
+func (state *GameState) finishingOrder(winner int) []int {
+	order := []int{}
+	if winner >= 0 {
+		order = append(order, winner)
+	}
+	for _, seat := range state.ranking() {
+		if seat != winner {
+			order = append(order, seat)
+		}
+	}
+	return order
+}
+
+// award adds a round's points and result to the match.
+func (m *Match) award(order []int, winner int) {
+	for place, seat := range order {
+		if place < len(placePoints) {
+			m.Points[seat] += placePoints[place]
+		}
+	}
+	if winner >= 0 {
+		m.Wins[winner]++
+	}
+	m.Orders = append(m.Orders, order)
+	m.Played++
+}
+
+// leaders returns the seats with the most points.
+func (m *Match) leaders() []int {
+	best, seats := -1, []int{}
+	for seat, p := range m.Points {
+		switch {
+		case p > best:
+			best, seats = p, []int{seat}
+		case p == best:
+			seats = append(seats, seat)
+		}
+	}
+	return seats
+}
+
+// dealRound clears the boards, table and pile and deals the next round,
+// rotating who starts.
+func (state *GameState) dealRound() error {
+	state.Draw, state.Table, state.History, state.Undo = nil, nil, nil, nil
+	state.initDrawStack(len(state.Boards))
+	for _, b := range state.Boards {
+		b.Grid, b.Hand, b.Resigned = [maxBoardSize][maxBoardSize]int{}, nil, false
+		state.blockHoles(b)
+		if err := state.arrangeDiagonal(b); err != nil {
+			return err
+		}
+	}
+	state.dealHands()
+	if state.Clock != nil {
+		state.Clock.reset(len(state.Boards))
+	}
+	state.Current = state.Match.Played % len(state.Boards)
+	return nil
+}
+
+// playRound plays the current deal to its end. It returns the winner, -1
+// for none, and false if the players quit instead.
+func (state *GameState) playRound() (winner int, finished bool) {
+	state.Over = false
+	state.playGame()
+	if !state.Over {
+		return -1, false
+	}
+	return state.Winner, true
+}
+
+// playMatch plays every round of the match with the scoreboard in between.
+// A match resumed from a save first plays out the round it was saved in.
+func (state *GameState) playMatch(resumed bool) {
+	m := state.Match
+	if resumed && m.Played > 0 {
+		state.printScoreboard(os.Stdout)
+	}
+	for m.Played < m.Rounds {
+		if m.Played > 0 && !resumed {
+			if err := state.dealRound(); err != nil {
+				fmt.Println("Failed to deal:", err)
+				return
+			}
+		}
+		resumed = false
+		fmt.Printf("=== Round %d of %d ===\n", m.Played+1, m.Rounds)
+		state.PrettyPrintBoardsGridCentered()
+		winner, finished := state.playRound()
+		if !finished {
+			fmt.Println("Match abandoned.")
+			return
+		}
+		m.award(state.finishingOrder(winner), winner)
+		state.printScoreboard(os.Stdout)
+	}
+	leaders := m.leaders()
+	if len(leaders) > 1 {
+		fmt.Print("The match is tied between")
+		for _, seat := range leaders {
+			fmt.Printf(" %s", state.seatLabel(seat))
+		}
+		fmt.Println(".")
+		return
+	}
+	fmt.Printf("%s wins the match with %d points!\n", state.seatLabel(leaders[0]), m.Points[leaders[0]])
+}
+
+// printScoreboard shows the running match standings, leader first.
+func (state *GameState) printScoreboard(w io.Writer) {
+	m := state.Match
+	order := make([]int, len(m.Points))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return m.Points[order[a]] > m.Points[order[b]] })
+	fmt.Fprintf(w, "--- Scoreboard after round %d of %d ---\n", m.Played, m.Rounds)
+	fmt.Fprintf(w, "%-18s %6s %6s %6s\n", "Seat", "Points", "Wins", "Last")
+	last := map[int]int{}
+	if n := len(m.Orders); n > 0 {
+		for place, seat := range m.Orders[n-1] {
+			last[seat] = place + 1
+		}
+	}
+	for _, seat := range order {
+		fmt.Fprintf(w, "%-18s %6d %6d %6s\n", state.seatLabel(seat), m.Points[seat], m.Wins[seat], ordinal(last[seat]))
+	}
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch n {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The TUI takes the mouse too: a click on a table tile takes it, a click on
+// the pile line draws, and while placing a click on a cell puts the tile
+// there. Hovering over a cell tells the values it can still take. The
+// terminal reports the mouse in the SGR protocol, which xterm and most of
+// its successors speak.
+
+const (
+	mouseOn  = "\033[?1003h\033[?1006h" // report every move, in SGR form
+	mouseOff = "\033[?1003l\033[?1006l"
+)
+
+// Kinds of thing under the pointer.
+const (
+	overNothing = iota
+	overTableTile
+	overPile
+	overCell
+)
+
+// mouseTarget is what the pointer is over.
+type mouseTarget struct {
+	kind int
+	tile int // overTableTile
+	seat int // overCell
+	cell Cell
+}
+
+// tileSpan is where a table tile's label is on screen.
+type tileSpan struct {
+	from, to int // columns, 1-based and inclusive
+	tile     int
+}
+
+// screenLayout places the TUI's clickable parts, worked out from the same
+// geometry the renderer draws with. Lines and columns are 1-based, as the
+// terminal reports them.
+type screenLayout struct {
+	table     []tileSpan
+	tableLine int
+	boardsTop int // line of the first row of boards' headers
+	rowHeight int // lines per row of boards
+	perRow    int
+	boards    int
+	pileLine  int // the TUI's pile count under the boards
+	pileWidth int
+}
+
+// layout works out where redraw puts everything for state.
+func (state *GameState) layout() screenLayout {
+	_, boardWidth := boardGeometry()
+	perRow := boardsPerRow(boardWidth, len(state.Boards))
+	totalWidth := boardWidth*perRow + (perRow-1)*2
+
+	l := screenLayout{tableLine: 3, perRow: perRow, boards: len(state.Boards)}
+	tiles := append([]int{}, state.Table...)
+	sort.Ints(tiles)
+	labels := make([]string, len(tiles))
+	for i, t := range tiles {
+		labels[i] = tileLabel(t)
+	}
+	content := strings.Join(labels, ",")
+	x := 2 + (totalWidth-len(content))/2 // after the table's left border
+	for i, label := range labels {
+		l.table = append(l.table, tileSpan{from: x, to: x + len(label) - 1, tile: tiles[i]})
+		x += len(label) + 1
+	}
+
+	l.boardsTop = 5
+	if state.OpenPile {
+		l.boardsTop++
+	}
+	l.rowHeight = 3 + 2*BoardSize
+	if state.HandSize > 0 {
+		l.rowHeight++
+	}
+	groups := (len(state.Boards) + perRow - 1) / perRow
+	l.pileLine = l.boardsTop + groups*l.rowHeight
+	l.pileWidth = len(fmt.Sprintf("Pile: %d tiles", len(state.Draw)))
+	return l
+}
+
+// at finds what is at column x of line y.
+func (l screenLayout) at(x, y int) mouseTarget {
+	if y == l.tableLine {
+		for _, s := range l.table {
+			if x >= s.from && x <= s.to {
+				return mouseTarget{kind: overTableTile, tile: s.tile}
+			}
+		}
+		return mouseTarget{}
+	}
+	if y == l.pileLine && x >= 1 && x <= l.pileWidth {
+		return mouseTarget{kind: overPile}
+	}
+	if y < l.boardsTop || y >= l.pileLine {
+		return mouseTarget{}
+	}
+	group, line := (y-l.boardsTop)/l.rowHeight, (y-l.boardsTop)%l.rowHeight
+	// cells are on the odd lines after the header and the column letters
+	if line < 3 || line%2 == 0 || (line-3)/2 >= BoardSize {
+		return mouseTarget{}
+	}
+	gutter, boardWidth := boardGeometry()
+	k, in := (x-1)/(boardWidth+2), (x-1)%(boardWidth+2)
+	seat := group*l.perRow + k
+	if k >= l.perRow || seat >= l.boards {
+		return mouseTarget{}
+	}
+	in -= gutter + 1 // past the row number and the left border
+	if in < 0 || in%(cellWidth+1) == cellWidth || in/(cellWidth+1) >= BoardSize {
+		return mouseTarget{}
+	}
+	return mouseTarget{kind: overCell, seat: seat, cell: Cell{R: (line - 3) / 2, C: in / (cellWidth + 1)}}
+}
+
+// parseMouse reads the rest of an SGR mouse report after "ESC [ <": the
+// button, column and line, then M for a press or m for a release.
+func parseMouse(read func() (byte, error)) (button, x, y int, press bool, err error) {
+	var field []byte
+	var nums []int
+	for {
+		b, err := read()
+		if err != nil {
+			return 0, 0, 0, false, err
+		}
+		if b != ';' && b != 'M' && b != 'm' {
+			field = append(field, b)
+			continue
+		}
+		n, err := strconv.Atoi(string(field))
+		if err != nil {
+			return 0, 0, 0, false, fmt.Errorf("bad mouse report %q", field)
+		}
+		nums, field = append(nums, n), nil
+		if b == ';' {
+			continue
+		}
+		if len(nums) != 3 {
+			return 0, 0, 0, false, fmt.Errorf("mouse report with %d numbers", len(nums))
+		}
+		return nums[0], nums[1], nums[2], b == 'M', nil
+	}
+}
+
+// cellHint tells what a hovered cell can still take.
+func (state *GameState) cellHint(seat int, cell Cell) string {
+	v := state.Boards[seat].Grid[cell.R][cell.C]
+	if v == Blocked {
+		return fmt.Sprintf("%s is blocked.", cell)
+	}
+	if v != 0 {
+		return fmt.Sprintf("%s holds %s.", cell, tileLabel(v))
+	}
+	defer state.asSeat(seat)()
+	lo, hi := state.cellRange(cell.R, cell.C)
+	switch {
+	case lo > hi:
+		return fmt.Sprintf("Only a wildcard fits %s.", cell)
+	case lo == hi:
+		return fmt.Sprintf("%s takes %d.", cell, lo)
+	}
+	return fmt.Sprintf("%s takes %d-%d.", cell, lo, hi)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// With -move-log every draw and move is written to a file as the game goes,
+// one JSON object per line, with who made it and when. Unlike the session
+// transcript it holds only the play, so replays, reviews and bug reports can
+// read it back without picking through the prompts.
+
+// MoveLog is the file the moves are logged to.
+type MoveLog struct {
+	f   io.WriteCloser
+	enc *json.Encoder
+}
+
+// LoggedMove is one line of the move log.
+type LoggedMove struct {
+	Time    time.Time  `json:"time"`
+	Turn    int        `json:"turn"` // from 1, as the display counts
+	Seat    int        `json:"seat"`
+	Player  string     `json:"player"`
+	Type    string     `json:"type"` // start, draw, place, swap, discard, steal, undo, resign, note or end
+	Tile    int        `json:"tile,omitempty"`
+	From    string     `json:"from,omitempty"` // draws: pile, table or hand
+	Board   *int       `json:"board,omitempty"`
+	Cell    *Cell      `json:"cell,omitempty"`
+	OldTile int        `json:"old_tile,omitempty"` // swaps: the tile sent to the table
+	Message string     `json:"message,omitempty"`  // end: how the game ended
+	Game    *SavedGame `json:"game,omitempty"`     // start and undo: the position
+
+	Annotation *Annotation `json:"annotation,omitempty"` // note: what was written
+}
+
+// newMoveLog starts a move log at path, replacing any earlier one.
+func newMoveLog(path string) (*MoveLog, error) {
+	f, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MoveLog{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (l *MoveLog) Close() error {
+	return l.f.Close()
+}
+
+// logEvent writes e, filling in the time, the turn and the player. Nothing
+// is written without a move log.
+func (state *GameState) logEvent(e LoggedMove) {
+	if state.MoveLog == nil {
+		return
+	}
+	e.Time, e.Turn = time.Now(), state.Turn+1
+	if e.Seat >= 0 && e.Seat < len(state.Boards) {
+		e.Player = state.seatLabel(e.Seat)
+	}
+	state.MoveLog.enc.Encode(e)
+}
+
+// logDraw logs the tile the current seat drew.
+func (state *GameState) logDraw(move Move) {
+	state.logEvent(LoggedMove{Seat: state.Current, Type: "draw", Tile: move.Tile, From: drawnFrom(move)})
+}
+
+// logMove logs a move the current seat made; old is the tile a swap sent
+// to the table.
+func (state *GameState) logMove(move Move, old int) {
+	e := LoggedMove{Seat: state.Current, Type: moveTypeNames[move.Type], Tile: move.Tile, Cell: move.Cell}
+	if move.Cell != nil {
+		board := state.moveSeat(state.Current, move)
+		if move.Type == Steal {
+			board = move.Target
+		}
+		e.Board = &board
+	}
+	if move.Type == Swap {
+		e.OldTile = old
+	}
+	state.logEvent(e)
+}
+
+// logPosition logs the position as it stands, at the start of a game or
+// after a move is taken back, so a replay can pick up from it.
+func (state *GameState) logPosition(kind string) {
+	if state.MoveLog == nil {
+		return
+	}
+	g := state.saved()
+	state.logEvent(LoggedMove{Seat: state.Current, Type: kind, Game: &g})
+}
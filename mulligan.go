@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mulliganCost is the average distance of a starting tile from its best
+// diagonal cell above which a computer redraws.
+const mulliganCost = 1.5
+
+// returnToPile shuffles tiles back into the draw pile.
+func (state *GameState) returnToPile(tiles ...int) {
+	if len(tiles) == 0 {
+		return
+	}
+	state.Draw = append(state.Draw, tiles...)
+	rng.Shuffle(len(state.Draw), func(i, j int) { state.Draw[i], state.Draw[j] = state.Draw[j], state.Draw[i] })
+}
+
+// wantsMulligan decides whether board's player rejects the starting tiles
+// dealt. Computers redraw when even the best arrangement leaves the tiles
+// far from the cells they suit; humans are asked.
+func (state *GameState) wantsMulligan(board *Board, tiles []int) bool {
+	if board.IsAi {
+		return state.arrangementCost(tiles) > mulliganCost
+	}
+	fmt.Printf("Your starting tiles: %s. Take a mulligan and redraw them? (y/N): ", handLabel(tiles))
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
+
+// arrangementCost is the average diagonalCost of tiles in their best
+// arrangement.
+func (state *GameState) arrangementCost(tiles []int) float64 {
+	if len(tiles) == 0 {
+		return 0
+	}
+	total := 0.0
+	for i, d := range state.bestArrangement(tiles) {
+		total += state.diagonalCost(tiles[i], d)
+	}
+	return total / float64(len(tiles))
+}
+
+func promptMulligan() bool {
+	fmt.Print("Allow each player one mulligan to redraw their starting diagonal? (y/N): ")
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Seats can carry names: humans give theirs at setup and every computer
+// gets one of these. Unnamed seats keep "Player 0" and "Computer 1".
+var computerNames = []string{
+	"Robo Rita", "Deep Tile", "Captain Count", "Ada Abacus",
+	"Sir Sorts-a-Lot", "Lady Luck", "Max Ascending", "The Incrementor",
+}
+
+// maxNameLength keeps names short enough for the board headers.
+const maxNameLength = 20
+
+// checkName tidies a name and refuses ones that would garble the saves and
+// board headers.
+func checkName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	switch {
+	case len(name) > maxNameLength:
+		return "", fmt.Errorf("%q is longer than %d characters", name, maxNameLength)
+	case strings.ContainsAny(name, ",\""):
+		return "", fmt.Errorf("%q has a comma or quote", name)
+	}
+	return name, nil
+}
+
+// computerName picks a name no other seat has yet.
+func (state *GameState) computerName() string {
+	taken := map[string]bool{}
+	for _, b := range state.Boards {
+		taken[b.Name] = true
+	}
+	var free []string
+	for _, name := range computerNames {
+		if !taken[name] {
+			free = append(free, name)
+		}
+	}
+	if len(free) == 0 {
+		return ""
+	}
+	return free[rng.Intn(len(free))]
+}
+
+// promptName asks a human seat's name, blank for the numbered label.
+func promptName(seat int) string {
+	for {
+		fmt.Printf(tr("Name for player %d (blank for Player %d): "), seat, seat)
+		line, _ := reader.ReadString('\n')
+		name, err := checkName(line)
+		if err == nil {
+			return name
+		}
+		fmt.Println(err)
+	}
+}
+
+// names lists every seat's name, empty for unnamed ones, or nil when no
+// seat has one.
+func (state *GameState) names() []string {
+	names := make([]string, len(state.Boards))
+	named := false
+	for i, b := range state.Boards {
+		names[i], named = b.Name, named || b.Name != ""
+	}
+	if !named {
+		return nil
+	}
+	return names
+}
@@ -0,0 +1,60 @@
+package main
+
+import "strconv"
+
+// Number words for narration, so text-to-speech engines read "seventeen"
+// rather than spelling out digits. Languages without a table fall back to
+// digits.
+var numberWordTables = map[string]struct {
+	ones     []string // 0-19 (0-29 for Spanish, which writes 21-29 as one word)
+	tens     []string // indexed by n/10
+	hundreds []string // indexed by n/100
+	join     func(tens, ones string) string
+	hundred  func(hundreds, rest string) string
+}{
+	"en": {
+		ones: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+			"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"},
+		tens: []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"},
+		hundreds: []string{"", "one hundred", "two hundred", "three hundred", "four hundred", "five hundred",
+			"six hundred", "seven hundred", "eight hundred", "nine hundred"},
+		join:    func(t, o string) string { return t + "-" + o },
+		hundred: func(h, rest string) string { return h + " and " + rest },
+	},
+	"es": {
+		ones: []string{"cero", "uno", "dos", "tres", "cuatro", "cinco", "seis", "siete", "ocho", "nueve", "diez",
+			"once", "doce", "trece", "catorce", "quince", "dieciséis", "diecisiete", "dieciocho", "diecinueve",
+			"veinte", "veintiuno", "veintidós", "veintitrés", "veinticuatro", "veinticinco", "veintiséis",
+			"veintisiete", "veintiocho", "veintinueve"},
+		tens: []string{"", "", "veinte", "treinta", "cuarenta", "cincuenta", "sesenta", "setenta", "ochenta", "noventa"},
+		hundreds: []string{"", "ciento", "doscientos", "trescientos", "cuatrocientos", "quinientos",
+			"seiscientos", "setecientos", "ochocientos", "novecientos"},
+		join:    func(t, o string) string { return t + " y " + o },
+		hundred: func(h, rest string) string { return h + " " + rest },
+	},
+}
+
+// numberWord spells n (0-999) in the given language, or returns its digits
+// when it can't.
+func numberWord(lang string, n int) string {
+	w, ok := numberWordTables[lang]
+	if !ok || n < 0 || n > 999 {
+		return strconv.Itoa(n)
+	}
+	if n < len(w.ones) {
+		return w.ones[n]
+	}
+	if n < 100 {
+		if n%10 == 0 {
+			return w.tens[n/10]
+		}
+		return w.join(w.tens[n/10], w.ones[n%10])
+	}
+	if n%100 == 0 {
+		if lang == "es" && n == 100 {
+			return "cien"
+		}
+		return w.hundreds[n/100]
+	}
+	return w.hundred(w.hundreds[n/100], numberWord(lang, n%100))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// cellRange is the values an empty cell can still take between its row and
+// column neighbours; lo > hi when no tile but a wildcard fits.
+func (state *GameState) cellRange(r, c int) (lo, hi int) {
+	lo1, hi1 := state.rowConstraints(r, c)
+	lo2, hi2 := state.colConstraints(r, c)
+	return max(lo1, lo2), min(hi1, hi2)
+}
+
+// printOdds lists every empty cell of the current seat's board with the
+// values it can take and how many of the unseen tiles, those still in the
+// pile, fall in that range. Table tiles are shown apart, as they can be
+// taken at will.
+func (state *GameState) printOdds(w io.Writer) {
+	board := state.Boards[state.Current]
+	unseen := len(state.Draw)
+	fmt.Fprintf(w, tr("Odds for %s's board, %d unseen tiles:\n"), state.seatLabel(state.Current), unseen)
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if board.Grid[r][c] != 0 {
+				continue
+			}
+			cell := Cell{R: r, C: c}
+			lo, hi := state.cellRange(r, c)
+			fits, onTable := 0, 0
+			for _, t := range state.Draw {
+				if t == Wildcard || (t >= lo && t <= hi) {
+					fits++
+				}
+			}
+			for _, t := range state.Table {
+				if t == Wildcard || (t >= lo && t <= hi) {
+					onTable++
+				}
+			}
+			values := fmt.Sprintf("%d-%d", lo, hi)
+			switch {
+			case lo > hi:
+				values = tr("none")
+			case lo == hi:
+				values = fmt.Sprint(lo)
+			}
+			pct := 0.0
+			if unseen > 0 {
+				pct = 100 * float64(fits) / float64(unseen)
+			}
+			fmt.Fprintf(w, tr("  %-3s %-7s %3d unseen (%4.1f%%), %d on the table\n"), cell, values, fits, pct, onTable)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// In the open-pile variant the top tile of the draw pile lies face up, so
+// every player knows what a pile draw gives before choosing pile or table.
+
+// pileVisible reports whether the current seat can see the pile's top
+// tile, through the open-pile variant or the peek handicap.
+func (state *GameState) pileVisible() bool {
+	return len(state.Draw) > 0 && (state.OpenPile || state.Boards[state.Current].Handicap.Peek)
+}
+
+// pileTopLabel describes the face-up pile tile for the renderer.
+func (state *GameState) pileTopLabel() string {
+	if len(state.Draw) == 0 {
+		return "Pile: empty"
+	}
+	return fmt.Sprintf("Pile: %d tiles, top %s", len(state.Draw), tileLabel(state.Draw[0]))
+}
+
+// prefersPile reports whether a computer that can see the pile's top tile
+// should draw it rather than make tableMove.
+func (state *GameState) prefersPile(tableMove Move) bool {
+	return state.pileVisible() && state.peekBeatsTable(tableMove)
+}
+
+func promptOpenPile() bool {
+	fmt.Print("Play with the top pile tile face up? (y/N): ")
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
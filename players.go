@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxPlayers is how many seats a game can have.
+const maxPlayers = 4
+
+// promptCount asks for a whole number from lo to hi, re-asking until it gets
+// one. A blank answer, or the end of the input, takes def.
+func promptCount(prompt string, lo, hi, def int) int {
+	for {
+		fmt.Print(prompt)
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return def
+		}
+		n, err := strconv.Atoi(line)
+		if err == nil && n >= lo && n <= hi {
+			return n
+		}
+		fmt.Printf(tr("Please enter a number from %d to %d.\n"), lo, hi)
+	}
+}
+
+// promptPlayers settles how many human and computer players there are, from
+// -humans and -computers or by asking. The counts asked for are re-asked
+// until the total is a playable 1-4, and then shown for confirmation.
+func promptPlayers() (humans, computers int, err error) {
+	for {
+		humans = setup.humans
+		if !setup.has("humans") {
+			humans = promptCount(tr("Number of human players (0-4, default 1): "), 0, maxPlayers, 1)
+		} else if humans < 0 || humans > maxPlayers {
+			return 0, 0, fmt.Errorf("-humans must be 0-4")
+		}
+		computers = setup.computers
+		if !setup.has("computers") {
+			computers = promptCount(tr("Number of computer players (0-4, default 1): "), 0, maxPlayers, 1)
+		} else if computers < 0 || computers > maxPlayers {
+			return 0, 0, fmt.Errorf("-computers must be 0-4")
+		}
+		asked := !setup.has("humans") || !setup.has("computers")
+		total := humans + computers
+		if total < 1 || total > maxPlayers {
+			if !asked {
+				return 0, 0, fmt.Errorf("-humans and -computers make %d players, a game has 1-4", total)
+			}
+			fmt.Printf(tr("A game has 1 to 4 players, not %d; try again.\n"), total)
+			continue
+		}
+		if !asked || confirmPlayers(humans, computers) {
+			return humans, computers, nil
+		}
+	}
+}
+
+// confirmPlayers shows the players chosen and asks whether to go ahead.
+func confirmPlayers(humans, computers int) bool {
+	fmt.Printf(tr("%d human and %d computer players. Go ahead? (Y/n): "), humans, computers)
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line != "n" && line != "no"
+}
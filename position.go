@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A position string holds a whole position on one line, to paste into a
+// chat, a bug report or -position instead of passing save files around. It
+// has four fields separated by spaces:
+//
+//	1.../.11../..14./...18|2.../.3../..7./...10 7,5,17,4 0 bruno+hand2
+//
+// The boards come first, separated by |. A board's rows are separated by /
+// and its cells by dots, an empty cell being left blank, # a hole and * a
+// wildcard; a hand follows the board after a ~. Then come the table ("-"
+// when empty), the seat to move and the rules, joined by + ("classic" for
+// none). The draw pile is not kept: the tiles not in sight are shuffled
+// into it, as when loading a CSV save.
+
+// encodePosition writes the position string of the game.
+func (state *GameState) encodePosition() string {
+	boards := make([]string, len(state.Boards))
+	for i, b := range state.Boards {
+		rows := make([]string, BoardSize)
+		for r := 0; r < BoardSize; r++ {
+			cells := make([]string, BoardSize)
+			for c := 0; c < BoardSize; c++ {
+				if v := b.Grid[r][c]; v != 0 {
+					cells[c] = tileLabel(v)
+				}
+			}
+			rows[r] = strings.Join(cells, ".")
+		}
+		boards[i] = strings.Join(rows, "/")
+		if len(b.Hand) > 0 {
+			boards[i] += "~" + joinTiles(b.Hand)
+		}
+	}
+	table := "-"
+	if len(state.Table) > 0 {
+		table = joinTiles(state.Table)
+	}
+	return fmt.Sprintf("%s %s %d %s", strings.Join(boards, "|"), table, state.Current, state.positionRules())
+}
+
+// joinTiles lists tiles separated by commas.
+func joinTiles(tiles []int) string {
+	labels := make([]string, len(tiles))
+	for i, t := range tiles {
+		labels[i] = tileLabel(t)
+	}
+	return strings.Join(labels, ",")
+}
+
+// positionRules names the rules that change how a position plays.
+func (state *GameState) positionRules() string {
+	var rules []string
+	if state.BrunoVariant {
+		rules = append(rules, "bruno")
+	}
+	if state.ForcedTable {
+		rules = append(rules, "forcedtable")
+	}
+	if state.HandSize > 0 {
+		rules = append(rules, fmt.Sprintf("hand%d", state.HandSize))
+	}
+	if state.StealSwap {
+		rules = append(rules, "steal")
+	}
+	if state.OpenPile {
+		rules = append(rules, "openpile")
+	}
+	if state.DiagonalRule {
+		rules = append(rules, "increasingdiagonal")
+	}
+	if state.SharedPool {
+		rules = append(rules, "sharedpool")
+	}
+	if state.NonDecreasing {
+		rules = append(rules, "nondecreasing")
+	}
+	if state.End == EndPileScore {
+		rules = append(rules, "pilescore")
+	}
+	if state.Teams {
+		rules = append(rules, "teams")
+	}
+	if state.maxTile() != MaxTile {
+		rules = append(rules, fmt.Sprintf("tiles1-%d", state.maxTile()))
+	}
+	if state.Distribution != nil {
+		rules = append(rules, "dist"+state.Distribution.String())
+	}
+	if len(rules) == 0 {
+		return "classic"
+	}
+	return strings.Join(rules, "+")
+}
+
+// applyRules turns on the rules named in spec, as positionRules names them.
+func (state *GameState) applyRules(spec string) error {
+	var dist string
+	for _, rule := range strings.Split(spec, "+") {
+		switch {
+		case rule == "classic":
+		case rule == "bruno":
+			state.BrunoVariant = true
+		case rule == "forcedtable":
+			state.ForcedTable = true
+		case rule == "steal":
+			state.StealSwap = true
+		case rule == "openpile":
+			state.OpenPile = true
+		case rule == "increasingdiagonal":
+			state.DiagonalRule = true
+		case rule == "sharedpool":
+			state.SharedPool = true
+		case rule == "nondecreasing":
+			state.NonDecreasing = true
+		case rule == "pilescore":
+			state.End = EndPileScore
+		case rule == "teams":
+			state.Teams = true
+		case strings.HasPrefix(rule, "hand"):
+			n, err := strconv.Atoi(rule[len("hand"):])
+			if err != nil || n < 1 || n > maxHandSize {
+				return fmt.Errorf("rule %q: a hand holds 1-%d tiles", rule, maxHandSize)
+			}
+			state.HandSize = n
+		case strings.HasPrefix(rule, "tiles1-"):
+			n, err := strconv.Atoi(rule[len("tiles1-"):])
+			if err != nil || n < minTileRange() || n > maxTileRange {
+				return fmt.Errorf("rule %q: the highest tile is %d-%d", rule, minTileRange(), maxTileRange)
+			}
+			state.TileRange = n
+		case strings.HasPrefix(rule, "dist"):
+			dist = rule[len("dist"):]
+		default:
+			return fmt.Errorf("unknown rule %q", rule)
+		}
+	}
+	if dist != "" {
+		d, err := parseDistribution(dist, state.maxTile())
+		if err != nil {
+			return fmt.Errorf("rule dist: %w", err)
+		}
+		state.Distribution = d
+	}
+	return nil
+}
+
+// decodePosition sets the game up from a position string.
+func (state *GameState) decodePosition(s string) error {
+	fields := strings.Fields(s)
+	if len(fields) != 4 {
+		return fmt.Errorf("a position has 4 fields (boards, table, seat to move, rules), not %d", len(fields))
+	}
+	// the rules first, as the tile range bounds the tiles
+	if err := state.applyRules(fields[3]); err != nil {
+		return err
+	}
+
+	used := map[int]int{}
+	tiles := func(list string) ([]int, error) {
+		var out []int
+		for _, label := range strings.Split(list, ",") {
+			t, err := parseTile(label, state.maxTile())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, t)
+			used[t]++
+		}
+		return out, nil
+	}
+	boards := strings.Split(fields[0], "|")
+	if len(boards) > maxPlayers {
+		return fmt.Errorf("%d boards, a game has 1-%d", len(boards), maxPlayers)
+	}
+	if state.Teams && len(boards) != teamSeats {
+		return fmt.Errorf("team play needs %d boards, not %d", teamSeats, len(boards))
+	}
+	for i, spec := range boards {
+		b := &Board{}
+		spec, hand, hasHand := strings.Cut(spec, "~")
+		if hasHand {
+			var err error
+			if b.Hand, err = tiles(hand); err != nil {
+				return fmt.Errorf("board %d hand: %w", i, err)
+			}
+		}
+		rows := strings.Split(spec, "/")
+		if i == 0 {
+			if len(rows) < minBoardSize || len(rows) > maxBoardSize {
+				return fmt.Errorf("board 0 has %d rows, boards are %d-%d", len(rows), minBoardSize, maxBoardSize)
+			}
+			BoardSize = len(rows)
+		}
+		if len(rows) != BoardSize {
+			return fmt.Errorf("board %d has %d rows, board 0 has %d", i, len(rows), BoardSize)
+		}
+		for r, row := range rows {
+			cells := strings.Split(row, ".")
+			if len(cells) != BoardSize {
+				return fmt.Errorf("board %d row %d has %d cells, expected %d", i, r+1, len(cells), BoardSize)
+			}
+			for c, cell := range cells {
+				switch cell {
+				case "":
+				case blockedLabel:
+					b.Grid[r][c] = Blocked
+				default:
+					t, err := parseTile(cell, state.maxTile())
+					if err != nil {
+						return fmt.Errorf("board %d %s: %w", i, Cell{R: r, C: c}, err)
+					}
+					b.Grid[r][c] = t
+					used[t]++
+				}
+			}
+		}
+		state.Boards = append(state.Boards, b)
+	}
+	if fields[1] != "-" {
+		var err error
+		if state.Table, err = tiles(fields[1]); err != nil {
+			return fmt.Errorf("table: %w", err)
+		}
+	}
+	seat, err := strconv.Atoi(fields[2])
+	if err != nil || seat < 0 || seat >= len(state.Boards) {
+		return fmt.Errorf("seat to move %q is not a seat 0-%d", fields[2], len(state.Boards)-1)
+	}
+	state.Current = seat
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if state.Boards[0].Grid[r][c] == Blocked {
+				state.Holes = append(state.Holes, Cell{R: r, C: c})
+			}
+		}
+	}
+	if err := state.checkPosition(state.step()); err != nil {
+		return err
+	}
+	state.Draw = state.unusedTiles(used)
+	return nil
+}
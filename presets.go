@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Preset bundles the setup knobs of one style of game, so casual players
+// can pick a name instead of configuring each one.
+type Preset struct {
+	Name      string
+	About     string
+	BoardSize int
+	TileRange int // every player brings one set of tiles 1-TileRange
+	End       EndMode
+}
+
+// presets lists the named game styles; the first standard one is the default.
+var presets = []Preset{
+	{Name: "quick", About: "3x3 boards, tiles 1-12", BoardSize: 3, TileRange: 12},
+	{Name: "standard", About: "4x4 boards, tiles 1-20", BoardSize: standardBoardSize, TileRange: MaxTile},
+	{Name: "grande", About: "5x5 boards, tiles 1-30, fewest empty cells wins if the pile runs out", BoardSize: 5, TileRange: 30, End: EndPileScore},
+}
+
+const defaultPreset = "standard"
+
+func lookupPreset(name string) (Preset, bool) {
+	for _, p := range presets {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// applyPreset sets up the game for p. The board size is shared by every
+// board, so it is package state like the terminal.
+func (state *GameState) applyPreset(p Preset) {
+	BoardSize = p.BoardSize
+	state.TileRange = p.TileRange
+	state.End = p.End
+	if p.TileRange == MaxTile {
+		state.TileRange = 0
+	}
+}
+
+// promptPreset asks for a preset, or for each knob when the player picks
+// custom.
+func (state *GameState) promptPreset(players int) {
+	names := make([]string, 0, len(presets)+1)
+	for _, p := range presets {
+		fmt.Printf("  %-9s %s\n", p.Name, p.About)
+		names = append(names, p.Name)
+	}
+	fmt.Printf("  %-9s %s\n", "custom", "choose board size, holes, tile range, wildcards, tile distribution and end condition")
+	names = append(names, "custom")
+	for {
+		fmt.Printf("Game preset (%s; default %s): ", strings.Join(names, ", "), defaultPreset)
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = defaultPreset
+		}
+		if p, ok := lookupPreset(line); ok {
+			state.applyPreset(p)
+			return
+		}
+		if strings.EqualFold(line, "custom") {
+			state.promptCustomSetup(players)
+			return
+		}
+		fmt.Printf("Unknown preset %q.\n", line)
+	}
+}
+
+func (state *GameState) promptCustomSetup(players int) {
+	fmt.Printf("Board size (%d-%d, default %d): ", minBoardSize, maxBoardSize, standardBoardSize)
+	line, _ := reader.ReadString('\n')
+	BoardSize = standardBoardSize
+	if n, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && n >= minBoardSize && n <= maxBoardSize {
+		BoardSize = n
+	}
+	state.promptHoles()
+	fmt.Printf("Highest tile value (%d-%d, default %d): ", minTileRange(), maxTileRange, MaxTile)
+	line, _ = reader.ReadString('\n')
+	if n, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && n >= minTileRange() && n <= maxTileRange {
+		state.TileRange = n
+	}
+	fmt.Printf("Number of wildcard (%s) tiles (0-8, default 0): ", wildcardLabel)
+	line, _ = reader.ReadString('\n')
+	if n, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && n >= 0 && n <= 8 {
+		state.Wildcards = n
+	}
+	state.promptDistribution(players)
+	fmt.Print("If the pile runs out, rank boards by fewest empty cells? (y/N): ")
+	line, _ = reader.ReadString('\n')
+	if answer := strings.TrimSpace(strings.ToLower(line)); answer == "y" || answer == "yes" {
+		state.End = EndPileScore
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Every named human seat keeps a profile of its own in the -profiles
+// directory, found again by the name given at setup: games, wins, the cells
+// left empty, the rules played most and the settings of the last game.
+// -player picks a profile before setup and answers every setup question
+// not given on the command line the way its last game did.
+
+// profilesDir holds the named profiles; empty to not keep them.
+var profilesDir string
+
+// defaultProfilesDir keeps the named profiles beside the local profile.
+func defaultProfilesDir() string {
+	return filepath.Join(filepath.Dir(defaultProfilePath()), "profiles")
+}
+
+// profileFile is where the profile of the player called name is kept.
+func profileFile(name string) string {
+	return filepath.Join(profilesDir, profileSlug(name)+".json")
+}
+
+// profileSlug spells a name the way its profile's file is named.
+func profileSlug(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, name)
+}
+
+// loadNamedProfile reads the profile of the player called name, a new one
+// when they have none yet.
+func loadNamedProfile(name string) (*Profile, error) {
+	p, err := loadProfile(profileFile(name))
+	if err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		p.Name = name
+	}
+	return p, nil
+}
+
+// recordSeat adds a finished game to the profile of the player at seat.
+func (p *Profile) recordSeat(state *GameState, seat, winner int, now time.Time) {
+	p.Games++
+	if state.won(seat, winner) {
+		p.Wins++
+	}
+	p.EmptyCells += emptyCells(state.Boards[seat])
+	if p.Variants == nil {
+		p.Variants = map[string]int{}
+	}
+	for _, rule := range strings.Split(state.positionRules(), "+") {
+		p.Variants[rule]++
+	}
+	if !state.Boards[seat].IsAi {
+		p.Settings = state.setupSettings()
+	}
+	p.LastPlayed = now
+	for _, a := range achievements {
+		if a.earned(state, seat, winner) {
+			p.earn(a, now)
+		}
+	}
+}
+
+// updateNamedProfiles records the finished game in the profile of every
+// named human seat and of every computer strategy, and rates it.
+func (state *GameState) updateNamedProfiles(winner int) {
+	now := time.Now()
+	var seats []ratedSeat
+	loaded := map[string]*Profile{}
+	for seat, b := range state.Boards {
+		var path, name string
+		switch {
+		case b.IsAi:
+			name = state.strategyFor(seat).Name()
+			path = computerProfileFile(name)
+		case b.Name != "":
+			name, path = b.Name, profileFile(b.Name)
+		default:
+			continue
+		}
+		p := loaded[path]
+		if p == nil {
+			var err error
+			if p, err = loadProfile(path); err != nil {
+				fmt.Println("Failed to read profile:", err)
+				continue
+			}
+			if p.Name == "" {
+				p.Name = name
+			}
+			loaded[path] = p
+		}
+		p.recordSeat(state, seat, winner, now)
+		seats = append(seats, ratedSeat{seat: seat, path: path, p: p})
+	}
+	if state.rated() {
+		state.printRatings(rateGame(state, seats, winner))
+	}
+	for path, p := range loaded {
+		if err := p.save(path); err != nil {
+			fmt.Println("Failed to save profile:", err)
+		}
+	}
+}
+
+// averageEmpty is how many cells the player left empty a game.
+func (p *Profile) averageEmpty() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return float64(p.EmptyCells) / float64(p.Games)
+}
+
+// favoriteVariants lists the rules played most, at most n of them.
+func (p *Profile) favoriteVariants(n int) []string {
+	var rules []string
+	for rule := range p.Variants {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if p.Variants[rules[i]] != p.Variants[rules[j]] {
+			return p.Variants[rules[i]] > p.Variants[rules[j]]
+		}
+		return rules[i] < rules[j]
+	})
+	return rules[:min(n, len(rules))]
+}
+
+// setupSettings answers the setup questions the way this game was set up,
+// by flag name.
+func (state *GameState) setupSettings() map[string]string {
+	humans, computers := 0, 0
+	var strategies []string
+	for _, b := range state.Boards {
+		if !b.IsAi {
+			humans++
+			continue
+		}
+		computers++
+		if b.Strategy != "" {
+			strategies = append(strategies, b.Strategy)
+		} else {
+			strategies = append(strategies, defaultStrategy.Name())
+		}
+	}
+	s := map[string]string{
+		"analyze":             strconv.FormatBool(state.Analyze),
+		"humans":              strconv.Itoa(humans),
+		"computers":           strconv.Itoa(computers),
+		"strategies":          strings.Join(strategies, ","),
+		"increasing-diagonal": strconv.FormatBool(state.DiagonalRule),
+		"shared-pool":         strconv.FormatBool(state.SharedPool),
+		"mulligan":            strconv.FormatBool(state.Mulligan),
+		"bruno":               strconv.FormatBool(state.BrunoVariant),
+		"nondecreasing":       strconv.FormatBool(state.NonDecreasing),
+		"forced-table":        strconv.FormatBool(state.ForcedTable),
+		"open-pile":           strconv.FormatBool(state.OpenPile),
+		"steal":               strconv.FormatBool(state.StealSwap),
+		"hand":                strconv.Itoa(state.HandSize),
+		"clock":               "0s",
+	}
+	if state.BrunoVariant {
+		s["bruno-along"] = state.Bruno.directions().String()
+		s["bruno-chain"] = strconv.Itoa(state.Bruno.maxChain())
+	}
+	if len(state.Boards) == teamSeats {
+		s["teams"] = strconv.FormatBool(state.Teams)
+	}
+	if state.Clock != nil {
+		s["clock"] = state.Clock.Bank.String()
+		s["clock-forfeit"] = strconv.FormatBool(state.Clock.Forfeit)
+	}
+	if preset := state.presetName(); preset != "" {
+		s["preset"] = preset
+	}
+	return s
+}
+
+// presetName is the preset the game was set up with, or "" for a custom
+// one.
+func (state *GameState) presetName() string {
+	if state.Distribution != nil || state.Wildcards > 0 || len(state.Holes) > 0 {
+		return ""
+	}
+	for _, p := range presets {
+		if p.BoardSize == BoardSize && p.TileRange == state.maxTile() && p.End == state.End {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// preferSettings answers the setup questions not given on the command line
+// from settings, through the flags of fs, and lists those it answered.
+func (s *Setup) preferSettings(fs *flag.FlagSet, settings map[string]string) ([]string, error) {
+	var used []string
+	for name, value := range settings {
+		if s.given[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("setting %s: %w", name, err)
+		}
+		s.given[name] = true
+		used = append(used, name)
+	}
+	sort.Strings(used)
+	return used, nil
+}
+
+// describeSettings names the settings that are switched on or set.
+func describeSettings(settings map[string]string) string {
+	var parts []string
+	for name, value := range settings {
+		switch value {
+		case "false", "0", "0s", "":
+		case "true":
+			parts = append(parts, name)
+		default:
+			parts = append(parts, name+" "+value)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// greetPlayer says what a returning player's profile holds. Players new to
+// the profiles are not greeted.
+func greetPlayer(name string) {
+	if profilesDir == "" || name == "" {
+		return
+	}
+	if _, err := os.Stat(profileFile(name)); err != nil {
+		return
+	}
+	p, err := loadNamedProfile(name)
+	if err != nil || p.Games == 0 {
+		return
+	}
+	fmt.Printf(tr("Welcome back, %s: %d played, %d won, %.1f empty cells a game.\n"), name, p.Games, p.Wins, p.averageEmpty())
+}
+
+// usePlayerProfile is -player: it greets the player and takes the settings
+// of their last game for the setup questions not given as flags.
+func usePlayerProfile(name string) error {
+	name, err := checkName(name)
+	if err != nil {
+		return err
+	}
+	p, err := loadNamedProfile(name)
+	if err != nil {
+		return err
+	}
+	setup.player = p.Name
+	if p.Games == 0 {
+		fmt.Printf("New profile for %s.\n", name)
+		return nil
+	}
+	greetPlayer(p.Name)
+	used, err := setup.preferSettings(flag.CommandLine, p.Settings)
+	if err != nil {
+		return err
+	}
+	if len(used) > 0 {
+		fmt.Printf("Setting up like your last game: %s.\n", describeSettings(p.Settings))
+	}
+	return nil
+}
+
+// printNamedProfile shows a player's record.
+func printNamedProfile(w io.Writer, p *Profile) {
+	fmt.Fprintf(w, "%s\n", p.Name)
+	fmt.Fprintf(w, "  Games: %d  Wins: %d  Empty cells: %.1f a game\n", p.Games, p.Wins, p.averageEmpty())
+	if p.RatedGames > 0 {
+		fmt.Fprintf(w, "  Rating: %.0f (rated games: %d)\n", p.Rating, p.RatedGames)
+	}
+	if fav := p.favoriteVariants(3); len(fav) > 0 {
+		var counts []string
+		for _, rule := range fav {
+			counts = append(counts, fmt.Sprintf("%s (%d)", rule, p.Variants[rule]))
+		}
+		fmt.Fprintf(w, "  Favorite rules: %s\n", strings.Join(counts, ", "))
+	}
+	if len(p.Settings) > 0 {
+		fmt.Fprintf(w, "  Last game: %s\n", describeSettings(p.Settings))
+	}
+	if !p.LastPlayed.IsZero() {
+		fmt.Fprintf(w, "  Last played: %s\n", p.LastPlayed.Format("2006-01-02 15:04"))
+	}
+}
+
+// runProfiles is the profiles command: show the named profiles, or only
+// those of the names given.
+func runProfiles(names []string) {
+	if profilesDir == "" {
+		fmt.Println("profiles needs -profiles, the directory the profiles are kept in")
+		return
+	}
+	var profiles []*Profile
+	if len(names) == 0 {
+		profiles = readProfiles(profilesDir)
+	}
+	for _, name := range names {
+		if _, err := os.Stat(profileFile(name)); err != nil {
+			fmt.Printf("No profile for %s.\n", name)
+			continue
+		}
+		p, err := loadNamedProfile(name)
+		if err != nil {
+			fmt.Println("Failed to read profile:", err)
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	if len(profiles) == 0 && len(names) == 0 {
+		fmt.Println("No profiles yet; they are kept for every named player.")
+	}
+	for _, p := range profiles {
+		printNamedProfile(os.Stdout, p)
+	}
+}
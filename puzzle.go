@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPuzzleDraws is how many tiles a solo puzzle allows.
+const defaultPuzzleDraws = 25
+
+// Puzzle scoring: points per filled cell, for solving, and per draw left.
+const (
+	puzzleCellPoints  = 10
+	puzzleSolvedBonus = 50
+	puzzleSparePoints = 5
+)
+
+// Puzzle is a solo game: one board, a draw order fixed by the seed, and a
+// limit on how many tiles the player may take from the pile or table.
+type Puzzle struct {
+	Seed  int64
+	Limit int
+	Draws int
+	Daily string // date of the daily challenge, empty for other puzzles
+}
+
+// dailySeed derives the daily challenge's seed from the UTC date, so
+// everyone playing on the same day gets the same draws and diagonals.
+func dailySeed(now time.Time) (int64, string) {
+	date := now.UTC().Format("2006-01-02")
+	seed, _ := strconv.ParseInt(strings.ReplaceAll(date, "-", ""), 10, 64)
+	return seed, date
+}
+
+// newPuzzle deals the puzzle numbered seed for a single human player.
+func newPuzzle(seed int64, limit int) (*GameState, error) {
+	seedRNG(seed)
+	state := &GameState{Heuristics: defaultHeuristics, Puzzle: &Puzzle{Seed: seed, Limit: limit}}
+	state.initDrawStack(1)
+	b := &Board{}
+	if err := state.fillRandomDiagonal(b); err != nil {
+		return nil, err
+	}
+	state.Boards = []*Board{b}
+	return state, nil
+}
+
+// countPuzzleDraw records that the player took a tile.
+func (state *GameState) countPuzzleDraw() {
+	p := state.Puzzle
+	if p == nil {
+		return
+	}
+	p.Draws++
+	fmt.Printf("Draw %d of %d.\n", p.Draws, p.Limit)
+}
+
+// checkPuzzleLimit ends the puzzle once every draw is used up.
+func (state *GameState) checkPuzzleLimit() {
+	if p := state.Puzzle; p != nil && p.Draws >= p.Limit {
+		state.gameOver(-1, fmt.Sprintf("Out of draws: the board is not complete after %d.", p.Limit))
+	}
+}
+
+// score rates how far the player got with board, and whether it is solved.
+func (p *Puzzle) score(board *Board) (int, bool) {
+	filled := BoardSize*BoardSize - emptyCells(board)
+	score := filled * puzzleCellPoints
+	solved := board.IsFull()
+	if solved {
+		score += puzzleSolvedBonus + (p.Limit-p.Draws)*puzzleSparePoints
+	}
+	return score, solved
+}
+
+// printPuzzleResult is the solo result screen.
+func (state *GameState) printPuzzleResult(w io.Writer) {
+	p, board := state.Puzzle, state.Boards[0]
+	score, solved := p.score(board)
+	filled := BoardSize*BoardSize - emptyCells(board)
+	fmt.Fprintf(w, "=== Puzzle #%d ===\n", p.Seed)
+	if solved {
+		fmt.Fprintf(w, "Solved in %d of %d draws!\n", p.Draws, p.Limit)
+	} else {
+		fmt.Fprintf(w, "Not solved: %d cells left after %d of %d draws.\n", emptyCells(board), p.Draws, p.Limit)
+	}
+	fmt.Fprintf(w, "  filled cells  %3d x %2d = %4d\n", filled, puzzleCellPoints, filled*puzzleCellPoints)
+	if solved {
+		spare := p.Limit - p.Draws
+		fmt.Fprintf(w, "  solved bonus            %4d\n", puzzleSolvedBonus)
+		fmt.Fprintf(w, "  spare draws   %3d x %2d = %4d\n", spare, puzzleSparePoints, spare*puzzleSparePoints)
+	}
+	fmt.Fprintf(w, "  score                   %4d\n", score)
+	fmt.Fprintf(w, "Replay this puzzle with: -seed %d puzzle\n", p.Seed)
+	if p.Daily != "" {
+		fmt.Fprintf(w, "\nShare your result:\n%s", p.shareText(board, term.Unicode))
+	}
+}
+
+// shareText is the spoiler-free daily result: a summary line and the
+// board's filled cells.
+func (p *Puzzle) shareText(board *Board, unicode bool) string {
+	filled, empty := "#", "."
+	if unicode {
+		filled, empty = "🟩", "⬜"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Unlucky Numbers daily %s: %d/%d turns, %d empty\n", p.Daily, p.Draws, p.Limit, emptyCells(board))
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if board.Grid[r][c] == 0 {
+				b.WriteString(empty)
+			} else {
+				b.WriteString(filled)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Named players and computer strategies carry an Elo rating in their
+// profiles, updated after every rated game: one with at least two rated
+// seats, no handicaps and no A/B test, as neither says much about strength.
+// Every pair of seats plays a game of its own, the winner beating each
+// loser and everyone drawing when no one wins; losers to a third seat and
+// teammates do not score against each other. The computers' profiles live
+// in profiles/computers, one a strategy, so a new strategy's rating shows
+// how it fares against the people and the strategies before it.
+
+// ratedSeat is a seat whose game counts towards its profile's rating.
+type ratedSeat struct {
+	seat int
+	path string
+	p    *Profile
+}
+
+// computerProfileFile is where a computer strategy's profile is kept.
+func computerProfileFile(strategy string) string {
+	return filepath.Join(profilesDir, "computers", profileSlug(strategy)+".json")
+}
+
+// rating is the profile's Elo rating, eloStart before its first rated game.
+func (p *Profile) rating() float64 {
+	if p.RatedGames == 0 {
+		return eloStart
+	}
+	return p.Rating
+}
+
+// rated reports whether the game counts towards the ratings.
+func (state *GameState) rated() bool {
+	if state.Puzzle != nil || state.ABTest != nil || state.Analyze {
+		return false
+	}
+	for _, b := range state.Boards {
+		if b.Handicap != (Handicap{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// ratingChange is a seat's rating before and after a game.
+type ratingChange struct {
+	seat          int
+	before, after float64
+}
+
+// rateGame updates the ratings of seats after the game winner decided and
+// returns the changes, or nothing when fewer than two profiles played.
+func rateGame(state *GameState, seats []ratedSeat, winner int) []ratingChange {
+	before := map[string]float64{}
+	for _, s := range seats {
+		before[s.path] = s.p.rating()
+	}
+	if len(before) < 2 {
+		return nil
+	}
+	delta := map[string]float64{}
+	for i, a := range seats {
+		for _, b := range seats[i+1:] {
+			if a.path == b.path {
+				continue // one player or strategy on both seats
+			}
+			wonA, wonB := state.won(a.seat, winner), state.won(b.seat, winner)
+			score := 0.5
+			switch {
+			case winner >= 0 && wonA == wonB:
+				continue
+			case wonA:
+				score = 1
+			case wonB:
+				score = 0
+			}
+			ra, rb := eloUpdate(before[a.path], before[b.path], score)
+			delta[a.path] += ra - before[a.path]
+			delta[b.path] += rb - before[b.path]
+		}
+	}
+	var changes []ratingChange
+	done := map[string]bool{}
+	for _, s := range seats {
+		if !done[s.path] {
+			s.p.Rating = before[s.path] + delta[s.path]
+			s.p.RatedGames++
+			done[s.path] = true
+		}
+		changes = append(changes, ratingChange{seat: s.seat, before: before[s.path], after: s.p.Rating})
+	}
+	return changes
+}
+
+// printRatings shows each rated seat's new rating and how it moved.
+func (state *GameState) printRatings(changes []ratingChange) {
+	if len(changes) == 0 {
+		return
+	}
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		who := state.seatLabel(c.seat)
+		if state.Boards[c.seat].IsAi {
+			who += " (" + state.strategyFor(c.seat).Name() + ")"
+		}
+		parts[i] = fmt.Sprintf("%s %.0f (%+.0f)", who, c.after, c.after-c.before)
+	}
+	fmt.Printf(tr("Ratings: %s\n"), strings.Join(parts, ", "))
+}
+
+// printRatingsTable lists every rated profile, highest first.
+func printRatingsTable(w io.Writer, profiles []*Profile, computers map[*Profile]bool) {
+	var rated []*Profile
+	for _, p := range profiles {
+		if p.RatedGames > 0 {
+			rated = append(rated, p)
+		}
+	}
+	if len(rated) == 0 {
+		fmt.Fprintln(w, "No rated games yet.")
+		return
+	}
+	sort.SliceStable(rated, func(i, j int) bool { return rated[i].Rating > rated[j].Rating })
+	fmt.Fprintf(w, "%-22s %-9s %6s %6s\n", "Player", "", "Rated", "Elo")
+	for _, p := range rated {
+		kind := "human"
+		if computers[p] {
+			kind = "computer"
+		}
+		fmt.Fprintf(w, "%-22s %-9s %6d %6.0f\n", p.Name, kind, p.RatedGames, p.Rating)
+	}
+}
+
+// runRatings is the ratings command: every rated player and strategy.
+func runRatings() {
+	if profilesDir == "" {
+		fmt.Println("ratings needs -profiles, the directory the profiles are kept in")
+		return
+	}
+	humans := readProfiles(profilesDir)
+	computers := readProfiles(filepath.Join(profilesDir, "computers"))
+	isComputer := map[*Profile]bool{}
+	for _, p := range computers {
+		isComputer[p] = true
+	}
+	printRatingsTable(os.Stdout, append(humans, computers...), isComputer)
+}
+
+// readProfiles reads every named profile in dir.
+func readProfiles(dir string) []*Profile {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	var profiles []*Profile
+	for _, f := range files {
+		p, err := loadProfile(f)
+		if err != nil || p.Name == "" {
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
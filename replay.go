@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// replay plays a -move-log back: the position at the start, then every
+// draw and move in turn, with the boards drawn after each move. The
+// spectator's keys pace it, so it can be paused and stepped a move at a
+// time.
+
+// runReplay plays back the move log at path, waiting delay between moves
+// and writing every move's evaluation to export when it is not nil, and
+// returns the position it ends in.
+func runReplay(path string, delay time.Duration, export *EvalExport) (*GameState, error) {
+	if delay > 0 {
+		spectator = startSpectator(delay)
+		defer stopSpectator()
+	}
+	return walkMoveLog(path, func(state *GameState, e LoggedMove, said string) bool {
+		if e.Game != nil {
+			state.EvalExport = export // a start or undo begins a position afresh
+		}
+		fmt.Print(said)
+		if e.Type == "draw" || e.Type == "end" || e.Type == "note" {
+			return false // the boards are drawn once the tile is played
+		}
+		state.PrettyPrintBoardsGridCentered()
+		if spectator != nil && spectator.wait() {
+			fmt.Println(tr("Stopped watching."))
+			return true
+		}
+		return false
+	})
+}
+
+// walkMoveLog goes through the move log at path, handing visit the
+// position after each line and what happened in it, until visit returns
+// true. It returns the position it ends in.
+func walkMoveLog(path string, visit func(state *GameState, e LoggedMove, said string) bool) (*GameState, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var state *GameState
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<24) // start and undo lines hold a whole position
+	for line := 1; scanner.Scan(); line++ {
+		var e LoggedMove
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		var said string
+		switch {
+		case e.Game != nil:
+			state = &GameState{}
+			if err := state.restoreSaved(*e.Game); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if e.Type == "undo" {
+				said = fmt.Sprintf(tr("%s takes a move back.\n"), e.Player)
+			} else {
+				said = fmt.Sprintf(tr("Replaying the game started %s.\n"), e.Time.Local().Format("2006-01-02 15:04"))
+			}
+		case state == nil:
+			return nil, fmt.Errorf("line %d: the log has no starting position before its first %s", line, e.Type)
+		default:
+			if said, err = state.replayEvent(e); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		if visit(state, e, said) {
+			return state, nil
+		}
+	}
+	if state == nil {
+		return nil, fmt.Errorf("%s holds no game", path)
+	}
+	return state, scanner.Err()
+}
+
+// replayEvent applies a logged draw or move to state and says what
+// happened, a line ending in a newline.
+func (state *GameState) replayEvent(e LoggedMove) (string, error) {
+	if e.Type == "end" {
+		return term.text(e.Message) + "\n", nil
+	}
+	if e.Type == "note" {
+		if e.Annotation == nil {
+			return "", fmt.Errorf("note with nothing written")
+		}
+		state.Annotations = append(state.Annotations, *e.Annotation)
+		return state.describeAnnotation(*e.Annotation), nil
+	}
+	if e.Seat < 0 || e.Seat >= len(state.Boards) {
+		return "", fmt.Errorf("seat %d but only %d boards", e.Seat, len(state.Boards))
+	}
+	if e.Turn-1 != state.Turn {
+		// a new turn: the last one ended by refilling its player's hand
+		state.refillHand(state.Current)
+		state.Turn = e.Turn - 1
+	}
+	state.Current = e.Seat
+	who := state.seatLabel(e.Seat)
+	switch e.Type {
+	case "draw":
+		if err := state.replayDraw(e); err != nil {
+			return "", err
+		}
+		state.Draws++
+		return fmt.Sprintf(tr(map[string]string{
+			"pile":  "%s draws %s from the pile.\n",
+			"table": "%s takes %s from the table.\n",
+			"hand":  "%s plays %s from the hand.\n",
+		}[e.From]), who, tileLabel(e.Tile)), nil
+	case "resign":
+		state.Boards[e.Seat].Resigned = true
+		if state.Teams {
+			state.Boards[state.partner(e.Seat)].Resigned = true
+		}
+		return fmt.Sprintf(tr("%s resigns.\n"), who), nil
+	}
+	move, err := SavedMove{Seat: e.Seat, Type: e.Type, Tile: e.Tile, Cell: e.Cell}.move()
+	if err != nil {
+		return "", err
+	}
+	if move.Cell == nil || e.Board == nil || !onBoard(move.Cell.R, move.Cell.C) || *e.Board < 0 || *e.Board >= len(state.Boards) {
+		if move.Type != Discard {
+			return "", fmt.Errorf("%s without a cell on a board", e.Type)
+		}
+	} else if move.Type == Steal {
+		move.Target = *e.Board
+	} else {
+		move.Partner = *e.Board != e.Seat
+	}
+	exported := state.evalMove(move)
+	state.commitMove(move)
+	state.History = append(state.History, Played{Seat: e.Seat, Move: move})
+	exported()
+	where := ""
+	if move.Partner {
+		where = fmt.Sprintf(tr(" on partner %d's board"), *e.Board)
+	}
+	switch move.Type {
+	case Place:
+		return fmt.Sprintf(tr("%s places %s at %s%s.\n"), who, tileLabel(move.Tile), move.Cell, where), nil
+	case Swap:
+		return fmt.Sprintf(tr("%s swaps %s into %s%s; %s goes to the table.\n"), who, tileLabel(move.Tile), move.Cell, where, tileLabel(e.OldTile)), nil
+	case Discard:
+		return fmt.Sprintf(tr("%s discards %s.\n"), who, tileLabel(move.Tile)), nil
+	}
+	return fmt.Sprintf(tr("%s steals %s from %s on %s's board.\n"), who, tileLabel(move.Tile), move.Cell, state.seatLabel(*e.Board)), nil
+}
+
+// replayDraw takes a logged draw's tile from where it came.
+func (state *GameState) replayDraw(e LoggedMove) error {
+	switch e.From {
+	case "table":
+		if !contains(state.Table, e.Tile) {
+			return fmt.Errorf("%s is not on the table", tileLabel(e.Tile))
+		}
+		state.removeTileFromTable(e.Tile)
+	case "hand":
+		if !contains(state.Boards[e.Seat].Hand, e.Tile) {
+			return fmt.Errorf("%s is not in the hand", tileLabel(e.Tile))
+		}
+		state.playFromHand(e.Tile)
+	case "pile":
+		for i, t := range state.Draw {
+			if t == e.Tile {
+				state.Draw = append(state.Draw[:i], state.Draw[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not in the pile", tileLabel(e.Tile))
+	default:
+		return fmt.Errorf("draw from %q", e.From)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quitting stops the game for everyone; resigning only takes the player out.
+// The others play on without them, and when one player is left, or in team
+// play the other team, they win.
+
+// activeSeats lists the seats still playing.
+func (state *GameState) activeSeats() []int {
+	var seats []int
+	for i, b := range state.Boards {
+		if !b.Resigned {
+			seats = append(seats, i)
+		}
+	}
+	return seats
+}
+
+// resignedSeats lists the resigned seats for a save.
+func (state *GameState) resignedSeats() []string {
+	var seats []string
+	for i, b := range state.Boards {
+		if b.Resigned {
+			seats = append(seats, strconv.Itoa(i))
+		}
+	}
+	return seats
+}
+
+// resign takes seat out of the game, with its partner in team play, and
+// ends the game once a single player or team remains.
+func (state *GameState) resign(seat int) {
+	state.Boards[seat].Resigned = true
+	state.logEvent(LoggedMove{Seat: seat, Type: "resign"})
+	if state.Teams {
+		state.Boards[state.partner(seat)].Resigned = true
+	}
+	active := state.activeSeats()
+	if len(active) == 1 || state.Teams {
+		winner := active[0]
+		state.gameOver(winner, fmt.Sprintf(tr("%s resigns. GAME OVER! %s wins."), state.seatLabel(seat), state.winnerLabel(winner)))
+		return
+	}
+	fmt.Printf(tr("%s resigns and leaves the game.\n"), state.seatLabel(seat))
+}
+
+// promptResign asks before resigning, as it cannot be taken back.
+func promptResign() bool {
+	fmt.Print(tr("Resign and leave the game? (y/N): "))
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// The reinforcement-learning agent scores a candidate move with a linear
+// model over these features of the move and the position it leaves.
+var rlFeatureNames = []string{"bias", "score", "row_prob", "col_prob", "risk", "fill", "deviation", "swap", "discard"}
+
+// rlWeights is the persisted model of the learning agent.
+type rlWeights struct {
+	Features []string  `json:"features"`
+	Weights  []float64 `json:"weights"`
+	Games    int       `json:"games"` // self-play games trained on so far
+}
+
+// initialRLWeights starts the model off trusting the hand-tuned score.
+func initialRLWeights() *rlWeights {
+	w := &rlWeights{Features: rlFeatureNames, Weights: make([]float64, len(rlFeatureNames))}
+	w.Weights[1] = 1
+	return w
+}
+
+func loadRLWeights(path string) (*rlWeights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &rlWeights{}
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, err
+	}
+	if len(w.Weights) != len(rlFeatureNames) {
+		return nil, fmt.Errorf("%s has %d weights, expected %d", path, len(w.Weights), len(rlFeatureNames))
+	}
+	for i, name := range w.Features {
+		if name != rlFeatureNames[i] {
+			return nil, fmt.Errorf("%s: feature %d is %q, expected %q", path, i, name, rlFeatureNames[i])
+		}
+	}
+	return w, nil
+}
+
+func (w *rlWeights) save(path string) error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (w *rlWeights) value(phi []float64) float64 {
+	v := 0.0
+	for i, x := range phi {
+		v += w.Weights[i] * x
+	}
+	return v
+}
+
+// rlFeatures describes playing move from the current position.
+func (state *GameState) rlFeatures(move Move) []float64 {
+	if move.Partner {
+		defer state.asSeat(state.partner(state.Current))()
+	}
+	board := state.Boards[state.Current]
+	free := float64(BoardSize*BoardSize - BoardSize - len(state.Holes))
+	empty := emptyCells(board)
+	phi := make([]float64, len(rlFeatureNames))
+	phi[0] = 1
+	if move.Type == Discard {
+		phi[5] = 1 - float64(empty)/free
+		phi[8] = 1
+		return phi
+	}
+	r, c := move.Cell.R, move.Cell.C
+	if board.Grid[r][c] == 0 {
+		empty--
+	}
+	phi[1] = state.placementScore(move.Tile, r, c) / 100
+	phi[2] = state.futureRowProbability(r, c)
+	phi[3] = state.futureColProbability(r, c)
+	phi[4] = state.riskFactor(move.Tile, r, c)
+	phi[5] = 1 - float64(empty)/free
+	if isTile(move.Tile) {
+		phi[6] = math.Abs(xOfT(move.Tile, state.maxTile())-float64(2+r+c)) / float64(2*BoardSize)
+	}
+	if move.Type == Swap {
+		phi[7] = 1
+	}
+	return phi
+}
+
+// rlStrategy plays the move the linear model values highest. While
+// learning it explores with probability epsilon and remembers the features
+// of every move it makes.
+type rlStrategy struct {
+	w        *rlWeights
+	epsilon  float64
+	learning bool
+	chosen   [][]float64
+}
+
+func (*rlStrategy) Name() string { return "rl" }
+
+// bestFor returns the highest valued move for tile, including discarding.
+func (s *rlStrategy) bestFor(state *GameState, tile int, allowDiscard bool) (Move, []float64, float64) {
+	candidates := state.bestMoves(tile)
+	if allowDiscard || len(candidates) == 0 {
+		candidates = append(candidates, Move{Type: Discard, Tile: tile})
+	}
+	if s.learning && rng.Float64() < s.epsilon {
+		m := candidates[rng.Intn(len(candidates))]
+		phi := state.rlFeatures(m)
+		return m, phi, s.w.value(phi)
+	}
+	var best Move
+	var bestPhi []float64
+	bestValue := math.Inf(-1)
+	for _, m := range candidates {
+		phi := state.rlFeatures(m)
+		if v := s.w.value(phi); v > bestValue {
+			best, bestPhi, bestValue = m, phi, v
+		}
+	}
+	return best, bestPhi, bestValue
+}
+
+func (s *rlStrategy) PickFromTable(state *GameState) (Move, bool) {
+	if len(state.Table) == 0 {
+		return Move{}, false
+	}
+	// A pile draw is worth the average value of the best move per unseen tile.
+	pile, seen := 0.0, map[int]float64{}
+	for _, t := range state.Draw {
+		v, ok := seen[t]
+		if !ok {
+			_, _, v = s.bestFor(state, t, true)
+			seen[t] = v
+		}
+		pile += v
+	}
+	if len(state.Draw) > 0 {
+		pile /= float64(len(state.Draw))
+	}
+
+	var best Move
+	var bestPhi []float64
+	bestValue, found := pile, false
+	for _, t := range state.Table {
+		if len(state.bestMoves(t)) == 0 {
+			continue
+		}
+		m, phi, v := s.bestFor(state, t, false)
+		if m.Type != Discard && v > bestValue {
+			best, bestPhi, bestValue, found = m, phi, v, true
+		}
+	}
+	if found && s.learning {
+		s.chosen = append(s.chosen, bestPhi)
+	}
+	return best, found
+}
+
+func (s *rlStrategy) ChooseMove(state *GameState, tile int) (Move, bool) {
+	m, phi, _ := s.bestFor(state, tile, true)
+	if s.learning {
+		s.chosen = append(s.chosen, phi)
+	}
+	return m, m.Type != Discard
+}
+
+// learn applies TD(0) updates along the moves made in one game, ending in
+// reward: each move's value is pulled towards the value of the next one, the
+// last towards the reward.
+func (s *rlStrategy) learn(reward, alpha float64) {
+	for k, phi := range s.chosen {
+		target := reward
+		if k+1 < len(s.chosen) {
+			target = s.w.value(s.chosen[k+1])
+		}
+		delta := target - s.w.value(phi)
+		for i, x := range phi {
+			s.w.Weights[i] += alpha * delta * x
+		}
+	}
+	s.chosen = s.chosen[:0]
+}
+
+// trainRL runs self-play games between two learning copies sharing w, then
+// reports how the trained model fares against the greedy strategy.
+func trainRL(w *rlWeights, games int, alpha, epsilon float64, report func(string)) {
+	for g := 0; g < games; g++ {
+		state, err := newHeadlessGame(2)
+		if err != nil {
+			report(err.Error())
+			return
+		}
+		a := &rlStrategy{w: w, epsilon: epsilon, learning: true}
+		b := &rlStrategy{w: w, epsilon: epsilon, learning: true}
+		winner := state.playHeadless([]Strategy{a, b}, nil)
+		a.learn(boolReward(winner == 0), alpha)
+		b.learn(boolReward(winner == 1), alpha)
+		w.Games++
+		if (g+1)%1000 == 0 {
+			report(fmt.Sprintf("%d games, weights %.3f", g+1, w.Weights))
+		}
+	}
+
+	const evalGames = 200
+	wins := 0
+	for g := 0; g < evalGames; g++ {
+		state, err := newHeadlessGame(2)
+		if err != nil {
+			report(err.Error())
+			return
+		}
+		seats := []Strategy{&rlStrategy{w: w}, greedyStrategy{}}
+		if g%2 == 1 {
+			seats[0], seats[1] = seats[1], seats[0]
+		}
+		if winner := state.playHeadless(seats, nil); winner >= 0 && seats[winner].Name() == "rl" {
+			wins++
+		}
+	}
+	report(fmt.Sprintf("rl won %d of %d games against greedy", wins, evalGames))
+}
+
+func boolReward(won bool) float64 {
+	if won {
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	registerStrategy(&rlStrategy{w: initialRLWeights()})
+}
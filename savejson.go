@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// A CSV save keeps only the boards, the table and a few records; loading one
+// reshuffles the draw pile and asks the rules again. A JSON save keeps the
+// whole game: the pile in order, who plays each seat, the rules, the moves so
+// far and a tile drawn but not yet played, so the game goes on exactly where
+// it stopped. Saves whose file name ends in .json use it.
+
+// SavedGame is a game in a JSON save.
+type SavedGame struct {
+	Version    int          `json:"version"` // see jsonVersion
+	BoardSize  int          `json:"board_size"`
+	Current    int          `json:"current"`
+	Analyze    bool         `json:"analyze,omitempty"`
+	Seed       int64        `json:"seed,omitempty"`
+	Rules      SavedRules   `json:"rules"`
+	Boards     []SavedBoard `json:"boards"`
+	Table      []int        `json:"table"`
+	Draw       []int        `json:"draw"` // top of the pile first
+	Pending    *SavedMove   `json:"pending,omitempty"`
+	History    []SavedMove  `json:"history,omitempty"`
+	Turn       int          `json:"turn"`
+	Draws      int          `json:"draws"`
+	ExtraTurns int          `json:"extra_turns,omitempty"`
+	Backfills  []Backfill   `json:"backfills,omitempty"`
+	Puzzle     *Puzzle      `json:"puzzle,omitempty"`
+	Match      *Match       `json:"match,omitempty"` // the match this game is a round of
+	Notes      []Annotation `json:"notes,omitempty"`
+	Checksum   string       `json:"checksum,omitempty"` // of the rest, see checksum.go
+}
+
+// SavedRules are the variant settings of a saved game.
+type SavedRules struct {
+	TileRange     int          `json:"tile_range,omitempty"`
+	Wildcards     int          `json:"wildcards,omitempty"`
+	Distribution  Distribution `json:"distribution,omitempty"`
+	SharedPool    bool         `json:"shared_pool,omitempty"`
+	DiagonalRule  bool         `json:"increasing_diagonal,omitempty"`
+	NonDecreasing bool         `json:"nondecreasing,omitempty"`
+	Bruno         bool         `json:"bruno,omitempty"`
+	BrunoAlong    string       `json:"bruno_along,omitempty"`
+	BrunoChain    int          `json:"bruno_chain,omitempty"`
+	ForcedTable   bool         `json:"forced_table,omitempty"`
+	OpenPile      bool         `json:"open_pile,omitempty"`
+	StealSwap     bool         `json:"steal,omitempty"`
+	HandSize      int          `json:"hand,omitempty"`
+	Mulligan      bool         `json:"mulligan,omitempty"`
+	Teams         bool         `json:"teams,omitempty"`
+	End           EndMode      `json:"end,omitempty"`
+	Holes         []Cell       `json:"holes,omitempty"`
+}
+
+// SavedBoard is one seat of a saved game.
+type SavedBoard struct {
+	Grid       [][]int `json:"grid"` // BoardSize rows of BoardSize cells
+	Name       string  `json:"name,omitempty"`
+	Computer   bool    `json:"computer,omitempty"`
+	Controlled bool    `json:"controlled,omitempty"`
+	Strategy   string  `json:"strategy,omitempty"`
+	Handicap   string  `json:"handicap,omitempty"`
+	Hand       []int   `json:"hand,omitempty"`
+	TableTakes int     `json:"table_takes,omitempty"`
+	StealUsed  bool    `json:"steal_used,omitempty"`
+	Resigned   bool    `json:"resigned,omitempty"`
+}
+
+// SavedMove is a move in a saved game's history, or the tile drawn and not
+// yet played.
+type SavedMove struct {
+	Seat      int    `json:"seat"`
+	Type      string `json:"type"`
+	Tile      int    `json:"tile"`
+	OldTile   int    `json:"old_tile,omitempty"`
+	Cell      *Cell  `json:"cell,omitempty"`
+	Partner   bool   `json:"partner,omitempty"`
+	FromTable bool   `json:"from_table,omitempty"`
+	Target    int    `json:"target,omitempty"`
+}
+
+func savedMove(seat int, m Move) SavedMove {
+	return SavedMove{Seat: seat, Type: moveTypeNames[m.Type], Tile: m.Tile, OldTile: m.OldTile, Cell: m.Cell,
+		Partner: m.Partner, FromTable: m.FromTable, Target: m.Target}
+}
+
+func (m SavedMove) move() (Move, error) {
+	for t, name := range moveTypeNames {
+		if name == m.Type {
+			return Move{Type: t, Tile: m.Tile, OldTile: m.OldTile, Cell: m.Cell, Partner: m.Partner,
+				FromTable: m.FromTable, Target: m.Target}, nil
+		}
+	}
+	return Move{}, fmt.Errorf("unknown move type %q", m.Type)
+}
+
+// isJSONSave reports whether filename is saved as JSON rather than CSV.
+func isJSONSave(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(plainName(filename)), ".json")
+}
+
+// isCSVSave reports whether filename is named as a CSV save.
+func isCSVSave(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(plainName(filename)), ".csv")
+}
+
+// saved captures the game for a JSON save.
+func (state *GameState) saved() SavedGame {
+	g := SavedGame{
+		Version: jsonVersion, BoardSize: BoardSize, Current: state.Current, Analyze: state.Analyze, Seed: state.Seed,
+		Table: append([]int{}, state.Table...), Draw: append([]int{}, state.Draw...),
+		Turn: state.Turn, Draws: state.Draws, ExtraTurns: state.ExtraTurns, Backfills: state.Backfills, Puzzle: state.Puzzle, Match: state.Match,
+		Rules: SavedRules{
+			TileRange: state.TileRange, Wildcards: state.Wildcards, Distribution: state.Distribution,
+			SharedPool: state.SharedPool, DiagonalRule: state.DiagonalRule, NonDecreasing: state.NonDecreasing,
+			Bruno: state.BrunoVariant, ForcedTable: state.ForcedTable, OpenPile: state.OpenPile,
+			StealSwap: state.StealSwap, HandSize: state.HandSize, Mulligan: state.Mulligan, Teams: state.Teams,
+			End: state.End, Holes: state.Holes,
+		},
+	}
+	if state.BrunoVariant {
+		g.Rules.BrunoAlong, g.Rules.BrunoChain = state.Bruno.directions().String(), state.Bruno.maxChain()
+	}
+	for _, b := range state.Boards {
+		sb := SavedBoard{Name: b.Name, Computer: b.IsAi, Controlled: b.Controlled, Strategy: b.Strategy,
+			Hand: b.Hand, TableTakes: b.TableTakes, StealUsed: b.StealUsed, Resigned: b.Resigned}
+		if b.Handicap != (Handicap{}) {
+			sb.Handicap = b.Handicap.String()
+		}
+		for r := 0; r < BoardSize; r++ {
+			sb.Grid = append(sb.Grid, append([]int{}, b.Grid[r][:BoardSize]...))
+		}
+		g.Boards = append(g.Boards, sb)
+	}
+	if p := state.Pending; p != nil {
+		m := savedMove(state.Current, *p)
+		g.Pending = &m
+	}
+	for _, p := range state.History {
+		g.History = append(g.History, savedMove(p.Seat, p.Move))
+	}
+	g.Notes = state.Annotations
+	return g
+}
+
+func (state *GameState) saveToJSON(filename string) error {
+	g := state.saved()
+	sum, err := jsonChecksum(g)
+	if err != nil {
+		return err
+	}
+	g.Checksum = sum
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, append(data, '\n'))
+}
+
+func (state *GameState) loadFromJSON(filename string) error {
+	data, err := readFile(filename)
+	if err != nil {
+		return err
+	}
+	var g SavedGame
+	if err := json.Unmarshal(data, &g); err != nil {
+		return fmt.Errorf("%s is not a readable save, it may have been cut short: %w", filename, err)
+	}
+	if err := checkJSONChecksum(filename, g); err != nil {
+		return err
+	}
+	if err := checkJSONVersion(g); err != nil {
+		return err
+	}
+	if err := state.restoreSaved(g); err != nil {
+		return err
+	}
+	return state.checkPosition(state.step())
+}
+
+// restoreSaved puts a JSON save's game into state, checking it as it goes.
+func (state *GameState) restoreSaved(g SavedGame) error {
+	if g.BoardSize < minBoardSize || g.BoardSize > maxBoardSize {
+		return fmt.Errorf("board size %d is not in %d-%d", g.BoardSize, minBoardSize, maxBoardSize)
+	}
+	BoardSize = g.BoardSize
+	r := g.Rules
+	if r.TileRange != 0 && (r.TileRange < minTileRange() || r.TileRange > maxTileRange) {
+		return fmt.Errorf("highest tile %d is not in %d-%d", r.TileRange, minTileRange(), maxTileRange)
+	}
+	if r.HandSize < 0 || r.HandSize > maxHandSize {
+		return fmt.Errorf("hand of %d tiles is not in 0-%d", r.HandSize, maxHandSize)
+	}
+	if err := r.Distribution.checkCopies(); err != nil {
+		return err
+	}
+	state.TileRange, state.Wildcards, state.Distribution = r.TileRange, r.Wildcards, r.Distribution
+	state.SharedPool, state.DiagonalRule, state.NonDecreasing = r.SharedPool, r.DiagonalRule, r.NonDecreasing
+	state.BrunoVariant, state.Bruno = r.Bruno, BrunoRules{}
+	if r.Bruno {
+		dirs, err := parseBrunoDirections(r.BrunoAlong)
+		if err != nil {
+			return err
+		}
+		state.Bruno = BrunoRules{Directions: dirs, MaxChain: r.BrunoChain}
+	}
+	state.ForcedTable, state.OpenPile, state.StealSwap = r.ForcedTable, r.OpenPile, r.StealSwap
+	state.HandSize, state.Mulligan, state.Teams, state.End = r.HandSize, r.Mulligan, r.Teams, r.End
+	state.Holes = r.Holes
+	for _, h := range state.Holes {
+		if !onBoard(h.R, h.C) {
+			return fmt.Errorf("hole %s is off the board", h)
+		}
+	}
+
+	// tile checks a value found in the pile, on the table or in a hand
+	tile := func(where string, v int) error {
+		if v == Wildcard || v >= 1 && v <= state.maxTile() {
+			return nil
+		}
+		return fmt.Errorf("%s: %d is not a tile", where, v)
+	}
+	if len(g.Boards) == 0 || len(g.Boards) > maxPlayers {
+		return fmt.Errorf("%d boards, a game has 1-%d", len(g.Boards), maxPlayers)
+	}
+	if state.Teams && len(g.Boards) != teamSeats {
+		return fmt.Errorf("team play needs %d boards, not %d", teamSeats, len(g.Boards))
+	}
+	state.Boards = nil
+	for i, sb := range g.Boards {
+		b := &Board{IsAi: sb.Computer, Controlled: sb.Controlled, Strategy: sb.Strategy, Hand: sb.Hand,
+			TableTakes: sb.TableTakes, StealUsed: sb.StealUsed, Resigned: sb.Resigned}
+		name, err := checkName(sb.Name)
+		if err != nil {
+			return fmt.Errorf("board %d: %w", i, err)
+		}
+		b.Name = name
+		if b.Strategy != "" {
+			if _, err := lookupStrategy(b.Strategy); err != nil {
+				return fmt.Errorf("board %d: %w", i, err)
+			}
+		}
+		if sb.Handicap != "" {
+			if b.Handicap, err = parseHandicap(sb.Handicap); err != nil {
+				return fmt.Errorf("board %d: %w", i, err)
+			}
+		}
+		if len(sb.Grid) != BoardSize {
+			return fmt.Errorf("board %d has %d rows, expected %d", i, len(sb.Grid), BoardSize)
+		}
+		for r, row := range sb.Grid {
+			if len(row) != BoardSize {
+				return fmt.Errorf("board %d row %d has %d cells, expected %d", i, r+1, len(row), BoardSize)
+			}
+			for c, v := range row {
+				if v != 0 && v != Blocked {
+					if err := tile(fmt.Sprintf("board %d %s", i, Cell{R: r, C: c}), v); err != nil {
+						return err
+					}
+				}
+				b.Grid[r][c] = v
+			}
+		}
+		for _, v := range b.Hand {
+			if err := tile(fmt.Sprintf("board %d hand", i), v); err != nil {
+				return err
+			}
+		}
+		state.Boards = append(state.Boards, b)
+	}
+	if g.Current < 0 || g.Current >= len(state.Boards) {
+		return fmt.Errorf("current seat %d but only %d boards", g.Current, len(state.Boards))
+	}
+	for _, v := range g.Table {
+		if err := tile("table", v); err != nil {
+			return err
+		}
+	}
+	for _, v := range g.Draw {
+		if err := tile("draw pile", v); err != nil {
+			return err
+		}
+	}
+	state.Current, state.Analyze, state.Seed = g.Current, g.Analyze, g.Seed
+	state.Table, state.Draw = append([]int{}, g.Table...), append([]int{}, g.Draw...)
+	state.Turn, state.Draws, state.ExtraTurns = g.Turn, g.Draws, g.ExtraTurns
+	state.Backfills, state.Puzzle = g.Backfills, g.Puzzle
+	if g.Match != nil {
+		if err := g.Match.check(len(state.Boards)); err != nil {
+			return fmt.Errorf("match: %w", err)
+		}
+	}
+	state.Match = g.Match
+	for i, a := range g.Notes {
+		if a.Seat < 0 || a.Seat >= len(state.Boards) {
+			return fmt.Errorf("note %d: seat %d but only %d boards", i+1, a.Seat, len(state.Boards))
+		}
+	}
+	state.Annotations = g.Notes
+
+	state.Pending = nil
+	if g.Pending != nil {
+		m, err := g.Pending.move()
+		if err != nil {
+			return fmt.Errorf("pending tile: %w", err)
+		}
+		if err := tile("pending tile", m.Tile); err != nil {
+			return err
+		}
+		state.Pending = &m
+	}
+	state.History = nil
+	for i, sm := range g.History {
+		m, err := sm.move()
+		if err != nil {
+			return fmt.Errorf("move %d: %w", i+1, err)
+		}
+		if sm.Seat < 0 || sm.Seat >= len(state.Boards) {
+			return fmt.Errorf("move %d: seat %d but only %d boards", i+1, sm.Seat, len(state.Boards))
+		}
+		state.History = append(state.History, Played{Seat: sm.Seat, Move: m})
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Saves go to one directory, -saves, where they are listed newest first with
+// who is playing and how far the game got, so a game can be loaded or thrown
+// away by its number instead of by typing its file name back exactly.
+
+// savesDir is where saves given without a directory are written and where
+// the saves are listed from.
+var savesDir = "."
+
+// SaveSlot is a save found in the saves directory.
+type SaveSlot struct {
+	Path    string
+	ModTime time.Time
+	Players []string
+	Turn    int // from 1; 0 for CSV saves, which do not keep it
+	ToMove  string
+}
+
+// savePath puts a save's file name in the saves directory, unless it names
+// a directory of its own.
+func savePath(filename string) string {
+	if filepath.Base(filename) != filename {
+		return filename
+	}
+	return filepath.Join(savesDir, filename)
+}
+
+// listSaves reads every save in dir, newest first. Files that do not load
+// as a save are left out.
+func listSaves(dir string) []SaveSlot {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var slots []SaveSlot
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(isJSONSave(name) || isCSVSave(name)) {
+			continue
+		}
+		if slot, err := readSaveSlot(filepath.Join(dir, name)); err == nil {
+			slots = append(slots, slot)
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].ModTime.After(slots[j].ModTime) })
+	return slots
+}
+
+// readSaveSlot loads the save at path aside to describe it, leaving the
+// board size and the random numbers of the game being set up alone.
+func readSaveSlot(path string) (SaveSlot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return SaveSlot{}, err
+	}
+	size, seeded := BoardSize, rng
+	defer func() { BoardSize, rng = size, seeded }()
+	rng = rand.New(rand.NewSource(0))
+
+	state := &GameState{}
+	if err := state.loadSave(path); err != nil {
+		return SaveSlot{}, err
+	}
+	slot := SaveSlot{Path: path, ModTime: info.ModTime()}
+	for i := range state.Boards {
+		slot.Players = append(slot.Players, state.seatLabel(i))
+	}
+	if state.Current >= 0 && state.Current < len(state.Boards) {
+		slot.ToMove = state.seatLabel(state.Current)
+	}
+	if isJSONSave(path) {
+		slot.Turn = state.Turn + 1
+	}
+	return slot, nil
+}
+
+// loadSave loads a save in either format.
+func (state *GameState) loadSave(path string) error {
+	if isJSONSave(path) {
+		return state.loadFromJSON(path)
+	}
+	return state.loadFromCSV(path)
+}
+
+// printSaves lists the saves by number.
+func printSaves(w io.Writer, slots []SaveSlot) {
+	if len(slots) == 0 {
+		fmt.Fprintln(w, tr("No saved games."))
+		return
+	}
+	for i, s := range slots {
+		progress := fmt.Sprintf(tr("%s to move"), s.ToMove)
+		if s.Turn > 0 {
+			progress = fmt.Sprintf(tr("turn %d, %s to move"), s.Turn, s.ToMove)
+		}
+		fmt.Fprintf(w, "%3d. %-24s %s  %s  (%s)\n", i+1, filepath.Base(s.Path), s.ModTime.Format("2006-01-02 15:04"), strings.Join(s.Players, ", "), progress)
+	}
+}
+
+// pickSave turns a save's number in the list into the save.
+func pickSave(slots []SaveSlot, arg string) (SaveSlot, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(slots) {
+		return SaveSlot{}, fmt.Errorf(tr("there is no save %s; the saves are numbered 1-%d"), arg, len(slots))
+	}
+	return slots[n-1], nil
+}
+
+// deleteSaves removes the saves numbered in args, after asking.
+func deleteSaves(slots []SaveSlot, args []string) {
+	var doomed []SaveSlot
+	for _, arg := range args {
+		s, err := pickSave(slots, arg)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		doomed = append(doomed, s)
+	}
+	if len(doomed) == 0 {
+		fmt.Println(tr("Give the numbers of the saves to delete."))
+		return
+	}
+	for _, s := range doomed {
+		fmt.Printf(tr("Delete %s? (y/N): "), filepath.Base(s.Path))
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(strings.ToLower(line))
+		if line != "y" && line != "yes" {
+			continue
+		}
+		if err := os.Remove(s.Path); err != nil {
+			fmt.Println(tr("Failed to delete:"), err)
+			continue
+		}
+		fmt.Printf(tr("Deleted %s.\n"), filepath.Base(s.Path))
+	}
+}
+
+// promptLoad asks which game to load, by file name or by number in the
+// list of saves, and returns its path, or "" for a new game. Saves can be
+// listed and deleted from the same prompt.
+func promptLoad() string {
+	slots := listSaves(savesDir)
+	if len(slots) > 0 {
+		fmt.Println(tr("Saved games:"))
+		printSaves(os.Stdout, slots)
+	}
+	for {
+		fmt.Print(tr("Load a saved game? (number, file name, l to list, delete N, blank for new game): "))
+		line, _ := reader.ReadString('\n')
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			return ""
+		case fields[0] == "l" || fields[0] == "list":
+			printSaves(os.Stdout, slots)
+		case fields[0] == "delete":
+			deleteSaves(slots, fields[1:])
+			slots = listSaves(savesDir)
+		case isNumber(fields[0]):
+			s, err := pickSave(slots, fields[0])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			return s.Path
+		default:
+			return findSave(strings.TrimSpace(line))
+		}
+	}
+}
+
+// findSave looks for a save's file name in the saves directory when it is
+// not in the working one.
+func findSave(filename string) string {
+	if _, err := os.Stat(filename); err == nil {
+		return filename
+	}
+	if p := savePath(filename); p != filename {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return filename
+}
+
+// isNumber reports whether s is a whole number.
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// runSaves is the saves command: list the saves, or delete some by number.
+func runSaves(args []string) {
+	slots := listSaves(savesDir)
+	if len(args) > 0 && args[0] == "delete" {
+		deleteSaves(slots, args[1:])
+		return
+	}
+	printSaves(os.Stdout, slots)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Saves carry the number of the format they were written in, so a save from
+// a newer build is refused with a plain message instead of a parse error.
+// The formats so far have only added records or moved them, and the loaders
+// still read every older layout as it is, so nothing is rewritten on load.
+//
+// CSV formats: 2 added the VERSION record, 3 the SETTINGS record, 4 moved
+// the names and seats from the NAMES record and the seats setting to BOARD
+// headers, and 5 ended the save in a CHECKSUM record. JSON format 2 added
+// the checksum.
+
+// csvVersion is the CSV format saveToCSV writes, in a VERSION record before
+// everything else. Saves from before the formats were numbered have no
+// VERSION record and are format 1.
+const csvVersion = 5
+
+// jsonVersion is the JSON format saveToJSON writes. JSON saves without a
+// version are format 1.
+const jsonVersion = 2
+
+// checkCSVVersion takes the VERSION record off records, refusing a save in
+// a newer format than csvVersion.
+func checkCSVVersion(records [][]string) ([][]string, error) {
+	version := 1
+	if len(records) > 0 && len(records[0]) > 0 && records[0][0] == "VERSION" {
+		if len(records[0]) < 2 {
+			return nil, fmt.Errorf("VERSION record missing format number")
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(records[0][1]))
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("VERSION record: %q is not a format number", records[0][1])
+		}
+		version, records = v, records[1:]
+	}
+	if version > csvVersion {
+		return nil, fmt.Errorf("the save is in format %d, newer than the %d this version reads; update the game to load it", version, csvVersion)
+	}
+	return records, nil
+}
+
+// checkJSONVersion refuses a JSON save in a newer format than jsonVersion.
+func checkJSONVersion(g SavedGame) error {
+	if g.Version > jsonVersion {
+		return fmt.Errorf("the save is in format %d, newer than the %d this version reads; update the game to load it", g.Version, jsonVersion)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// With -script the prompts read their answers from a file instead of the
+// keyboard, one line per answer in the same words a player would type. Lines
+// starting with # are comments. When the file runs out the game stops and
+// the final position is printed, so a script can set up a position and
+// leave it on screen.
+
+// scriptReader answers the prompts from a script file, echoing each answer
+// after its prompt as if it had been typed.
+type scriptReader struct {
+	r     *bufio.Reader
+	line  int
+	state *GameState // printed when the script ends
+	end   func()     // called when the script runs out; endScript if nil
+}
+
+// script is the script being played, if any.
+var script *scriptReader
+
+// openScript starts reading the prompts' answers from path.
+func openScript(path string) (*scriptReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptReader{r: bufio.NewReader(f)}, nil
+}
+
+// ReadString returns the script's next answer, skipping comments.
+func (s *scriptReader) ReadString(delim byte) (string, error) {
+	for {
+		line, err := s.r.ReadString(delim)
+		if line == "" && err != nil {
+			if err == io.EOF {
+				s.finish()
+			}
+			return "", err
+		}
+		s.line++
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if !strings.HasSuffix(line, string(delim)) {
+			line += string(delim)
+		}
+		fmt.Print(line)
+		return line, nil
+	}
+}
+
+// finish ends the game once the script has no more answers.
+func (s *scriptReader) finish() {
+	if s.end != nil {
+		s.end()
+		return
+	}
+	s.endScript()
+}
+
+// endScript prints where the script left the game and exits.
+func (s *scriptReader) endScript() {
+	fmt.Printf(tr("Script finished after %d lines.\n"), s.line)
+	if s.state != nil {
+		s.state.PrettyPrintBoardsGridCentered()
+		s.state.clearRunning()
+	}
+	stopTUI()
+	stopSpectator()
+	endTranscript()
+	os.Exit(0)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// takeControl hands the listed seats (e.g. "1,3") to the human at the
+// keyboard for testing. The seats are marked so output and dumps make clear
+// that one person is playing them on another player's behalf.
+func (state *GameState) takeControl(spec string) error {
+	for _, f := range strings.Split(spec, ",") {
+		seat, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return fmt.Errorf("seat %q is not a number", f)
+		}
+		if seat < 0 || seat >= len(state.Boards) {
+			return fmt.Errorf("seat %d does not exist (have %d)", seat, len(state.Boards))
+		}
+		b := state.Boards[seat]
+		b.IsAi = false
+		b.Controlled = true
+	}
+	return nil
+}
+
+// seatLabel names a seat the way the board headers do.
+func (state *GameState) seatLabel(seat int) string {
+	b := state.Boards[seat]
+	switch {
+	case b.Name != "" && b.Controlled:
+		return b.Name + tr(" [test]")
+	case b.Name != "":
+		return b.Name
+	case b.IsAi:
+		return fmt.Sprintf(tr("Computer %d"), seat)
+	case b.Controlled:
+		return fmt.Sprintf(tr("Player %d [test]"), seat)
+	}
+	return fmt.Sprintf(tr("Player %d"), seat)
+}
+
+// Backfill records a seat a computer took over after its player left, so
+// results of such games can be told apart.
+type Backfill struct {
+	Seat     int
+	Strategy string
+	At       time.Time
+}
+
+// backfillSeat hands an abandoned human seat to a computer playing the named
+// strategy, rather than leaving it to discard every turn.
+func (state *GameState) backfillSeat(seat int, strategy string) error {
+	if seat < 0 || seat >= len(state.Boards) {
+		return fmt.Errorf("seat %d does not exist (have %d)", seat, len(state.Boards))
+	}
+	s, err := lookupStrategy(strategy)
+	if err != nil {
+		return err
+	}
+	b := state.Boards[seat]
+	if b.IsAi {
+		return fmt.Errorf("seat %d is already a computer", seat)
+	}
+	b.IsAi = true
+	b.Controlled = false
+	b.Strategy = s.Name()
+	state.Backfills = append(state.Backfills, Backfill{Seat: seat, Strategy: s.Name(), At: time.Now()})
+	return nil
+}
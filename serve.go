@@ -0,0 +1,756 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The serve command runs games over HTTP, so a web or phone front end can
+// play against the engine without its own copy of the rules:
+//
+//	POST /games                          start a game, answering its state
+//	GET  /games/{id}                     the game's state
+//	POST /games/{id}/moves               a move by the seat to play
+//	GET  /games/{id}/moves               the moves it may make now
+//	GET  /games/{id}/recommendations     the engine's advice for that seat
+//	POST /games/{id}/seats/{n}/backfill  hand a seat whose player left to the computer
+//	GET  /games/{id}/socket?seat=1       a WebSocket to play a seat over
+//	POST /unlucky.Engine/{method}        gRPC, as unlucky.proto has it
+//
+// Everything is JSON. A game is started with
+//
+//	{"players": 2, "humans": 1, "names": ["Ann"], "strategy": "greedy", "seed": 7}
+//
+// or with "position" holding a position string, which brings its boards
+// and rules along. The answer alone carries "tokens", a secret for each
+// human seat ("" for the computer's) that whoever plays the seat sends with
+// its moves, as {"type": "draw", "token": "..."}. A human turn is two moves, as at the keyboard: taking a
+// tile, {"type": "draw"} from the pile or {"type": "take", "tile": 7} from
+// the table, then playing it, {"type": "place", "cell": "B3"} (a swap when
+// the cell holds a tile; "partner": true in team play) or {"type":
+// "discard"}. The computer seats move as soon as their turn comes, so each
+// answer leaves a human to play or the game over. Boards are rows of tiles,
+// 0 for an empty cell, -1 a wildcard and -2 a hole. Errors come as
+// {"error": "..."}.
+//
+// A seat whose player has left for good is handed to the computer with
+// {"strategy": "cautious", "token": "..."}, the token of any seat at the
+// table, and the game's "backfills" say which seats went when, so the
+// results of such games can be told apart.
+//
+// The engine keeps its board size and random numbers in globals, so the
+// server plays one request at a time, each game with its own. Games are
+// forgotten once left alone for a while, finished ones sooner, and new
+// games are refused with 503 while the server holds as many as it keeps.
+
+// defaultServeAddress is where serve listens when not told.
+const defaultServeAddress = "localhost:8080"
+
+// Limits on what a client may send, far above any real request.
+const (
+	maxServeBody     = 1 << 16 // bytes in a request's JSON
+	maxServePosition = 1 << 11 // bytes in a position to start from
+	maxServedGames   = 1000    // games kept at once
+)
+
+// How long a game is kept without a request or a socket, once over and
+// while still being played.
+const (
+	servedOverIdle = 10 * time.Minute
+	servedIdle     = 2 * time.Hour
+)
+
+// servedGame is a game the server plays.
+type servedGame struct {
+	id     string
+	state  *GameState
+	size   int        // the board size it is played on
+	rng    *rand.Rand // its own random numbers
+	over   bool
+	winner int       // the seat that won, -1 for none
+	tokens []string  // the secret each human seat's moves carry, "" for a computer's
+	used   time.Time // when a request last named it
+
+	sockets map[*gameSocket]bool // the clients connected over WebSockets
+	told    int                  // moves in the history the sockets have been sent
+}
+
+// gameServer holds the games being played.
+type gameServer struct {
+	mu    sync.Mutex
+	games map[string]*servedGame
+	next  int
+}
+
+// serveError is a request the game refuses, with the HTTP status to say so.
+type serveError struct {
+	status int
+	msg    string
+}
+
+func (e *serveError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &serveError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...any) error {
+	return &serveError{http.StatusConflict, fmt.Sprintf(format, args...)}
+}
+
+func forbidden(format string, args ...any) error {
+	return &serveError{http.StatusForbidden, fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...any) error {
+	return &serveError{http.StatusNotFound, fmt.Sprintf(format, args...)}
+}
+
+// servedSeat is a seat as the state shows it.
+type servedSeat struct {
+	Name     string `json:"name"`
+	Computer bool   `json:"computer"`
+}
+
+// servedMove is a move as the state and the recommendations show it.
+type servedMove struct {
+	Seat    int      `json:"seat"`
+	Type    string   `json:"type"`
+	Tile    int      `json:"tile"`
+	Cell    string   `json:"cell,omitempty"`
+	Partner bool     `json:"partner,omitempty"`
+	Score   *float64 `json:"score,omitempty"`
+}
+
+// servedState is what GET /games/{id} answers.
+type servedState struct {
+	ID        string           `json:"id"`
+	Seats     []servedSeat     `json:"seats"`
+	Boards    [][][]int        `json:"boards"`
+	Table     []int            `json:"table"`
+	Pile      int              `json:"pile"`
+	Current   int              `json:"current"`
+	Drawn     int              `json:"drawn"` // the tile the current seat holds, 0 before drawing
+	Over      bool             `json:"over"`
+	Winner    int              `json:"winner"`
+	History   []servedMove     `json:"history"`
+	Position  string           `json:"position"`
+	Backfills []servedBackfill `json:"backfills,omitempty"` // seats the computer took over
+}
+
+// servedBackfill is a seat the computer took over, as the state shows it.
+type servedBackfill struct {
+	Seat     int       `json:"seat"`
+	Strategy string    `json:"strategy"`
+	At       time.Time `json:"at"`
+}
+
+// createdGame is what POST /games answers: the game, and the seats' tokens
+// that no other answer shows.
+type createdGame struct {
+	servedState
+	Tokens []string `json:"tokens"`
+}
+
+// newGameRequest is the body of POST /games.
+type newGameRequest struct {
+	Players  int      `json:"players"`
+	Humans   *int     `json:"humans"`
+	Names    []string `json:"names"`
+	Strategy string   `json:"strategy"`
+	Position string   `json:"position"`
+	Seed     *int64   `json:"seed"`
+}
+
+// moveRequest is the body of POST /games/{id}/moves.
+type moveRequest struct {
+	Type    string `json:"type"`
+	Tile    int    `json:"tile"`
+	Cell    string `json:"cell"`
+	Partner bool   `json:"partner"`
+	Token   string `json:"token"` // the seat's, from the game's creation
+}
+
+// backfillRequest is the body of POST /games/{id}/seats/{n}/backfill.
+type backfillRequest struct {
+	Strategy string `json:"strategy"` // the default strategy when left out
+	Token    string `json:"token"`    // a seat's at the table, from the game's creation
+}
+
+// serveGames serves games at addr until the server fails.
+func serveGames(addr string) error {
+	fmt.Printf("Serving games on http://%s/games, and gRPC there too\n", addr)
+	srv := &http.Server{Addr: addr, Handler: newGameServer().handler(), Protocols: serveProtocols()}
+	return srv.ListenAndServe()
+}
+
+// serveProtocols is HTTP/1 for the JSON and the sockets, and HTTP/2
+// without TLS for gRPC.
+func serveProtocols() *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	return p
+}
+
+func newGameServer() *gameServer {
+	return &gameServer{games: map[string]*servedGame{}}
+}
+
+// handler routes the requests.
+func (s *gameServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /games", func(w http.ResponseWriter, r *http.Request) {
+		var req newGameRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeServeError(w, err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		g, err := s.newGame(req)
+		if err != nil {
+			writeServeError(w, err)
+			return
+		}
+		defer g.enter()()
+		writeJSON(w, http.StatusCreated, createdGame{g.view(), g.tokens})
+	})
+	mux.HandleFunc("GET /games/{id}", s.withGame(func(g *servedGame, r *http.Request) (any, error) {
+		return g.view(), nil
+	}))
+	mux.HandleFunc("POST /games/{id}/moves", s.withGame(func(g *servedGame, r *http.Request) (any, error) {
+		var req moveRequest
+		if err := decodeBody(r, &req); err != nil {
+			return nil, err
+		}
+		if err := g.remoteMove(req); err != nil {
+			return nil, err
+		}
+		return g.view(), nil
+	}))
+	mux.HandleFunc("GET /games/{id}/moves", s.withGame(func(g *servedGame, r *http.Request) (any, error) {
+		return g.legalMoves()
+	}))
+	mux.HandleFunc("POST /games/{id}/seats/{seat}/backfill", s.withGame(func(g *servedGame, r *http.Request) (any, error) {
+		seat, err := strconv.Atoi(r.PathValue("seat"))
+		if err != nil {
+			return nil, badRequest("seat %q is not a number", r.PathValue("seat"))
+		}
+		var req backfillRequest
+		if err := decodeBody(r, &req); err != nil {
+			return nil, err
+		}
+		if err := g.backfill(seat, req); err != nil {
+			return nil, err
+		}
+		return g.view(), nil
+	}))
+	mux.HandleFunc("GET /games/{id}/socket", s.connect)
+	mux.HandleFunc("POST /unlucky.Engine/{method}", s.grpcHandler)
+	mux.HandleFunc("GET /games/{id}/recommendations", s.withGame(func(g *servedGame, r *http.Request) (any, error) {
+		return g.recommend()
+	}))
+	return mux
+}
+
+// withGame finds the game a request names and runs fn on it with the
+// engine set up for it, answering what fn returns.
+func (s *gameServer) withGame(fn func(g *servedGame, r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		g, err := s.game(r.PathValue("id"))
+		if err != nil {
+			writeServeError(w, err)
+			return
+		}
+		defer g.enter()()
+		v, err := fn(g, r)
+		if err != nil {
+			writeServeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxServeBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("unreadable request: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeServeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var se *serveError
+	if errors.As(err, &se) {
+		status = se.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// game finds the game id names, noting that it is in use.
+func (s *gameServer) game(id string) (*servedGame, error) {
+	g := s.games[id]
+	if g == nil {
+		return nil, notFound("no game %q", id)
+	}
+	g.used = time.Now()
+	return g, nil
+}
+
+// sweep forgets the games left alone too long as of now: finished games
+// after servedOverIdle, the rest after servedIdle. A game with a socket
+// connected is in use.
+func (s *gameServer) sweep(now time.Time) {
+	for id, g := range s.games {
+		idle := now.Sub(g.used)
+		if len(g.sockets) == 0 && (g.over && idle > servedOverIdle || idle > servedIdle) {
+			delete(s.games, id)
+		}
+	}
+}
+
+// enter puts the game's board size and random numbers in the engine's
+// globals and returns the function that puts the earlier ones back.
+func (g *servedGame) enter() func() {
+	size, random := BoardSize, rng
+	BoardSize, rng = g.size, g.rng
+	return func() { BoardSize, rng = size, random }
+}
+
+// newGame deals the game req asks for and plays the computer seats up to
+// the first human's turn.
+func (s *gameServer) newGame(req newGameRequest) (*servedGame, error) {
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	size, random := BoardSize, rng
+	defer func() { BoardSize, rng = size, random }()
+	BoardSize, rng = standardBoardSize, rand.New(rand.NewSource(seed))
+
+	s.sweep(time.Now())
+	if len(s.games) >= maxServedGames {
+		return nil, &serveError{http.StatusServiceUnavailable, fmt.Sprintf("the server is playing %d games, as many as it keeps; try again later", len(s.games))}
+	}
+	var state *GameState
+	if len(req.Position) > maxServePosition {
+		return nil, badRequest("a position of %d bytes, over %d", len(req.Position), maxServePosition)
+	}
+	if req.Position != "" {
+		state = &GameState{Heuristics: defaultHeuristics}
+		if err := state.decodePosition(req.Position); err != nil {
+			return nil, badRequest("invalid position: %v", err)
+		}
+		if req.Players != 0 && req.Players != len(state.Boards) {
+			return nil, badRequest("the position has %d boards, not %d", len(state.Boards), req.Players)
+		}
+	} else {
+		players := req.Players
+		if players == 0 {
+			players = 2
+		}
+		if players < 1 || players > maxPlayers {
+			return nil, badRequest("a game has 1-%d players, not %d", maxPlayers, players)
+		}
+		var err error
+		if state, err = newHeadlessGame(players); err != nil {
+			return nil, err
+		}
+	}
+	if state.HandSize > 0 {
+		return nil, badRequest("the hand variant is not served")
+	}
+	humans := 1
+	if req.Humans != nil {
+		humans = *req.Humans
+	}
+	if humans < 0 || humans > len(state.Boards) {
+		return nil, badRequest("%d humans at %d seats", humans, len(state.Boards))
+	}
+	if len(req.Names) > humans {
+		return nil, badRequest("%d names for %d humans", len(req.Names), humans)
+	}
+	if req.Strategy != "" {
+		if _, err := lookupStrategy(req.Strategy); err != nil {
+			return nil, badRequest("%v", err)
+		}
+	}
+	// the first seats are the humans', the rest the computer's
+	for i, b := range state.Boards {
+		b.Name, b.IsAi, b.Strategy = "", i >= humans, ""
+		if i < len(req.Names) {
+			b.Name = req.Names[i]
+		}
+	}
+	for _, b := range state.Boards {
+		if b.IsAi {
+			b.Name, b.Strategy = state.computerName(), req.Strategy
+		}
+	}
+	state.Seed = seed
+
+	s.next++
+	g := &servedGame{id: strconv.Itoa(s.next), state: state, size: BoardSize, rng: rng, winner: -1, used: time.Now(), sockets: map[*gameSocket]bool{}}
+	for _, b := range state.Boards {
+		token := ""
+		if !b.IsAi {
+			token = crand.Text()
+		}
+		g.tokens = append(g.tokens, token)
+	}
+	g.playComputers()
+	g.told = len(state.History)
+	s.games[g.id] = g
+	return g, nil
+}
+
+// move makes a move for the seat to play.
+func (g *servedGame) move(req moveRequest) error {
+	state := g.state
+	if g.over {
+		return conflict("the game is over")
+	}
+	switch req.Type {
+	case "draw", "take":
+		if state.Pending != nil {
+			return conflict("%s is already drawn; place or discard it", tileLabel(state.Pending.Tile))
+		}
+		if req.Type == "take" {
+			if !contains(state.Table, req.Tile) {
+				return badRequest("%s is not on the table", tileLabel(req.Tile))
+			}
+			if state.ForcedTable && len(state.bestMoves(req.Tile)) == 0 {
+				return badRequest("%s fits nowhere, and tiles taken from the table must be placed", tileLabel(req.Tile))
+			}
+			state.removeTileFromTable(req.Tile)
+			state.Pending = &Move{Type: Draw, Tile: req.Tile, FromTable: true}
+		} else {
+			tile, err := state.popDraw()
+			if err != nil {
+				g.finish(state.pileWinner())
+				return nil
+			}
+			state.Pending = &Move{Type: Draw, Tile: tile}
+		}
+		state.Draws++
+		return nil
+	case "place", "discard":
+		if state.Pending == nil {
+			return conflict("draw or take a tile first")
+		}
+		tile := state.Pending.Tile
+		if req.Type == "discard" {
+			if state.Pending.FromTable && state.ForcedTable {
+				return conflict("a tile taken from the table must be placed")
+			}
+			g.play(Move{Type: Discard, Tile: tile})
+			return nil
+		}
+		cell, err := parseCell(req.Cell)
+		if err != nil {
+			return badRequest("cell %q: %v", req.Cell, err)
+		}
+		if req.Partner && !state.Teams {
+			return badRequest("a partner's board is only played in team play")
+		}
+		target := state.Current
+		if req.Partner {
+			target = state.partner(target)
+		}
+		restore := state.asSeat(target)
+		feasible := state.isPlacementFeasible(tile, cell.R, cell.C)
+		restore()
+		if !feasible {
+			return badRequest("%s cannot go at %s", tileLabel(tile), cell)
+		}
+		move := Move{Type: Place, Tile: tile, Cell: &cell, Partner: req.Partner}
+		if old := state.Boards[target].Grid[cell.R][cell.C]; old != 0 {
+			move.Type, move.OldTile = Swap, old
+		}
+		g.play(move)
+		return nil
+	}
+	return badRequest("unknown move type %q: draw, take, place or discard", req.Type)
+}
+
+// remoteMove makes a move sent over HTTP or gRPC with the token of the
+// seat to play, unless the seat is played over a WebSocket, and tells the
+// sockets.
+func (g *servedGame) remoteMove(req moveRequest) error {
+	seat := g.state.Current
+	if g.socketFor(seat) != nil {
+		return conflict("%s plays over a WebSocket", g.state.seatLabel(seat))
+	}
+	if !g.over && !g.holdsSeat(seat, req.Token) {
+		return forbidden("the move needs %s's token", g.state.seatLabel(seat))
+	}
+	if err := g.move(req); err != nil {
+		return err
+	}
+	g.broadcast()
+	return nil
+}
+
+// holdsSeat reports whether token is the human seat's.
+func (g *servedGame) holdsSeat(seat int, token string) bool {
+	want := g.tokens[seat]
+	return want != "" && subtle.ConstantTimeCompare([]byte(want), []byte(token)) == 1
+}
+
+// atTable reports whether token is one of the human seats'.
+func (g *servedGame) atTable(token string) bool {
+	for seat := range g.tokens {
+		if g.holdsSeat(seat, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// backfill hands a human seat whose player has left to the computer,
+// playing strategy, and plays on when it is the seat's turn. A tile the
+// seat had drawn goes back where it came from, for the computer to choose
+// afresh.
+func (g *servedGame) backfill(seat int, req backfillRequest) error {
+	state := g.state
+	if seat < 0 || seat >= len(state.Boards) {
+		return badRequest("seat %d is not a seat 0-%d", seat, len(state.Boards)-1)
+	}
+	if !g.atTable(req.Token) {
+		return forbidden("backfilling a seat needs the token of a seat at the table")
+	}
+	switch {
+	case g.over:
+		return conflict("the game is over")
+	case state.Boards[seat].IsAi:
+		return conflict("%s is already the computer's", state.seatLabel(seat))
+	case g.socketFor(seat) != nil:
+		return conflict("%s is still played over a WebSocket", state.seatLabel(seat))
+	}
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = defaultStrategy.Name()
+	}
+	if err := state.backfillSeat(seat, strategy); err != nil {
+		return badRequest("%v", err)
+	}
+	g.tokens[seat] = ""
+	if seat == state.Current {
+		if p := state.Pending; p != nil {
+			if p.FromTable {
+				state.Table = append(state.Table, p.Tile)
+			} else {
+				state.Draw = append([]int{p.Tile}, state.Draw...)
+			}
+			state.Pending = nil
+			state.Draws--
+		}
+		g.playComputers()
+	}
+	g.broadcast()
+	return nil
+}
+
+// legalMoves lists the moves the seat to play may make: where to take a
+// tile from, or with a tile drawn, every cell it may go in and the discard.
+func (g *servedGame) legalMoves() ([]servedMove, error) {
+	state := g.state
+	if g.over {
+		return nil, conflict("the game is over")
+	}
+	seat := state.Current
+	moves := []servedMove{}
+	if state.Pending == nil {
+		moves = append(moves, servedMove{Seat: seat, Type: "draw"})
+		taken := map[int]bool{}
+		for _, t := range state.Table {
+			if !taken[t] && (!state.ForcedTable || len(state.bestMoves(t)) > 0) {
+				moves = append(moves, servedMove{Seat: seat, Type: "take", Tile: t})
+			}
+			taken[t] = true
+		}
+		return moves, nil
+	}
+	tile := state.Pending.Tile
+	boards := []int{seat}
+	if state.Teams {
+		boards = append(boards, state.partner(seat))
+	}
+	for _, target := range boards {
+		restore := state.asSeat(target)
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if !state.isPlacementFeasible(tile, r, c) {
+					continue
+				}
+				m := Move{Type: Place, Tile: tile, Cell: &Cell{R: r, C: c}, Partner: target != seat, Score: state.placementScore(tile, r, c)}
+				if state.Boards[target].Grid[r][c] != 0 {
+					m.Type = Swap
+				}
+				moves = append(moves, servedMoveOf(seat, m, true))
+			}
+		}
+		restore()
+	}
+	if !state.Pending.FromTable || !state.ForcedTable {
+		moves = append(moves, servedMove{Seat: seat, Type: "discard", Tile: tile})
+	}
+	return moves, nil
+}
+
+// play makes a human's move with the tile drawn and goes on to the next
+// turn unless the move ends the game or earns a Bruno extra turn.
+func (g *servedGame) play(move Move) {
+	state := g.state
+	seat := state.Current
+	state.Pending = nil
+	state.commitMove(move)
+	state.History = append(state.History, Played{Seat: seat, Move: move})
+	if target := state.moveSeat(seat, move); state.Boards[target].IsFull() {
+		g.finish(target)
+		return
+	}
+	if state.ExtraTurns < state.Bruno.maxChain() && state.earnsExtraTurn(move) {
+		state.ExtraTurns++
+		return
+	}
+	g.endTurn()
+	g.playComputers()
+}
+
+// endTurn passes the turn to the next seat still playing.
+func (g *servedGame) endTurn() {
+	state := g.state
+	state.Turn++
+	state.ExtraTurns = 0
+	for i := 0; i < len(state.Boards); i++ {
+		state.Current = (state.Current + 1) % len(state.Boards)
+		if !state.Boards[state.Current].Resigned {
+			return
+		}
+	}
+}
+
+// playComputers plays the computer seats' turns until a human is to play
+// or the game is over.
+func (g *servedGame) playComputers() {
+	state := g.state
+	for !g.over && state.Boards[state.Current].IsAi {
+		seat := state.Current
+		for extra := 0; ; extra++ {
+			move, dry := state.headlessTurn(state.strategyFor(seat))
+			if dry {
+				g.finish(state.pileWinner())
+				return
+			}
+			if move.Type != Steal {
+				state.Draws++
+			}
+			state.History = append(state.History, Played{Seat: seat, Move: move})
+			if target := state.moveSeat(seat, move); state.Boards[target].IsFull() {
+				g.finish(target)
+				return
+			}
+			if extra >= state.Bruno.maxChain() || !state.earnsExtraTurn(move) {
+				break
+			}
+		}
+		g.endTurn()
+	}
+}
+
+// finish ends the game, won by winner or by no one when it is -1.
+func (g *servedGame) finish(winner int) {
+	g.over, g.winner = true, winner
+	g.state.Pending = nil
+}
+
+// view is the game's state as the server answers it.
+func (g *servedGame) view() servedState {
+	state := g.state
+	v := servedState{ID: g.id, Table: append([]int{}, state.Table...), Pile: len(state.Draw), Current: state.Current,
+		Over: g.over, Winner: g.winner, History: []servedMove{}, Position: state.encodePosition()}
+	for i, b := range state.Boards {
+		v.Seats = append(v.Seats, servedSeat{Name: state.seatLabel(i), Computer: b.IsAi})
+		rows := make([][]int, g.size)
+		for r := range rows {
+			rows[r] = append([]int{}, b.Grid[r][:g.size]...)
+		}
+		v.Boards = append(v.Boards, rows)
+	}
+	if state.Pending != nil {
+		v.Drawn = state.Pending.Tile
+	}
+	for _, p := range state.History {
+		v.History = append(v.History, servedMoveOf(p.Seat, p.Move, false))
+	}
+	for _, b := range state.Backfills {
+		v.Backfills = append(v.Backfills, servedBackfill{Seat: b.Seat, Strategy: b.Strategy, At: b.At})
+	}
+	return v
+}
+
+// servedMoveOf shows a move, with its engine score when scored is set.
+func servedMoveOf(seat int, m Move, scored bool) servedMove {
+	sm := servedMove{Seat: seat, Type: moveTypeNames[m.Type], Tile: m.Tile, Partner: m.Partner}
+	if m.Type == Draw && m.FromTable {
+		sm.Type = "take"
+	}
+	if m.Cell != nil {
+		sm.Cell = m.Cell.String()
+	}
+	if scored {
+		score := m.Score
+		sm.Score = &score
+	}
+	return sm
+}
+
+// servedAdvice is what GET /games/{id}/recommendations answers: before
+// drawing, whether to take a tile from the table or draw from the pile;
+// with a tile drawn, the moves for it, best first.
+type servedAdvice struct {
+	Take  int          `json:"take,omitempty"` // the table tile to take, 0 to draw from the pile
+	Moves []servedMove `json:"moves"`
+}
+
+// recommend is the engine's advice for the seat to play.
+func (g *servedGame) recommend() (servedAdvice, error) {
+	state := g.state
+	if g.over {
+		return servedAdvice{}, conflict("the game is over")
+	}
+	advice := servedAdvice{Moves: []servedMove{}}
+	tile := 0
+	if state.Pending != nil {
+		tile = state.Pending.Tile
+	} else if move, fromTable := state.drawTileRecommendation(); fromTable {
+		advice.Take, tile = move.Tile, move.Tile
+	}
+	if tile != 0 {
+		for _, m := range state.bestMoves(tile) {
+			advice.Moves = append(advice.Moves, servedMoveOf(state.Current, m, true))
+		}
+	}
+	return advice, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A CSV save keeps the game's settings in a SETTINGS record of key=value
+// fields, so a loaded game goes on under the same rules instead of asking
+// for the variants again:
+//
+//	SETTINGS,mode=play,rules=bruno+hand2,bruno=row/2
+//
+// mode is play or analyze, rules are named as in position strings and bruno
+// gives the Bruno directions and chain limit. Each board's rows follow a
+// BOARD header with the player's name and who plays the seat:
+//
+//	BOARD,Deep Tile,computer/cautious
+//
+// a person (human) or the computer, with its strategy when it is not the
+// default. Saves from before the headers keep the names in a NAMES record
+// and the seats in the settings, as seats=human+computer/cautious; both
+// still load, and saves without settings ask as before.
+
+// csvSettings is the SETTINGS record of the game.
+func (state *GameState) csvSettings() []string {
+	mode := "play"
+	if state.Analyze {
+		mode = "analyze"
+	}
+	rec := []string{"SETTINGS", "mode=" + mode, "rules=" + state.positionRules()}
+	if state.BrunoVariant {
+		rec = append(rec, fmt.Sprintf("bruno=%s/%d", state.Bruno.directions(), state.Bruno.maxChain()))
+	}
+	return rec
+}
+
+// boardHeader is the BOARD record heading a board's rows.
+func boardHeader(b *Board) []string {
+	return []string{"BOARD", b.Name, seatKind(b)}
+}
+
+// seatKind says who plays a board, as BOARD headers and seats settings do.
+func seatKind(b *Board) string {
+	switch {
+	case b.IsAi && b.Strategy != "":
+		return "computer/" + b.Strategy
+	case b.IsAi:
+		return "computer"
+	}
+	return "human"
+}
+
+// parseSeatKind reads who plays a board.
+func parseSeatKind(s string) (computer bool, strategy string, err error) {
+	kind, strategy, _ := strings.Cut(strings.TrimSpace(s), "/")
+	if kind != "human" && kind != "computer" {
+		return false, "", fmt.Errorf("seat %q is not human or computer", s)
+	}
+	if strategy != "" {
+		if _, err := lookupStrategy(strategy); err != nil {
+			return false, "", fmt.Errorf("seat %q: %w", s, err)
+		}
+	}
+	return kind == "computer", strategy, nil
+}
+
+// parseBoardHeader reads a BOARD record into b.
+func parseBoardHeader(rec []string, b *Board) error {
+	if len(rec) != 3 {
+		return fmt.Errorf("BOARD record needs a name and human or computer, got %d fields", len(rec)-1)
+	}
+	name, err := checkName(rec[1])
+	if err != nil {
+		return fmt.Errorf("BOARD record: %w", err)
+	}
+	if b.IsAi, b.Strategy, err = parseSeatKind(rec[2]); err != nil {
+		return fmt.Errorf("BOARD record: %w", err)
+	}
+	b.Name = name
+	return nil
+}
+
+// parseCSVSettings applies the fields of a SETTINGS record and returns its
+// seats, which wait for the boards to be read.
+func (state *GameState) parseCSVSettings(fields []string) ([]string, error) {
+	var seats []string
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a key=value setting", field)
+		}
+		switch key {
+		case "mode":
+			if value != "play" && value != "analyze" {
+				return nil, fmt.Errorf("mode %q is not play or analyze", value)
+			}
+			state.Analyze = value == "analyze"
+		case "rules":
+			if err := state.applyRules(value); err != nil {
+				return nil, err
+			}
+		case "bruno":
+			along, chain, _ := strings.Cut(value, "/")
+			dirs, err := parseBrunoDirections(along)
+			if err != nil {
+				return nil, fmt.Errorf("bruno: %w", err)
+			}
+			n, err := strconv.Atoi(chain)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("bruno: chain limit %q is not a number", chain)
+			}
+			state.Bruno = BrunoRules{Directions: dirs, MaxChain: n}
+		case "seats":
+			seats = strings.Split(value, "+")
+			for _, s := range seats {
+				if _, _, err := parseSeatKind(s); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return seats, nil
+}
+
+// applySeats makes the boards the people's and the computer's the seats
+// setting of an older save says.
+func (state *GameState) applySeats(seats []string) error {
+	if seats == nil {
+		return nil
+	}
+	if len(seats) != len(state.Boards) {
+		return fmt.Errorf("SETTINGS record has %d seats for %d boards", len(seats), len(state.Boards))
+	}
+	for i, s := range seats {
+		b := state.Boards[i]
+		b.IsAi, b.Strategy, _ = parseSeatKind(s)
+	}
+	return nil
+}
+
+// hasCSVSettings reports whether the CSV save at filename keeps its
+// settings, so the game need not ask for them.
+func hasCSVSettings(filename string) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return false
+	}
+	for _, rec := range records {
+		if len(rec) > 0 && rec[0] == "SETTINGS" {
+			return true
+		}
+	}
+	return false
+}
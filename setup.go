@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Setup holds answers to the setup questions given on the command line, so
+// a game can be started from a script or another tool without them being
+// asked. A question is skipped when its flag was given, or when -defaults
+// takes the default answer for everything left out.
+type Setup struct {
+	given    map[string]bool
+	defaults bool
+
+	load               string
+	player             string
+	position           string
+	paste              bool
+	analyze            bool
+	increasingDiagonal bool
+	humans             int
+	names              string
+	computers          int
+	strategies         string
+	teams              bool
+	preset             string
+	sharedPool         bool
+	mulligan           bool
+	handicaps          string
+	bruno              bool
+	brunoAlong         string
+	brunoChain         int
+	nonDecreasing      bool
+	forcedTable        bool
+	openPile           bool
+	steal              bool
+	hand               int
+	clock              time.Duration
+	clockForfeit       bool
+}
+
+var setup = &Setup{given: map[string]bool{}}
+
+// register adds the setup flags to fs.
+func (s *Setup) register(fs *flag.FlagSet) {
+	fs.BoolVar(&s.defaults, "defaults", false, "take the default answer to every setup question not given by a flag")
+	fs.StringVar(&s.load, "load", "", "CSV or JSON save to load the game from, instead of setting up a new one")
+	fs.StringVar(&s.player, "player", "", "your profile's name: you take the first seat, set up like your last game unless other flags say otherwise")
+	fs.StringVar(&s.position, "position", "", "start from a position string, as the position command prints it, with the first -humans seats played by people")
+	fs.BoolVar(&s.paste, "paste", false, "start from boards pasted on standard input as the game draws them, or as rows of tiles and dots, ending in a blank line")
+	fs.BoolVar(&s.analyze, "analyze", false, "analyze mode: enter the boards by hand, with no draw pile")
+	fs.BoolVar(&s.increasingDiagonal, "increasing-diagonal", false, "the main diagonal must strictly increase too")
+	fs.IntVar(&s.humans, "humans", 1, "number of human players (0-4)")
+	fs.StringVar(&s.names, "names", "", "comma separated names of the human players (default: Player 0, Player 1, ...)")
+	fs.IntVar(&s.computers, "computers", 1, "number of computer players (0-4)")
+	fs.StringVar(&s.strategies, "strategies", "", "comma separated strategy of each computer seat (default: -ai)")
+	fs.BoolVar(&s.teams, "teams", false, "with four players, play 2v2 teams")
+	fs.StringVar(&s.preset, "preset", defaultPreset, "game preset: quick, standard, grande or custom")
+	fs.BoolVar(&s.sharedPool, "shared-pool", false, "one set of tiles for all players")
+	fs.BoolVar(&s.mulligan, "mulligan", false, "each player may redraw their starting diagonal once")
+	fs.StringVar(&s.handicaps, "handicaps", "", "comma separated handicap of each seat, e.g. d1,,peek")
+	fs.BoolVar(&s.bruno, "bruno", false, "Bruno variant: an extra turn for placing next to a twin")
+	fs.StringVar(&s.brunoAlong, "bruno-along", "diagonal", "directions Bruno twins count along: diagonal, row, column, orthogonal or any")
+	fs.IntVar(&s.brunoChain, "bruno-chain", maxExtraTurns, "most Bruno extra turns in a row")
+	fs.BoolVar(&s.nonDecreasing, "nondecreasing", false, "allow equal values next to each other in a row or column")
+	fs.BoolVar(&s.forcedTable, "forced-table", false, "a tile taken from the table must be placed")
+	fs.BoolVar(&s.openPile, "open-pile", false, "the top tile of the draw pile is face up")
+	fs.BoolVar(&s.steal, "steal", false, "once per game a player may steal a tile from another board")
+	fs.IntVar(&s.hand, "hand", 0, fmt.Sprintf("tiles each player holds in hand (0-%d)", maxHandSize))
+	fs.DurationVar(&s.clock, "clock", 0, "time on each human's clock, e.g. 5m (0 for no clock)")
+	fs.BoolVar(&s.clockForfeit, "clock-forfeit", false, "a player out of time forfeits instead of the computer moving for them")
+}
+
+// noteGiven records which flags of fs were given, after parsing.
+func (s *Setup) noteGiven(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) { s.given[f.Name] = true })
+}
+
+// has reports whether the answer to the question behind the named flag
+// comes from the command line.
+func (s *Setup) has(name string) bool {
+	return s.defaults || s.given[name]
+}
+
+// yesNo answers a y/N question from the named flag, or asks it.
+func (s *Setup) yesNo(name string, value bool, ask func() bool) bool {
+	if s.has(name) {
+		return value
+	}
+	return ask()
+}
+
+// seatHandicaps reads -handicaps for players seats.
+func (s *Setup) seatHandicaps(players int) ([]Handicap, error) {
+	handicaps := make([]Handicap, players)
+	for seat, spec := range strings.Split(s.handicaps, ",") {
+		if seat >= players {
+			return nil, fmt.Errorf("-handicaps lists %d seats, the game has %d", len(strings.Split(s.handicaps, ",")), players)
+		}
+		h, err := parseHandicap(spec)
+		if err != nil {
+			return nil, fmt.Errorf("-handicaps seat %d: %w", seat, err)
+		}
+		handicaps[seat] = h
+	}
+	return handicaps, nil
+}
+
+// seatStrategies reads -strategies for the computers seats, filling any
+// left out with the default strategy.
+func (s *Setup) seatStrategies(computers int) ([]string, error) {
+	names := make([]string, computers)
+	var specs []string
+	if s.strategies != "" {
+		specs = strings.Split(s.strategies, ",")
+	}
+	if len(specs) > computers {
+		return nil, fmt.Errorf("-strategies lists %d seats, the game has %d computers", len(specs), computers)
+	}
+	for i := range names {
+		names[i] = defaultStrategy.Name()
+		if i < len(specs) && strings.TrimSpace(specs[i]) != "" {
+			st, err := lookupStrategy(specs[i])
+			if err != nil {
+				return nil, err
+			}
+			names[i] = st.Name()
+		}
+	}
+	return names, nil
+}
+
+// seatNames reads -names for the humans seats; those left out stay
+// unnamed.
+func (s *Setup) seatNames(humans int) ([]string, error) {
+	names := make([]string, humans)
+	if s.names == "" {
+		return names, nil
+	}
+	specs := strings.Split(s.names, ",")
+	if len(specs) > humans {
+		return nil, fmt.Errorf("-names lists %d players, the game has %d humans", len(specs), humans)
+	}
+	for i, spec := range specs {
+		name, err := checkName(spec)
+		if err != nil {
+			return nil, fmt.Errorf("-names: %w", err)
+		}
+		names[i] = name
+	}
+	return names, nil
+}
+
+// brunoRules reads -bruno-along and -bruno-chain.
+func (s *Setup) brunoRules() (BrunoRules, error) {
+	dirs, err := parseBrunoDirections(s.brunoAlong)
+	if err != nil {
+		return BrunoRules{}, err
+	}
+	if s.brunoChain < 1 || s.brunoChain > maxExtraTurns {
+		return BrunoRules{}, fmt.Errorf("-bruno-chain must be 1-%d", maxExtraTurns)
+	}
+	return BrunoRules{Directions: dirs, MaxChain: s.brunoChain}, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// In the shared-pool variant the pile holds a single set of tiles 1-maxTile
+// whatever the number of players, so every value is unique game-wide: once
+// a 7 is on someone's board no other 7 will come, and an empty run of cells
+// needs as many different values as it has cells.
+
+// canFillGap reports whether cells empty cells strictly between lo and hi,
+// climbing by at least step, can all be filled from remaining. A value is
+// used once per line unless step is 0; a wildcard fills any one cell.
+func canFillGap(remaining []int, lo, hi, cells, step int) bool {
+	values := make([]int, 0, len(remaining))
+	for _, t := range remaining {
+		if t == Wildcard {
+			cells--
+			continue
+		}
+		values = append(values, t)
+	}
+	if cells <= 0 {
+		return true
+	}
+	sort.Ints(values)
+	last := lo
+	for _, t := range values {
+		if t > hi-step {
+			break
+		}
+		if t >= last+step {
+			last = t
+			if cells--; cells == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func promptSharedPool(maxTile int) bool {
+	fmt.Printf("Share one set of tiles 1-%d between all players, so every value is unique? (y/N): ", maxTile)
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
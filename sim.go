@@ -0,0 +1,107 @@
+package main
+
+const (
+	maxExtraTurns    = 8    // default cap on chained Bruno extra turns
+	maxHeadlessTurns = 1000 // guards against table swap cycles
+)
+
+// clone deep-copies the parts of the state a game mutates, so simulations
+// can play on without touching the real game.
+func (state *GameState) clone() *GameState {
+	c := *state
+	c.Boards = make([]*Board, len(state.Boards))
+	for i, b := range state.Boards {
+		nb := *b
+		c.Boards[i] = &nb
+	}
+	c.Table = append([]int{}, state.Table...)
+	c.Draw = append([]int{}, state.Draw...)
+	for _, b := range c.Boards {
+		b.Hand = append([]int{}, b.Hand...)
+	}
+	c.ABTest, c.MoveLog, c.EvalExport = nil, nil, nil
+	return &c
+}
+
+// earnsExtraTurn reports whether a just committed move earns a Bruno extra
+// turn, without announcing it.
+func (state *GameState) earnsExtraTurn(move Move) bool {
+	if !state.BrunoVariant || move.Cell == nil || move.Type == Steal {
+		return false
+	}
+	_, ok := state.brunoMatch(state.Boards[state.moveSeat(state.Current, move)], move.Tile, move.Cell.R, move.Cell.C)
+	return ok
+}
+
+// headlessTurn plays one decision for the current seat with strat, without
+// any output. It returns the move made and whether the pile ran dry.
+func (state *GameState) headlessTurn(strat Strategy) (Move, bool) {
+	if steal, ok := state.stealRecommendation(); ok {
+		state.commitMove(steal)
+		return steal, false
+	}
+	move, fromTable := strat.PickFromTable(state)
+	if fromTable && state.tablePickAllowed(move) && !state.prefersPile(move) {
+		state.removeTileFromTable(move.Tile)
+	} else {
+		var tile int
+		if state.holdsHand() {
+			tile, _ = state.bestHandTile()
+			state.playFromHand(tile)
+		} else {
+			var err error
+			if tile, err = state.popDraw(); err != nil {
+				return Move{}, true
+			}
+		}
+		var ok bool
+		move, ok = strat.ChooseMove(state, tile)
+		if !ok {
+			move = Move{Type: Discard, Tile: tile}
+		}
+	}
+	state.commitMove(move)
+	state.refillHand(state.Current)
+	return move, false
+}
+
+// playHeadless plays the game to the end silently, seat i using seats[i],
+// and returns the winning seat, or pileWinner if the pile ran dry first. observe, if
+// not nil, sees every move right after it is made.
+func (state *GameState) playHeadless(seats []Strategy, observe func(seat int, move Move)) int {
+	for turn := 0; turn < maxHeadlessTurns; turn++ {
+		seat := state.Current
+		for extra := 0; ; extra++ {
+			move, dry := state.headlessTurn(seats[seat])
+			if dry {
+				return state.pileWinner()
+			}
+			if observe != nil {
+				observe(seat, move)
+			}
+			if target := state.moveSeat(seat, move); state.Boards[target].IsFull() {
+				return target
+			}
+			if extra >= state.Bruno.maxChain() || !state.earnsExtraTurn(move) {
+				break
+			}
+		}
+		state.Current = (seat + 1) % len(state.Boards)
+	}
+	return -1
+}
+
+// newHeadlessGame deals a fresh random game for the given number of
+// computer seats.
+func newHeadlessGame(players int) (*GameState, error) {
+	state := &GameState{Heuristics: defaultHeuristics}
+	state.initDrawStack(players)
+	for p := 0; p < players; p++ {
+		b := &Board{IsAi: true}
+		if err := state.fillRandomDiagonal(b); err != nil {
+			return nil, err
+		}
+		state.Boards = append(state.Boards, b)
+	}
+	return state, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// With no humans at the table the game plays itself; the spectator paces
+// it so it can be watched, one move per delay, and takes single keys to
+// pause, step, change the speed or stop.
+
+// defaultWatchDelay is the pause between moves of a game with no humans.
+const defaultWatchDelay = time.Second
+
+// fastestWatchDelay and slowestWatchDelay bound the speed keys.
+const (
+	fastestWatchDelay = 50 * time.Millisecond
+	slowestWatchDelay = 10 * time.Second
+)
+
+// spectator paces the running computer-only game, or is nil.
+var spectator *Spectator
+
+type Spectator struct {
+	delay  time.Duration
+	paused bool
+	keys   <-chan rune
+	saved  string // stty settings to restore, empty when stty is not used
+}
+
+// startSpectator paces the game by delay and listens for keys; on a
+// terminal they need no enter.
+func startSpectator(delay time.Duration) *Spectator {
+	s := &Spectator{delay: delay}
+	if isTerminal(os.Stdin) {
+		if saved, err := stty("-g"); err == nil {
+			if _, err := stty("-icanon", "-echo", "min", "1"); err == nil {
+				s.saved = strings.TrimSpace(saved)
+			}
+		}
+	}
+	keys := make(chan rune)
+	go func() {
+		in := bufio.NewReader(os.Stdin)
+		for {
+			r, _, err := in.ReadRune()
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- r
+		}
+	}()
+	s.keys = keys
+	fmt.Println(s.help())
+	return s
+}
+
+// onlyComputers reports whether no human plays a seat.
+func (state *GameState) onlyComputers() bool {
+	for _, b := range state.Boards {
+		if !b.IsAi {
+			return false
+		}
+	}
+	return true
+}
+
+// stopSpectator gives the terminal back its settings, if watching.
+func stopSpectator() {
+	if spectator != nil && spectator.saved != "" {
+		stty(spectator.saved)
+	}
+	spectator = nil
+}
+
+func (s *Spectator) help() string {
+	return fmt.Sprintf("Watching at %s a move: space pauses, n steps, + and - change the speed, q stops.", s.delay)
+}
+
+// wait holds the game between two moves: for the delay, or while paused
+// until a step or resume. It reports true when the viewer stops watching.
+func (s *Spectator) wait() bool {
+	var timer <-chan time.Time
+	if !s.paused {
+		timer = time.After(s.delay)
+	}
+	for {
+		select {
+		case <-timer:
+			return false
+		case key, ok := <-s.keys:
+			if !ok {
+				// nothing more to read: play on at the set pace
+				s.keys = nil
+				if s.paused {
+					s.paused, timer = false, time.After(s.delay)
+				}
+				continue
+			}
+			switch key {
+			case ' ', 'p':
+				s.paused = !s.paused
+				if s.paused {
+					fmt.Println("Paused: space resumes, n plays the next move, q stops.")
+					timer = nil
+				} else {
+					fmt.Println("Resumed.")
+					timer = time.After(s.delay)
+				}
+			case 'n':
+				if s.paused {
+					return false
+				}
+			case '+':
+				s.delay = max(s.delay/2, fastestWatchDelay)
+				fmt.Println(s.help())
+			case '-':
+				s.delay = min(s.delay*2, slowestWatchDelay)
+				fmt.Println(s.help())
+			case 'q':
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// In screen-reader mode (-screen-reader) the boards are told in sentences,
+// one line per row, instead of drawn: grid art reads as a stream of dashes
+// and bars.
+var screenReader bool
+
+// With -number-words the tiles are told as words in the language of the
+// messages, "seventeen" rather than "17", for speech engines that read the
+// digits one by one.
+var numberWords bool
+
+// spokenTile is how a tile is told in narration and screen-reader mode.
+func spokenTile(v int) string {
+	if numberWords && v > 0 {
+		return numberWord(locale, v)
+	}
+	return tileLabel(v)
+}
+
+// cellWord is how a cell's content is read out.
+func cellWord(v int) string {
+	switch v {
+	case 0:
+		return tr("empty")
+	case Wildcard:
+		return tr("wildcard")
+	case Blocked:
+		return tr("blocked")
+	}
+	return spokenTile(v)
+}
+
+// describeBoards tells the table, the pile and every board, then the last
+// move and where a held tile may go.
+func (state *GameState) describeBoards(w io.Writer) {
+	fmt.Fprintf(w, "%s.\n", state.counters())
+	if len(state.Table) == 0 {
+		fmt.Fprintln(w, tr("Table: empty."))
+	} else {
+		table := append([]int{}, state.Table...)
+		sort.Ints(table)
+		words := make([]string, len(table))
+		for i, t := range table {
+			words[i] = cellWord(t)
+		}
+		fmt.Fprintf(w, tr("Table: %s.\n"), strings.Join(words, ", "))
+	}
+	if state.OpenPile {
+		fmt.Fprintf(w, "%s.\n", state.pileTopLabel())
+	} else {
+		fmt.Fprintf(w, tr("Pile: %d tiles.\n"), len(state.Draw))
+	}
+	for i, b := range state.Boards {
+		turn := ""
+		if i == state.Current {
+			turn = tr(", to play")
+		}
+		fmt.Fprintf(w, tr("%s's board%s, %d empty cells:\n"), state.seatLabel(i), turn, emptyCells(b))
+		for r := 0; r < BoardSize; r++ {
+			words := make([]string, BoardSize)
+			for c := range words {
+				words[c] = cellWord(b.Grid[r][c])
+			}
+			fmt.Fprintf(w, tr("%s, row %d: %s.\n"), state.seatLabel(i), r+1, strings.Join(words, ", "))
+		}
+		if state.HandSize > 0 {
+			fmt.Fprintf(w, "%s's %s.\n", state.seatLabel(i), state.handFooter(i))
+		}
+	}
+	if n := len(state.History); n > 0 {
+		fmt.Fprintf(w, tr("Last move: %s\n"), state.describePlayed(state.History[n-1]))
+	}
+	legal := state.legalCells()
+	for seat := range state.Boards {
+		cells, ok := legal[seat]
+		if !ok {
+			continue
+		}
+		var names []string
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if cell := (Cell{R: r, C: c}); cells[cell] {
+					names = append(names, cell.String())
+				}
+			}
+		}
+		if len(names) == 0 {
+			fmt.Fprintf(w, tr("%s fits nowhere on %s's board.\n"), spokenTile(state.Holding), state.seatLabel(seat))
+			continue
+		}
+		fmt.Fprintf(w, tr("%s can go on %s's board at %s.\n"), spokenTile(state.Holding), state.seatLabel(seat), strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// In the steal-swap variant each player may, once per game and instead of
+// drawing, take a placed tile off an opponent's board and send it to the
+// table.
+
+// canSteal reports whether the current seat still has its steal.
+func (state *GameState) canSteal() bool {
+	return state.StealSwap && !state.Boards[state.Current].StealUsed && len(state.Boards) > 1
+}
+
+// stealable reports whether the current seat may steal (r,c) from seat.
+func (state *GameState) stealable(seat, r, c int) bool {
+	if seat < 0 || seat >= len(state.Boards) || seat == state.Current || state.teammates(seat, state.Current) {
+		return false
+	}
+	return onBoard(r, c) && state.Boards[seat].Grid[r][c] != 0 && state.Boards[seat].Grid[r][c] != Blocked
+}
+
+// applySteal makes a steal, announcing it.
+func (state *GameState) applySteal(move Move) {
+	fmt.Printf("%s steals %s from %s on %s's board; it goes to the table.\n",
+		state.seatLabel(state.Current), tileLabel(move.Tile), move.Cell, state.seatLabel(move.Target))
+	state.commitMove(move)
+	state.History = append(state.History, Played{Seat: state.Current, Move: move})
+	state.logMove(move, 0)
+}
+
+// refillChance is the share of unseen tiles that could fill (r,c) on seat's
+// board again, not counting the stolen tile itself.
+func (state *GameState) refillChance(seat, r, c int) float64 {
+	board := state.Boards[seat]
+	old := board.Grid[r][c]
+	board.Grid[r][c] = 0
+	defer func() { board.Grid[r][c] = old }()
+	defer state.asSeat(seat)()
+	lo1, hi1 := state.rowConstraints(r, c)
+	lo2, hi2 := state.colConstraints(r, c)
+	lo, hi := max(lo1, lo2), min(hi1, hi2)
+	fits, total := 0, 0
+	for _, t := range append(state.Draw, state.Table...) {
+		total++
+		if t == Wildcard || (t >= lo && t <= hi) {
+			fits++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(fits) / float64(total)
+}
+
+// stealRecommendation decides whether a computer should spend its steal.
+// Stealing costs the turn and the victim may take the tile straight back,
+// so it is only worth it against an opponent one tile from winning, when
+// the stealer cannot win this turn itself. It takes the tile that is
+// hardest to replace.
+func (state *GameState) stealRecommendation() (Move, bool) {
+	if !state.canSteal() {
+		return Move{}, false
+	}
+	if emptyCells(state.Boards[state.Current]) <= 1 {
+		if _, fromTable := state.drawTileRecommendation(); fromTable {
+			return Move{}, false
+		}
+	}
+	best, bestChance, found := Move{}, 2.0, false
+	for seat, b := range state.Boards {
+		if seat == state.Current || state.teammates(seat, state.Current) || emptyCells(b) > 1 {
+			continue
+		}
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if b.Grid[r][c] == 0 || b.Grid[r][c] == Blocked {
+					continue
+				}
+				if chance := state.refillChance(seat, r, c); chance < bestChance {
+					best = Move{Type: Steal, Tile: b.Grid[r][c], Cell: &Cell{R: r, C: c}, Target: seat}
+					bestChance, found = chance, true
+				}
+			}
+		}
+	}
+	return best, found
+}
+
+// promptSteal asks the current human which tile to steal; false if they
+// change their mind.
+func (state *GameState) promptSteal() (Move, bool) {
+	fmt.Print("Steal from which seat? (blank to cancel): ")
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Move{}, false
+	}
+	seat, err := strconv.Atoi(line)
+	if err != nil || seat < 0 || seat >= len(state.Boards) || seat == state.Current || state.teammates(seat, state.Current) {
+		fmt.Println("Pick an opponent's seat number.")
+		return Move{}, false
+	}
+	fmt.Printf("Cell to steal from %s (like B3): ", state.seatLabel(seat))
+	line, _ = reader.ReadString('\n')
+	cell, err := parseCell(strings.TrimSpace(line))
+	if err != nil || !state.stealable(seat, cell.R, cell.C) {
+		fmt.Println("There is no tile to steal there.")
+		return Move{}, false
+	}
+	return Move{Type: Steal, Tile: state.Boards[seat].Grid[cell.R][cell.C], Cell: &cell, Target: seat}, true
+}
+
+func promptStealSwap() bool {
+	fmt.Print("Allow one steal per player, taking a tile off an opponent's board? (y/N): ")
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
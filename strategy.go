@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Strategy decides how a computer seat plays its turn. Both methods must
+// leave the state untouched; the caller applies whatever they return.
+type Strategy interface {
+	Name() string
+	// PickFromTable returns the move to make with a table tile, or false
+	// when the seat should draw from the pile instead.
+	PickFromTable(state *GameState) (Move, bool)
+	// ChooseMove returns the move for a tile the seat is holding, or false
+	// when the tile should be discarded to the table.
+	ChooseMove(state *GameState, tile int) (Move, bool)
+}
+
+// greedyStrategy is the original hand-tuned heuristic: take the best table
+// tile if it clears the threshold, otherwise play the top-scored move.
+type greedyStrategy struct{}
+
+func (greedyStrategy) Name() string { return "greedy" }
+
+func (greedyStrategy) PickFromTable(state *GameState) (Move, bool) {
+	return state.drawTileRecommendation()
+}
+
+func (greedyStrategy) ChooseMove(state *GameState, tile int) (Move, bool) {
+	recs := state.bestMoves(tile)
+	if len(recs) == 0 {
+		return Move{}, false
+	}
+	return recs[0], true
+}
+
+// randomStrategy always draws from the pile and places the tile in a random
+// legal cell. It is a baseline for comparing other strategies.
+type randomStrategy struct{}
+
+func (randomStrategy) Name() string { return "random" }
+
+func (randomStrategy) PickFromTable(state *GameState) (Move, bool) {
+	return Move{}, false
+}
+
+func (randomStrategy) ChooseMove(state *GameState, tile int) (Move, bool) {
+	recs := state.bestMoves(tile)
+	if len(recs) == 0 {
+		return Move{}, false
+	}
+	return recs[rng.Intn(len(recs))], true
+}
+
+// cautiousStrategy plays like greedy but always scores with risk-aware
+// heuristics, whatever the game's own setting is.
+type cautiousStrategy struct{}
+
+func (cautiousStrategy) Name() string { return "cautious" }
+
+func (cautiousStrategy) PickFromTable(state *GameState) (Move, bool) {
+	defer state.withHeuristics(riskAware)()
+	return greedyStrategy{}.PickFromTable(state)
+}
+
+func (cautiousStrategy) ChooseMove(state *GameState, tile int) (Move, bool) {
+	defer state.withHeuristics(riskAware)()
+	return greedyStrategy{}.ChooseMove(state, tile)
+}
+
+func riskAware(h *Heuristics) { h.RiskAware = true }
+
+// withHeuristics applies edit to the state's heuristics and returns a
+// function restoring the previous ones.
+func (state *GameState) withHeuristics(edit func(*Heuristics)) func() {
+	saved := state.Heuristics
+	edit(&state.Heuristics)
+	return func() { state.Heuristics = saved }
+}
+
+var strategies = map[string]Strategy{}
+
+func registerStrategy(s Strategy) {
+	strategies[s.Name()] = s
+}
+
+func init() {
+	registerStrategy(greedyStrategy{})
+	registerStrategy(randomStrategy{})
+	registerStrategy(cautiousStrategy{})
+}
+
+var defaultStrategy Strategy = greedyStrategy{}
+
+func lookupStrategy(name string) (Strategy, error) {
+	s, ok := strategies[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (have %s)", name, strings.Join(strategyNames(), ", "))
+	}
+	return s, nil
+}
+
+func strategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// strategyFor returns the strategy that plays the given seat this turn.
+func (state *GameState) strategyFor(seat int) Strategy {
+	if state.ABTest != nil && state.ABTest.Seat == seat {
+		return state.ABTest.current()
+	}
+	if name := state.Boards[seat].Strategy; name != "" {
+		if s, err := lookupStrategy(name); err == nil {
+			return s
+		}
+	}
+	return defaultStrategy
+}
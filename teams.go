@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// In team play four seats form two teams, seats 0 and 2 against seats 1
+// and 3, so turns alternate between the teams. Partners may place drawn
+// tiles on either board, pass tiles through the table, and win together as
+// soon as either board is full.
+
+// teamSeats is the number of seats a team game needs.
+const teamSeats = 4
+
+// team numbers a seat's team from 1.
+func team(seat int) int { return seat%2 + 1 }
+
+// partner is the other seat of seat's team.
+func (state *GameState) partner(seat int) int {
+	return (seat + 2) % len(state.Boards)
+}
+
+// teammates reports whether seats a and b play for the same team.
+func (state *GameState) teammates(a, b int) bool {
+	return state.Teams && team(a) == team(b)
+}
+
+// moveSeat is the seat whose board a move by seat is played on.
+func (state *GameState) moveSeat(seat int, move Move) int {
+	if move.Type == Steal {
+		return move.Target
+	}
+	if move.Partner {
+		return state.partner(seat)
+	}
+	return seat
+}
+
+// asSeat makes seat the current one, for evaluating its board, and returns
+// a function restoring the previous one.
+func (state *GameState) asSeat(seat int) func() {
+	saved := state.Current
+	state.Current = seat
+	return func() { state.Current = saved }
+}
+
+// partnerMoves ranks tile's moves on the current seat's partner's board.
+func (state *GameState) partnerMoves(tile int) []Move {
+	defer state.asSeat(state.partner(state.Current))()
+	moves := state.boardMoves(tile)
+	for i := range moves {
+		moves[i].Partner = true
+	}
+	return moves
+}
+
+// teamMoves adds the partner's board to the current seat's moves in team
+// play, ranking them all together.
+func (state *GameState) teamMoves(moves []Move, tile int) []Move {
+	if !state.Teams {
+		return moves
+	}
+	moves = append(moves, state.partnerMoves(tile)...)
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Score > moves[j].Score
+	})
+	return moves
+}
+
+// winnerLabel names who won when seat's board decided the game.
+func (state *GameState) winnerLabel(seat int) string {
+	if !state.Teams {
+		return state.seatLabel(seat)
+	}
+	return fmt.Sprintf("Team %d (%s and %s)", team(seat), state.seatLabel(seat%2), state.seatLabel(seat%2+2))
+}
+
+func promptTeams() bool {
+	fmt.Print("Play 2v2 teams, seats 0 and 2 against 1 and 3? (y/N): ")
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(strings.ToLower(line))
+	return line == "y" || line == "yes"
+}
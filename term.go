@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// termCaps is what the terminal the game writes to can show. Output falls
+// back from Unicode box drawing to ASCII and from colour to plain text, so
+// the same binary stays readable over plain SSH sessions and in CI logs.
+type termCaps struct {
+	Unicode bool
+	Color   bool
+	Width   int // columns, 0 when unknown
+}
+
+// term is the terminal the game renders for; plain ASCII until main detects
+// or overrides it.
+var term termCaps
+
+// detectTerm guesses the capabilities from the environment: Unicode needs a
+// UTF-8 locale, colour an interactive terminal. TERM=dumb gets neither, and
+// NO_COLOR (https://no-color.org) turns colour off.
+func detectTerm(getenv func(string) string, tty bool) termCaps {
+	locale := getenv("LC_ALL")
+	if locale == "" {
+		locale = getenv("LC_CTYPE")
+	}
+	if locale == "" {
+		locale = getenv("LANG")
+	}
+	locale = strings.ToUpper(locale)
+	name := getenv("TERM")
+	dumb := name == "dumb"
+	return termCaps{
+		Unicode: !dumb && (strings.Contains(locale, "UTF-8") || strings.Contains(locale, "UTF8")),
+		Color:   tty && !dumb && name != "" && getenv("NO_COLOR") == "",
+		Width:   detectWidth(getenv, tty),
+	}
+}
+
+// detectWidth finds how many columns the terminal has: COLUMNS when the
+// shell exports it, otherwise stty's idea of the terminal, otherwise 0.
+func detectWidth(getenv func(string) string, tty bool) int {
+	if n, err := strconv.Atoi(getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if !tty {
+		return 0
+	}
+	out, err := stty("size")
+	if err != nil {
+		return 0
+	}
+	var rows, cols int
+	if _, err := fmt.Sscan(out, &rows, &cols); err != nil {
+		return 0
+	}
+	return cols
+}
+
+// isTerminal reports whether f is a character device rather than a file or
+// pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// override applies a comma separated -term spec to the detected
+// capabilities: auto keeps them, unicode/ascii and color/mono force one,
+// and width=N sets the columns to lay the boards out in.
+func (t termCaps) override(spec string) (termCaps, error) {
+	for _, word := range strings.Split(spec, ",") {
+		word = strings.TrimSpace(strings.ToLower(word))
+		if n, ok := strings.CutPrefix(word, "width="); ok {
+			width, err := strconv.Atoi(n)
+			if err != nil || width < 0 {
+				return t, fmt.Errorf("bad terminal width %q", n)
+			}
+			t.Width = width
+			continue
+		}
+		switch word {
+		case "", "auto":
+		case "unicode", "utf8":
+			t.Unicode = true
+		case "ascii":
+			t.Unicode = false
+		case "color", "colour":
+			t.Color = true
+		case "mono", "plain":
+			t.Color = false
+		default:
+			return t, fmt.Errorf("unknown terminal capability %q (want auto, unicode, ascii, color, mono or width=N)", word)
+		}
+	}
+	return t, nil
+}
+
+// boxEnv names the environment variable choosing the box drawing for
+// every game, as -box does for one: auto, unicode or ascii.
+const boxEnv = "UNLUCKY_BOX"
+
+// withBox picks the box drawing: unicode lines, the plain ASCII layout, or
+// auto to keep what the terminal supports.
+func (t termCaps) withBox(choice string) (termCaps, error) {
+	switch strings.TrimSpace(strings.ToLower(choice)) {
+	case "", "auto":
+	case "unicode", "utf8":
+		t.Unicode = true
+	case "ascii":
+		t.Unicode = false
+	default:
+		return t, fmt.Errorf("unknown box drawing %q (want auto, unicode or ascii)", choice)
+	}
+	return t, nil
+}
+
+// boxGlyphs draws grid borders: corners, tees pointing into the grid, a
+// cross, and the horizontal and vertical lines.
+type boxGlyphs struct {
+	H, V                                 string
+	TopLeft, TopRight, BotLeft, BotRight string
+	TeeDown, TeeUp, TeeRight, TeeLeft    string
+	Cross                                string
+}
+
+var (
+	asciiBox   = boxGlyphs{"-", "|", "+", "+", "+", "+", "+", "+", "+", "+", "+"}
+	unicodeBox = boxGlyphs{"─", "│", "┌", "┐", "└", "┘", "┬", "┴", "├", "┤", "┼"}
+)
+
+func (t termCaps) box() boxGlyphs {
+	if t.Unicode {
+		return unicodeBox
+	}
+	return asciiBox
+}
+
+// ANSI styles used by paint.
+const (
+	styleBold    = "1"
+	styleReverse = "7"
+	styleRed     = "31"
+	styleGreen   = "32"
+	styleYellow  = "33"
+	styleBlue    = "34"
+	styleCyan    = "36"
+
+	styleGreenBackground = "42"
+)
+
+// tileStyle tints a numbered tile by which third of the range its value
+// falls in: blue for low, yellow for middle, red for high.
+func tileStyle(tile, maxTile int) string {
+	switch third := 3 * tile / (maxTile + 1); {
+	case third < 1:
+		return styleBlue
+	case third < 2:
+		return styleYellow
+	}
+	return styleRed
+}
+
+// paint wraps s in an ANSI style when the terminal has colour. Pad s before
+// painting it: the escape codes take no room on screen.
+func (t termCaps) paint(style, s string) string {
+	if !t.Color {
+		return s
+	}
+	return "\033[" + style + "m" + s + "\033[0m"
+}
+
+var asciiPunctuation = strings.NewReplacer("—", "-", "’", "'", "×", "x")
+
+// text spells typographic punctuation in ASCII when the terminal lacks
+// Unicode.
+func (t termCaps) text(s string) string {
+	if t.Unicode {
+		return s
+	}
+	return asciiPunctuation.Replace(s)
+}
+
+// boardsPerRow is how many boards boardWidth wide fit side by side, with
+// the table's frame and the gaps between them, in the terminal's width.
+func boardsPerRow(boardWidth, boards int) int {
+	if term.Width <= 0 {
+		return boards
+	}
+	return max(1, min(boards, term.Width/(boardWidth+2)))
+}
+
+// seatRange lists the seats from first up to, not including, end.
+func seatRange(first, end int) []int {
+	seats := make([]int, 0, end-first)
+	for seat := first; seat < end; seat++ {
+		seats = append(seats, seat)
+	}
+	return seats
+}
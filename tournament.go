@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	eloStart = 1500.0
+	eloK     = 16.0
+)
+
+// standing is one strategy's line in the tournament results.
+type standing struct {
+	Name                string
+	Wins, Losses, Draws int
+	Elo                 float64
+}
+
+func (s standing) games() int { return s.Wins + s.Losses + s.Draws }
+
+// eloUpdate returns the new ratings of a and b after a game where a scored
+// 1 (win), 0.5 (draw) or 0 (loss).
+func eloUpdate(a, b, score float64) (float64, float64) {
+	expected := 1 / (1 + math.Pow(10, (b-a)/400))
+	delta := eloK * (score - expected)
+	return a + delta, b - delta
+}
+
+// runTournament plays every strategy against every other over games
+// two-player games per pairing. Each deal is played twice with the seats
+// swapped, and deals are seeded from seed so runs are reproducible.
+func runTournament(names []string, games int, seed int64) ([]standing, [][]int, error) {
+	players := make([]Strategy, len(names))
+	for i, name := range names {
+		s, err := lookupStrategy(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		players[i] = s
+	}
+	table := make([]standing, len(players))
+	for i, p := range players {
+		table[i] = standing{Name: p.Name(), Elo: eloStart}
+	}
+	// wins[i][j] counts games strategy i won against strategy j.
+	wins := make([][]int, len(players))
+	for i := range wins {
+		wins[i] = make([]int, len(players))
+	}
+
+	for i := 0; i < len(players); i++ {
+		for j := i + 1; j < len(players); j++ {
+			for g := 0; g < games; g++ {
+				seedRNG(seed + int64(g/2))
+				state, err := newHeadlessGame(2)
+				if err != nil {
+					return nil, nil, err
+				}
+				first, second := i, j
+				if g%2 == 1 {
+					first, second = j, i
+				}
+				winner := state.playHeadless([]Strategy{players[first], players[second]}, nil)
+				score := 0.5
+				switch winner {
+				case 0:
+					score = 1
+				case 1:
+					score = 0
+				}
+				a, b := &table[first], &table[second]
+				switch score {
+				case 1:
+					a.Wins++
+					b.Losses++
+					wins[first][second]++
+				case 0:
+					a.Losses++
+					b.Wins++
+					wins[second][first]++
+				default:
+					a.Draws++
+					b.Draws++
+				}
+				a.Elo, b.Elo = eloUpdate(a.Elo, b.Elo, score)
+			}
+		}
+	}
+	return table, wins, nil
+}
+
+func printTournament(w io.Writer, table []standing, wins [][]int) {
+	order := make([]int, len(table))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return table[order[a]].Elo > table[order[b]].Elo })
+
+	fmt.Fprintf(w, "%-10s %6s %5s %6s %5s %7s %6s\n", "Strategy", "Games", "Wins", "Losses", "Draws", "Score%", "Elo")
+	for _, i := range order {
+		s := table[i]
+		pct := 0.0
+		if s.games() > 0 {
+			pct = 100 * (float64(s.Wins) + 0.5*float64(s.Draws)) / float64(s.games())
+		}
+		fmt.Fprintf(w, "%-10s %6d %5d %6d %5d %6.1f%% %6.0f\n", s.Name, s.games(), s.Wins, s.Losses, s.Draws, pct, s.Elo)
+	}
+
+	fmt.Fprintf(w, "\nWins (row beat column):\n%-10s", "")
+	for _, j := range order {
+		fmt.Fprintf(w, " %9s", table[j].Name)
+	}
+	fmt.Fprintln(w)
+	for _, i := range order {
+		fmt.Fprintf(w, "%-10s", table[i].Name)
+		for _, j := range order {
+			if i == j {
+				fmt.Fprintf(w, " %9s", "-")
+			} else {
+				fmt.Fprintf(w, " %9d", wins[i][j])
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
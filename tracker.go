@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// seenTiles counts the tiles the current seat can see: those on every
+// board and the table, its own hand and the pile's top tile when it is
+// face up. Wildcards are counted under Wildcard.
+func (state *GameState) seenTiles() map[int]int {
+	seen := map[int]int{}
+	for _, b := range state.Boards {
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if v := b.Grid[r][c]; v != 0 && v != Blocked {
+					seen[v]++
+				}
+			}
+		}
+	}
+	for _, t := range state.Table {
+		seen[t]++
+	}
+	for _, t := range state.Boards[state.Current].Hand {
+		seen[t]++
+	}
+	if state.pileVisible() {
+		seen[state.Draw[0]]++
+	}
+	return seen
+}
+
+// printTracker shows, ten values to a row, how many tiles of each value
+// have been seen and how many are still out of sight, the way players
+// track them on paper.
+func (state *GameState) printTracker(w io.Writer) {
+	copies := state.tileCopies(len(state.Boards))
+	seen := state.seenTiles()
+	fmt.Fprintln(w, tr("Tile tracker, seen and unseen copies of each value:"))
+	for lo := 1; lo <= state.maxTile(); lo += 10 {
+		hi := min(lo+9, state.maxTile())
+		rows := []struct {
+			label string
+			count func(t int) int
+		}{
+			{tr("tile"), func(t int) int { return t }},
+			{tr("seen"), func(t int) int { return seen[t] }},
+			{tr("unseen"), func(t int) int { return max(copies[t]-seen[t], 0) }},
+		}
+		for _, row := range rows {
+			fmt.Fprintf(w, "%-7s", row.label)
+			for t := lo; t <= hi; t++ {
+				fmt.Fprintf(w, "%4d", row.count(t))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if state.Wildcards > 0 {
+		fmt.Fprintf(w, tr("Wildcards: %d seen, %d unseen.\n"), seen[Wildcard], max(state.Wildcards-seen[Wildcard], 0))
+	}
+}
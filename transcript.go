@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lineReader is what the prompts read answers from.
+type lineReader interface {
+	ReadString(delim byte) (string, error)
+}
+
+// transcript records a session to a file: everything printed and every line
+// typed, interleaved as they appeared on the terminal.
+type transcript struct {
+	file   fileWriter
+	stdout *os.File // the real standard output
+	pipe   *os.File // write end standing in for os.Stdout
+	mu     sync.Mutex
+	synced chan struct{}
+	done   chan struct{}
+}
+
+// sessionTranscript is the transcript being recorded, if any.
+var sessionTranscript *transcript
+
+// startTranscript starts recording the session to path by routing standard
+// output through a pipe and echoing every answer read by reader.
+func startTranscript(path string) error {
+	f, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t := &transcript{file: f, stdout: os.Stdout, pipe: w, synced: make(chan struct{}), done: make(chan struct{})}
+	fmt.Fprintf(f, "# transcript of %q started %s\n", strings.Join(os.Args, " "), time.Now().Format(time.RFC3339))
+	go t.copy(r)
+	os.Stdout = w
+	reader = recordingReader{reader, t}
+	sessionTranscript = t
+	return nil
+}
+
+// copy forwards the program's output to the terminal and the file. A NUL
+// byte is a barrier from recordInput, acknowledged once everything printed
+// before it has been written.
+func (t *transcript) copy(r *os.File) {
+	defer close(t.done)
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		for chunk := buf[:n]; len(chunk) > 0; {
+			out, rest, barrier := bytes.Cut(chunk, []byte{0})
+			t.mu.Lock()
+			t.stdout.Write(out)
+			t.file.Write(out)
+			t.mu.Unlock()
+			if !barrier {
+				break
+			}
+			t.synced <- struct{}{}
+			chunk = rest
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// recordInput writes a line the user typed after the prompt it answers.
+func (t *transcript) recordInput(line string) {
+	t.pipe.Write([]byte{0})
+	<-t.synced
+	t.mu.Lock()
+	t.file.WriteString(line)
+	t.mu.Unlock()
+}
+
+// endTranscript flushes and closes the session transcript, if recording.
+func endTranscript() {
+	t := sessionTranscript
+	if t == nil {
+		return
+	}
+	sessionTranscript = nil
+	os.Stdout = t.stdout
+	t.pipe.Close()
+	<-t.done
+	if err := t.file.Close(); err != nil {
+		fmt.Println("Failed to save transcript:", err)
+	}
+}
+
+// recordingReader echoes every line read into the transcript.
+type recordingReader struct {
+	lineReader
+	t *transcript
+}
+
+func (r recordingReader) ReadString(delim byte) (string, error) {
+	line, err := r.lineReader.ReadString(delim)
+	r.t.recordInput(line)
+	return line, err
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The terminal UI (-tui) takes over the human turns: it redraws the whole
+// screen each time, with every board, the table and the pile count, and the
+// player picks tiles and cells with a cursor, or the mouse, instead of
+// typing them.
+// Computer turns and the rest of the game keep the plain output; questions
+// that need typing, like a save file name, drop back to a normal prompt.
+
+// tui is the running terminal UI, or nil for the plain prompts.
+var screen *tui
+
+type tui struct {
+	in    io.ByteReader
+	saved string // stty settings to restore, empty when stty is not used
+	msg   string // status line under the boards
+	since int    // history already seen by the player
+
+	// The cursor the renderer asks about through cellStyle; the legal
+	// cells come from the state's held tile.
+	placing bool
+	board   int
+	cursor  Cell
+
+	state  *GameState   // the game last drawn
+	layout screenLayout // where the last redraw put things
+	mouse  mouseTarget  // what the last click or hover was over
+	hint   string       // about the hovered cell
+}
+
+// startTUI switches the terminal to reading single keys without echo.
+func startTUI() (*tui, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("the terminal UI needs stty and a terminal: %w", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	fmt.Print(mouseOn)
+	return &tui{in: bufio.NewReader(os.Stdin), saved: strings.TrimSpace(saved)}, nil
+}
+
+// stopTUI gives the terminal back its settings, if the UI is running.
+func stopTUI() {
+	if screen != nil {
+		fmt.Print(mouseOff)
+		if screen.saved != "" {
+			stty(screen.saved)
+		}
+	}
+	screen = nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// cooked runs prompt with the terminal back in line mode.
+func (t *tui) cooked(prompt func()) {
+	fmt.Print(mouseOff)
+	defer fmt.Print(mouseOn)
+	if t.saved != "" {
+		stty(t.saved)
+		defer stty("-icanon", "-echo", "min", "1")
+	}
+	prompt()
+}
+
+// key reads one key press: arrows and hjkl as up, down, left and right,
+// enter, tab, back, or the character typed. The end of input reads as q.
+// A left click reads as click and the pointer moving onto something else
+// as hover, with what they were over in t.mouse.
+func (t *tui) key() string {
+	for {
+		if k := t.event(); k != "" {
+			return k
+		}
+	}
+}
+
+// event reads one key or mouse report, empty for reports that change
+// nothing.
+func (t *tui) event() string {
+	b, err := t.in.ReadByte()
+	if err != nil {
+		return "q"
+	}
+	switch b {
+	case '\r', '\n':
+		return "enter"
+	case '\t':
+		return "tab"
+	case 127, 8:
+		return "back"
+	case 'k':
+		return "up"
+	case 'j':
+		return "down"
+	case 'h':
+		return "left"
+	case 'l':
+		return "right"
+	case 27:
+		if next, _ := t.in.ReadByte(); next == '[' || next == 'O' {
+			code, _ := t.in.ReadByte()
+			if code == '<' {
+				return t.mouseEvent()
+			}
+			if dir, ok := map[byte]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}[code]; ok {
+				return dir
+			}
+		}
+		return "back"
+	}
+	return strings.ToLower(string(rune(b)))
+}
+
+// mouseEvent reads a mouse report: left presses click and moves onto a
+// different target hover, showing a hovered cell's range.
+func (t *tui) mouseEvent() string {
+	button, x, y, press, err := parseMouse(t.in.ReadByte)
+	if err != nil || !press {
+		return ""
+	}
+	target := t.layout.at(x, y)
+	switch {
+	case button == 0:
+		t.mouse = target
+		return "click"
+	case button&32 != 0 && target != t.mouse:
+		t.mouse, t.hint = target, ""
+		if target.kind == overCell {
+			t.hint = t.state.cellHint(target.seat, target.cell)
+		}
+		return "hover"
+	}
+	return ""
+}
+
+// redraw clears the screen and shows the game, what happened since the
+// player last looked, the status line and the keys.
+func (t *tui) redraw(state *GameState, keys string) {
+	t.state, t.layout = state, state.layout()
+	fmt.Print("\033[H\033[2J")
+	state.PrettyPrintBoardsGridCentered()
+	fmt.Printf("Pile: %d tiles   Table: %d tiles\n", len(state.Draw), len(state.Table))
+	if t.since > len(state.History) {
+		t.since = 0
+	}
+	for _, p := range state.History[t.since:] {
+		if p.Seat != state.Current {
+			fmt.Println(state.describePlayed(p))
+		}
+	}
+	if t.msg != "" {
+		fmt.Println(t.msg)
+	}
+	if t.hint != "" {
+		fmt.Println(t.hint)
+	}
+	fmt.Print(keys)
+}
+
+// describePlayed is one line about a move, for the catch-up lines.
+func (state *GameState) describePlayed(p Played) string {
+	who, m := state.seatLabel(p.Seat), p.Move
+	switch m.Type {
+	case Discard:
+		return fmt.Sprintf(tr("%s discarded %s."), who, spokenTile(m.Tile))
+	case Steal:
+		return fmt.Sprintf(tr("%s stole %s from %s."), who, spokenTile(m.Tile), state.seatLabel(m.Target))
+	case Swap:
+		return fmt.Sprintf(tr("%s swapped %s in for %s at %s."), who, spokenTile(m.Tile), spokenTile(m.OldTile), m.Cell)
+	}
+	return fmt.Sprintf(tr("%s placed %s at %s."), who, spokenTile(m.Tile), m.Cell)
+}
+
+// cellStyle is the overlay style of a cell while a tile is being placed:
+// the cursor stands out.
+func (t *tui) cellStyle(seat, r, c int) string {
+	if t.cursorAt(seat, r, c) {
+		return styleReverse
+	}
+	return ""
+}
+
+// cursorAt reports the cursor cell, which plain terminals bracket.
+func (t *tui) cursorAt(seat, r, c int) bool {
+	return t.placing && seat == t.board && t.cursor == Cell{R: r, C: c}
+}
+
+// chooseTile lets the player pick one of tiles with left and right.
+func (t *tui) chooseTile(state *GameState, tiles []int, what string) (int, bool) {
+	i := 0
+	for {
+		labels := make([]string, len(tiles))
+		for j, tile := range tiles {
+			labels[j] = " " + tileLabel(tile) + " "
+			if j == i {
+				labels[j] = term.paint(styleReverse, "["+tileLabel(tile)+"]")
+			}
+		}
+		t.msg = fmt.Sprintf("%s: %s", what, strings.Join(labels, ""))
+		t.redraw(state, "left/right choose, enter take, backspace back\n")
+		switch t.key() {
+		case "left":
+			i = (i + len(tiles) - 1) % len(tiles)
+		case "right":
+			i = (i + 1) % len(tiles)
+		case "enter":
+			return tiles[i], true
+		case "back", "q":
+			return 0, false
+		}
+	}
+}
+
+// draw is the TUI's take on promptDrawOrSave: where the turn's tile comes
+// from. It reports true when the player quit.
+func (t *tui) draw(state *GameState) (Move, bool) {
+	t.msg = fmt.Sprintf("%s to play.", state.seatLabel(state.Current))
+	if state.pileVisible() && len(state.Draw) > 0 {
+		t.msg += " The pile shows " + tileLabel(state.Draw[0]) + "."
+	}
+	for {
+		source := "[p]ile"
+		if state.holdsHand() {
+			source = "[p]lay from hand " + handLabel(state.Boards[state.Current].Hand)
+		}
+		keys := source + ", [t]able, [r]ecommend, [s]ave, [q]uit"
+		if state.canSteal() {
+			keys += ", steal [x]"
+		}
+		t.redraw(state, keys+"\n")
+		key, picked := t.key(), 0
+		if key == "click" {
+			// the pile line draws, a table tile is taken
+			switch t.mouse.kind {
+			case overPile:
+				key = "p"
+			case overTableTile:
+				key, picked = "t", t.mouse.tile
+			}
+		}
+		switch key {
+		case "p", "enter":
+			if state.holdsHand() {
+				tile, ok := t.chooseTile(state, state.Boards[state.Current].Hand, "Play from hand")
+				if ok {
+					return state.playFromHand(tile), false
+				}
+				continue
+			}
+			tile, err := state.popDraw()
+			if err != nil {
+				state.pileExhausted()
+				return Move{}, false
+			}
+			return Move{Tile: tile, Type: Draw}, false
+		case "t":
+			if len(state.Table) == 0 {
+				t.msg = "The table is empty."
+				continue
+			}
+			tile, ok := picked, picked != 0
+			if !ok {
+				tile, ok = t.chooseTile(state, state.Table, "Take from the table")
+			}
+			if !ok {
+				continue
+			}
+			if state.ForcedTable && len(state.bestMoves(tile)) == 0 {
+				t.msg = fmt.Sprintf("%s fits nowhere, and tiles taken from the table must be placed.", tileLabel(tile))
+				continue
+			}
+			state.removeTileFromTable(tile)
+			return Move{Tile: tile, Type: Draw, FromTable: true}, false
+		case "r":
+			move, fromTable := state.drawTileRecommendation()
+			switch {
+			case fromTable:
+				t.msg = fmt.Sprintf("Take %s from the table for %s.", tileLabel(move.Tile), move.Cell)
+			case state.holdsHand():
+				tile, _ := state.bestHandTile()
+				t.msg = fmt.Sprintf("Play %s from your hand.", tileLabel(tile))
+			default:
+				t.msg = "Draw from the pile."
+			}
+		case "x":
+			if !state.canSteal() {
+				continue
+			}
+			var move Move
+			var ok bool
+			t.cooked(func() { move, ok = state.promptSteal() })
+			if ok {
+				state.applyMove(move)
+				t.since = len(state.History)
+				return move, false
+			}
+		case "s":
+			t.cooked(state.promptSave)
+		case "q":
+			return Move{}, true
+		}
+	}
+}
+
+// place is the TUI's take on promptPlacement for a human: move the cursor
+// to a cell and press enter, or discard.
+func (t *tui) place(state *GameState, move Move) {
+	current, tile := state.Current, move.Tile
+	t.placing, t.board, state.Holding = true, current, tile
+	defer func() { t.placing, t.since, state.Holding = false, len(state.History), 0 }()
+	if recs := state.bestMoves(tile); len(recs) > 0 && !recs[0].Partner {
+		t.cursor = *recs[0].Cell
+	}
+	t.msg = fmt.Sprintf("Place %s.", tileLabel(tile))
+	for {
+		keys := "arrows move, enter place, [d]iscard, [r]ecommend, [s]ave"
+		if state.Teams {
+			keys += ", tab switch to your partner's board"
+		}
+		t.redraw(state, keys+"\n")
+		key := t.key()
+		if key == "click" && t.mouse.kind == overCell {
+			// a click on a cell places there, on the boards one may
+			if seat := t.mouse.seat; seat == current || (state.Teams && seat == state.partner(current)) {
+				t.board, t.cursor, key = seat, t.mouse.cell, "enter"
+			} else {
+				t.msg = fmt.Sprintf("%s is not your board.", state.seatLabel(seat))
+			}
+		}
+		switch key {
+		case "up":
+			t.cursor.R = (t.cursor.R + BoardSize - 1) % BoardSize
+		case "down":
+			t.cursor.R = (t.cursor.R + 1) % BoardSize
+		case "left":
+			t.cursor.C = (t.cursor.C + BoardSize - 1) % BoardSize
+		case "right":
+			t.cursor.C = (t.cursor.C + 1) % BoardSize
+		case "tab":
+			if state.Teams {
+				if t.board == current {
+					t.board = state.partner(current)
+				} else {
+					t.board = current
+				}
+			}
+		case "r":
+			recs := state.bestMoves(tile)
+			if len(recs) == 0 {
+				t.msg = fmt.Sprintf("%s fits nowhere: discard it.", tileLabel(tile))
+				continue
+			}
+			t.board, t.cursor = state.moveSeat(current, recs[0]), *recs[0].Cell
+			t.msg = fmt.Sprintf("Best: %s, score %.2f.", t.cursor, recs[0].Score)
+		case "s":
+			t.cooked(state.promptSave)
+		case "d", "q":
+			if move.FromTable && state.ForcedTable {
+				t.msg = "A tile taken from the table must be placed."
+				continue
+			}
+			state.applyMove(Move{Type: Discard, Tile: tile})
+			return
+		case "enter":
+			if !state.legalCells()[t.board][t.cursor] {
+				t.msg = fmt.Sprintf("%s cannot go at %s.", tileLabel(tile), t.cursor)
+				continue
+			}
+			cell := t.cursor
+			placed := Move{Type: Place, Tile: tile, Cell: &cell, Partner: t.board != current}
+			if old := state.Boards[t.board].Grid[cell.R][cell.C]; old != 0 {
+				placed.Type, placed.OldTile = Swap, old
+			}
+			if !state.applyMove(placed) {
+				return
+			}
+			// An extra turn draws a new tile and places it the same way.
+			t.since, t.placing, state.Holding = len(state.History), false, 0
+			next, quit := t.draw(state)
+			if quit || next.Type == Steal || state.Over {
+				return
+			}
+			move, tile, t.board = next, next.Tile, current
+			state.Pending = &move
+			t.placing, state.Holding = true, tile
+			t.msg = fmt.Sprintf("Extra turn! Place %s.", tileLabel(tile))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import "fmt"
+
+// A human can take a move back with u at either prompt. Every human turn
+// starts by remembering the position; undo after drawing puts the tile back
+// and restarts the turn, and undo before drawing goes back to the seat's
+// previous turn, taking back the computers' replies with it.
+
+// undoDepth bounds how many turns back undo can go.
+const undoDepth = 50
+
+// Undo holds the positions at the start of the recent human turns.
+type Undo struct {
+	turns  []*GameState
+	undone bool // the turn in progress was taken back
+}
+
+// markTurn remembers the position at the start of a human turn.
+func (state *GameState) markTurn() {
+	if state.Undo == nil {
+		state.Undo = &Undo{}
+	}
+	u := state.Undo
+	snap := state.clone()
+	snap.Undo = nil
+	snap.History = append([]Played{}, state.History...)
+	if state.Puzzle != nil {
+		puzzle := *state.Puzzle
+		snap.Puzzle = &puzzle
+	}
+	u.turns = append(u.turns, snap)
+	if len(u.turns) > undoDepth {
+		u.turns = u.turns[1:]
+	}
+}
+
+// undo takes back the turn in progress once a tile is drawn, or else the
+// seat's previous turn. It reports whether anything was taken back.
+func (state *GameState) undo(drawn bool) bool {
+	u := state.Undo
+	if u == nil {
+		fmt.Println(tr("Nothing to undo."))
+		return false
+	}
+	turns := u.turns
+	if !drawn && len(turns) > 0 {
+		turns = turns[:len(turns)-1] // this turn has not begun
+		if len(turns) > 0 && turns[len(turns)-1].Current != state.Current {
+			fmt.Printf(tr("%s has moved since, so your last move stands.\n"), state.seatLabel(turns[len(turns)-1].Current))
+			return false
+		}
+	}
+	if len(turns) == 0 {
+		fmt.Println(tr("Nothing to undo."))
+		return false
+	}
+	snap := turns[len(turns)-1]
+	u.turns = turns[:len(turns)-1]
+	state.restore(snap)
+	state.logPosition("undo")
+	u.undone = true
+	fmt.Println(tr("Move taken back."))
+	return true
+}
+
+// restore puts back what a turn changes, keeping the boards' addresses.
+func (state *GameState) restore(snap *GameState) {
+	for i, b := range snap.Boards {
+		*state.Boards[i] = *b
+	}
+	state.Table = snap.Table
+	state.Draw = snap.Draw
+	state.History = snap.History
+	state.Current = snap.Current
+	state.ExtraTurns = snap.ExtraTurns
+	state.Turn, state.Draws = snap.Turn, snap.Draws
+	state.Holding = 0
+	if snap.Puzzle != nil {
+		*state.Puzzle = *snap.Puzzle
+	}
+}
+
+// takeUndone reports whether the turn in progress was taken back, clearing
+// the mark.
+func (state *GameState) takeUndone() bool {
+	if state.Undo == nil || !state.Undo.undone {
+		return false
+	}
+	state.Undo.undone = false
+	return true
+}
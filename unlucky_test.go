@@ -1,12 +1,32 @@
 package main
 
 import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func exampleStateForTests() *GameState {
 	board1 := &Board{
-		Grid: [BoardSize][BoardSize]int{
+		Grid: [maxBoardSize][maxBoardSize]int{
 			{5, 0, 0, 9},
 			{0, 7, 0, 0},
 			{0, 0, 10, 19},
@@ -14,7 +34,7 @@ func exampleStateForTests() *GameState {
 		},
 	}
 	board2 := &Board{
-		Grid: [BoardSize][BoardSize]int{
+		Grid: [maxBoardSize][maxBoardSize]int{
 			{6, 0, 0, 0},
 			{0, 10, 0, 0},
 			{0, 0, 14, 0},
@@ -59,7 +79,7 @@ func TestBestMoves(t *testing.T) {
 		switch m.Type {
 		case Place:
 			// Ensure the suggested placement is feasible given remaining tiles
-			if !state.isPlacementFeasible(m.Cell.R, m.Cell.C, drawTile) {
+			if !state.isPlacementFeasible(drawTile, m.Cell.R, m.Cell.C) {
 				t.Errorf("Suggested infeasible placement at (%d,%d) for tile %d", m.Cell.R, m.Cell.C, drawTile)
 			}
 		case Swap:
@@ -95,3 +115,3697 @@ func TestSwapLegality(t *testing.T) {
 		t.Logf("No swaps found — correct if no legal swaps exist")
 	}
 }
+
+func TestABTestAlternates(t *testing.T) {
+	ab, err := newABTest("greedy,random", 1, t.TempDir()+"/ab.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ab.Close()
+	state := exampleStateForTests()
+	state.ABTest = ab
+
+	for turn, want := range []string{"greedy", "random", "greedy"} {
+		if got := state.strategyFor(1).Name(); got != want {
+			t.Errorf("turn %d: expected %s, got %s", turn, want, got)
+		}
+		if got := state.strategyFor(0).Name(); got != defaultStrategy.Name() {
+			t.Errorf("turn %d: seat 0 should use %s, got %s", turn, defaultStrategy.Name(), got)
+		}
+		state.abEndTurn(1)
+	}
+
+	if _, err := newABTest("greedy", 1, t.TempDir()+"/ab.log"); err == nil {
+		t.Errorf("Expected an error for a single strategy")
+	}
+}
+
+func TestPromptStrategy(t *testing.T) {
+	saved := reader
+	defer func() { reader = saved }()
+	reader = bufio.NewReader(strings.NewReader("reckless\n Cautious \n\n"))
+
+	if got := promptStrategy(1); got != "cautious" {
+		t.Errorf("Expected an unknown strategy asked again and cautious chosen, got %q", got)
+	}
+	if got := promptStrategy(2); got != defaultStrategy.Name() {
+		t.Errorf("Expected a blank answer to choose %s, got %q", defaultStrategy.Name(), got)
+	}
+}
+
+func FuzzLoadFromCSV(f *testing.F) {
+	f.Add("TURN,1\nTABLE,7,5\n5,.,.,9\n.,7,.,.\n.,.,10,19\n.,.,19,20\n")
+	f.Add("TURN,3\nTABLE\n1,2,3,4\n")
+	f.Add("TURN\nTABLE,x\n")
+	f.Add("TABLE\nTURN,0\n")
+	f.Add("TURN,0\nTABLE,.\n1,2,3,4\n5,6,7,8\n9,10,11,12\n13,14,15,99\n")
+	f.Add("TURN,0\nDIST,1-20x2\nTABLE,7\n1,.,.,.\n.,5,.,.\n.,.,9,.\n.,.,.,13\n")
+	f.Add("TURN,0\nDIST,1-20x300000000\nTABLE\n1,.,.,.\n.,5,.,.\n.,.,9,.\n.,.,.,13\n")
+	f.Add("TURN,0\nRANGE,30\nTABLE,25\n1,.,.,.\n.,5,.,.\n.,.,9,.\n.,.,.,13\n")
+	f.Fuzz(func(t *testing.T, data string) {
+		name := filepath.Join(t.TempDir(), "save.csv")
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		state := &GameState{}
+		if err := state.loadFromCSV(name); err != nil {
+			return
+		}
+		if state.Current < 0 || state.Current >= len(state.Boards) {
+			t.Errorf("loaded Current %d with %d boards", state.Current, len(state.Boards))
+		}
+		for _, tile := range state.Table {
+			if tile < 1 || tile > state.maxTile() {
+				t.Errorf("loaded table tile %d", tile)
+			}
+		}
+	})
+}
+
+func FuzzParseCell(f *testing.F) {
+	for _, s := range []string{"0,0", "3, 3", "4,0", "-1,2", "1", "a,b", "1,2,3", "B3", "d1", "A0", "E1", "B"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		cell, err := parseCell(s)
+		if err == nil && !onBoard(cell.R, cell.C) {
+			t.Errorf("parseCell(%q) = %v, off the board", s, cell)
+		}
+	})
+}
+
+func FuzzParseTile(f *testing.F) {
+	for _, s := range []string{"1", "20", "0", "21", "-5", "x", " 7 "} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		tile, err := parseTile(s, MaxTile)
+		if err == nil && (tile < 1 || tile > MaxTile) {
+			t.Errorf("parseTile(%q) = %d, out of range", s, tile)
+		}
+	})
+}
+
+func TestPlacementOffBoard(t *testing.T) {
+	state := exampleStateForTests()
+	for _, cell := range []Cell{{-1, 0}, {0, -1}, {BoardSize, 0}, {0, BoardSize}} {
+		if state.isPlacementFeasible(8, cell.R, cell.C) {
+			t.Errorf("Expected (%d,%d) to be infeasible", cell.R, cell.C)
+		}
+	}
+	if _, err := (&GameState{}).popDraw(); err != errPileEmpty {
+		t.Errorf("Expected errPileEmpty from an empty pile, got %v", err)
+	}
+}
+
+func TestRiskAwareScoring(t *testing.T) {
+	state := &GameState{
+		Boards: []*Board{{Grid: [maxBoardSize][maxBoardSize]int{
+			{5, 0, 0, 19},
+			{1, 2, 3, 20},
+			{1, 2, 3, 20},
+			{1, 2, 3, 20},
+		}}},
+		Draw: []int{6, 8, 9, 10, 11},
+	}
+	// (0,1) can only be filled by the single 6 once 7 sits at (0,2), but by
+	// any of five tiles once 12 does.
+	thin, wide := state.riskFactor(7, 0, 2), state.riskFactor(12, 0, 2)
+	if thin != 0.5 {
+		t.Errorf("Expected a gap hinging on one tile to halve the score, got %v", thin)
+	}
+	if wide <= thin {
+		t.Errorf("Expected the well supported gap to score higher: %v vs %v", wide, thin)
+	}
+
+	plain := state.placementScore(12, 0, 2)
+	state.Heuristics.RiskAware = true
+	if got := state.placementScore(12, 0, 2); got != plain*wide {
+		t.Errorf("Expected risk-aware score %v, got %v", plain*wide, got)
+	}
+}
+
+func TestTableEquity(t *testing.T) {
+	state := exampleStateForTests()
+	state.Table = append(state.Table, 7)
+	equities := state.tableEquity()
+	if len(equities) != 4 {
+		t.Fatalf("Expected one entry per distinct table tile, got %d", len(equities))
+	}
+	cost := state.pileExpectation()
+	for i, e := range equities {
+		if i > 0 && e.Equity > equities[i-1].Equity {
+			t.Errorf("Equities not ranked: %v before %v", equities[i-1].Equity, e.Equity)
+		}
+		if e.Equity != e.Score-cost {
+			t.Errorf("tile %d: equity %v != score %v - pile %v", e.Tile, e.Equity, e.Score, cost)
+		}
+	}
+	if best, fromTable := state.drawTileRecommendation(); fromTable && best.Tile != equities[0].Tile {
+		t.Errorf("Recommendation %d disagrees with top equity %d", best.Tile, equities[0].Tile)
+	}
+}
+
+func TestBrunoAwareScoring(t *testing.T) {
+	state := exampleStateForTests()
+	state.Current = 1
+	withTwin := state.placementScore(10, 2, 0) // diagonal to the 10 at (1,1)
+	noTwin := state.placementScore(10, 0, 1)
+
+	state.BrunoVariant = true
+	if got := state.placementScore(10, 2, 0); got != withTwin*brunoExtraTurnBonus {
+		t.Errorf("Expected extra-turn bonus for a diagonal twin, got %v vs %v", got, withTwin)
+	}
+	if got := state.placementScore(10, 0, 1); got != noTwin {
+		t.Errorf("Expected no bonus without a diagonal twin, got %v vs %v", got, noTwin)
+	}
+
+	state.Current = 0
+	if !state.giftsBrunoMatch(10) {
+		t.Errorf("Expected discarding 10 to gift board 1 an extra turn")
+	}
+	if state.giftsBrunoMatch(2) {
+		t.Errorf("Expected 2 to be safe to discard")
+	}
+	if state.Current != 0 {
+		t.Errorf("giftsBrunoMatch changed Current to %d", state.Current)
+	}
+}
+
+func TestTableThresholdFallsAsGameProgresses(t *testing.T) {
+	state := &GameState{Boards: []*Board{{}, {}}}
+	for i := 0; i < BoardSize; i++ {
+		state.Boards[0].Grid[i][i] = i*5 + 1
+	}
+	for i := 0; i < 2*MaxTile-2*BoardSize; i++ {
+		state.Draw = append(state.Draw, i%MaxTile+1)
+	}
+	opening := state.tableThreshold()
+	if opening < defaultHeuristics.OpeningThreshold*0.9 {
+		t.Errorf("Expected an opening threshold near %v, got %v", defaultHeuristics.OpeningThreshold, opening)
+	}
+
+	state.Boards[0].Grid[0][1] = 3
+	state.Boards[0].Grid[1][0] = 4
+	state.Draw = state.Draw[:10]
+	middle := state.tableThreshold()
+
+	state.Draw = nil
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if state.Boards[0].Grid[r][c] == 0 {
+				state.Boards[0].Grid[r][c] = r + c + 1
+			}
+		}
+	}
+	endgame := state.tableThreshold()
+	if !(opening > middle && middle > endgame) {
+		t.Errorf("Expected threshold to fall: %v, %v, %v", opening, middle, endgame)
+	}
+	if endgame != defaultHeuristics.EndgameThreshold {
+		t.Errorf("Expected endgame threshold %v, got %v", defaultHeuristics.EndgameThreshold, endgame)
+	}
+}
+
+func TestTakeControl(t *testing.T) {
+	state := exampleStateForTests()
+	state.Boards[1].IsAi = true
+	if err := state.takeControl("1"); err != nil {
+		t.Fatal(err)
+	}
+	if b := state.Boards[1]; b.IsAi || !b.Controlled {
+		t.Errorf("Expected seat 1 to be a controlled human seat, got %+v", *b)
+	}
+	if got := state.seatLabel(1); got != "Player 1 [test]" {
+		t.Errorf("Unexpected label %q", got)
+	}
+	for _, bad := range []string{"2", "-1", "x"} {
+		if err := state.takeControl(bad); err == nil {
+			t.Errorf("Expected an error for seat %q", bad)
+		}
+	}
+}
+
+func TestPlayHeadless(t *testing.T) {
+	state, err := newHeadlessGame(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := 0
+	winner := state.playHeadless([]Strategy{greedyStrategy{}, randomStrategy{}}, func(int, Move) { moves++ })
+	if winner >= 0 && !state.Boards[winner].IsFull() {
+		t.Errorf("Winner %d does not have a full board", winner)
+	}
+	if moves == 0 {
+		t.Errorf("Expected moves to be observed")
+	}
+}
+
+func TestRLLearnsTowardsReward(t *testing.T) {
+	s := &rlStrategy{w: initialRLWeights(), learning: true}
+	state := exampleStateForTests()
+	if _, ok := s.ChooseMove(state, 8); !ok {
+		t.Fatalf("Expected a legal move for 8")
+	}
+	phi := s.chosen[0]
+	before := s.w.value(phi)
+	s.learn(1, 0.1)
+	if after := s.w.value(phi); after <= before && before < 1 {
+		t.Errorf("Expected value to move towards the reward: %v -> %v", before, after)
+	}
+	if len(s.chosen) != 0 {
+		t.Errorf("Expected the trace to be cleared after learning")
+	}
+}
+
+func TestNumberWord(t *testing.T) {
+	cases := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 0, "zero"},
+		{"en", 17, "seventeen"},
+		{"en", 20, "twenty"},
+		{"en", 42, "forty-two"},
+		{"en", 115, "one hundred and fifteen"},
+		{"es", 17, "diecisiete"},
+		{"es", 22, "veintidós"},
+		{"es", 45, "cuarenta y cinco"},
+		{"es", 100, "cien"},
+		{"es", 101, "ciento uno"},
+		{"xx", 17, "17"},
+		{"en", 1000, "1000"},
+	}
+	for _, c := range cases {
+		if got := numberWord(c.lang, c.n); got != c.want {
+			t.Errorf("numberWord(%q, %d) = %q, want %q", c.lang, c.n, got, c.want)
+		}
+	}
+}
+
+func TestEvalModel(t *testing.T) {
+	m := &evalModel{
+		Features: []string{"bias", "filled", "cell_0_0"},
+		Layers:   []modelLayer{{Weights: [][]float64{{1, 2, 4}}, Bias: []float64{0.5}}},
+	}
+	if err := m.validate(); err != nil {
+		t.Fatal(err)
+	}
+	state := exampleStateForTests()
+	filled := 1 - float64(emptyCells(state.Boards[0]))/float64(BoardSize*BoardSize)
+	want := 0.5 + 1 + 2*filled + 4*5.0/MaxTile
+	if got := m.evaluate(state); got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	bad := []*evalModel{
+		{Features: []string{"nope"}, Layers: m.Layers},
+		{Features: []string{"cell_4_0"}, Layers: m.Layers},
+		{Features: []string{"bias"}, Layers: m.Layers},
+		{Features: m.Features},
+	}
+	for i, b := range bad {
+		if err := b.validate(); err == nil {
+			t.Errorf("model %d: expected a validation error", i)
+		}
+	}
+
+	if _, ok := (modelStrategy{model: m}).ChooseMove(state, 8); !ok {
+		t.Errorf("Expected the model strategy to place an 8")
+	}
+}
+
+func TestBackfillSeat(t *testing.T) {
+	state := exampleStateForTests()
+	if err := state.backfillSeat(0, "random"); err != nil {
+		t.Fatal(err)
+	}
+	if !state.Boards[0].IsAi || state.strategyFor(0).Name() != "random" {
+		t.Errorf("Expected seat 0 to be played by random")
+	}
+	if len(state.Backfills) != 1 || state.Backfills[0].Seat != 0 || state.Backfills[0].Strategy != "random" {
+		t.Errorf("Expected the backfill to be recorded, got %+v", state.Backfills)
+	}
+	if err := state.backfillSeat(0, "greedy"); err == nil {
+		t.Errorf("Expected an error backfilling a computer seat")
+	}
+	if err := state.backfillSeat(1, "nope"); err == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+}
+
+func TestDebugDump(t *testing.T) {
+	state := exampleStateForTests()
+	state.Seed = 424242
+	state.Draw = []int{13, 1, 8}
+	var dump strings.Builder
+	state.writeDebugDump(&dump)
+	for _, want := range []string{
+		"seed and hashes: withheld until the game is over\n",
+		"draw (3, sorted): [1 8 13]\n",
+		"table (4): [7 5 17 4]\n",
+		"board 1 (human):\n",
+	} {
+		if !strings.Contains(dump.String(), want) {
+			t.Errorf("Expected %q in the dump, got:\n%s", want, dump.String())
+		}
+	}
+	for _, leak := range []string{"424242", "[13 1 8]", drawHash(state.Draw), state.stateHash()} {
+		if strings.Contains(dump.String(), leak) {
+			t.Errorf("Expected %q kept out of a mid-game dump, got:\n%s", leak, dump.String())
+		}
+	}
+
+	state.Over = true
+	dump.Reset()
+	state.writeDebugDump(&dump)
+	for _, want := range []string{"seed: 424242\n", "draw order hash: " + drawHash(state.Draw) + "\n", "state hash: " + state.stateHash() + "\n"} {
+		if !strings.Contains(dump.String(), want) {
+			t.Errorf("Expected %q once the game is over, got:\n%s", want, dump.String())
+		}
+	}
+}
+
+func TestTournament(t *testing.T) {
+	a, b := eloUpdate(eloStart, eloStart, 1)
+	if a <= eloStart || a+b != 2*eloStart {
+		t.Errorf("Expected the winner to gain what the loser drops, got %v and %v", a, b)
+	}
+
+	table, wins, err := runTournament([]string{"greedy", "random"}, 4, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range table {
+		if s.games() != 4 {
+			t.Errorf("%s played %d games, expected 4", s.Name, s.games())
+		}
+	}
+	if table[0].Wins != wins[0][1] || table[1].Wins != wins[1][0] {
+		t.Errorf("Standings %+v disagree with head-to-head %v", table, wins)
+	}
+	again, _, _ := runTournament([]string{"greedy", "random"}, 4, 7)
+	if again[0] != table[0] {
+		t.Errorf("Expected seeded tournaments to repeat: %+v vs %+v", again[0], table[0])
+	}
+	if _, _, err := runTournament([]string{"greedy", "nope"}, 1, 1); err == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+}
+
+func TestArchiveSeparatesRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	state := exampleStateForTests()
+	for i, bruno := range []bool{false, true, true} {
+		state.BrunoVariant = bruno
+		state.History = []Played{{Seat: 0, Move: Move{Type: Place, Tile: 3 + i, Cell: &Cell{R: 0, C: 1}}}}
+		if err := appendArchive(path, state.archiveEntry(0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	games, err := readArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 3 || games[0].Rules != "classic" || games[1].Rules != "bruno" {
+		t.Fatalf("Unexpected archive %+v", games)
+	}
+	var out strings.Builder
+	printOpeningStats(&out, games)
+	for _, want := range []string{"== bruno: 2 games ==", "== classic: 1 games ==", "place at B1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWildcards(t *testing.T) {
+	state := exampleStateForTests()
+	if !state.isPlacementFeasible(Wildcard, 1, 0) {
+		t.Errorf("Expected a wildcard to fit any empty cell")
+	}
+	if tile, err := parseTile("*", MaxTile); err != nil || tile != Wildcard || tileLabel(Wildcard) != "*" {
+		t.Errorf("Expected \"*\" to round-trip as a wildcard, got %d, %v", tile, err)
+	}
+
+	// Neighbours ignore a wildcard: only the 9 to its right constrains (0,2).
+	state.Boards[0].Grid[0][1] = Wildcard
+	if !state.isPlacementFeasible(8, 0, 2) {
+		t.Errorf("Expected 8 to fit between a wildcard and 9")
+	}
+	if state.isPlacementFeasible(10, 0, 2) {
+		t.Errorf("Expected 10 not to fit left of 9")
+	}
+
+	name := filepath.Join(t.TempDir(), "wild.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Boards[0].Grid[0][1] != Wildcard {
+		t.Errorf("Expected the wildcard to survive a save, got %d", loaded.Boards[0].Grid[0][1])
+	}
+}
+
+func TestTermCapabilities(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	cases := []struct {
+		vars map[string]string
+		tty  bool
+		want termCaps
+	}{
+		{map[string]string{"TERM": "xterm-256color", "LANG": "en_US.UTF-8"}, true, termCaps{Unicode: true, Color: true}},
+		{map[string]string{"TERM": "xterm", "LANG": "en_US.UTF-8"}, false, termCaps{Unicode: true}},
+		{map[string]string{"TERM": "xterm", "LANG": "en_US.UTF-8", "LC_ALL": "C"}, true, termCaps{Color: true}},
+		{map[string]string{"TERM": "xterm", "LANG": "C.utf8", "NO_COLOR": "1"}, true, termCaps{Unicode: true}},
+		{map[string]string{"TERM": "dumb", "LANG": "en_US.UTF-8"}, true, termCaps{}},
+	}
+	for _, c := range cases {
+		if got := detectTerm(env(c.vars), c.tty); got != c.want {
+			t.Errorf("detectTerm(%v, %v) = %+v, expected %+v", c.vars, c.tty, got, c.want)
+		}
+	}
+
+	got, err := termCaps{Unicode: true, Color: true}.override("ascii, mono")
+	if err != nil || got != (termCaps{}) {
+		t.Errorf("Expected ascii,mono to drop both capabilities, got %+v, %v", got, err)
+	}
+	if _, err := (termCaps{}).override("sixel"); err == nil {
+		t.Errorf("Expected an error for an unknown capability")
+	}
+	if s := (termCaps{}).text("a — b’s"); s != "a - b's" {
+		t.Errorf("Expected ASCII punctuation, got %q", s)
+	}
+	if s := (termCaps{}).paint(styleBold, "x"); s != "x" {
+		t.Errorf("Expected no escape codes without colour, got %q", s)
+	}
+}
+
+func TestTileRange(t *testing.T) {
+	for _, hi := range []int{16, 20, 30} {
+		if x := xOfT(hi, hi); math.Abs(x-xOfT(MaxTile, MaxTile)) > 1e-9 {
+			t.Errorf("Expected the top tile of 1-%d to aim at the same cell as %d, got %v", hi, MaxTile, x)
+		}
+	}
+	state := exampleStateForTests()
+	state.TileRange = 30
+	if lo, hi := state.rowConstraints(1, 2); lo != 8 || hi != 30 {
+		t.Errorf("Expected (1,2) to allow 8-30 right of 7, got %d-%d", lo, hi)
+	}
+	if _, err := parseTile("25", state.maxTile()); err != nil {
+		t.Errorf("Expected 25 to be a tile in 1-30: %v", err)
+	}
+	if _, err := parseTile("25", MaxTile); err == nil {
+		t.Errorf("Expected 25 to be out of the standard range")
+	}
+
+	state.Boards[0].Grid[3][3] = 25
+	name := filepath.Join(t.TempDir(), "range.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.maxTile() != 30 || loaded.Boards[0].Grid[3][3] != 25 {
+		t.Errorf("Expected the range to survive a save, got 1-%d", loaded.maxTile())
+	}
+	if want := 2*30 - 11 - len(state.Table); len(loaded.Draw) != want { // 11 tiles on the boards
+		t.Errorf("Expected %d tiles left in the pile, got %d", want, len(loaded.Draw))
+	}
+}
+
+func TestTranscriptInterleavesInputAndOutput(t *testing.T) {
+	saved := reader
+	defer func() { reader = saved }()
+	reader = bufio.NewReader(strings.NewReader("p\n2\n"))
+
+	path := filepath.Join(t.TempDir(), "session.txt")
+	if err := startTranscript(path); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print("Play or Analyze? (p/a): ")
+	reader.ReadString('\n')
+	fmt.Println("Play mode selected.")
+	fmt.Print("Number of human players: ")
+	reader.ReadString('\n')
+	endTranscript()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Play or Analyze? (p/a): p\nPlay mode selected.\nNumber of human players: 2\n"
+	if _, body, _ := strings.Cut(string(data), "\n"); body != want {
+		t.Errorf("Expected transcript\n%q, got\n%q", want, body)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	for _, p := range presets {
+		state := &GameState{}
+		state.applyPreset(p)
+		if BoardSize != p.BoardSize || state.maxTile() != p.TileRange {
+			t.Errorf("%s: got %dx%d boards with tiles 1-%d", p.Name, BoardSize, BoardSize, state.maxTile())
+		}
+		corner := float64(2 + 2*(BoardSize-1))
+		if x := xOfT(p.TileRange, p.TileRange); math.Abs(x-corner) > 0.2 {
+			t.Errorf("%s: expected the top tile to aim at the far corner %v, got %v", p.Name, corner, x)
+		}
+		state.initDrawStack(2)
+		if len(state.Draw) != 2*p.TileRange {
+			t.Errorf("%s: expected a pile of %d, got %d", p.Name, 2*p.TileRange, len(state.Draw))
+		}
+	}
+
+	grande, _ := lookupPreset("grande")
+	state := &GameState{Boards: []*Board{{}}, Table: []int{}}
+	state.applyPreset(grande)
+	state.Boards[0].Grid[4][4] = 30
+	name := filepath.Join(t.TempDir(), "grande.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	BoardSize = standardBoardSize
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if BoardSize != 5 || loaded.maxTile() != 30 || loaded.Boards[0].Grid[4][4] != 30 {
+		t.Errorf("Expected a 5x5 game with tiles 1-30 back, got %dx%d with 1-%d", BoardSize, BoardSize, loaded.maxTile())
+	}
+}
+
+func TestNonDecreasingRule(t *testing.T) {
+	state := exampleStateForTests()
+	// (1,2) sits right of the 7 and above the 10.
+	if state.isPlacementFeasible(7, 1, 2) || state.isPlacementFeasible(10, 1, 2) {
+		t.Errorf("Expected equal neighbours to be illegal by default")
+	}
+	lo, hi := state.rowConstraints(1, 2)
+	state.NonDecreasing = true
+	if !state.isPlacementFeasible(7, 1, 2) || !state.isPlacementFeasible(10, 1, 2) {
+		t.Errorf("Expected equal neighbours to be legal under the non-decreasing rule")
+	}
+	if state.isPlacementFeasible(6, 1, 2) {
+		t.Errorf("Expected a smaller tile to stay illegal")
+	}
+	if lo2, hi2 := state.rowConstraints(1, 2); lo2 != lo-1 || hi2 != hi {
+		t.Errorf("Expected the row range to widen to include 7, got %d-%d from %d-%d", lo2, hi2, lo, hi)
+	}
+	if state.placementScore(7, 1, 2) <= 0 {
+		t.Errorf("Expected the AI to value the newly legal placement")
+	}
+}
+
+func TestPileScoring(t *testing.T) {
+	state := exampleStateForTests() // board 0 has 9 empty cells, board 1 has 12
+	if got := state.pileWinner(); got != -1 {
+		t.Errorf("Expected no winner under classic rules, got %d", got)
+	}
+	state.End = EndPileScore
+	if got := state.pileWinner(); got != 0 {
+		t.Errorf("Expected the board with fewer empty cells to win, got %d", got)
+	}
+	state.Boards[1].Grid[0][1] = 7
+	state.Boards[1].Grid[0][2] = 8
+	state.Boards[1].Grid[0][3] = 9
+	if got := state.pileWinner(); got != -1 {
+		t.Errorf("Expected a tie between equally empty boards, got %d", got)
+	}
+	state.Draw = nil
+	state.Table = nil
+	if got := state.playHeadless([]Strategy{greedyStrategy{}, greedyStrategy{}}, nil); got != -1 {
+		t.Errorf("Expected the tie to stand when the pile is already empty, got %d", got)
+	}
+}
+
+func TestTeamPlay(t *testing.T) {
+	state, err := newHeadlessGame(teamSeats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Teams = true
+	tile := state.Draw[0]
+	own, all := state.boardMoves(tile), state.bestMoves(tile)
+	if len(all) != len(own)+len(state.partnerMoves(tile)) {
+		t.Errorf("Expected %d own moves plus the partner's, got %d in all", len(own), len(all))
+	}
+	for _, m := range all {
+		if !m.Partner {
+			continue
+		}
+		next := state.clone()
+		next.commitMove(m)
+		if next.Boards[2].Grid[m.Cell.R][m.Cell.C] != tile || next.Boards[0].Grid != state.Boards[0].Grid {
+			t.Errorf("Expected a partner move to land on seat 2's board only")
+		}
+		break
+	}
+
+	winner := state.playHeadless([]Strategy{greedyStrategy{}, greedyStrategy{}, greedyStrategy{}, greedyStrategy{}}, nil)
+	if winner >= 0 && !state.Boards[winner].IsFull() {
+		t.Errorf("Expected seat %d's board to be full", winner)
+	}
+
+	tied := exampleStateForTests()
+	tied.Boards = append(tied.Boards, tied.Boards[0], &Board{})
+	tied.Teams, tied.End = true, EndPileScore
+	if got := tied.pileWinner(); got != 0 {
+		t.Errorf("Expected partners tied on empty cells to win together, got %d", got)
+	}
+}
+
+func TestPuzzle(t *testing.T) {
+	a, err := newPuzzle(42, defaultPuzzleDraws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newPuzzle(42, defaultPuzzleDraws)
+	if fmt.Sprint(a.Draw) != fmt.Sprint(b.Draw) || a.Boards[0].Grid != b.Boards[0].Grid {
+		t.Errorf("Expected the same seed to deal the same puzzle")
+	}
+
+	p := &Puzzle{Limit: 25, Draws: 20}
+	board := &Board{}
+	board.Grid[0][0], board.Grid[1][1] = 1, 5
+	if score, solved := p.score(board); solved || score != 2*puzzleCellPoints {
+		t.Errorf("Expected 2 filled cells to score %d, got %d (solved %v)", 2*puzzleCellPoints, score, solved)
+	}
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			board.Grid[r][c] = r + c + 1
+		}
+	}
+	want := BoardSize*BoardSize*puzzleCellPoints + puzzleSolvedBonus + 5*puzzleSparePoints
+	if score, solved := p.score(board); !solved || score != want {
+		t.Errorf("Expected a solved board to score %d, got %d", want, score)
+	}
+}
+
+func TestDailyChallenge(t *testing.T) {
+	tokyo := time.Date(2026, 3, 2, 8, 0, 0, 0, time.FixedZone("JST", 9*3600))
+	seed, date := dailySeed(tokyo)
+	if seed != 20260301 || date != "2026-03-01" {
+		t.Errorf("Expected the UTC date to pick the seed, got %d for %s", seed, date)
+	}
+	p := &Puzzle{Limit: 25, Draws: 18, Daily: date}
+	board := &Board{}
+	board.Grid[0][0] = 3
+	want := "Unlucky Numbers daily 2026-03-01: 18/25 turns, 15 empty\n#...\n....\n....\n....\n"
+	if got := p.shareText(board, false); got != want {
+		t.Errorf("Expected share text\n%s, got\n%s", want, got)
+	}
+}
+
+func TestMatchScoring(t *testing.T) {
+	state := exampleStateForTests() // board 0 has fewer empty cells than board 1
+	state.Match = newMatch(3, 2)
+	if got := state.finishingOrder(1); fmt.Sprint(got) != "[1 0]" {
+		t.Errorf("Expected the winner first, got %v", got)
+	}
+	if got := state.finishingOrder(-1); fmt.Sprint(got) != "[0 1]" {
+		t.Errorf("Expected fewest empty cells first without a winner, got %v", got)
+	}
+	m := state.Match
+	m.award(state.finishingOrder(1), 1)
+	m.award(state.finishingOrder(-1), -1)
+	if m.Points[0] != placePoints[1]+placePoints[0] || m.Points[1] != placePoints[0]+placePoints[1] || m.Wins[1] != 1 {
+		t.Errorf("Unexpected points %v and wins %v", m.Points, m.Wins)
+	}
+	if got := m.leaders(); len(got) != 2 {
+		t.Errorf("Expected a tie for the lead, got %v", got)
+	}
+
+	if err := state.dealRound(); err != nil {
+		t.Fatal(err)
+	}
+	if state.Current != 0 || len(state.Table) != 0 || emptyCells(state.Boards[1]) != BoardSize*BoardSize-BoardSize {
+		t.Errorf("Expected a fresh deal started by seat 0 after two rounds")
+	}
+}
+
+func TestHandicap(t *testing.T) {
+	for in, want := range map[string]Handicap{"": {}, "d2": {Withheld: 2}, "peek": {Peek: true}, "d1+peek\n": {Withheld: 1, Peek: true}} {
+		if h, err := parseHandicap(in); err != nil || h != want {
+			t.Errorf("parseHandicap(%q) = %+v, %v; expected %+v", in, h, err, want)
+		}
+	}
+	for _, bad := range []string{"d0", fmt.Sprintf("d%d", BoardSize), "fast"} {
+		if _, err := parseHandicap(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	state := &GameState{}
+	state.initDrawStack(1)
+	b := &Board{Handicap: Handicap{Withheld: 2}}
+	if err := state.fillRandomDiagonal(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := emptyCells(b); got != BoardSize*BoardSize-BoardSize+2 {
+		t.Errorf("Expected two diagonal cells withheld, %d cells empty", got)
+	}
+	if len(state.Draw) != MaxTile-BoardSize+2 {
+		t.Errorf("Expected withheld tiles to stay in the pile, %d left", len(state.Draw))
+	}
+
+	state = exampleStateForTests()
+	state.Draw = []int{8}
+	if !state.peekBeatsTable(Move{Score: 0}) || state.peekBeatsTable(Move{Score: 1000}) {
+		t.Errorf("Expected the peeked tile to be compared with the table move")
+	}
+}
+
+func TestForcedTablePlacement(t *testing.T) {
+	state := exampleStateForTests()
+	state.ForcedTable = true
+	if state.tablePickAllowed(Move{Type: Discard, Tile: 7}) {
+		t.Errorf("Expected a table tile not to be discarded again")
+	}
+	if !state.tablePickAllowed(Move{Type: Place, Tile: 8, Cell: &Cell{R: 2, C: 1}}) {
+		t.Errorf("Expected a table tile to be placed")
+	}
+
+	state.Table = []int{8, 1}
+	state.Boards[0].Grid[0][0] = 1
+	if moves := state.bestMoves(1); len(moves) != 0 {
+		t.Fatalf("Expected 1 to fit nowhere, got %d moves", len(moves))
+	}
+	for _, e := range state.tableEquity() {
+		if e.Tile == 1 {
+			t.Errorf("Expected an unplaceable table tile to have no equity under the rule")
+		}
+	}
+	state.ForcedTable = false
+	if len(state.tableEquity()) != 2 {
+		t.Errorf("Expected both table tiles rated without the rule")
+	}
+}
+
+func TestBrunoRules(t *testing.T) {
+	for in, want := range map[string]BrunoDirections{"": 0, "diagonal": BrunoDiagonal, "orthogonal": BrunoOrthogonal, "diagonal+row": BrunoDiagonal | BrunoRow, "any\n": BrunoAny} {
+		if d, err := parseBrunoDirections(in); err != nil || d != want {
+			t.Errorf("parseBrunoDirections(%q) = %v, %v; expected %v", in, d, err, want)
+		}
+	}
+	if _, err := parseBrunoDirections("knight"); err == nil {
+		t.Errorf("Expected an unknown direction to be rejected")
+	}
+	if s := (BrunoDiagonal | BrunoColumn).String(); s != "diagonal+column" {
+		t.Errorf("Expected diagonal+column, got %q", s)
+	}
+
+	state := exampleStateForTests()
+	board := state.Boards[0]
+	// 19 at (2,3) has a twin at (3,2) diagonally but none orthogonally.
+	if _, ok := state.brunoMatch(board, 19, 2, 3); !ok {
+		t.Errorf("Expected the diagonal twin to match by default")
+	}
+	state.Bruno.Directions = BrunoOrthogonal
+	if _, ok := state.brunoMatch(board, 19, 2, 3); ok {
+		t.Errorf("Expected no orthogonal twin")
+	}
+	if _, ok := state.brunoMatch(board, 20, 2, 3); !ok {
+		t.Errorf("Expected the 20 below (2,3) to match orthogonally")
+	}
+
+	state.Bruno = BrunoRules{MaxChain: 2}
+	for i, want := range []bool{true, true, false} {
+		if got := state.checkBrunoExtra(board, 2, 3); got != want {
+			t.Errorf("Extra turn %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestOpenPile(t *testing.T) {
+	state := exampleStateForTests()
+	hidden := state.pileExpectation()
+	state.OpenPile = true
+	state.Draw = append([]int{8}, state.Draw...)
+	top := state.bestMoves(8)[0].Score
+	if got := state.pileExpectation(); got != top {
+		t.Errorf("Expected the open pile to be worth its top tile, %.2f; got %.2f (hidden %.2f)", top, got, hidden)
+	}
+	if move, fromTable := state.drawTileRecommendation(); fromTable && move.Score <= top {
+		t.Errorf("Expected a table pick only when it beats the face-up %d, got %+v", 8, move)
+	}
+	if !strings.Contains(state.pileTopLabel(), "top 8") {
+		t.Errorf("Expected the renderer label to show the top tile, got %q", state.pileTopLabel())
+	}
+}
+
+func TestHandOfTiles(t *testing.T) {
+	state := exampleStateForTests()
+	state.HandSize = 2
+	pile := len(state.Draw)
+	state.dealHands()
+	if len(state.Boards[0].Hand) != 2 || len(state.Boards[1].Hand) != 2 || len(state.Draw) != pile-4 {
+		t.Fatalf("Expected two tiles dealt to each hand, got %v and %v", state.Boards[0].Hand, state.Boards[1].Hand)
+	}
+
+	state.Boards[0].Hand = []int{1, 8}
+	if tile, score := state.bestHandTile(); tile != 8 || score <= 0 {
+		t.Errorf("Expected 8 to be the better hand tile, got %d (%.2f)", tile, score)
+	}
+	if move := state.playFromHand(8); move.Type != FromHand || fmt.Sprint(state.Boards[0].Hand) != "[1]" {
+		t.Errorf("Expected 8 to leave the hand, got %+v with hand %v", move, state.Boards[0].Hand)
+	}
+	state.refillHand(0)
+	if len(state.Boards[0].Hand) != 2 {
+		t.Errorf("Expected the hand drawn back up to 2, got %v", state.Boards[0].Hand)
+	}
+
+	sim := state.clone()
+	sim.Boards[0].Hand[0]++
+	if sim.Boards[0].Hand[0] == state.Boards[0].Hand[0] {
+		t.Errorf("Expected clone to copy hands")
+	}
+	move, _ := sim.headlessTurn(greedyStrategy{})
+	if len(sim.Boards[0].Hand) != 2 && len(sim.Draw) > 0 {
+		t.Errorf("Expected the hand refilled after %+v, got %v", move, sim.Boards[0].Hand)
+	}
+}
+
+func TestTileDistribution(t *testing.T) {
+	d, err := parseDistribution("1-20x2, 8-13", MaxTile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d[1] != 2 || d[10] != 3 || d.total() != 46 || d.String() != "1-7x2,8-13x3,14-20x2" {
+		t.Errorf("Unexpected distribution %v (%d tiles)", d, d.total())
+	}
+	for _, bad := range []string{"0-5", "5-3", "1-20xq", "*x2", "", "1-20x300000000", "7x60,7x60"} {
+		if _, err := parseDistribution(bad, MaxTile); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	state := &GameState{Distribution: d}
+	state.initDrawStack(4)
+	if len(state.Draw) != 46 {
+		t.Errorf("Expected the pile to follow the distribution, not the player count: %d tiles", len(state.Draw))
+	}
+	if err := (&GameState{Distribution: Distribution{1: 3}}).checkDistribution(2); err == nil {
+		t.Errorf("Expected a pile too small for the diagonals to be rejected")
+	}
+
+	state.Boards = []*Board{{}, {}}
+	for _, b := range state.Boards {
+		if err := state.fillRandomDiagonal(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := filepath.Join(t.TempDir(), "dist.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Distribution.String() != d.String() || len(loaded.Draw) != len(state.Draw) {
+		t.Errorf("Expected the distribution to survive a save, got %v with %d in the pile", loaded.Distribution, len(loaded.Draw))
+	}
+
+	saved := reader
+	defer func() { reader = saved }()
+	reader = bufio.NewReader(strings.NewReader("edit\n10=0\n1-3+1\n\n"))
+	edited := &GameState{}
+	edited.promptDistribution(2)
+	if got := edited.Distribution; got[10] != 0 || got[2] != 3 || got[20] != 2 {
+		t.Errorf("Unexpected edited distribution %v", got)
+	}
+}
+
+func TestDiagonalRule(t *testing.T) {
+	state := exampleStateForTests()
+	// Leave (1,1) with only a 6 at (3,3) below it on the diagonal: rows and
+	// columns do not link the two cells, the diagonal rule does.
+	state.Boards[0].Grid[1][1] = 0
+	state.Boards[0].Grid[0][0], state.Boards[0].Grid[0][3] = 0, 0
+	state.Boards[0].Grid[2][2] = 0
+	state.Boards[0].Grid[3][3] = 6
+	state.Boards[0].Grid[2][3], state.Boards[0].Grid[3][2] = 0, 0
+	if !state.isPlacementFeasible(9, 1, 1) {
+		t.Fatalf("Expected 9 at (1,1) to be legal without the rule")
+	}
+	state.DiagonalRule = true
+	if state.isPlacementFeasible(9, 1, 1) {
+		t.Errorf("Expected 9 above a 6 further down the diagonal to be rejected")
+	}
+
+	state = &GameState{DiagonalRule: true, Distribution: Distribution{1: 4, 2: 4, 3: 4, 4: 4, 5: 4}}
+	state.initDrawStack(1)
+	for i := 0; i < 3; i++ {
+		b := &Board{}
+		if err := state.fillRandomDiagonal(b); err != nil {
+			t.Fatal(err)
+		}
+		if !diagonalIncreases(b) {
+			t.Errorf("Expected a strictly increasing diagonal, got %v", b.Grid)
+		}
+	}
+}
+
+func TestAchievements(t *testing.T) {
+	state := &GameState{
+		Boards: []*Board{{Grid: [maxBoardSize][maxBoardSize]int{
+			{1, 2, 3, 4},
+			{5, 7, 9, 10},
+			{6, 8, 11, 12},
+			{13, 14, 15, 16},
+		}}, {IsAi: true}},
+		Draw: []int{17, 18, 19, 20, 20, 19},
+	}
+	path := filepath.Join(t.TempDir(), "profile.json")
+	p, err := loadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlocked := p.record(state, 0, time.Now())
+	var ids []string
+	for _, a := range unlocked {
+		ids = append(ids, a.ID)
+	}
+	if fmt.Sprint(ids) != "[first-win no-table straight early-finish]" {
+		t.Errorf("Unexpected achievements %v", ids)
+	}
+	if err := p.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	state.Boards[0].TableTakes = 1
+	p, err = loadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unlocked := p.record(state, 1, time.Now()); len(unlocked) != 0 {
+		t.Errorf("Expected nothing new after a loss, got %v", unlocked)
+	}
+	if p.Games != 2 || p.Wins != 1 || p.Achievements["straight"].Count != 2 || p.Achievements["first-win"].Count != 1 {
+		t.Errorf("Unexpected profile %+v", p)
+	}
+	var out strings.Builder
+	printProfile(&out, p)
+	if !strings.Contains(out.String(), "4 of 5 achievements earned") {
+		t.Errorf("Unexpected profile listing:\n%s", out.String())
+	}
+}
+
+func TestChessClock(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	tr := newTimedReader(bufio.NewReader(pr))
+	if _, err := tr.readTimeout('\n', 10*time.Millisecond); err != errTimeout {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	pw.Write([]byte("late\n")) // the answer to the prompt that gave up
+	time.Sleep(10 * time.Millisecond)
+	go pw.Write([]byte("next\n"))
+	if line, err := tr.readTimeout('\n', time.Second); err != nil || line != "next\n" {
+		t.Errorf("Expected the line typed too late dropped and the next read, got %q, %v", line, err)
+	}
+
+	state := exampleStateForTests()
+	state.Clock = newClock(20*time.Millisecond, len(state.Boards), false)
+	saved := reader
+	defer func() { reader = saved }()
+	reader = clockReader{tr, state}
+	expired := state.timed(func() {
+		for i := 0; i < 2; i++ {
+			if _, err := reader.ReadString('\n'); err != errTimeout {
+				t.Errorf("Expected read %d cut off, got %v", i, err)
+			}
+		}
+	})
+	if !expired || state.Clock.Left[0] != 0 {
+		t.Errorf("Expected seat 0 to run out of time, left %v", state.Clock.Left[0])
+	}
+	state.Clock.Left[0] = 20 * time.Millisecond
+	pile := len(state.Draw)
+	var move Move
+	var quit bool
+	if !state.timed(func() { move, quit = state.promptDrawOrSave() }) || move != (Move{}) || quit || len(state.Draw) != pile {
+		t.Errorf("Expected the draw prompt to give up without drawing, got %+v, quit %v", move, quit)
+	}
+	if !state.timed(func() { t.Errorf("Expected a seat out of time not to be prompted") }) {
+		t.Errorf("Expected the seat to stay out of time")
+	}
+
+	before := len(state.History)
+	state.timeUp(nil)
+	if len(state.History) == before || state.Boards[0].IsAi {
+		t.Errorf("Expected the computer to move once for the seat and hand it back")
+	}
+	if s := formatClock(90 * time.Second); s != "1:30" {
+		t.Errorf("Expected 1:30, got %s", s)
+	}
+}
+
+func TestStealSwap(t *testing.T) {
+	state := exampleStateForTests()
+	state.StealSwap = true
+	if _, ok := state.stealRecommendation(); ok {
+		t.Errorf("Expected no steal while nobody is close to winning")
+	}
+
+	// Seat 1 is one tile from a full board.
+	state.Boards[1].Grid = [maxBoardSize][maxBoardSize]int{
+		{1, 2, 3, 4},
+		{5, 6, 7, 8},
+		{9, 10, 11, 12},
+		{13, 14, 15, 0},
+	}
+	move, ok := state.stealRecommendation()
+	if !ok || move.Type != Steal || move.Target != 1 {
+		t.Fatalf("Expected a steal from seat 1, got %+v, %v", move, ok)
+	}
+	table := len(state.Table)
+	state.applyMove(move)
+	if state.Boards[1].Grid[move.Cell.R][move.Cell.C] != 0 || len(state.Table) != table+1 || !state.Boards[0].StealUsed {
+		t.Errorf("Expected the stolen %d on the table and the steal spent", move.Tile)
+	}
+	if _, ok := state.stealRecommendation(); ok || state.canSteal() {
+		t.Errorf("Expected only one steal per game")
+	}
+	if state.stealable(0, 0, 0) {
+		t.Errorf("Expected a seat not to steal from itself")
+	}
+}
+
+func TestBlockedCells(t *testing.T) {
+	if holes, err := parseHoles("0,3 2,1 0,3"); err != nil || len(holes) != 2 {
+		t.Errorf("Expected two holes, got %v, %v", holes, err)
+	}
+	for _, bad := range []string{"1,1", "9", "0,9"} {
+		if _, err := parseHoles(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if holes, _ := parseHoles("3"); len(holes) != 3 {
+		t.Errorf("Expected three random holes, got %v", holes)
+	}
+
+	state := &GameState{
+		Boards: []*Board{{Grid: [maxBoardSize][maxBoardSize]int{
+			{1, 2, 3, 4},
+			{5, 6, 0, 8},
+			{9, 10, 11, 12},
+			{13, 14, 15, 16},
+		}}},
+		Holes: []Cell{{R: 1, C: 2}},
+	}
+	state.blockHoles(state.Boards[0])
+	if !state.Boards[0].IsFull() || emptyCells(state.Boards[0]) != 0 {
+		t.Errorf("Expected a board full but for its hole to count as full")
+	}
+	if state.isPlacementFeasible(7, 1, 2) {
+		t.Errorf("Expected a hole never to take a tile")
+	}
+
+	// 6 and 8 sit either side of the hole: no 7 is needed between them.
+	state.Boards[0].Grid[1][1] = 0
+	state.Draw = []int{17}
+	if !state.isPlacementFeasible(6, 1, 1) {
+		t.Errorf("Expected the neighbours across a hole to need no tile between them")
+	}
+
+	name := filepath.Join(t.TempDir(), "holes.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Boards[0].Grid[1][2] != Blocked || fmt.Sprint(loaded.Holes) != fmt.Sprint(state.Holes) {
+		t.Errorf("Expected the hole to survive a save, got %v", loaded.Holes)
+	}
+}
+
+func TestOfficialSetup(t *testing.T) {
+	state := &GameState{}
+	if got := state.bestArrangement([]int{3, 18}); fmt.Sprint(got) != "[0 3]" {
+		t.Errorf("Expected a low and a high tile at the diagonal's ends, got %v", got)
+	}
+	if got := state.bestArrangement([]int{5, 6}); fmt.Sprint(got) != "[0 1]" {
+		t.Errorf("Expected two low tiles near the top, got %v", got)
+	}
+	for _, bad := range [][]int{{1}, {2, 2}, {2, 0}, {0, 4}} {
+		if validArrangement([]int{5, 6}, bad) == nil {
+			t.Errorf("Expected cells %v to be rejected", bad)
+		}
+	}
+	if err := validArrangement([]int{Wildcard, 6}, []int{3, 0}); err != nil {
+		t.Errorf("Expected a wildcard to go anywhere: %v", err)
+	}
+
+	saved := reader
+	defer func() { reader = saved }()
+	reader = bufio.NewReader(strings.NewReader("1 0\n1 3\n"))
+	if got := state.promptArrangement([]int{5, 6}, []int{0, 1}); fmt.Sprint(got) != "[1 3]" {
+		t.Errorf("Expected the player's valid arrangement, got %v", got)
+	}
+
+	state = &GameState{}
+	state.initDrawStack(2)
+	b := &Board{IsAi: true, Handicap: Handicap{Withheld: 2}}
+	if err := state.arrangeDiagonal(b); err != nil {
+		t.Fatal(err)
+	}
+	if !diagonalIncreases(b) || emptyCells(b) != BoardSize*BoardSize-2 {
+		t.Errorf("Expected two increasing diagonal tiles, got %v", b.Grid)
+	}
+}
+
+func TestMulligan(t *testing.T) {
+	state := &GameState{}
+	if cost := state.arrangementCost([]int{1, 2, 3, 4}); cost <= mulliganCost {
+		t.Errorf("Expected four low tiles to call for a mulligan, cost %.2f", cost)
+	}
+	if cost := state.arrangementCost([]int{1, 7, 13, 20}); cost > mulliganCost {
+		t.Errorf("Expected a well spread diagonal to be kept, cost %.2f", cost)
+	}
+
+	saved := reader
+	defer func() { reader = saved }()
+	reader = bufio.NewReader(strings.NewReader("y\n"))
+	state = &GameState{Mulligan: true}
+	state.initDrawStack(2)
+	total := len(state.Draw)
+	b := &Board{}
+	if err := state.arrangeDiagonal(b); err != nil {
+		t.Fatal(err)
+	}
+	if placed := BoardSize*BoardSize - emptyCells(b); placed != BoardSize || len(state.Draw)+placed != total {
+		t.Errorf("Expected the rejected tiles back in the pile: %d placed, %d of %d left", placed, len(state.Draw), total)
+	}
+}
+
+func TestSharedPool(t *testing.T) {
+	state := &GameState{SharedPool: true}
+	state.initDrawStack(4)
+	if len(state.Draw) != MaxTile {
+		t.Errorf("Expected one set of %d tiles for four players, got %d", MaxTile, len(state.Draw))
+	}
+	if err := state.checkDistribution(4); err != nil {
+		t.Errorf("Expected one set to deal four diagonals: %v", err)
+	}
+
+	// Two empty cells between a 5 and a 9 need two different values.
+	if !canFillGap([]int{6, 8}, 5, 9, 2, 1) {
+		t.Errorf("Expected 6 and 8 to fill a two-cell gap between 5 and 9")
+	}
+	if canFillGap([]int{7, 7, 12}, 5, 9, 2, 1) {
+		t.Errorf("Expected two 7s not to fill a strictly climbing gap")
+	}
+	if !canFillGap([]int{7, 7}, 5, 9, 2, 0) || !canFillGap([]int{7, Wildcard}, 5, 9, 2, 1) {
+		t.Errorf("Expected repeats to fill a non-decreasing gap, and a wildcard any cell")
+	}
+
+	state = &GameState{
+		Boards: []*Board{{Grid: [maxBoardSize][maxBoardSize]int{
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 9},
+		}}},
+		Draw: []int{7, 7},
+	}
+	if state.isPlacementFeasible(6, 3, 0) {
+		t.Errorf("Expected 6 to be rejected with only 7s left for the two cells before 9")
+	}
+	state.Draw = append(state.Draw, 8)
+	if !state.isPlacementFeasible(6, 3, 0) {
+		t.Errorf("Expected 6 to be legal with 7 and 8 left")
+	}
+}
+
+func TestBracket(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5} {
+		met := map[[2]int]int{}
+		for _, round := range roundRobinSchedule(n) {
+			for _, m := range round {
+				if m[1] >= 0 {
+					met[m]++
+				}
+			}
+		}
+		if len(met) != n*(n-1)/2 {
+			t.Errorf("Expected %d entrants to meet in %d pairs, got %v", n, n*(n-1)/2, met)
+		}
+		for m, k := range met {
+			if k != 1 || m[0] >= m[1] {
+				t.Errorf("Expected pair %v once with the better seed first, got %d", m, k)
+			}
+		}
+	}
+
+	if _, err := parseEntrants([]string{"greedy"}); err == nil {
+		t.Errorf("Expected one participant to be rejected")
+	}
+	entrants, err := parseEntrants([]string{"greedy", "random", "human:Ann", "greedy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entrants[2].human() || entrants[2].Name != "Ann" || entrants[3].Name != "greedy#2" {
+		t.Errorf("Unexpected entrants %v %v", entrants[2], entrants[3])
+	}
+
+	seedRNG(1)
+	entrants, _ = parseEntrants([]string{"greedy", "random", "cautious"})
+	var out strings.Builder
+	champion, err := runBracket("knockout", entrants, 1, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "greedy           bye") || champion.Losses != 0 {
+		t.Errorf("Expected the top seed's bye and an unbeaten champion, got %s:\n%s", champion.Name, out.String())
+	}
+}
+
+func TestCampaign(t *testing.T) {
+	for n, stage := range campaign {
+		state, err := newCampaignGame(n)
+		if err != nil {
+			t.Fatalf("stage %d: %v", n+1, err)
+		}
+		if len(state.Boards) != len(stage.Opponents)+1 || state.Boards[0].IsAi || !state.Boards[1].IsAi {
+			t.Errorf("Stage %d: expected the human in seat 0 against %v", n+1, stage.Opponents)
+		}
+		if filled := BoardSize*BoardSize - emptyCells(state.Boards[0]); filled != BoardSize-stage.Player.Withheld {
+			t.Errorf("Stage %d: expected %d diagonal tiles for the human, got %d", n+1, BoardSize-stage.Player.Withheld, filled)
+		}
+	}
+	if state, _ := newCampaignGame(4); !state.StealSwap {
+		t.Errorf("Expected the pickpocket stage to allow steals")
+	}
+
+	p := &Profile{}
+	if p.clearStage(0); p.Campaign != 1 {
+		t.Errorf("Expected clearing stage 1 to unlock stage 2, got %d", p.Campaign)
+	}
+	if p.clearStage(0) || p.Campaign != 1 {
+		t.Errorf("Expected replaying a cleared stage not to change progress")
+	}
+	path := filepath.Join(t.TempDir(), "profile.json")
+	if err := p.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := playCampaign(path, 3); err == nil {
+		t.Errorf("Expected stage 4 to be locked after clearing one stage")
+	}
+}
+
+func TestSetupFlags(t *testing.T) {
+	s := &Setup{given: map[string]bool{}}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	s.register(fs)
+	args := []string{"-defaults", "-humans", "0", "-computers", "3", "-strategies", "cautious,,random",
+		"-preset", "quick", "-shared-pool", "-handicaps", ",d1,"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	s.noteGiven(fs)
+	savedSetup, savedReader := setup, reader
+	defer func() { setup, reader, BoardSize = savedSetup, savedReader, standardBoardSize }()
+	setup = s
+	// Any question still asked would read this and fail the checks below.
+	reader = bufio.NewReader(strings.NewReader("2\n2\ncustom\n"))
+
+	state := &GameState{}
+	if err := state.setUpBoards(); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Boards) != 3 || BoardSize != 3 || !state.SharedPool {
+		t.Fatalf("Expected three quick boards with a shared pool, got %d of size %d", len(state.Boards), BoardSize)
+	}
+	var got []string
+	for _, b := range state.Boards {
+		got = append(got, b.Strategy+"/"+b.Handicap.String())
+	}
+	if want := "cautious/none greedy/d1 random/none"; strings.Join(got, " ") != want {
+		t.Errorf("Expected seats %q, got %q", want, strings.Join(got, " "))
+	}
+
+	s.handicaps = "d1,d1,d1,d1"
+	if err := (&GameState{}).setUpBoards(); err == nil {
+		t.Errorf("Expected too many handicaps to be rejected")
+	}
+}
+
+func TestColorBoard(t *testing.T) {
+	for tile, want := range map[int]string{1: styleBlue, 6: styleBlue, 7: styleYellow, 13: styleYellow, 14: styleRed, 20: styleRed} {
+		if got := tileStyle(tile, MaxTile); got != want {
+			t.Errorf("tileStyle(%d) = %s, want %s", tile, got, want)
+		}
+	}
+
+	state := exampleStateForTests()
+	if len(state.lastPlaced()) != 0 {
+		t.Errorf("Expected no placed tile before any move")
+	}
+	state.History = []Played{
+		{Seat: 0, Move: Move{Type: Place, Cell: &Cell{R: 0, C: 1}, Tile: 6}},
+		{Seat: 1, Move: Move{Type: Place, Cell: &Cell{R: 3, C: 0}, Tile: 3}},
+		{Seat: 0, Move: Move{Type: Place, Cell: &Cell{R: 0, C: 2}, Tile: 8}},
+		{Seat: 1, Move: Move{Type: Place, Cell: &Cell{R: 2, C: 1}, Tile: 9}},
+		{Seat: 0, Move: Move{Type: Discard, Tile: 4}},
+	}
+	if got := state.lastPlaced(); len(got) != 2 || got[0] != (Cell{R: 0, C: 2}) || got[1] != (Cell{R: 2, C: 1}) {
+		t.Errorf("Expected C1 on board 0 and B3 on board 1 as the last placed, got %v", got)
+	}
+
+	saved := term
+	defer func() { term = saved }()
+	term = termCaps{Color: true}
+	if got := term.paint(styleRed+";"+styleReverse, "20"); got != "\033[31;7m20\033[0m" {
+		t.Errorf("Unexpected painted tile %q", got)
+	}
+}
+
+func TestTerminalUI(t *testing.T) {
+	defer func() { screen = nil }()
+	state := exampleStateForTests()
+	best := *state.bestMoves(1)[0].Cell
+	// Draw the pile's 1 and place it where the cursor starts, on the best cell.
+	screen = &tui{in: bufio.NewReader(strings.NewReader("p\n"))}
+	move, quit := state.promptDrawOrSave()
+	if quit || move.Tile != 1 {
+		t.Fatalf("Expected to draw the pile's 1, got %+v, quit %v", move, quit)
+	}
+	state.promptPlacement(move)
+	if state.Boards[0].Grid[best.R][best.C] != 1 {
+		t.Errorf("Expected 1 at the recommended %v, got %v", best, state.Boards[0].Grid)
+	}
+
+	// Take the second table tile and discard it again.
+	screen = &tui{in: bufio.NewReader(strings.NewReader("tl\nd"))}
+	if move, _ = state.promptDrawOrSave(); move.Tile != 5 || !move.FromTable {
+		t.Fatalf("Expected 5 from the table, got %+v", move)
+	}
+	state.promptPlacement(move)
+	if state.Table[len(state.Table)-1] != 5 || screen.placing {
+		t.Errorf("Expected 5 discarded to the table, got %v", state.Table)
+	}
+	screen.placing, screen.board, screen.cursor = true, 0, Cell{R: 1, C: 2}
+	if screen.cellStyle(0, 1, 2) != styleReverse || screen.cellStyle(0, 0, 1) != "" || screen.cellStyle(1, 1, 2) != "" {
+		t.Errorf("Expected the cursor highlighted on the current board only")
+	}
+	if got := state.describePlayed(state.History[len(state.History)-1]); got != "Player 0 discarded 5." {
+		t.Errorf("Unexpected catch-up line %q", got)
+	}
+}
+
+func TestLegalCellHighlight(t *testing.T) {
+	state := exampleStateForTests()
+	if state.legalCells() != nil {
+		t.Errorf("Expected nothing marked while no tile is held")
+	}
+	state.Holding = 8
+	legal := state.legalCells()
+	for r := 0; r < BoardSize; r++ {
+		for c := 0; c < BoardSize; c++ {
+			if legal[0][Cell{R: r, C: c}] != state.isPlacementFeasible(8, r, c) {
+				t.Errorf("Expected (%d,%d) marked exactly when 8 can go there", r, c)
+			}
+		}
+	}
+	if _, ok := legal[1]; ok {
+		t.Errorf("Expected only the current board marked outside team play")
+	}
+	state.Teams = true
+	state.Boards = append(state.Boards, &Board{IsAi: true}, &Board{})
+	if _, ok := state.legalCells()[2]; !ok {
+		t.Errorf("Expected the partner's board marked in team play")
+	}
+	state.Boards[0].IsAi = true
+	if state.legalCells() != nil {
+		t.Errorf("Expected a computer's held tile not to be marked")
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	if heatLevel(0, 10, 5) != 0 || heatLevel(10, 10, 5) != 4 || heatLevel(5, 10, 5) != 2 || heatLevel(3, 0, 5) != 0 {
+		t.Errorf("Unexpected heat levels")
+	}
+	saved := term
+	defer func() { term = saved }()
+	term = termCaps{}
+	state := exampleStateForTests()
+	var out strings.Builder
+	state.printHeatmap(&out, 8)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != BoardSize+2 {
+		t.Fatalf("Expected a title, column letters and %d rows, got:\n%s", BoardSize, out.String())
+	}
+	// (2,0) is 8's best cell and (0,0) is taken by a 5 it cannot swap.
+	if !strings.HasPrefix(lines[4], "3 |#") || !strings.HasPrefix(lines[2], "1 |   --") {
+		t.Errorf("Expected the best cell hottest and illegal cells blank, got:\n%s", out.String())
+	}
+}
+
+func TestAlgebraicNotation(t *testing.T) {
+	for s, want := range map[string]Cell{"B3": {R: 2, C: 1}, "a1": {}, " D4 ": {R: 3, C: 3}, "2,1": {R: 2, C: 1}} {
+		got, err := parseCell(s)
+		if err != nil || got != want {
+			t.Errorf("parseCell(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"E1", "A0", "A5", "3B", "B", "?3"} {
+		if _, err := parseCell(s); err == nil {
+			t.Errorf("Expected parseCell(%q) to fail", s)
+		}
+	}
+	if got := (Cell{R: 2, C: 1}).String(); got != "B3" {
+		t.Errorf("Expected (2,1) to read B3, got %s", got)
+	}
+	state := exampleStateForTests()
+	move := Move{Type: Place, Tile: 8, Cell: &Cell{R: 2, C: 0}}
+	state.History = append(state.History, Played{Seat: 0, Move: move})
+	if got := state.describePlayed(state.History[0]); !strings.Contains(got, "at A3.") {
+		t.Errorf("Expected the move described at A3, got %q", got)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	keys := "x\x1b[Dy\r" + // type x, step left, insert y
+		"ab\x7fc\x01z\r" + // backspace, then home
+		"\x1b[A\x1b[A\r" + // two lines back in the history
+		"stan\tx\x0b\r" // complete a preset, then ctrl-k
+	e := &lineEditor{in: bufio.NewReader(strings.NewReader(keys)), out: io.Discard, complete: completeWord}
+	for _, want := range []string{"yx\n", "zac\n", "yx\n", "standard x\n"} {
+		if got, err := e.edit(); err != nil || got != want {
+			t.Errorf("edit() = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := e.edit(); err != io.EOF {
+		t.Errorf("Expected the end of input, got %v", err)
+	}
+	if len(e.history) != 4 || e.history[1] != "zac" {
+		t.Errorf("Unexpected history %q", e.history)
+	}
+	if got := completeWord("b"); len(got) < BoardSize || got[0] != "B1" || got[BoardSize-1] != (Cell{R: BoardSize - 1, C: 1}).String() {
+		t.Errorf("Expected the B column to complete b, got %q", got)
+	}
+}
+
+func TestVerbosity(t *testing.T) {
+	defer func() { verbosity = Normal }()
+	state := exampleStateForTests()
+	var out strings.Builder
+	state.explainScores(&out, 8)
+	if out.Len() != 0 {
+		t.Errorf("Expected no score breakdown at normal verbosity, got:\n%s", out.String())
+	}
+	verbosity = Verbose
+	state.explainScores(&out, 8)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != explainedMoves || !strings.Contains(lines[0], "place A3: base") || !strings.Contains(lines[0], "row odds") {
+		t.Errorf("Expected the best placements broken down, got:\n%s", out.String())
+	}
+}
+
+func TestSpectator(t *testing.T) {
+	keys := make(chan rune, 8)
+	s := &Spectator{delay: time.Hour, keys: keys}
+	keys <- '+'
+	keys <- ' '
+	keys <- 'n'
+	if s.wait() || !s.paused || s.delay != 30*time.Minute {
+		t.Errorf("Expected a speed-up, a pause and one step, got %+v", s)
+	}
+	keys <- 'q'
+	if !s.wait() {
+		t.Errorf("Expected q to stop watching")
+	}
+	close(keys)
+	s.delay = time.Millisecond
+	if s.wait() || s.paused {
+		t.Errorf("Expected play to go on once the keys run out")
+	}
+
+	state := exampleStateForTests()
+	if state.onlyComputers() {
+		t.Errorf("Expected the example game to have a human seat")
+	}
+	for _, b := range state.Boards {
+		b.IsAi = true
+	}
+	if !state.onlyComputers() {
+		t.Errorf("Expected a computer-only game")
+	}
+}
+
+func TestCompactLayout(t *testing.T) {
+	saved := term
+	defer func() { term = saved }()
+	caps, err := termCaps{}.override("ascii,width=80")
+	if err != nil || caps.Width != 80 {
+		t.Fatalf("override(width=80) = %+v, %v", caps, err)
+	}
+	if _, err := caps.override("width=wide"); err == nil {
+		t.Errorf("Expected a bad width to be refused")
+	}
+	if got := detectWidth(func(k string) string { return map[string]string{"COLUMNS": "100"}[k] }, false); got != 100 {
+		t.Errorf("Expected COLUMNS to set the width, got %d", got)
+	}
+	boardWidth := 27 // a 4x4 board with its row numbers
+	for width, want := range map[int]int{0: 4, 120: 4, 80: 2, 40: 1, 10: 1} {
+		term = termCaps{Width: width}
+		if got := boardsPerRow(boardWidth, 4); got != want {
+			t.Errorf("boardsPerRow at %d columns = %d, want %d", width, got, want)
+		}
+	}
+}
+
+func TestBoxDrawingChoice(t *testing.T) {
+	for choice, want := range map[string]bool{"": true, "auto": true, "ascii": false, "Unicode": true} {
+		got, err := termCaps{Unicode: true}.withBox(choice)
+		if err != nil || got.Unicode != want {
+			t.Errorf("withBox(%q) = %+v, %v; want Unicode %v", choice, got, err, want)
+		}
+	}
+	if got, _ := (termCaps{}).withBox("unicode"); got.box() != unicodeBox {
+		t.Errorf("Expected unicode to draw with box lines")
+	}
+	if _, err := (termCaps{}).withBox("rounded"); err == nil {
+		t.Errorf("Expected an unknown box drawing to be refused")
+	}
+}
+
+func TestScreenReaderBoards(t *testing.T) {
+	state := exampleStateForTests()
+	state.Table = []int{12, 3}
+	state.History = []Played{{Seat: 1, Move: Move{Type: Place, Tile: 9, Cell: &Cell{R: 2, C: 1}}}}
+	state.Holding = 8
+	var out strings.Builder
+	state.describeBoards(&out)
+	text := out.String()
+	for _, want := range []string{
+		"Table: 3, 12.\n",
+		"Player 0, row 1: 5, empty, empty, 9.\n",
+		"Last move: Player 1 placed 9 at B3.\n",
+		"8 can go on Player 0's board at ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.ContainsAny(text, "|+-") {
+		t.Errorf("Expected no grid art, got:\n%s", text)
+	}
+}
+
+func TestScreenReaderNumberWords(t *testing.T) {
+	defer func() { numberWords, locale = false, defaultLocale }()
+	numberWords = true
+	state := exampleStateForTests()
+	state.Table = []int{12, Wildcard}
+	state.History = []Played{{Seat: 1, Move: Move{Type: Place, Tile: 19, Cell: &Cell{R: 2, C: 1}}}}
+	state.Holding = 8
+	var out strings.Builder
+	state.describeBoards(&out)
+	for _, want := range []string{
+		"Table: wildcard, twelve.\n",
+		"Player 0, row 1: five, empty, empty, nine.\n",
+		"Last move: Player 1 placed nineteen at B3.\n",
+		"eight can go on Player 0's board at ",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	locale = "es"
+	out.Reset()
+	state.describeBoards(&out)
+	if want := "diecinueve"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected the tiles told in Spanish words, got:\n%s", out.String())
+	}
+}
+
+func TestLocalization(t *testing.T) {
+	defer func() { locale = defaultLocale }()
+	verbs := regexp.MustCompile(`%[-+# 0-9.*]*[a-zA-Z%]`)
+	for lang, catalog := range catalogs {
+		for en, msg := range catalog {
+			if want, got := verbs.FindAllString(en, -1), verbs.FindAllString(msg, -1); strings.Join(want, " ") != strings.Join(got, " ") {
+				t.Errorf("%s: %q has verbs %v, want %v as in %q", lang, msg, got, want, en)
+			}
+		}
+	}
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	cases := []struct {
+		vars map[string]string
+		want string
+	}{
+		{map[string]string{"LANG": "es_ES.UTF-8"}, "es"},
+		{map[string]string{"LANG": "es_ES.UTF-8", "LC_ALL": "C"}, "en"},
+		{map[string]string{"LANG": "de_DE.UTF-8"}, "en"},
+		{map[string]string{"LC_MESSAGES": "es", "LANG": "en_US"}, "es"},
+	}
+	for _, c := range cases {
+		if got := detectLocale(env(c.vars)); got != c.want {
+			t.Errorf("detectLocale(%v) = %s, want %s", c.vars, got, c.want)
+		}
+	}
+	if err := setLocale("es", env(nil)); err != nil || tr("Game saved.") != "Partida guardada." {
+		t.Errorf("Expected Spanish messages, got %q, %v", tr("Game saved."), err)
+	}
+	if got := fmt.Sprintf(tr("%s is %v tile %s, %s\n"), "Ana", tr("placing"), tileLabel(Wildcard), "B3"); got != "Ana está colocando la ficha *, B3\n" {
+		t.Errorf("Expected the computer's move told in Spanish with the wildcard's label, got %q", got)
+	}
+	if got := tr("a message without a translation"); got != "a message without a translation" {
+		t.Errorf("Expected untranslated messages in English, got %q", got)
+	}
+	if err := setLocale("tlh", env(nil)); err == nil {
+		t.Errorf("Expected a language without messages to be refused")
+	}
+}
+
+func TestPlayerNames(t *testing.T) {
+	if name, err := checkName("  Ada   Lovelace \n"); err != nil || name != "Ada Lovelace" {
+		t.Errorf("checkName = %q, %v, want Ada Lovelace", name, err)
+	}
+	for _, bad := range []string{"Smith, John", `"Q"`, strings.Repeat("x", maxNameLength+1)} {
+		if _, err := checkName(bad); err == nil {
+			t.Errorf("Expected %q to be refused", bad)
+		}
+	}
+	s := &Setup{names: "Ada, Grace"}
+	if names, err := s.seatNames(3); err != nil || strings.Join(names, "|") != "Ada|Grace|" {
+		t.Errorf("seatNames = %q, %v", names, err)
+	}
+	if _, err := s.seatNames(1); err == nil {
+		t.Errorf("Expected more names than humans to be refused")
+	}
+
+	state := exampleStateForTests()
+	if state.names() != nil {
+		t.Errorf("Expected no names on unnamed seats, got %q", state.names())
+	}
+	state.Boards[0].Name = "Ada"
+	if got := state.seatLabel(0); got != "Ada" {
+		t.Errorf("seatLabel = %q, want Ada", got)
+	}
+	state.Boards[1].Name = state.computerName()
+	if state.Boards[1].Name == "" || state.Boards[1].Name == state.Boards[0].Name {
+		t.Errorf("Expected a fresh computer name, got %q", state.Boards[1].Name)
+	}
+
+	name := filepath.Join(t.TempDir(), "names.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(loaded.names(), "|"), strings.Join(state.names(), "|"); got != want {
+		t.Errorf("Expected names %q after loading, got %q", want, got)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	state := exampleStateForTests()
+	if state.undo(false) {
+		t.Errorf("Expected nothing to undo before the first turn")
+	}
+	board := state.Boards[0]
+
+	// undo after drawing puts the tile back and restarts the turn
+	state.markTurn()
+	tile, _ := state.popDraw()
+	state.commitMove(Move{Type: Place, Tile: tile, Cell: &Cell{R: 0, C: 1}})
+	if !state.undo(true) || !state.takeUndone() {
+		t.Fatalf("Expected the drawn tile to be taken back")
+	}
+	if board.Grid[0][1] != 0 || len(state.Draw) != 19 || state.Draw[0] != tile {
+		t.Errorf("Expected B1 empty and %d back on the pile, got %d and %v", tile, board.Grid[0][1], state.Draw)
+	}
+	if state.Boards[0] != board {
+		t.Errorf("Expected the boards to keep their addresses")
+	}
+
+	// undo before drawing goes back to the seat's previous turn
+	state.markTurn()
+	state.applyMove(Move{Type: Swap, Tile: 4, OldTile: 7, Cell: &Cell{R: 1, C: 1}})
+	state.Current = 1
+	state.commitMove(Move{Type: Discard, Tile: 1})
+	state.Current = 0
+	state.markTurn()
+	if !state.undo(false) {
+		t.Fatalf("Expected the last turn to be taken back")
+	}
+	if board.Grid[1][1] != 7 || len(state.Table) != 4 || len(state.History) != 0 {
+		t.Errorf("Expected the swap and discard undone, got %d, table %v, history %v", board.Grid[1][1], state.Table, state.History)
+	}
+
+	// another human's move since stands
+	state.markTurn()
+	state.Current = 1
+	state.markTurn()
+	if state.undo(false) {
+		t.Errorf("Expected another seat's move not to be taken back")
+	}
+}
+
+func TestOdds(t *testing.T) {
+	state := exampleStateForTests()
+	if lo, hi := state.cellRange(0, 1); lo != 6 || hi != 6 {
+		t.Errorf("Expected B1 to take 6 only, got %d-%d", lo, hi)
+	}
+	var out strings.Builder
+	state.printOdds(&out)
+	text := out.String()
+	for _, want := range []string{
+		"Odds for Player 0's board, 19 unseen tiles:\n",
+		"  B1  6         1 unseen ( 5.3%), 0 on the table\n",
+		"  A2  6         1 unseen ( 5.3%), 0 on the table\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "A1") {
+		t.Errorf("Expected filled cells left out:\n%s", text)
+	}
+}
+
+func TestTileTracker(t *testing.T) {
+	state := exampleStateForTests()
+	seen := state.seenTiles()
+	// 20 is on both boards, 7 on a board and the table, 4 only on the table
+	if seen[20] != 2 || seen[7] != 2 || seen[4] != 1 || seen[1] != 0 {
+		t.Errorf("Unexpected seen counts %v", seen)
+	}
+	var out strings.Builder
+	state.printTracker(&out)
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) < 7 {
+		t.Fatalf("Expected two blocks of three rows, got:\n%s", out.String())
+	}
+	if want := "unseen    2   2   2   1   0   1   0   2   1   0"; lines[3] != want {
+		t.Errorf("Expected %q, got %q", want, lines[3])
+	}
+	if want := "seen      0   0   0   1   0   0   1   0   2   2"; lines[5] != want {
+		t.Errorf("Expected %q, got %q", want, lines[5])
+	}
+}
+
+func TestResign(t *testing.T) {
+	state := exampleStateForTests()
+	state.Boards = append(state.Boards, &Board{IsAi: true})
+	state.resign(0)
+	if got := fmt.Sprint(state.activeSeats()); got != "[1 2]" {
+		t.Errorf("Expected seats 1 and 2 to play on, got %s", got)
+	}
+	if got := state.ranking(); got[len(got)-1] != 0 {
+		t.Errorf("Expected the resigned seat ranked last, got %v", got)
+	}
+
+	name := filepath.Join(t.TempDir(), "resigned.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Boards[0].Resigned || loaded.Boards[1].Resigned {
+		t.Errorf("Expected only seat 0 resigned after loading")
+	}
+
+	// the last player standing wins; in a match the round ends
+	state.Match = newMatch(1, 3)
+	state.resign(1)
+	if !state.Over || state.Winner != 2 {
+		t.Errorf("Expected seat 2 to win the round, got over %v winner %d", state.Over, state.Winner)
+	}
+}
+
+func TestSaveMidTurn(t *testing.T) {
+	state := exampleStateForTests()
+	state.Pending = &Move{Type: Draw, Tile: 6, FromTable: true}
+	name := filepath.Join(t.TempDir(), "midturn.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if p := loaded.Pending; p == nil || *p != *state.Pending {
+		t.Fatalf("Expected the drawn tile back as %+v, got %+v", *state.Pending, p)
+	}
+	if contains(loaded.Draw, 6) {
+		t.Errorf("Expected the drawn 6 kept out of the pile, got %v", loaded.Draw)
+	}
+	for _, from := range []Move{{Type: Draw}, {Type: Draw, FromTable: true}, {Type: FromHand}} {
+		if got, err := parseDrawnFrom(drawnFrom(from)); err != nil || got != from {
+			t.Errorf("Expected %+v to survive a save, got %+v, %v", from, got, err)
+		}
+	}
+}
+
+func TestBlunderCheck(t *testing.T) {
+	saved := reader
+	defer func() { reader = saved }()
+	state := exampleStateForTests()
+	ranked := state.bestMoves(11)
+	if len(ranked) < 2 {
+		t.Fatalf("Expected several moves for 11, got %v", ranked)
+	}
+	best, worst := ranked[0], ranked[len(ranked)-1]
+	state.BlunderMargin = best.Score - worst.Score
+	if state.BlunderMargin <= 0 {
+		t.Fatalf("Expected the moves for 11 to score differently")
+	}
+
+	reader = bufio.NewReader(strings.NewReader("n\ny\n"))
+	if !state.confirmMove(best) {
+		t.Errorf("Expected the best move to go ahead unasked")
+	}
+	if state.confirmMove(worst) {
+		t.Errorf("Expected no to stop the worst move")
+	}
+	if !state.confirmMove(worst) {
+		t.Errorf("Expected yes to play the worst move")
+	}
+	state.BlunderMargin = 0
+	if !state.confirmMove(worst) {
+		t.Errorf("Expected no question with the check off")
+	}
+}
+
+func TestTerminalUIMouse(t *testing.T) {
+	defer func() { screen = nil }()
+	state := exampleStateForTests()
+	// The table reads 4,5,7,17 centred in the box; board rows start on line
+	// 8 and C1 of the first board spans columns 16-20.
+	screen = &tui{in: bufio.NewReader(strings.NewReader("\033[<0;30;3M"))}
+	move, quit := state.promptDrawOrSave()
+	if quit || move.Tile != 7 || !move.FromTable {
+		t.Fatalf("Expected a click to take the table's 7, got %+v, quit %v", move, quit)
+	}
+	screen.in = bufio.NewReader(strings.NewReader("\033[<35;18;8M\033[<0;18;8m\033[<0;18;8M"))
+	state.promptPlacement(move)
+	if state.Boards[0].Grid[0][2] != 7 {
+		t.Errorf("Expected a click to place 7 at C1, got %v", state.Boards[0].Grid)
+	}
+	if screen.hint != "C1 takes 6-8." {
+		t.Errorf("Expected the hovered cell's range, got %q", screen.hint)
+	}
+
+	l := state.layout()
+	for _, c := range []struct {
+		x, y int
+		want mouseTarget
+	}{
+		{15, 8, mouseTarget{}}, // the border between B1 and C1
+		{18, 9, mouseTarget{}}, // a line between rows
+		{4, 14, mouseTarget{kind: overCell, seat: 0, cell: Cell{R: 3, C: 0}}},
+		{33, 8, mouseTarget{kind: overCell, seat: 1, cell: Cell{R: 0, C: 0}}},
+		{1, l.pileLine, mouseTarget{kind: overPile}},
+	} {
+		if got := l.at(c.x, c.y); got != c.want {
+			t.Errorf("at(%d, %d) = %+v, want %+v", c.x, c.y, got, c.want)
+		}
+	}
+	button, x, y, press, err := parseMouse(bufio.NewReader(strings.NewReader("2;40;12m")).ReadByte)
+	if err != nil || button != 2 || x != 40 || y != 12 || press {
+		t.Errorf("Unexpected mouse report %d %d %d %v %v", button, x, y, press, err)
+	}
+}
+
+func TestTurnCounters(t *testing.T) {
+	state := exampleStateForTests()
+	state.History = []Played{{Seat: 0, Move: Move{Type: Discard, Tile: 4}}, {Seat: 1, Move: Move{Type: Discard, Tile: 3}}}
+	state.Turn, state.Draws = 13, 12
+	if got := state.counters(); got != "Turn 14, moves 2, draws 12" {
+		t.Errorf("Unexpected counters %q", got)
+	}
+	state.markTurn()
+	state.Turn, state.Draws = 14, 13
+	state.undo(true)
+	if state.Turn != 13 || state.Draws != 12 {
+		t.Errorf("Expected undo to put the counters back, got turn %d and %d draws", state.Turn, state.Draws)
+	}
+}
+
+func TestTileCounts(t *testing.T) {
+	state := exampleStateForTests()
+	if table, pile := state.tileCounts(); table != "Table: 4 tiles" || pile != "Draw pile: 19 tiles" {
+		t.Errorf("Unexpected counts %q and %q", table, pile)
+	}
+	state.Table, state.Draw = []int{7}, nil
+	if table, pile := state.tileCounts(); table != "Table: 1 tile" || pile != "Draw pile: 0 tiles" {
+		t.Errorf("Unexpected counts %q and %q", table, pile)
+	}
+}
+
+func TestAutoHints(t *testing.T) {
+	saved := reader
+	defer func() { reader, autoHints = saved, false }()
+	autoHints = true
+	state := exampleStateForTests()
+	best := state.bestMoves(8) // five moves, of which three are shown
+	second := best[1]
+	reader = bufio.NewReader(strings.NewReader("4\n2\n"))
+	state.promptPlacement(Move{Type: Draw, Tile: 8})
+	if state.Boards[0].Grid[second.Cell.R][second.Cell.C] != 8 {
+		t.Errorf("Expected hint 2 to place 8 at %s, got %v", second.Cell, state.Boards[0].Grid)
+	}
+	if got := len(state.printHints(1)); got != 1 {
+		t.Errorf("Expected the single move for 1 as the only hint, got %d", got)
+	}
+}
+
+func TestScriptInput(t *testing.T) {
+	saved := reader
+	defer func() { reader = saved }()
+	ended := false
+	s := &scriptReader{r: bufio.NewReader(strings.NewReader("# place the 8\nr\n2")), end: func() { ended = true }}
+	reader = s
+	state := exampleStateForTests()
+	second := state.bestMoves(8)[1]
+	state.promptPlacement(Move{Type: Draw, Tile: 8})
+	if state.Boards[0].Grid[second.Cell.R][second.Cell.C] != 8 {
+		t.Errorf("Expected the script to place 8 at %s, got %v", second.Cell, state.Boards[0].Grid)
+	}
+	if ended {
+		t.Error("Expected the script to still have lines")
+	}
+	if _, err := reader.ReadString('\n'); err != io.EOF || !ended {
+		t.Errorf("Expected the script to end, got %v", err)
+	}
+	if s.line != 3 {
+		t.Errorf("Expected 3 lines read, got %d", s.line)
+	}
+}
+
+func TestPromptPlayers(t *testing.T) {
+	savedSetup, savedReader := setup, reader
+	defer func() { setup, reader = savedSetup, savedReader }()
+	setup = &Setup{given: map[string]bool{}}
+	// a bad answer, a total of five, then a count turned down before the one kept
+	reader = bufio.NewReader(strings.NewReader("x\n3\n2\n\n2\nn\n\n3\ny\n"))
+	humans, computers, err := promptPlayers()
+	if err != nil || humans != 1 || computers != 3 {
+		t.Errorf("Expected 1 human and 3 computers, got %d and %d (%v)", humans, computers, err)
+	}
+
+	setup = &Setup{given: map[string]bool{"humans": true, "computers": true}, humans: 3, computers: 2}
+	if _, _, err := promptPlayers(); err == nil {
+		t.Error("Expected five players from the flags to be rejected")
+	}
+}
+
+func TestSaveJSON(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	state := exampleStateForTests()
+	state.Boards[1].IsAi, state.Boards[1].Strategy, state.Boards[1].Name = true, "cautious", "Deep Tile"
+	state.Boards[0].Handicap = Handicap{Withheld: 1}
+	state.BrunoVariant, state.Bruno = true, BrunoRules{Directions: BrunoRow, MaxChain: 2}
+	state.OpenPile, state.Wildcards, state.Turn, state.Draws = true, 1, 7, 6
+	state.Pending = &Move{Type: Draw, Tile: 16, FromTable: true}
+	state.History = []Played{{Seat: 1, Move: Move{Type: Place, Tile: 9, Cell: &Cell{R: 0, C: 3}}}}
+	name := filepath.Join(t.TempDir(), "game.json")
+	if err := state.saveToJSON(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromJSON(name); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(loaded.Draw) != fmt.Sprint(state.Draw) {
+		t.Errorf("Expected the pile in order %v, got %v", state.Draw, loaded.Draw)
+	}
+	for i, b := range loaded.Boards {
+		if fmt.Sprintf("%+v", *b) != fmt.Sprintf("%+v", *state.Boards[i]) {
+			t.Errorf("Board %d came back as %+v, want %+v", i, *b, *state.Boards[i])
+		}
+	}
+	if !loaded.BrunoVariant || loaded.Bruno != state.Bruno || !loaded.OpenPile || loaded.Wildcards != 1 {
+		t.Errorf("Expected the rules back, got bruno %v %+v, open pile %v, %d wildcards",
+			loaded.BrunoVariant, loaded.Bruno, loaded.OpenPile, loaded.Wildcards)
+	}
+	if p := loaded.Pending; p == nil || *p != *state.Pending || loaded.Turn != 7 || loaded.Draws != 6 {
+		t.Errorf("Expected the drawn tile and counters back, got %+v, turn %d, %d draws", p, loaded.Turn, loaded.Draws)
+	}
+	if len(loaded.History) != 1 || *loaded.History[0].Move.Cell != (Cell{R: 0, C: 3}) {
+		t.Errorf("Expected the history back, got %+v", loaded.History)
+	}
+
+	bad := state.saved()
+	bad.Boards[0].Grid[1][1] = 99
+	if err := (&GameState{}).restoreSaved(bad); err == nil {
+		t.Error("Expected a tile beyond the range to be rejected")
+	}
+}
+
+func TestSaveVersions(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "game.csv")
+	if err := exampleStateForTests().saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("VERSION,%d\n", csvVersion); !strings.HasPrefix(string(data), want) {
+		t.Fatalf("Expected the save to start with %q, got %q", want, data[:20])
+	}
+	// a save from before the formats were numbered, and the checksum
+	old := strings.SplitN(string(data), "\n", 2)[1]
+	old = old[:strings.Index(old, "CHECKSUM,")]
+	if err := os.WriteFile(name, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&GameState{}).loadFromCSV(name); err != nil {
+		t.Errorf("Expected a format 1 save to load, got %v", err)
+	}
+	if err := os.WriteFile(name, []byte("VERSION,99\n"+old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&GameState{}).loadFromCSV(name); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a newer save to be refused plainly, got %v", err)
+	}
+
+	g := exampleStateForTests().saved()
+	g.Version = 0
+	if err := checkJSONVersion(g); err != nil {
+		t.Errorf("Expected an unnumbered JSON save to be read, got %v", err)
+	}
+	g.Version = jsonVersion + 1
+	if err := checkJSONVersion(g); err == nil {
+		t.Error("Expected a newer JSON save to be refused")
+	}
+}
+
+func TestMoveLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "moves.jsonl")
+	log, err := newMoveLog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := exampleStateForTests()
+	state.MoveLog = log
+	state.logDraw(Move{Type: Draw, Tile: 6, FromTable: true})
+	state.applyMove(Move{Type: Swap, Tile: 6, Cell: &Cell{R: 0, C: 0}})
+	if state.clone().MoveLog != nil {
+		t.Error("Expected simulations not to log")
+	}
+	log.Close()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a draw and a swap, got %q", lines)
+	}
+	var draw, swap LoggedMove
+	if err := json.Unmarshal([]byte(lines[0]), &draw); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &swap); err != nil {
+		t.Fatal(err)
+	}
+	if draw.Type != "draw" || draw.Tile != 6 || draw.From != "table" || draw.Player != "Player 0" {
+		t.Errorf("Unexpected draw %+v", draw)
+	}
+	if swap.Type != "swap" || swap.OldTile != 5 || *swap.Cell != (Cell{R: 0, C: 0}) || *swap.Board != 0 {
+		t.Errorf("Unexpected swap %+v", swap)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	name := filepath.Join(t.TempDir(), "moves.jsonl")
+	log, err := newMoveLog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := exampleStateForTests()
+	state.MoveLog = log
+	state.logPosition("start")
+	tile, _ := state.popDraw()
+	state.logDraw(Move{Type: Draw, Tile: tile})
+	state.applyMove(Move{Type: Discard, Tile: tile})
+	state.Turn, state.Current = 1, 1
+	state.removeTileFromTable(17)
+	state.logDraw(Move{Type: Draw, Tile: 17, FromTable: true})
+	state.applyMove(Move{Type: Swap, Tile: 17, Cell: &Cell{R: 3, C: 3}})
+	log.Close()
+
+	replayed, err := runReplay(name, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range replayed.Boards {
+		if b.Grid != state.Boards[i].Grid {
+			t.Errorf("Board %d replayed as %v, want %v", i, b.Grid, state.Boards[i].Grid)
+		}
+	}
+	if fmt.Sprint(replayed.Table) != fmt.Sprint(state.Table) || fmt.Sprint(replayed.Draw) != fmt.Sprint(state.Draw) {
+		t.Errorf("Replayed table %v and pile %v, want %v and %v", replayed.Table, replayed.Draw, state.Table, state.Draw)
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	state := exampleStateForTests()
+	state.Boards[0].Grid[1][2] = Wildcard
+	state.Boards[1].Hand = []int{8}
+	state.Current, state.HandSize, state.OpenPile = 1, 1, true
+	pos := state.encodePosition()
+	if fields := strings.Fields(pos); len(fields) != 4 || fields[1] != "7,5,17,4" || fields[2] != "1" || fields[3] != "hand1+openpile" {
+		t.Fatalf("Unexpected position %q", pos)
+	}
+	decoded := &GameState{}
+	if err := decoded.decodePosition(pos); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.encodePosition(); got != pos {
+		t.Errorf("Expected the position back, got\n%s\nwant\n%s", got, pos)
+	}
+	tiles := len(decoded.Draw) + len(decoded.Table) + len(decoded.Boards[1].Hand)
+	for _, b := range decoded.Boards {
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				if b.Grid[r][c] > 0 {
+					tiles++
+				}
+			}
+		}
+	}
+	if tiles != decoded.tileTotal() {
+		t.Errorf("Expected the unseen tiles to make up the %d in the game, got %d", decoded.tileTotal(), tiles)
+	}
+
+	for _, bad := range []string{
+		"1.. - 0 classic",
+		"1.../.11../..14./...18 - 1 classic",
+		"1.../.11../..14./...18 - 0 giant",
+		"1.../.11../..14./...99 - 0 classic",
+		"1.../.11../..14. - 0 classic",
+		"1.../.5../..9./...13 - 0 dist1-20x300000000",
+	} {
+		if err := (&GameState{}).decodePosition(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	savedReader := reader
+	defer func() { reader, BoardSize = savedReader, standardBoardSize }()
+	dir := t.TempDir()
+	state := exampleStateForTests()
+	state.AutosaveDir = dir
+	for turn := 1; turn <= autosaveKeep+1; turn++ {
+		state.Turn = turn
+		state.autosave()
+	}
+	for n := 0; n < autosaveKeep; n++ {
+		loaded := &GameState{}
+		if err := loaded.loadFromJSON(autosavePath(dir, n)); err != nil {
+			t.Fatal(err)
+		}
+		if want := autosaveKeep + 1 - n; loaded.Turn != want {
+			t.Errorf("Expected autosave %d to be of turn %d, got %d", n, want, loaded.Turn)
+		}
+	}
+	if _, err := os.Stat(autosavePath(dir, autosaveKeep)); err == nil {
+		t.Errorf("Expected only %d autosaves kept", autosaveKeep)
+	}
+
+	reader = bufio.NewReader(strings.NewReader("n\n\n"))
+	if got := promptResume(dir); got != "" {
+		t.Errorf("Expected no resume when declined, got %q", got)
+	}
+	if got := promptResume(dir); got != autosavePath(dir, 0) {
+		t.Errorf("Expected to resume the newest autosave, got %q", got)
+	}
+	state.clearAutosave()
+	if got := promptResume(dir); got != "" {
+		t.Errorf("Expected no autosave left to offer, got %q", got)
+	}
+}
+
+func TestSaveSlots(t *testing.T) {
+	savedReader, savedDir := reader, savesDir
+	defer func() { reader, savesDir, BoardSize = savedReader, savedDir, standardBoardSize }()
+	savesDir = t.TempDir()
+	state := exampleStateForTests()
+	state.Turn = 4
+	if err := state.saveToJSON(savePath("old.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.saveToCSV(savePath("new.csv")); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(savePath("old.json"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	os.WriteFile(savePath("notes.csv"), []byte("not,a,save\n"), 0o644)
+
+	slots := listSaves(savesDir)
+	if len(slots) != 2 {
+		t.Fatalf("Expected the 2 saves listed without the other CSV, got %+v", slots)
+	}
+	if filepath.Base(slots[0].Path) != "new.csv" || slots[1].Turn != 5 {
+		t.Errorf("Expected the newest save first and the JSON save's turn, got %+v", slots)
+	}
+	if len(slots[0].Players) != len(state.Boards) {
+		t.Errorf("Expected %d players listed, got %v", len(state.Boards), slots[0].Players)
+	}
+
+	reader = bufio.NewReader(strings.NewReader("3\ndelete 1\ny\n1\n"))
+	if got := promptLoad(); got != savePath("old.json") {
+		t.Errorf("Expected save 1 to be the old one once the new one is deleted, got %q", got)
+	}
+	if _, err := os.Stat(savePath("new.csv")); err == nil {
+		t.Error("Expected the deleted save gone")
+	}
+	if got := findSave("old.json"); got != savePath("old.json") {
+		t.Errorf("Expected a bare file name found in the saves directory, got %q", got)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	save := func(turn, table string, rows ...string) string {
+		name := filepath.Join(t.TempDir(), "save.csv")
+		csv := "VERSION,2\nTURN," + turn + "\nTABLE," + table + "\n" + strings.Join(rows, "\n") + "\n"
+		if err := os.WriteFile(name, []byte(csv), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	empty := []string{".,.,.,.", ".,.,.,.", ".,.,.,.", ".,.,.,."}
+	for _, c := range []struct {
+		name, turn, table string
+		rows              []string
+		want              []string
+	}{
+		{"row out of order", "0", ".", append([]string{"5,.,3,.", ".,.,.,.", ".,.,.,.", ".,.,.,."}, empty...), []string{"5 at A1 and 3 at C1"}},
+		{"column out of order", "1", ".", append(append([]string{}, empty...), "9,.,.,.", ".,.,.,.", "2,.,.,.", ".,.,.,."), []string{"board 1: 9 at A1 and 2 at A3"}},
+		{"too many copies", "0", "4,4", append([]string{"4,.,.,.", ".,.,.,.", ".,.,.,.", ".,.,.,."}, empty...), []string{"tile 4 is in play 3 times, but the game has 2"}},
+		{"turn past the boards", "2", ".", append(append([]string{}, empty...), empty...), []string{"TURN index 2"}},
+	} {
+		err := (&GameState{}).loadFromCSV(save(c.turn, c.table, c.rows...))
+		if err == nil {
+			t.Errorf("%s: expected the save refused", c.name)
+			continue
+		}
+		for _, w := range c.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("%s: expected %q in %q", c.name, w, err)
+			}
+		}
+	}
+
+	// equal neighbours are only refused once the rules are known
+	state := &GameState{}
+	if err := state.loadFromCSV(save("0", ".", append([]string{"3,3,.,.", ".,.,.,.", ".,.,.,.", ".,.,.,."}, empty...)...)); err != nil {
+		t.Fatalf("Expected equal neighbours to load: %v", err)
+	}
+	if err := state.checkPosition(state.step()); err == nil {
+		t.Error("Expected equal neighbours refused without the non-decreasing rule")
+	}
+	state.NonDecreasing = true
+	if err := state.checkPosition(state.step()); err != nil {
+		t.Errorf("Expected equal neighbours allowed by the non-decreasing rule: %v", err)
+	}
+}
+
+func TestExportPicture(t *testing.T) {
+	state := exampleStateForTests()
+	state.Boards[1].Name = "Deep <Tile>"
+	p := state.picture()
+
+	var svg strings.Builder
+	if err := p.writeSVG(&svg); err != nil {
+		t.Fatal(err)
+	}
+	rects, dec := 0, xml.NewDecoder(strings.NewReader(svg.String()))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected well-formed SVG: %v", err)
+		}
+		if el, ok := tok.(xml.StartElement); ok && el.Name.Local == "rect" {
+			rects++
+		}
+	}
+	if want := 1 + len(state.Table) + len(state.Boards)*BoardSize*BoardSize; rects != want {
+		t.Errorf("Expected %d rectangles, got %d", want, rects)
+	}
+	if !strings.Contains(svg.String(), "Deep &lt;Tile&gt;") {
+		t.Error("Expected the seat names escaped in the SVG")
+	}
+
+	name := filepath.Join(t.TempDir(), "position.png")
+	if err := state.exportPicture(name); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != p.W || b.Dy() != p.H {
+		t.Errorf("Expected a %dx%d PNG, got %v", p.W, p.H, b)
+	}
+	// board 0's A1 holds a tile and B1 is empty; look inside their borders
+	for _, c := range []struct {
+		box  picBox
+		want color.RGBA
+	}{{p.boxes[len(state.Table)], picTile}, {p.boxes[len(state.Table)+1], picEmpty}} {
+		if got := color.RGBAModel.Convert(img.At(c.box.X+2, c.box.Y+2)); got != c.want {
+			t.Errorf("Expected %v inside the cell at %d,%d, got %v", c.want, c.box.X, c.box.Y, got)
+		}
+	}
+}
+
+func TestExportGIF(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "moves.jsonl")
+	log, err := newMoveLog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := exampleStateForTests()
+	state.MoveLog = log
+	state.logPosition("start")
+	for turn := 0; turn < 3; turn++ {
+		state.Turn, state.Current = turn, turn%2
+		tile, _ := state.popDraw()
+		state.logDraw(Move{Type: Draw, Tile: tile})
+		state.applyMove(Move{Type: Discard, Tile: tile})
+	}
+	log.Close()
+
+	out := filepath.Join(dir, "game.gif")
+	if err := exportGIF(name, out); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the start, then one frame a move; draws share their move's frame
+	if len(anim.Image) != 4 {
+		t.Fatalf("Expected 4 frames, got %d", len(anim.Image))
+	}
+	if anim.Delay[0] != gifFrameDelay || anim.Delay[3] != gifLastDelay {
+		t.Errorf("Expected the last frame held longer, got delays %v", anim.Delay)
+	}
+	for i, frame := range anim.Image {
+		if frame.Bounds().Dx() != anim.Config.Width || frame.Bounds().Dy() != anim.Config.Height {
+			t.Errorf("Frame %d is %v, not the %dx%d canvas", i, frame.Bounds(), anim.Config.Width, anim.Config.Height)
+		}
+	}
+}
+
+func TestCSVSettings(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	state := exampleStateForTests()
+	state.Boards[1].IsAi, state.Boards[1].Strategy = true, "cautious"
+	state.BrunoVariant, state.Bruno = true, BrunoRules{Directions: BrunoRow, MaxChain: 2}
+	state.StealSwap, state.HandSize = true, 1
+	name := filepath.Join(t.TempDir(), "settings.csv")
+	if err := state.saveToCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if !hasCSVSettings(name) {
+		t.Fatal("Expected the save to keep its settings")
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromCSV(name); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.BrunoVariant || loaded.Bruno.directions() != BrunoRow || loaded.Bruno.maxChain() != 2 || !loaded.StealSwap || loaded.HandSize != 1 || loaded.Analyze {
+		t.Errorf("Expected the rules back, got bruno=%v (%s) steal=%v hand=%d analyze=%v", loaded.BrunoVariant, loaded.Bruno, loaded.StealSwap, loaded.HandSize, loaded.Analyze)
+	}
+	if b := loaded.Boards[1]; !b.IsAi || b.Strategy != "cautious" || loaded.Boards[0].IsAi {
+		t.Errorf("Expected seat 1 the cautious computer's and seat 0 a person's, got %+v and %+v", b, loaded.Boards[0])
+	}
+	for i, b := range loaded.Boards {
+		if len(b.Hand) != 1 {
+			t.Errorf("Expected seat %d dealt a hand of 1, got %v", i, b.Hand)
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.csv")
+	os.WriteFile(bad, []byte("VERSION,3\nTURN,0\nSETTINGS,seats=human+robot\nTABLE,.\n"+strings.Repeat(".,.,.,.\n", 8)), 0o644)
+	if err := (&GameState{}).loadFromCSV(bad); err == nil || !strings.Contains(err.Error(), "robot") {
+		t.Errorf("Expected an unknown seat kind refused, got %v", err)
+	}
+}
+
+func TestMatchSave(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	state := exampleStateForTests()
+	state.Match = newMatch(3, 2)
+	state.Match.award([]int{1, 0}, 1)
+	dir := t.TempDir()
+	name := filepath.Join(dir, "match.json")
+	if err := state.saveToJSON(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromJSON(name); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%+v", loaded.Match) != fmt.Sprintf("%+v", state.Match) {
+		t.Errorf("Expected the match back as %+v, got %+v", state.Match, loaded.Match)
+	}
+
+	// standings for the wrong number of seats
+	state.Match.Points = []int{3, 5, 0}
+	if err := state.saveToJSON(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&GameState{}).loadFromJSON(name); err == nil || !strings.Contains(err.Error(), "match") {
+		t.Errorf("Expected a match that does not fit the game refused, got %v", err)
+	}
+}
+
+func TestCSVBoardHeaders(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	load := func(csv string) (*GameState, error) {
+		name := filepath.Join(dir, "save.csv")
+		if err := os.WriteFile(name, []byte(csv), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		state := &GameState{}
+		return state, state.loadFromCSV(name)
+	}
+	rows := "1,.,.,.\n.,5,.,.\n.,.,9,.\n.,.,.,13\n"
+	rows2 := "2,.,.,.\n.,6,.,.\n.,.,10,.\n.,.,.,14\n"
+
+	// the new layout, edited by hand with notes
+	state, err := load("VERSION,4\nTURN,1\n# who plays\nTABLE\nBOARD,Ann,human\n" + rows + "BOARD,Deep Tile,computer/cautious\n" + rows2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := state.Boards[0], state.Boards[1]; a.Name != "Ann" || a.IsAi || b.Name != "Deep Tile" || !b.IsAi || b.Strategy != "cautious" {
+		t.Errorf("Expected Ann's board and the cautious computer's, got %+v and %+v", a, b)
+	}
+
+	// the old layout, names in a NAMES record and seats in the settings
+	state, err = load("VERSION,3\nTURN,0\nSETTINGS,mode=analyze,rules=classic,seats=computer+human\nNAMES,Bot,Bea\nTABLE\n" + rows + rows2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := state.Boards[0], state.Boards[1]; a.Name != "Bot" || !a.IsAi || b.Name != "Bea" || b.IsAi || !state.Analyze {
+		t.Errorf("Expected the old layout's names and seats, got %+v and %+v", a, b)
+	}
+
+	for _, bad := range []string{
+		"VERSION,4\nTURN,0\nTABLE\n1,.,.,.\nBOARD,Ann,human\n.,5,.,.\n.,.,9,.\n.,.,.,13\n",
+		"VERSION,4\nTURN,0\nTABLE\n" + rows + "BOARD,Ann,robot\n" + rows2,
+		"VERSION,4\nTURN,0\nTABLE\n" + rows + "BOARD,Ann,human\n",
+	} {
+		if _, err := load(bad); err == nil {
+			t.Errorf("Expected a misplaced or bad BOARD header refused in\n%s", bad)
+		}
+	}
+}
+
+func TestImportBGA(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "bga.txt")
+	game := `Alice's starting clovers: 3, 8, 12, 19
+Bob's starting clovers: 2, 6, 11, 17
+12:01 Alice takes 7 from the draw pile.
+Alice places 7 on row 2, column 1.
+Bob: good luck!
+Bob takes 9 from the draw pile
+Bob puts 9 face up
+Alice takes 9 from the face-up clovers
+Alice replaces 12 with 9 on row 3, column 3
+Bob takes 12 from the table
+Bob places 12 on C2
+Alice wins!
+`
+	if err := os.WriteFile(name, []byte(game), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "moves.jsonl")
+	moves, err := importBGA(name, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moves != 4 {
+		t.Errorf("Expected 4 moves, got %d", moves)
+	}
+	var ended string
+	state, err := walkMoveLog(out, func(_ *GameState, e LoggedMove, _ string) bool {
+		if e.Type == "end" {
+			ended = e.Message
+		}
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Boards[0].Grid[1][0] != 7 || state.Boards[0].Grid[2][2] != 9 || state.Boards[1].Grid[1][2] != 12 {
+		t.Errorf("Expected the moves on the boards, got %v and %v", state.Boards[0].Grid, state.Boards[1].Grid)
+	}
+	if len(state.Table) != 0 || len(state.Draw) != 30 {
+		t.Errorf("Expected an empty table and 30 tiles in the pile, got %v and %d", state.Table, len(state.Draw))
+	}
+	if ended != "Alice wins!" {
+		t.Errorf("Expected the win logged, got %q", ended)
+	}
+
+	// a move the log never drew is caught with its line
+	bad := strings.Replace(game, "Bob takes 12 from the table\n", "", 1)
+	if err := os.WriteFile(name, []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importBGA(name, out); err == nil || !strings.Contains(err.Error(), "line 10") {
+		t.Errorf("Expected the bad move reported on line 10, got %v", err)
+	}
+}
+
+func TestNamedProfiles(t *testing.T) {
+	saved := profilesDir
+	defer func() { profilesDir = saved }()
+	profilesDir = t.TempDir()
+
+	state := exampleStateForTests()
+	state.Boards[0].Name = "Ana María"
+	state.Boards[1].IsAi, state.Boards[1].Strategy = true, "cautious"
+	state.BrunoVariant, state.HandSize = true, 2
+	state.updateNamedProfiles(0)
+	state.BrunoVariant, state.HandSize = false, 0
+	state.updateNamedProfiles(1)
+
+	p, err := loadNamedProfile("ana maría")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Ana María" || p.Games != 2 || p.Wins != 1 {
+		t.Errorf("Expected Ana María with 2 games and 1 win, got %q with %d and %d", p.Name, p.Games, p.Wins)
+	}
+	if avg := p.averageEmpty(); avg != 9 {
+		t.Errorf("Expected 9 empty cells a game, got %v", avg)
+	}
+	if p.Variants["bruno"] != 1 || p.Variants["hand2"] != 1 || p.Variants["classic"] != 1 {
+		t.Errorf("Expected bruno, hand2 and classic once each, got %v", p.Variants)
+	}
+	// the last game's settings answer the setup questions left out
+	s := &Setup{given: map[string]bool{}}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	s.register(fs)
+	if err := fs.Parse([]string{"-hand", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	s.noteGiven(fs)
+	if _, err := s.preferSettings(fs, p.Settings); err != nil {
+		t.Fatal(err)
+	}
+	if s.hand != 3 || s.bruno || s.computers != 1 || s.strategies != "cautious" || s.preset != "standard" || !s.has("open-pile") {
+		t.Errorf("Expected the last game's setup under -hand 3, got %+v", s)
+	}
+}
+
+func TestRatings(t *testing.T) {
+	saved := profilesDir
+	defer func() { profilesDir = saved }()
+	profilesDir = t.TempDir()
+
+	state := exampleStateForTests()
+	state.Boards = append(state.Boards, &Board{IsAi: true, Strategy: "cautious"})
+	state.Boards[0].Name = "Zoe"
+	state.Boards[1].IsAi, state.Boards[1].Strategy = true, "cautious"
+	state.updateNamedProfiles(0)
+
+	zoe, err := loadNamedProfile("Zoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cautious, err := loadProfile(computerProfileFile("cautious"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Zoe beat both cautious seats; they share a profile and do not play
+	// each other
+	if zoe.RatedGames != 1 || cautious.RatedGames != 1 || cautious.Games != 2 {
+		t.Fatalf("Expected one rated game each and two cautious seats, got %d, %d and %d", zoe.RatedGames, cautious.RatedGames, cautious.Games)
+	}
+	if zoe.Rating != eloStart+eloK || cautious.Rating != eloStart-eloK {
+		t.Errorf("Expected %v and %v, got %v and %v", eloStart+eloK, eloStart-eloK, zoe.Rating, cautious.Rating)
+	}
+
+	// a handicap leaves the ratings alone
+	state.Boards[0].Handicap = Handicap{Withheld: 1}
+	state.updateNamedProfiles(1)
+	if zoe, _ = loadNamedProfile("Zoe"); zoe.RatedGames != 1 || zoe.Games != 2 {
+		t.Errorf("Expected the handicapped game recorded but not rated, got %d rated of %d", zoe.RatedGames, zoe.Games)
+	}
+}
+
+func TestCrashResume(t *testing.T) {
+	savedReader := reader
+	defer func() { reader, BoardSize = savedReader, standardBoardSize }()
+	dir := t.TempDir()
+	state := exampleStateForTests()
+	state.AutosaveDir = dir
+	state.autosave()
+	state.markRunning()
+	// cut short with a tile drawn
+	tile, _ := state.popDraw()
+	state.Draws++
+	state.Pending = &Move{Type: Draw, Tile: tile}
+	state.autosaveDrawn()
+
+	reader = bufio.NewReader(strings.NewReader("\n"))
+	if got := promptResume(dir); got != drawnPath(dir) {
+		t.Fatalf("Expected to resume from the draw, got %q", got)
+	}
+	if _, err := os.Stat(runningPath(dir)); err == nil {
+		t.Errorf("Expected the marker gone once the crash was offered")
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromJSON(drawnPath(dir)); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Pending == nil || loaded.Pending.Tile != tile || len(loaded.Draw) != len(state.Draw) {
+		t.Errorf("Expected %d still to play with %d in the pile, got %+v and %d", tile, len(state.Draw), loaded.Pending, len(loaded.Draw))
+	}
+
+	// the end of the turn supersedes the drawn tile's save
+	state.Pending = nil
+	state.autosave()
+	if _, err := os.Stat(drawnPath(dir)); err == nil {
+		t.Errorf("Expected the drawn tile's save gone after the turn")
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	logName := filepath.Join(dir, "study.jsonl")
+	log, err := newMoveLog(logName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := exampleStateForTests()
+	state.Analyze = true
+	state.MoveLog = log
+	state.logPosition("start")
+	if !state.annotationCommand("note Keep the corners open", 0) || !state.annotationCommand("eval", 3) {
+		t.Fatal("Expected note and eval to be taken as commands")
+	}
+	if state.annotationCommand("B3", 3) {
+		t.Error("Expected a cell not to be taken as a command")
+	}
+	log.Close()
+	if len(state.Annotations) != 2 || state.Annotations[0].Note != "Keep the corners open" || state.Annotations[0].Turn != 1 {
+		t.Fatalf("Expected the note kept on turn 1, got %+v", state.Annotations)
+	}
+	eval := state.Annotations[1]
+	if eval.Tile != 3 || len(eval.Candidates) == 0 || len(eval.Candidates) > annotateCandidates {
+		t.Fatalf("Expected candidates for 3, got %+v", eval)
+	}
+	best := state.bestMoves(3)[0]
+	if c := eval.Candidates[0]; c.Score != best.Score || *c.Move.Cell != *best.Cell {
+		t.Errorf("Expected the best move first, got %+v", c)
+	}
+
+	name := filepath.Join(dir, "study.json")
+	if err := state.saveToJSON(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{}
+	if err := loaded.loadFromJSON(name); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Annotations) != 2 || loaded.Annotations[0].Note != state.Annotations[0].Note || len(loaded.Annotations[1].Candidates) != len(eval.Candidates) {
+		t.Errorf("Expected the notes back from the save, got %+v", loaded.Annotations)
+	}
+	var said string
+	if _, err := walkMoveLog(logName, func(_ *GameState, e LoggedMove, s string) bool {
+		if e.Type == "note" {
+			said += s
+		}
+		return false
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(said, "Keep the corners open") || !strings.Contains(said, "Candidates for 3") {
+		t.Errorf("Expected the replay to tell the notes, got %q", said)
+	}
+}
+
+func TestSaveChecksum(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize; forceLoad = false }()
+	dir := t.TempDir()
+	for _, name := range []string{"game.csv", "game.json"} {
+		name = filepath.Join(dir, name)
+		state := exampleStateForTests()
+		if err := state.saveToCSV(name); err != nil {
+			t.Fatal(err)
+		}
+		if isJSONSave(name) {
+			if err := state.saveToJSON(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := (&GameState{}).loadSave(name); err != nil {
+			t.Fatalf("Expected %s to load untouched, got %v", name, err)
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the turn handed on by hand
+		edited := strings.NewReplacer("TURN,0", "TURN,1", `"current": 0`, `"current": 1`).Replace(string(data))
+		if edited == string(data) {
+			t.Fatalf("Expected to find the seat to move in %s", data)
+		}
+		if err := os.WriteFile(name, []byte(edited), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&GameState{}).loadSave(name); err == nil || !strings.Contains(err.Error(), "-force") {
+			t.Errorf("Expected the edited %s refused, got %v", name, err)
+		}
+		forceLoad = true
+		if err := (&GameState{}).loadSave(name); err != nil {
+			t.Errorf("Expected -force to load the edited %s, got %v", name, err)
+		}
+		forceLoad = false
+
+		// cut short
+		if err := os.WriteFile(name, data[:len(data)*2/3], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&GameState{}).loadSave(name); err == nil || strings.Contains(err.Error(), "strconv") {
+			t.Errorf("Expected the cut %s refused plainly, got %v", name, err)
+		}
+	}
+}
+
+func TestCompressedFiles(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	gzipped := func(name string) bool {
+		data, err := os.ReadFile(name)
+		return err == nil && len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+	}
+	state := exampleStateForTests()
+	for _, name := range []string{"game.csv.gz", "game.json.gz"} {
+		name = filepath.Join(dir, name)
+		save := state.saveToCSV
+		if isJSONSave(name) {
+			save = state.saveToJSON
+		}
+		if err := save(name); err != nil {
+			t.Fatal(err)
+		}
+		if !gzipped(name) {
+			t.Errorf("Expected %s gzipped", name)
+		}
+		loaded := &GameState{}
+		if err := loaded.loadSave(name); err != nil {
+			t.Fatalf("Expected %s to load, got %v", name, err)
+		}
+		if loaded.Boards[0].Grid != state.Boards[0].Grid {
+			t.Errorf("Expected %s to give the board back, got %v", name, loaded.Boards[0].Grid)
+		}
+	}
+	if slots := listSaves(dir); len(slots) != 2 {
+		t.Errorf("Expected both compressed saves listed, got %+v", slots)
+	}
+
+	// the archive is appended to a game at a time
+	archive := filepath.Join(dir, "archive.jsonl.gz")
+	for i := 0; i < 2; i++ {
+		if err := appendArchive(archive, state.archiveEntry(0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if games, err := readArchive(archive); err != nil || len(games) != 2 || !gzipped(archive) {
+		t.Errorf("Expected two games read back from the gzipped archive, got %d (%v)", len(games), err)
+	}
+
+	// a move log reads back up to its last line while still open
+	moves := filepath.Join(dir, "moves.jsonl.gz")
+	log, err := newMoveLog(moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	state.MoveLog = log
+	state.logPosition("start")
+	state.logDraw(Move{Type: Draw, Tile: state.Table[0], FromTable: true})
+	events := 0
+	if _, err := walkMoveLog(moves, func(*GameState, LoggedMove, string) bool { events++; return false }); err != nil || events != 2 {
+		t.Errorf("Expected the start and the draw read from the open log, got %d (%v)", events, err)
+	}
+}
+
+func TestEvalExport(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "moves.jsonl")
+	log, err := newMoveLog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := exampleStateForTests()
+	state.MoveLog = log
+	state.logPosition("start")
+	state.removeTileFromTable(17)
+	state.logDraw(Move{Type: Draw, Tile: 17, FromTable: true})
+	state.applyMove(Move{Type: Place, Tile: 17, Cell: &Cell{R: 0, C: 1}})
+	log.Close()
+
+	out := filepath.Join(dir, "eval.csv")
+	export, err := newEvalExport(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeded := rng
+	if _, err := runReplay(name, 0, export); err != nil {
+		t.Fatal(err)
+	}
+	export.Close()
+	if rng != seeded {
+		t.Error("Expected the win chances not to use the game's random numbers")
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "turn,seat,player,move,") || !strings.Contains(lines[0], "win_Player 1") {
+		t.Fatalf("Expected a header and a row, got %q", lines)
+	}
+	row := strings.Split(lines[1], ",")
+	if row[3] != "place" || row[5] != "B1" || row[7] == "" || row[9] == "" {
+		t.Errorf("Expected the place at B1 with the best move beside it, got %q", row)
+	}
+	chances := 0.0
+	for _, f := range row[len(row)-2:] {
+		var p float64
+		if _, err := fmt.Sscan(f, &p); err != nil || p < 0 || p > 1 {
+			t.Fatalf("Expected win chances, got %q", row)
+		}
+		chances += p
+	}
+	if chances > 1.0001 {
+		t.Errorf("Expected win chances adding up to at most 1, got %q", row)
+	}
+}
+
+func TestBoardArt(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	art := `                 Turn 2, moves 1, draws 1
++-------------------- Table: 2 tiles --------------------+
+|                          7,20                          |
++----------------- Draw pile: 24 tiles ------------------+
+         Player 0                     Deep Tile
+     A     B     C     D          A     B     C     D
+  +-----+-----+-----+-----+    +-----+-----+-----+-----+
+1 |  1  |  .  |  +  |  .  |  1 |  2  |  .  |  .  |  .  |
+  +-----+-----+-----+-----+    +-----+-----+-----+-----+
+2 | <3> | 11  |  .  |  .  |  2 |  .  |  3  |  .  |  .  |
+  +-----+-----+-----+-----+    +-----+-----+-----+-----+
+3 |  .  |  .  | (14)|  .  |  3 |  .  |  .  |  7  |  .  |
+  +-----+-----+-----+-----+    +-----+-----+-----+-----+
+4 |  .  |  .  |  .  | 18  |  4 |  .  |  .  |  .  | 10  |
+  +-----+-----+-----+-----+    +-----+-----+-----+-----+
+`
+	want := "1.../3.11../..14./...18|2.../.3../..7./...10 7,20 0 classic"
+	unicode := strings.NewReplacer("|", "│", "-", "─").Replace(art)
+	dots := "table 7,20\n1 . . . | 2 . . .\n3 11 . . | . 3 . .\n. . 14 . | . . 7 .\n. . . 18 | . . . 10\n"
+	for name, in := range map[string]string{"ascii": art, "unicode": "\x1b[36m" + unicode + "\x1b[0m", "dots": dots} {
+		lines := readBoardArt(bufio.NewReader(strings.NewReader("\n" + in + "\nq\n")))
+		got, err := boardArtPosition(lines)
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", name, got, err, want)
+			continue
+		}
+		if err := (&GameState{}).decodePosition(got); err != nil {
+			t.Errorf("%s: the position read does not load: %v", name, err)
+		}
+	}
+
+	// a quick game's boards bring the quick game's tiles
+	got, err := boardArtPosition([]string{"1 . .", ". 5 .", ". . 12"})
+	if err != nil || got != "1../.5./..12 - 0 tiles1-12" {
+		t.Errorf("Expected a 3x3 board with tiles to 12, got %q, %v", got, err)
+	}
+	for _, bad := range [][]string{{"no boards here"}, {"1 | 5 | . | . |", "3 | . | . | . |"}, {"1 . . .", ". x . ."}} {
+		if _, err := boardArtPosition(bad); err == nil {
+			t.Errorf("Expected %q refused", bad)
+		}
+	}
+}
+
+func TestQuitWithTileDrawn(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	saved := reader
+	defer func() { reader = saved }()
+	for _, input := range []string{"q\n", ""} {
+		dir := t.TempDir()
+		state := exampleStateForTests()
+		state.AutosaveDir = dir
+		before := state.Boards[0].Grid
+		reader = bufio.NewReader(strings.NewReader(input))
+		tile, _ := state.popDraw()
+		state.promptPlacement(Move{Type: Draw, Tile: tile})
+		if !state.Stopped || state.Boards[0].Grid != before || len(state.History) != 0 {
+			t.Fatalf("Expected %q to stop the game with nothing played, got stopped=%v and %v", input, state.Stopped, state.Boards[0].Grid)
+		}
+		loaded := &GameState{}
+		if err := loaded.loadFromJSON(drawnPath(dir)); err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Pending == nil || loaded.Pending.Tile != tile {
+			t.Errorf("Expected %d kept to place on resuming, got %+v", tile, loaded.Pending)
+		}
+	}
+}
+
+func TestExportHTML(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "moves.jsonl")
+	log, err := newMoveLog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := exampleStateForTests()
+	state.MoveLog = log
+	state.logPosition("start")
+	var placed Move
+	for turn := 0; turn < 3; turn++ {
+		state.Turn, state.Current = turn, turn%2
+		tile, _ := state.popDraw()
+		state.logDraw(Move{Type: Draw, Tile: tile})
+		move := Move{Type: Discard, Tile: tile}
+		if turn == 1 {
+			if ranked := state.bestMoves(tile); len(ranked) > 0 {
+				move, placed = ranked[0], ranked[0]
+			}
+		}
+		state.applyMove(move)
+	}
+	log.Close()
+
+	out := filepath.Join(dir, "game.html")
+	if err := exportHTML(name, out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`const game = (.*);\n`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("Expected the game inside the page")
+	}
+	var page htmlReplay
+	if err := json.Unmarshal(m[1], &page); err != nil {
+		t.Fatal(err)
+	}
+	// the start, then one step a move; draws share their move's step
+	if len(page.Steps) != 4 {
+		t.Fatalf("Expected 4 steps, got %d", len(page.Steps))
+	}
+	if len(page.Players) != 2 || page.Players[1] != state.seatLabel(1) {
+		t.Errorf("Expected both players named, got %v", page.Players)
+	}
+	if placed.Cell == nil {
+		t.Fatal("Expected a move to place the tile")
+	}
+	step := page.Steps[2]
+	if want := []int{1, placed.Cell.R, placed.Cell.C}; fmt.Sprint(step.Last) != fmt.Sprint(want) {
+		t.Errorf("Expected the move at %v marked, got %v", want, step.Last)
+	}
+	if step.Boards[1][placed.Cell.R][placed.Cell.C] != tileLabel(placed.Tile) {
+		t.Errorf("Expected %s on the board, got %v", tileLabel(placed.Tile), step.Boards[1])
+	}
+	if !strings.Contains(step.Eval, "the engine's best move") {
+		t.Errorf("Expected the engine's best move judged so, got %q", step.Eval)
+	}
+	if !strings.Contains(step.Said, "draws") || !strings.HasPrefix(page.Steps[1].Eval, "Discarded") {
+		t.Errorf("Expected the draw told and the discard judged, got %q and %q", step.Said, page.Steps[1].Eval)
+	}
+}
+
+func TestServeGame(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	srv := httptest.NewServer(newGameServer().handler())
+	defer srv.Close()
+	call := func(method, path, body string, want int, v any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			data, _ := io.ReadAll(resp.Body)
+			t.Fatalf("%s %s %s: expected status %d, got %d: %s", method, path, body, want, resp.StatusCode, data)
+		}
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var created createdGame
+	call("POST", "/games", `{"names": ["Ann"], "seed": 5}`, http.StatusCreated, &created)
+	game := created.servedState
+	if len(game.Seats) != 2 || game.Seats[0].Name != "Ann" || !game.Seats[1].Computer || game.Current != 0 {
+		t.Fatalf("Expected Ann to play a computer and move first, got %+v", game)
+	}
+	if len(created.Tokens) != 2 || created.Tokens[0] == "" || created.Tokens[1] != "" {
+		t.Fatalf("Expected a token for Ann's seat alone, got %q", created.Tokens)
+	}
+	token := created.Tokens[0]
+	move := func(body string) string {
+		return strings.Replace(body, "{", fmt.Sprintf(`{"token": %q, `, token), 1)
+	}
+	call("POST", "/games/"+game.ID+"/moves", `{"type": "draw"}`, http.StatusForbidden, nil)
+	call("POST", "/games/"+game.ID+"/moves", `{"type": "draw", "token": "guess"}`, http.StatusForbidden, nil)
+	call("POST", "/games/"+game.ID+"/moves", move(`{"type": "place", "cell": "A1"}`), http.StatusConflict, nil)
+	call("POST", "/games/"+game.ID+"/moves", move(`{"type": "take", "tile": 21}`), http.StatusBadRequest, nil)
+	call("GET", "/games/404", "", http.StatusNotFound, nil)
+
+	// Ann follows the engine's advice, the computer answering each move
+	for turn := 0; !game.Over; turn++ {
+		if turn > maxHeadlessTurns {
+			t.Fatal("Expected the game to end")
+		}
+		var advice servedAdvice
+		call("GET", "/games/"+game.ID+"/recommendations", "", http.StatusOK, &advice)
+		if advice.Take != 0 {
+			call("POST", "/games/"+game.ID+"/moves", move(fmt.Sprintf(`{"type": "take", "tile": %d}`, advice.Take)), http.StatusOK, &game)
+		} else {
+			call("POST", "/games/"+game.ID+"/moves", move(`{"type": "draw"}`), http.StatusOK, &game)
+			if game.Over {
+				break
+			}
+			call("GET", "/games/"+game.ID+"/recommendations", "", http.StatusOK, &advice)
+		}
+		if game.Drawn == 0 {
+			t.Fatalf("Expected a tile drawn, got %+v", game)
+		}
+		if len(advice.Moves) == 0 {
+			call("POST", "/games/"+game.ID+"/moves", move(`{"type": "discard"}`), http.StatusOK, &game)
+		} else {
+			call("POST", "/games/"+game.ID+"/moves", move(fmt.Sprintf(`{"type": "place", "cell": %q}`, advice.Moves[0].Cell)), http.StatusOK, &game)
+		}
+		if !game.Over && (game.Current != 0 || game.Drawn != 0) {
+			t.Fatalf("Expected Ann to play next with no tile drawn, got %+v", game)
+		}
+	}
+	if len(game.History) < 2 || game.History[1].Seat != 1 {
+		t.Errorf("Expected the computer's moves in the history, got %+v", game.History)
+	}
+	call("POST", "/games/"+game.ID+"/moves", move(`{"type": "draw"}`), http.StatusConflict, nil)
+
+	call("POST", "/games", `{"position": "1.../.5../..9./...13 - 0 classic", "humans": 0}`, http.StatusCreated, &game)
+	if !game.Over || game.Winner != -1 && game.Winner != 0 {
+		t.Errorf("Expected a computer's solo game played out, got over %v winner %d", game.Over, game.Winner)
+	}
+	call("POST", "/games", `{"players": 9}`, http.StatusBadRequest, nil)
+	small := "1../.5./..9|2../.6./..10 - 0 classic"
+	call("POST", "/games", fmt.Sprintf(`{"position": %q}`, small), http.StatusCreated, &game)
+	if !strings.HasPrefix(game.Position, "1../.5./..9|2../.6./..10 ") || len(game.Boards[0]) != 3 {
+		t.Errorf("Expected the new game's position at its own 3 by 3 size, got %q", game.Position)
+	}
+	call("POST", "/games", fmt.Sprintf(`{"position": "1.../.5../..9./...13 - 0 classic+dist%s"}`, strings.Repeat("1-20,", 1000)), http.StatusBadRequest, nil)
+
+	// Ann leaves with a tile drawn and Bob hands her seat to the computer
+	call("POST", "/games", `{"players": 3, "humans": 2, "names": ["Ann", "Bob"], "seed": 3}`, http.StatusCreated, &created)
+	id, ann, bob := created.ID, created.Tokens[0], created.Tokens[1]
+	call("POST", "/games/"+id+"/moves", fmt.Sprintf(`{"type": "draw", "token": %q}`, ann), http.StatusOK, &game)
+	backfill := "/games/" + id + "/seats/0/backfill"
+	call("POST", backfill, `{"strategy": "cautious", "token": "guess"}`, http.StatusForbidden, nil)
+	call("POST", backfill, fmt.Sprintf(`{"strategy": "reckless", "token": %q}`, bob), http.StatusBadRequest, nil)
+	call("POST", "/games/"+id+"/seats/2/backfill", fmt.Sprintf(`{"token": %q}`, bob), http.StatusConflict, nil)
+	call("POST", "/games/"+id+"/seats/9/backfill", fmt.Sprintf(`{"token": %q}`, bob), http.StatusBadRequest, nil)
+	call("POST", backfill, fmt.Sprintf(`{"strategy": "cautious", "token": %q}`, bob), http.StatusOK, &game)
+	if !game.Seats[0].Computer || !game.Over && (game.Current != 1 || game.Drawn != 0) {
+		t.Fatalf("Expected the computer to play Ann's seat through to Bob's turn, got %+v", game)
+	}
+	if len(game.History) == 0 || game.History[0].Seat != 0 {
+		t.Errorf("Expected the computer's move for Ann's seat in the history, got %+v", game.History)
+	}
+	call("GET", "/games/"+id, "", http.StatusOK, &game)
+	if len(game.Backfills) != 1 || game.Backfills[0].Seat != 0 || game.Backfills[0].Strategy != "cautious" || game.Backfills[0].At.IsZero() {
+		t.Errorf("Expected Ann's seat recorded as backfilled by cautious, got %+v", game.Backfills)
+	}
+	call("POST", backfill, fmt.Sprintf(`{"token": %q}`, bob), http.StatusConflict, nil)
+	call("POST", "/games/"+id+"/seats/1/backfill", fmt.Sprintf(`{"token": %q}`, ann), http.StatusForbidden, nil)
+}
+
+func TestServedGamesExpire(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	s := newGameServer()
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+	post := func(body string, want int) createdGame {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/games", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("POST /games %s: expected status %d, got %s", body, want, resp.Status)
+		}
+		var created createdGame
+		json.NewDecoder(resp.Body).Decode(&created)
+		return created
+	}
+
+	over := post(`{"position": "1.../.5../..9./...13 - 0 classic", "humans": 0}`, http.StatusCreated)
+	playing := post(`{"seed": 5}`, http.StatusCreated)
+	now := time.Now()
+	s.games[over.ID].used = now.Add(-servedOverIdle - time.Minute)
+	s.games[playing.ID].used = now.Add(-servedOverIdle - time.Minute)
+	s.sweep(now)
+	if s.games[over.ID] != nil || s.games[playing.ID] == nil {
+		t.Fatalf("Expected the idle finished game forgotten and the game in play kept, got %v", s.games)
+	}
+	if resp, err := http.Get(srv.URL + "/games/" + playing.ID); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the game in play served, got %v, %v", resp, err)
+	}
+	if used := s.games[playing.ID].used; !used.After(now) {
+		t.Errorf("Expected a request to mark the game used, got %v", used)
+	}
+	s.games[playing.ID].used = now.Add(-servedIdle - time.Minute)
+	s.games[playing.ID].sockets[&gameSocket{seat: -1}] = true
+	s.sweep(now)
+	if s.games[playing.ID] == nil {
+		t.Fatal("Expected a game with a socket connected kept")
+	}
+	clear(s.games[playing.ID].sockets)
+	s.sweep(now)
+	if s.games[playing.ID] != nil {
+		t.Fatal("Expected a game left alone for hours forgotten")
+	}
+
+	for i := range maxServedGames {
+		s.games[fmt.Sprint("full", i)] = &servedGame{used: now, sockets: map[*gameSocket]bool{}}
+	}
+	post(`{"seed": 5}`, http.StatusServiceUnavailable)
+	delete(s.games, "full0")
+	post(`{"seed": 5}`, http.StatusCreated)
+}
+
+func TestGameSockets(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	// the handshake's example from RFC 6455
+	if got := wsAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Expected the RFC's accept key, got %q", got)
+	}
+	srv := httptest.NewServer(newGameServer().handler())
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/games", "application/json", strings.NewReader(`{"players": 3, "humans": 2, "names": ["Ann", "Bob"], "seed": 3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var game createdGame
+	json.NewDecoder(resp.Body).Decode(&game)
+	resp.Body.Close()
+	seat := func(n int) string { return fmt.Sprintf("?seat=%d&token=%s", n, game.Tokens[n]) }
+
+	dial := func(query string) (*wsConn, *http.Response) {
+		t.Helper()
+		conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		fmt.Fprintf(conn, "GET /games/%s/socket%s HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", game.ID, query)
+		r := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			return nil, resp
+		}
+		if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Fatalf("Expected the accept key, got %v", resp.Header)
+		}
+		return &wsConn{conn: conn, r: r, client: true}, resp
+	}
+	next := func(c *wsConn) socketMessage {
+		t.Helper()
+		data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m socketMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	send := func(c *wsConn, msg string) {
+		t.Helper()
+		if err := c.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, resp := dial("?seat=0&token=guess"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a seat refused without its token, got %s", resp.Status)
+	}
+	ann, _ := dial(seat(0))
+	bob, _ := dial(seat(1))
+	watcher, err := dialWebSocket("ws"+strings.TrimPrefix(srv.URL, "http")+"/games/"+game.ID+"/socket", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	for _, c := range []*wsConn{ann, bob, watcher} {
+		if m := next(c); m.Type != "state" || m.State.Current != 0 {
+			t.Fatalf("Expected the game on connecting, got %+v", m)
+		}
+	}
+	if _, resp := dial(seat(1)); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected a taken seat refused, got %s", resp.Status)
+	}
+	if _, resp := dial("?seat=2"); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected the computer's seat refused, got %s", resp.Status)
+	}
+
+	send(bob, `{"type": "draw"}`)
+	if m := next(bob); m.Type != "error" || !strings.Contains(m.Error, "Ann's turn") {
+		t.Errorf("Expected Bob told to wait for Ann, got %+v", m)
+	}
+	send(watcher, `{"type": "draw"}`)
+	if m := next(watcher); m.Type != "error" {
+		t.Errorf("Expected the watcher's move refused, got %+v", m)
+	}
+	resp, err = http.Post(srv.URL+"/games/"+game.ID+"/moves", "application/json", strings.NewReader(fmt.Sprintf(`{"type": "draw", "token": %q}`, game.Tokens[0])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected Ann's seat closed to plain HTTP moves, got %s", resp.Status)
+	}
+
+	send(ann, `{"type": "draw"}`)
+	var drawn int
+	for _, c := range []*wsConn{ann, bob, watcher} {
+		m := next(c)
+		if m.Type != "state" || m.State.Drawn == 0 {
+			t.Fatalf("Expected everyone to see Ann's tile, got %+v", m)
+		}
+		drawn = m.State.Drawn
+	}
+	send(ann, `{"type": "discard"}`)
+	for _, c := range []*wsConn{ann, bob, watcher} {
+		m := next(c)
+		if len(m.Moves) != 1 || m.Moves[0].Type != "discard" || m.Moves[0].Tile != drawn || m.State.Current != 1 {
+			t.Fatalf("Expected Ann's discard and Bob to play, got %+v", m)
+		}
+	}
+
+	// a seat comes free when its socket closes
+	ann.writeFrame(wsClose, nil)
+	if _, err := ann.ReadMessage(); err != errWSClosed {
+		t.Fatalf("Expected the socket closed, got %v", err)
+	}
+	for i := 0; ; i++ {
+		again, resp := dial(seat(0))
+		if again != nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Expected Ann's seat freed, got %s", resp.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// a dropped client's moves are ignored, even on its seat's turn
+	server := newGameServer()
+	g, err := server.newGame(newGameRequest{Seed: new(int64)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gone := &gameSocket{seat: 0, out: make(chan []byte, socketBacklog)}
+	g.sockets[gone] = true
+	g.drop(gone)
+	g.socketMove(gone, []byte(`{"type": "draw"}`))
+	if g.state.Pending != nil {
+		t.Errorf("Expected a dropped socket's move ignored, got %+v drawn", g.state.Pending)
+	}
+
+	// a watcher joining a 3 by 3 game is shown it at that size
+	resp, err = http.Post(srv.URL+"/games", "application/json", strings.NewReader(`{"position": "1../.5./..9|2../.6./..10 - 0 classic"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var small createdGame
+	json.NewDecoder(resp.Body).Decode(&small)
+	resp.Body.Close()
+	c, err := dialWebSocket("ws"+strings.TrimPrefix(srv.URL, "http")+"/games/"+small.ID+"/socket", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if m := next(c); m.State == nil || !strings.HasPrefix(m.State.Position, "1../.5./..9|2../.6./..10 ") {
+		t.Errorf("Expected the first state at the game's own size, got %+v", m.State)
+	}
+}
+
+func TestEngineProtocol(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	pos := "5...9/.7../..10./...12|6.../.8../..11./...13 3,15 0 classic"
+	in := strings.Join([]string{
+		"uln",
+		"isready",
+		"go",
+		"position " + pos + " tile 4",
+		"go",
+		"position " + pos,
+		"go",
+		"position 5...9/.7../..10./...12|6.../.8../..11./...13 13 0 classic tile 13", // both 13s are in sight
+		"setoption name strategy value cautious",
+		"frobnicate",
+		"quit",
+		"isready",
+	}, "\n") + "\n"
+	var out strings.Builder
+	runEngine(bufio.NewReader(strings.NewReader(in)), &out)
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if !strings.HasPrefix(line, "info move ") {
+			lines = append(lines, line)
+		}
+	}
+	want := []string{
+		"id name Unlucky Numbers",
+		"", // the strategy option
+		"ulnok",
+		"readyok",
+		"info string no position to move in",
+		"bestmove swap A1",
+		"bestmove take 3",
+		"info string invalid position: every 13 is in sight already",
+		"info string unknown command \"frobnicate\"",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines besides the moves considered, got %q", len(want), lines)
+	}
+	for i, w := range want {
+		if w != "" && lines[i] != w {
+			t.Errorf("Line %d: expected %q, got %q", i, w, lines[i])
+		}
+	}
+	if !strings.Contains(out.String(), "info move swap A1 score ") {
+		t.Errorf("Expected the moves considered listed with their scores, got %q", out.String())
+	}
+}
+
+// protoFields reads each message's field numbers by name from unlucky.proto,
+// so the gRPC test speaks the schema clients are generated from rather than
+// grpc.go's own numbers.
+func protoFields(t *testing.T) map[string]map[string]int {
+	t.Helper()
+	data, err := os.ReadFile("unlucky.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := map[string]map[string]int{}
+	field := regexp.MustCompile(`^\s*(?:repeated\s+|optional\s+)?\w+\s+(\w+)\s*=\s*(\d+);`)
+	var current map[string]int
+	for _, line := range strings.Split(string(data), "\n") {
+		if m := regexp.MustCompile(`^message (\w+) \{`).FindStringSubmatch(line); m != nil {
+			current = map[string]int{}
+			messages[m[1]] = current
+			continue
+		}
+		if strings.HasPrefix(line, "}") {
+			current = nil
+			continue
+		}
+		if m := field.FindStringSubmatch(line); m != nil && current != nil {
+			n, _ := strconv.Atoi(m[2])
+			current[m[1]] = n
+		}
+	}
+	return messages
+}
+
+func TestGRPCService(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	proto := protoFields(t)
+	if len(proto["Game"]) != 13 || len(proto["Move"]) != 6 || len(proto["PlayRequest"]) != 6 {
+		t.Fatalf("Expected the Game, Move and PlayRequest fields read from unlucky.proto, got %v", proto)
+	}
+	srv := httptest.NewUnstartedServer(newGameServer().handler())
+	srv.Config.Protocols = serveProtocols()
+	srv.Start()
+	defer srv.Close()
+	h2c := new(http.Protocols)
+	h2c.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: h2c}}
+	// decode names a message's fields as unlucky.proto declares them,
+	// failing on any field number the schema does not have.
+	decode := func(message string, data []byte) map[string][]pbField {
+		t.Helper()
+		list, err := pbFields(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := map[int]string{}
+		for name, num := range proto[message] {
+			names[num] = name
+		}
+		fields := map[string][]pbField{}
+		for _, f := range list {
+			name, ok := names[f.num]
+			if !ok {
+				t.Fatalf("%s: field %d is not in unlucky.proto", message, f.num)
+			}
+			fields[name] = append(fields[name], f)
+		}
+		return fields
+	}
+	call := func(method, reply string, req pbBuffer, want int) map[string][]pbField {
+		t.Helper()
+		body := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(req)))
+		resp, err := client.Post(srv.URL+"/unlucky.Engine/"+method, "application/grpc", strings.NewReader(string(append(body, req...))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		if resp.ProtoMajor != 2 {
+			t.Fatalf("Expected HTTP/2, got %s", resp.Proto)
+		}
+		if got := resp.Trailer.Get("Grpc-Status"); got != fmt.Sprint(want) {
+			t.Fatalf("%s: expected status %d, got %q: %s", method, want, got, resp.Trailer.Get("Grpc-Message"))
+		}
+		if want != grpcOK {
+			return nil
+		}
+		if len(data) < 5 || int(binary.BigEndian.Uint32(data[1:5])) != len(data)-5 {
+			t.Fatalf("%s: expected one framed message, got %x", method, data)
+		}
+		return decode(reply, data[5:])
+	}
+	token := ""
+	game := func(id, moveType, cell string) pbBuffer {
+		var b pbBuffer
+		b.string(proto["PlayRequest"]["id"], id)
+		b.string(proto["PlayRequest"]["type"], moveType)
+		b.string(proto["PlayRequest"]["cell"], cell)
+		b.string(proto["PlayRequest"]["token"], token)
+		return b
+	}
+
+	var req pbBuffer
+	req.string(proto["NewGameRequest"]["names"], "Ann")
+	req.int(proto["NewGameRequest"]["seed"], 5)
+	g := call("NewGame", "Game", req, grpcOK)
+	id := string(g["id"][0].data)
+	if len(g["seats"]) != 2 || g["size"][0].n != uint64(standardBoardSize) || len(g["boards"]) != 2 {
+		t.Fatalf("Expected two seats at %d by %d boards, got %+v", standardBoardSize, standardBoardSize, g)
+	}
+	if cells := decode("Board", g["boards"][0].data)["cells"]; len(cells) != 1 {
+		t.Errorf("Expected a board's cells packed, got %+v", cells)
+	}
+	if tokens := g["tokens"]; len(tokens) != 2 || len(tokens[0].data) == 0 || len(tokens[1].data) != 0 {
+		t.Fatalf("Expected a token for Ann's seat alone, got %+v", tokens)
+	}
+	if again := call("GetGame", "Game", game(id, "", ""), grpcOK); len(again["tokens"]) != 0 {
+		t.Errorf("Expected the tokens left out of the game after its creation, got %+v", again["tokens"])
+	}
+	call("Play", "Game", game(id, "draw", ""), grpcPermissionDenied)
+	token = string(g["tokens"][0].data)
+	if seat := decode("Seat", g["seats"][0].data); string(seat["name"][0].data) != "Ann" || len(seat["computer"]) != 0 {
+		t.Errorf("Expected Ann in the first seat, got %+v", seat)
+	}
+
+	legal := call("LegalMoves", "Moves", game(id, "", ""), grpcOK)["moves"]
+	if first := decode("Move", legal[0].data); string(first["type"][0].data) != "draw" {
+		t.Errorf("Expected a draw among the moves before drawing, got %+v", first)
+	}
+	if g := call("Play", "Game", game(id, "draw", ""), grpcOK); len(g["drawn"]) != 1 {
+		t.Fatalf("Expected a tile drawn, got %+v", g)
+	}
+	place, discard := "", false
+	for _, m := range call("LegalMoves", "Moves", game(id, "", ""), grpcOK)["moves"] {
+		move := decode("Move", m.data)
+		if string(move["type"][0].data) == "discard" {
+			discard = true
+		}
+		if cell := move["cell"]; len(cell) > 0 && place == "" {
+			place = string(cell[0].data)
+		}
+	}
+	if place == "" || !discard {
+		t.Fatalf("Expected cells to place at and the discard, got %q and %v", place, discard)
+	}
+	advice := call("Recommend", "Advice", game(id, "", ""), grpcOK)
+	if len(advice["moves"]) == 0 {
+		t.Fatalf("Expected moves recommended, got %+v", advice)
+	}
+	if best := decode("Move", advice["moves"][0].data); len(best["score"]) != 1 {
+		t.Errorf("Expected the recommended move scored, got %+v", best)
+	}
+	call("Play", "Game", game(id, "place", "Z9"), grpcInvalidArgument)
+	after := call("Play", "Game", game(id, "place", place), grpcOK)
+	if len(after["history"]) == 0 || len(after["position"]) != 1 {
+		t.Fatalf("Expected the move in the history beside the position, got %+v", after)
+	}
+	if last := decode("Move", after["history"][len(after["history"])-1].data); len(last["type"]) != 1 {
+		t.Errorf("Expected the last move's type, got %+v", last)
+	}
+	call("GetGame", "Game", game("404", "", ""), grpcNotFound)
+	req = nil
+	req.string(proto["NewGameRequest"]["position"], "1../.5./..9|2../.6./..10 - 0 classic")
+	small := call("NewGame", "Game", req, grpcOK)
+	if pos := string(small["position"][0].data); small["size"][0].n != 3 || !strings.HasPrefix(pos, "1../.5./..9|2../.6./..10 ") {
+		t.Errorf("Expected a new 3 by 3 game's position at its size, got %q", pos)
+	}
+	call("Resign", "Game", game(id, "", ""), grpcUnimplemented)
+}
+
+func TestDiscordBot(t *testing.T) {
+	defer func() { BoardSize = standardBoardSize }()
+	bot := newDiscordBot()
+	ann := discordUser{ID: "1", Username: "ann", GlobalName: "Ann"}
+	bob := discordUser{ID: "2", Username: "bob"}
+	say := func(u discordUser, content string, mentions ...discordUser) string {
+		return bot.handle(discordMessage{ChannelID: "general", Content: content, Author: u, Mentions: mentions})
+	}
+
+	if reply := say(ann, "hello there"); reply != "" {
+		t.Errorf("Expected other talk left alone, got %q", reply)
+	}
+	if reply := say(ann, "!un draw"); !strings.Contains(reply, "No game") {
+		t.Errorf("Expected no game yet, got %q", reply)
+	}
+	reply := say(ann, "!un new", bob, discordUser{ID: "3", Username: "somebot", Bot: true})
+	if !strings.Contains(reply, "New game: Ann vs bob.") || !strings.Contains(reply, "```") || !strings.Contains(reply, "<@1>, your turn") {
+		t.Fatalf("Expected Ann and Bob's game shown with Ann to play, got %q", reply)
+	}
+	if reply := say(bob, "!un new"); !strings.Contains(reply, "under way") {
+		t.Errorf("Expected a second game in the channel refused, got %q", reply)
+	}
+	if reply := say(bob, "!un draw"); reply != "It is Ann's turn." {
+		t.Errorf("Expected Bob told to wait, got %q", reply)
+	}
+	if reply := say(ann, "!un draw"); !strings.Contains(reply, "<@1>, place your") {
+		t.Fatalf("Expected Ann asked to place her tile, got %q", reply)
+	}
+	hint := say(ann, "!un hint")
+	cell := regexp.MustCompile(`1\. \w+ ([A-Z]\d+)`).FindStringSubmatch(hint)
+	if cell == nil {
+		t.Fatalf("Expected a numbered hint, got %q", hint)
+	}
+	reply = say(ann, "!un "+cell[1])
+	if !strings.Contains(reply, "Ann ") || !strings.Contains(reply, cell[1]) || !strings.Contains(reply, "<@2>, your turn") {
+		t.Errorf("Expected Ann's move told and Bob to play, got %q", reply)
+	}
+	if reply := say(bob, "!un frobnicate"); !strings.Contains(reply, "not a command") {
+		t.Errorf("Expected an unknown command explained, got %q", reply)
+	}
+	if reply := say(discordUser{ID: "9", Username: "eve"}, "!un stop"); !strings.Contains(reply, "Only the game's players") {
+		t.Errorf("Expected a bystander kept from stopping the game, got %q", reply)
+	}
+	if reply := say(bob, "!un stop"); reply != "bob stopped the game." {
+		t.Errorf("Expected Bob to stop the game, got %q", reply)
+	}
+	if len(bot.server.games) != 0 {
+		t.Errorf("Expected the stopped game gone from the server, got %v", bot.server.games)
+	}
+	if reply := say(ann, "!un new 2"); !strings.Contains(reply, "New game: Ann vs ") || strings.Count(reply, " vs ") != 2 {
+		t.Errorf("Expected Ann against two computers, got %q", reply)
+	}
+
+	// Ann plays on by discarding until the game ends, which frees it too
+	for turn := 0; !bot.games["general"].game.over; turn++ {
+		if turn > maxHeadlessTurns {
+			t.Fatal("Expected the game to end")
+		}
+		if reply := say(ann, "!un draw"); !bot.games["general"].game.over && !strings.Contains(reply, "place your") {
+			t.Fatalf("Expected Ann to hold a tile, got %q", reply)
+		}
+		if !bot.games["general"].game.over {
+			say(ann, "!un discard")
+		}
+	}
+	if len(bot.server.games) != 0 {
+		t.Errorf("Expected the finished game gone from the server, got %v", bot.server.games)
+	}
+	if reply := say(ann, "!un board"); !strings.Contains(reply, "Game over") {
+		t.Errorf("Expected the finished game still shown in the channel, got %q", reply)
+	}
+}
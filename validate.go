@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A save is checked once loaded, so a file edited by hand or cut short does
+// not start a game no one could have reached: boards that break the
+// ordering rule, holes that differ between boards or more copies of a tile
+// than the game has are listed one by one instead.
+
+// checkPosition reports every way the position breaks the rules. step is
+// how much a tile must rise along a row or column, state.step() unless the
+// rules are not known yet.
+func (state *GameState) checkPosition(step int) error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	holes := map[Cell]bool{}
+	for _, h := range state.Holes {
+		holes[h] = true
+	}
+	seen := map[int]int{}
+	for i, b := range state.Boards {
+		for r := 0; r < BoardSize; r++ {
+			for c := 0; c < BoardSize; c++ {
+				cell := Cell{R: r, C: c}
+				if (b.Grid[r][c] == Blocked) != holes[cell] {
+					add("board %d: %s does not match the holes of the game", i, cell)
+				}
+				if v := b.Grid[r][c]; isTile(v) {
+					seen[v]++
+				}
+			}
+		}
+		for _, line := range boardLines() {
+			last, at := 0, Cell{}
+			for _, cell := range line {
+				v := b.Grid[cell.R][cell.C]
+				if !isTile(v) {
+					continue
+				}
+				if last != 0 && v < last+step {
+					add("board %d: %d at %s and %d at %s do not increase", i, last, at, v, cell)
+				}
+				last, at = v, cell
+			}
+		}
+		if state.DiagonalRule && !diagonalIncreases(b) {
+			add("board %d: the diagonal does not increase", i)
+		}
+		for _, v := range b.Hand {
+			seen[v]++
+		}
+	}
+	for _, v := range state.Table {
+		seen[v]++
+	}
+	if state.Pending != nil {
+		seen[state.Pending.Tile]++
+	}
+	if !state.Analyze {
+		// analysis keeps no real pile, only the tiles it is told about
+		for _, v := range state.Draw {
+			seen[v]++
+		}
+	}
+	copies := state.tileCopies(len(state.Boards))
+	for t := 1; t <= state.maxTile(); t++ {
+		if seen[t] > copies[t] {
+			add("tile %d is in play %d times, but the game has %d", t, seen[t], copies[t])
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the position breaks the rules:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// boardLines lists the cells of every row, left to right, and every column,
+// top to bottom.
+func boardLines() [][]Cell {
+	var lines [][]Cell
+	for i := 0; i < BoardSize; i++ {
+		row, col := make([]Cell, BoardSize), make([]Cell, BoardSize)
+		for j := 0; j < BoardSize; j++ {
+			row[j], col[j] = Cell{R: i, C: j}, Cell{R: j, C: i}
+		}
+		lines = append(lines, row, col)
+	}
+	return lines
+}
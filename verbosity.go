@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Verbosity sets how much the program says about the computer players:
+// -quiet keeps their turns silent apart from the boards, -verbose adds the
+// numbers behind each placement.
+type Verbosity int
+
+const (
+	Quiet Verbosity = iota - 1
+	Normal
+	Verbose
+)
+
+var verbosity = Normal
+
+// explainedMoves is how many of the best placements -verbose breaks down.
+const explainedMoves = 3
+
+// narrate prints a line about what a computer player does, unless quiet,
+// translating format as tr does.
+func narrate(format string, args ...any) {
+	if verbosity > Quiet {
+		fmt.Printf(tr(format), args...)
+	}
+}
+
+// explainScores breaks down the scores of tile's best placements on the
+// current board when verbose: the base score and the chances of filling
+// the row and column around the cell, times any rule factors.
+func (state *GameState) explainScores(w io.Writer, tile int) {
+	if verbosity < Verbose {
+		return
+	}
+	moves := state.boardMoves(tile)
+	if len(moves) == 0 {
+		fmt.Fprintf(w, "  %s fits nowhere on seat %d's board.\n", tileLabel(tile), state.Current)
+		return
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Score > moves[j].Score })
+	if len(moves) > explainedMoves {
+		moves = moves[:explainedMoves]
+	}
+	for _, m := range moves {
+		r, c := m.Cell.R, m.Cell.C
+		if tile == Wildcard {
+			fmt.Fprintf(w, "  %s: wildcard score %.2f\n", m.Cell, m.Score)
+			continue
+		}
+		terms := []string{
+			fmt.Sprintf("base %.2f", baseScore(tile, r, c, state.maxTile())),
+			fmt.Sprintf("row odds %.2f", state.futureRowProbability(r, c)),
+			fmt.Sprintf("column odds %.2f", state.futureColProbability(r, c)),
+		}
+		if state.Heuristics.RiskAware {
+			terms = append(terms, fmt.Sprintf("risk %.2f", state.riskFactor(tile, r, c)))
+		}
+		if state.BrunoVariant {
+			terms = append(terms, fmt.Sprintf("Bruno %.2f", state.brunoFactor(tile, r, c)))
+		}
+		if state.DiagonalRule && r == c {
+			terms = append(terms, fmt.Sprintf("diagonal %.2f", state.diagonalFactor(tile, r)))
+		}
+		fmt.Fprintf(w, term.text("  %s %s: %s = %.2f\n"), map[MoveType]string{Place: "place", Swap: "swap"}[m.Type], m.Cell, strings.Join(terms, " × "), m.Score)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "strconv"
+
+// Wildcard is the clover tile: it fits in any cell regardless of its
+// neighbours, and the neighbours ignore it. A numbered tile can later replace
+// it, sending the wildcard to the table like any swapped tile.
+const Wildcard = -1
+
+// wildcardLabel is how a wildcard is written on screen and in saves.
+const wildcardLabel = "*"
+
+// isTile reports whether v is a numbered tile, as opposed to an empty cell,
+// a wildcard or a hole.
+func isTile(v int) bool {
+	return v > 0
+}
+
+// tileLabel renders a cell or tile value; empty cells are ".".
+func tileLabel(v int) string {
+	switch v {
+	case 0:
+		return "."
+	case Wildcard:
+		return wildcardLabel
+	case Blocked:
+		return blockedLabel
+	}
+	return strconv.Itoa(v)
+}
+
+// wildcardScore values putting a wildcard at (r,c): the fewer unseen tiles
+// could fill the cell, the more the wildcard is worth there.
+func (state *GameState) wildcardScore(r, c int) float64 {
+	lo1, hi1 := state.rowConstraints(r, c)
+	lo2, hi2 := state.colConstraints(r, c)
+	p := state.remainingProbability(max(lo1, lo2), min(hi1, hi2))
+	if max(lo1, lo2) >= min(hi1, hi2) {
+		p = 0 // only a wildcard can go here
+	}
+	return 100 * (1 - p)
+}